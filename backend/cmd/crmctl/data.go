@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"crm-project/internal/repository/postgres"
+)
+
+func runSeed(a *app, args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	fs.Parse(args)
+
+	if err := a.connect(); err != nil {
+		return fail(err)
+	}
+	added, err := postgres.NewAdminRepo(a.db).SeedLookups(context.Background())
+	if err != nil {
+		return fail(err)
+	}
+	for _, seed := range postgres.LookupSeeds {
+		fmt.Printf("%-15s %d added\n", seed.Table, added[seed.Table])
+	}
+	return 0
+}
+
+func runReassign(a *app, args []string) int {
+	fs := flag.NewFlagSet("reassign", flag.ExitOnError)
+	from := fs.String("from", "", "username whose records are moved (required)")
+	to := fs.String("to", "", "username who takes the records over (required)")
+	fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		fs.Usage()
+		return 2
+	}
+	if *from == *to {
+		return fail(fmt.Errorf("-from and -to are both %q", *from))
+	}
+	if err := a.connect(); err != nil {
+		return fail(err)
+	}
+	ctx := context.Background()
+	users := postgres.NewUserRepo(a.db)
+
+	fromUser, err := findUser(ctx, users, *from)
+	if err != nil {
+		return fail(err)
+	}
+	toUser, err := findUser(ctx, users, *to)
+	if err != nil {
+		return fail(err)
+	}
+	counts, err := postgres.NewAdminRepo(a.db).ReassignRecords(ctx, fromUser.ID, toUser.ID)
+	if err != nil {
+		return fail(fmt.Errorf("could not reassign records: %w", err))
+	}
+	fmt.Printf("moved from %q to %q: %d contacts, %d leads, %d deals, %d open tasks, %d upcoming events\n",
+		fromUser.Username, toUser.Username, counts.Contacts, counts.Leads, counts.Deals, counts.Tasks, counts.Events)
+	return 0
+}
+
+// runBackfillContacts gives contacts created before created_by existed an
+// owner. The server used to do this on every start with the newest user; here
+// the operator picks the owner.
+func runBackfillContacts(a *app, args []string) int {
+	fs := flag.NewFlagSet("backfill-contacts", flag.ExitOnError)
+	owner := fs.String("user", "", "username set as created_by (required)")
+	fs.Parse(args)
+
+	if *owner == "" {
+		fs.Usage()
+		return 2
+	}
+	if err := a.connect(); err != nil {
+		return fail(err)
+	}
+	ctx := context.Background()
+
+	user, err := findUser(ctx, postgres.NewUserRepo(a.db), *owner)
+	if err != nil {
+		return fail(err)
+	}
+	n, err := postgres.NewAdminRepo(a.db).BackfillContactCreatedBy(ctx, user.ID)
+	if err != nil {
+		return fail(fmt.Errorf("could not backfill contacts: %w", err))
+	}
+	fmt.Printf("set created_by to %q on %d contacts\n", user.Username, n)
+	return 0
+}
@@ -0,0 +1,106 @@
+// Command crmctl performs administrative tasks against the CRM database:
+// bootstrapping users, seeding lookup tables and one-off data fixes. It reads
+// the same config.yml as the server and goes through the same repositories.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"crm-project/internal/config"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+const usage = `usage: crmctl [-config path] [-database-url url] <command> [flags]
+
+commands:
+  user create          create a user
+  user reset-password  set a new password for a user
+  user set-role        change a user's role
+  hash-password        print the bcrypt hash of a password read from stdin
+  seed                 insert missing rows into the lookup tables
+  reassign             move one user's records to another user
+  backfill-contacts    set created_by on contacts that have none
+
+Run "crmctl <command> -h" for the flags of a command.`
+
+// command is a crmctl subcommand. It receives the arguments after its name and
+// returns the process exit code.
+type command func(app *app, args []string) int
+
+var commands = map[string]command{
+	"user":              runUser,
+	"hash-password":     runHashPassword,
+	"seed":              runSeed,
+	"reassign":          runReassign,
+	"backfill-contacts": runBackfillContacts,
+}
+
+// app carries what the subcommands share. The database connection is opened
+// on first use so commands that do not need it (hash-password) work offline.
+type app struct {
+	loader *config.Loader
+	logger *slog.Logger
+	cfg    *config.Config
+	db     *sqlx.DB
+}
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	global := flag.NewFlagSet("crmctl", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	loader := config.NewLoader(global)
+	global.Parse(os.Args[1:])
+
+	args := global.Args()
+	if len(args) == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		global.Usage()
+		os.Exit(2)
+	}
+
+	a := &app{loader: loader, logger: logger}
+	code := cmd(a, args[1:])
+	if a.db != nil {
+		a.db.Close()
+	}
+	os.Exit(code)
+}
+
+// connect loads the configuration and opens the database. Only the database
+// settings are required and role IDs are not resolved, so it also works
+// before the roles table has been seeded.
+func (a *app) connect() error {
+	if a.db != nil {
+		return nil
+	}
+	cfg, err := a.loader.Load(a.logger)
+	if err == nil {
+		err = cfg.ValidateDatabase()
+	}
+	if err != nil {
+		return fmt.Errorf("could not load configuration: %w", err)
+	}
+	db, err := sqlx.Connect("pgx", cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("could not connect to database: %w", err)
+	}
+	a.cfg, a.db = cfg, db
+	return nil
+}
+
+// fail prints err and returns the exit code for a failed command.
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "crmctl:", err)
+	return 1
+}
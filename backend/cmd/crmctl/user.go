@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"crm-project/internal/dto"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/util"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const userUsage = `usage: crmctl user <create|reset-password|set-role> [flags]`
+
+func runUser(a *app, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+	switch args[0] {
+	case "create":
+		return runUserCreate(a, args[1:])
+	case "reset-password":
+		return runUserResetPassword(a, args[1:])
+	case "set-role":
+		return runUserSetRole(a, args[1:])
+	}
+	fmt.Fprintln(os.Stderr, userUsage)
+	return 2
+}
+
+func runUserCreate(a *app, args []string) int {
+	fs := flag.NewFlagSet("user create", flag.ExitOnError)
+	username := fs.String("username", "", "login name (required)")
+	email := fs.String("email", "", "email address (required)")
+	role := fs.String("role", "Sales_Agent", "role name: Sales_Agent or Reception")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	fs.Parse(args)
+
+	password, generated, err := readOrGeneratePassword(*passwordStdin)
+	if err != nil {
+		return fail(err)
+	}
+	if err := a.connect(); err != nil {
+		return fail(err)
+	}
+	ctx := context.Background()
+	users := postgres.NewUserRepo(a.db)
+
+	roleID, err := lookupRole(ctx, postgres.NewAdminRepo(a.db), *role)
+	if err != nil {
+		return fail(err)
+	}
+	req := dto.CreateUserRequest{Username: *username, Password: password, Email: *email, RoleID: roleID}
+	if err := util.ValidateStruct(req); err != nil {
+		return fail(err)
+	}
+	if existing, err := users.GetByUsername(ctx, req.Username); err != nil {
+		return fail(err)
+	} else if existing != nil {
+		return fail(fmt.Errorf("username %q is already taken", req.Username))
+	}
+	if existing, err := users.GetByEmail(ctx, req.Email); err != nil {
+		return fail(err)
+	} else if existing != nil {
+		return fail(fmt.Errorf("email %q is already registered", req.Email))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fail(err)
+	}
+	id, err := users.Create(ctx, models.User{
+		Username:     req.Username,
+		PasswordHash: string(hash),
+		Email:        req.Email,
+		RoleID:       req.RoleID,
+	})
+	if err != nil {
+		return fail(fmt.Errorf("could not create user: %w", err))
+	}
+
+	fmt.Printf("created user %q (id %d, role %s)\n", req.Username, id, *role)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return 0
+}
+
+func runUserResetPassword(a *app, args []string) int {
+	fs := flag.NewFlagSet("user reset-password", flag.ExitOnError)
+	username := fs.String("username", "", "user whose password is reset (required)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	fs.Parse(args)
+
+	password, generated, err := readOrGeneratePassword(*passwordStdin)
+	if err != nil {
+		return fail(err)
+	}
+	if err := util.ValidateStruct(struct {
+		Password string `json:"password" validate:"required,min=8"`
+	}{password}); err != nil {
+		return fail(err)
+	}
+	if err := a.connect(); err != nil {
+		return fail(err)
+	}
+	ctx := context.Background()
+	users := postgres.NewUserRepo(a.db)
+
+	user, err := findUser(ctx, users, *username)
+	if err != nil {
+		return fail(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fail(err)
+	}
+	user.PasswordHash = string(hash)
+	if err := users.Update(ctx, *user); err != nil {
+		return fail(fmt.Errorf("could not update user: %w", err))
+	}
+
+	fmt.Printf("password reset for %q\n", user.Username)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return 0
+}
+
+func runUserSetRole(a *app, args []string) int {
+	fs := flag.NewFlagSet("user set-role", flag.ExitOnError)
+	username := fs.String("username", "", "user whose role is changed (required)")
+	role := fs.String("role", "", "new role name: Sales_Agent or Reception (required)")
+	by := fs.String("by", "", "username recorded as having made the change (required)")
+	reason := fs.String("reason", "", "reason recorded in the role history")
+	fs.Parse(args)
+
+	if *role == "" || *by == "" {
+		fs.Usage()
+		return 2
+	}
+	if err := a.connect(); err != nil {
+		return fail(err)
+	}
+	ctx := context.Background()
+	users := postgres.NewUserRepo(a.db)
+
+	roleID, err := lookupRole(ctx, postgres.NewAdminRepo(a.db), *role)
+	if err != nil {
+		return fail(err)
+	}
+	user, err := findUser(ctx, users, *username)
+	if err != nil {
+		return fail(err)
+	}
+	changedBy, err := findUser(ctx, users, *by)
+	if err != nil {
+		return fail(err)
+	}
+	if user.RoleID == roleID {
+		fmt.Printf("%q already has role %s\n", user.Username, *role)
+		return 0
+	}
+
+	var why *string
+	if r := strings.TrimSpace(*reason); r != "" {
+		why = &r
+	}
+	if _, err := users.ChangeRole(ctx, user.ID, roleID, changedBy.ID, why); err != nil {
+		return fail(fmt.Errorf("could not change role: %w", err))
+	}
+	fmt.Printf("changed role of %q to %s\n", user.Username, *role)
+	return 0
+}
+
+// runHashPassword replaces the old generate_hash.go helper for operators who
+// still need a raw hash, e.g. to fix a row by hand.
+func runHashPassword(a *app, args []string) int {
+	fs := flag.NewFlagSet("hash-password", flag.ExitOnError)
+	fs.Parse(args)
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return fail(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Println(string(hash))
+	return 0
+}
+
+// findUser looks a user up by username and reports a missing one as an error.
+func findUser(ctx context.Context, users postgres.UserRepository, username string) (*models.User, error) {
+	if username == "" {
+		return nil, errors.New("a username is required")
+	}
+	user, err := users.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %q not found", username)
+	}
+	return user, nil
+}
+
+// lookupRole returns the ID of a role by name. Run `crmctl seed` first if the
+// roles table is empty.
+func lookupRole(ctx context.Context, admin postgres.AdminRepository, name string) (int, error) {
+	id, err := admin.GetRoleID(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		return 0, fmt.Errorf("unknown role %q (run `crmctl seed` if the roles table is empty)", name)
+	}
+	return id, nil
+}
+
+// readOrGeneratePassword reads a password from stdin when fromStdin is set
+// and otherwise generates a random one. generated reports which happened so
+// the caller can show a generated password to the operator.
+func readOrGeneratePassword(fromStdin bool) (password string, generated bool, err error) {
+	if fromStdin {
+		password, err = readPassword(os.Stdin)
+		return password, false, err
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
+
+// readPassword reads the first line of r, so both `echo pass |` and a
+// password file work.
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password on stdin")
+	}
+	return password, nil
+}
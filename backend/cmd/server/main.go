@@ -4,10 +4,20 @@ import (
 	"context"
 	"crm-project/internal/api"
 	"crm-project/internal/api/handlers"
+	"crm-project/db/migrations"
+	"crm-project/internal/buildinfo"
 	"crm-project/internal/config"
+	"crm-project/internal/health"
+	"crm-project/internal/logging"
+	"crm-project/internal/metrics"
+	"crm-project/internal/migrate"
+	"crm-project/internal/notify"
+	"crm-project/internal/ratelimit"
 	"crm-project/internal/repository/postgres"
 	"crm-project/internal/service"
+	"crm-project/internal/tracing"
 	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
@@ -22,14 +32,46 @@ import (
 
 func main() {
 	// --- Initialize Logger & Config ---
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	logger.Info("Test log to confirm logging is working")
-	cfg, err := config.Load("config.yml", logger)
+	// Until the configuration is loaded, log at Info with the default
+	// redaction policy; the configured logger replaces this one below.
+	logger := logging.New(os.Stdout, logging.Options{Level: slog.LevelInfo})
+	slog.SetDefault(logger)
+
+	flags := flag.NewFlagSet("server", flag.ExitOnError)
+	loader := config.NewLoader(flags)
+	flags.Parse(os.Args[1:])
+
+	if flags.Arg(0) == "migrate" {
+		os.Exit(runMigrate(loader, flags.Args()[1:], logger))
+	}
+
+	cfg, err := loader.Load(logger)
 	if err != nil {
 		logger.Error("could not load configuration", "error", err)
 		os.Exit(1)
 	}
-	logger.Info("configuration loaded successfully")
+	if err := cfg.Validate(); err != nil {
+		logger.Error("could not load configuration", "error", err)
+		os.Exit(1)
+	}
+	logger = cfg.Logger(os.Stdout)
+	slog.SetDefault(logger)
+
+	// --- Tracing ---
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:       cfg.Tracing.Exporter,
+		OTLPEndpoint:   cfg.Tracing.OTLPEndpoint,
+		File:           cfg.Tracing.File,
+		SampleRatio:    cfg.Tracing.SampleRatio,
+		ServiceName:    "crm-server",
+		ServiceVersion: buildinfo.Get().Version,
+	})
+	if err != nil {
+		logger.Error("could not set up tracing", "error", err)
+		os.Exit(1)
+	}
+	info := buildinfo.Get()
+	logger.Info("configuration loaded successfully", "version", info.Version, "commit", info.Commit)
 
 	// --- Connect to Database ---
 	db, err := sqlx.Connect("pgx", cfg.Database.URL)
@@ -44,6 +86,29 @@ func main() {
 	}
 	logger.Info("successfully connected to the database!")
 
+	// --- Schema Check ---
+	// Refuse to serve against a schema that is behind (or has drifted from)
+	// the migrations embedded in this binary.
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		logger.Error("could not load migrations", "error", err)
+		os.Exit(1)
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		logger.Error("database schema check failed", "error", err)
+		os.Exit(1)
+	}
+
+	// --- Role IDs ---
+	// Services compare role IDs, which are assigned by the database.
+	if err := loadRoleIDs(context.Background(), postgres.NewAdminRepo(db), cfg); err != nil {
+		logger.Error("could not resolve role IDs", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("role IDs resolved",
+		"SalesAgentRoleID", cfg.Roles.SalesAgentID,
+		"ReceptionRoleID", cfg.Roles.ReceptionID)
+
 	// --- Dependency Injection ---
 	// Repository Layer
 	contactRepo := postgres.NewContactRepo(db)
@@ -52,25 +117,71 @@ func main() {
 	leadRepo := postgres.NewLeadRepo(db)
 	dealRepo := postgres.NewDealRepo(db)
 	taskRepo := postgres.NewTaskRepository(db)	
+	taskSeriesRepo := postgres.NewTaskSeriesRepo(db)
+	taskCommentRepo := postgres.NewTaskCommentRepo(db)
+	taskChecklistRepo := postgres.NewTaskChecklistRepo(db)
+	playbookRepo := postgres.NewPlaybookRepo(db)
 	commLogRepo := postgres.NewCommLogRepository(db) // Corrected from NewCommLogRepo
 	noteRepo := postgres.NewNoteRepository(db) // <- pass the underlying *sql.DB
 	eventRepo := postgres.NewEventRepository(db)
+	invitationRepo := postgres.NewInvitationRepo(db)
+	apiKeyRepo := postgres.NewAPIKeyRepo(db)
+	auditRepo := postgres.NewAuditRepo(db)
+	idempotencyRepo := postgres.NewIdempotencyRepo(db)
+	notificationRepo := postgres.NewNotificationRepo(db)
+	taskReminderRepo := postgres.NewTaskReminderRepo(db)
+	taskSLARepo := postgres.NewTaskSLARepo(db)
+	taskBulkRepo := postgres.NewTaskBulkRepo(db)
+	txManager := postgres.NewTxManager(db)
+
+
 
+	// Metrics
+	appMetrics := metrics.New(db)
+
+	// Rate Limiting
+	var limiter *api.RateLimiter
+	var rateLimitRepo *postgres.RateLimitRepo
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == config.RateLimitStorePostgres {
+			rateLimitRepo = postgres.NewRateLimitRepo(db)
+			store = rateLimitRepo
+		}
+		limiter = api.NewRateLimiter(store, cfg.RateLimits(), cfg.RateLimit.TrustProxy, logger)
+		logger.Info("rate limiting enabled", "store", cfg.RateLimit.Store)
+	}
 
+	// Email
+	emailSender, err := notify.NewEmailSender(cfg.Email.Sender, cfg.Email.File, cfg.Email.From)
+	if err != nil {
+		logger.Error("could not set up email", "error", err)
+		os.Exit(1)
+	}
 
 	// Service Layer
-	authService := service.NewAuthService(userRepo, cfg, logger)
-	contactService := service.NewContactService(contactRepo, cfg, logger)
+	auditService := service.NewAuditService(auditRepo, cfg, logger)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg, logger)
+	invitationService := service.NewInvitationService(invitationRepo, cfg, logger)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, cfg, logger)
+	authService := service.NewAuthService(userRepo, invitationService, appMetrics, cfg, logger)
+	contactService := service.NewContactService(contactRepo, auditService, cfg, logger)
 	userService := service.NewUserService(userRepo, cfg, logger)
-	propertyService := service.NewPropertyService(propertyRepo, cfg, logger)
-	leadService := service.NewLeadService(leadRepo, contactRepo, userRepo, propertyRepo, cfg, logger)
-	dealService := service.NewDealService(dealRepo, leadRepo, propertyRepo, cfg, logger)
-	reportService := service.NewReportService(userRepo, leadRepo, dealRepo, cfg, logger)
-	taskService := service.NewTaskService(taskRepo, cfg, logger)	
-	commLogService := service.NewCommLogService(commLogRepo) // Corrected to match service constructor
-	noteService := service.NewNoteService(noteRepo)
-	eventService := service.NewEventService(eventRepo)
-	tempService := service.NewTempService(userRepo, cfg, logger)
+	propertyService := service.NewPropertyService(propertyRepo, auditService, cfg, logger)
+	playbookService := service.NewPlaybookService(playbookRepo, taskRepo, userRepo, txManager, auditService, cfg, logger)
+	leadService := service.NewLeadService(leadRepo, contactRepo, userRepo, propertyRepo, auditService, playbookService, cfg, logger)
+	dealService := service.NewDealService(dealRepo, leadRepo, propertyRepo, txManager, auditService, playbookService, cfg, logger)
+	reportService := service.NewReportService(userRepo, leadRepo, dealRepo, taskSLARepo, cfg, logger)
+	taskService := service.NewTaskService(taskRepo, taskSeriesRepo, taskChecklistRepo, dealRepo, txManager, auditService, cfg, logger)	
+	taskCommentService := service.NewTaskCommentService(taskCommentRepo, taskService, txManager, cfg, logger)
+	taskChecklistService := service.NewTaskChecklistService(taskChecklistRepo, taskService, cfg, logger)
+	taskBulkService := service.NewTaskBulkService(taskBulkRepo, taskService, userRepo, txManager, auditService, cfg, logger)
+	commLogService := service.NewCommLogService(commLogRepo, auditService, cfg, logger)
+	noteService := service.NewNoteService(noteRepo, auditService, cfg, logger)
+	eventService := service.NewEventService(eventRepo, auditService, cfg, logger)
+	notificationService := service.NewNotificationService(notificationRepo, cfg, logger)
+	reminderService := service.NewReminderService(taskReminderRepo, notificationRepo, userRepo, emailSender, cfg, logger)
+	taskSLAService := service.NewTaskSLAService(taskSLARepo, notificationRepo, userRepo, emailSender, txManager, auditService, cfg, logger)
 	// Handler Layer
 
 
@@ -84,10 +195,19 @@ func main() {
 	reportHandler := handlers.NewReportHandler(reportService, logger)
 	
 taskHandler := handlers.NewTaskHandler(taskService)	
+	taskCommentHandler := handlers.NewTaskCommentHandler(taskCommentService, logger)
+	taskChecklistHandler := handlers.NewTaskChecklistHandler(taskChecklistService, logger)
+	taskBulkHandler := handlers.NewTaskBulkHandler(taskBulkService, logger)
+	playbookHandler := handlers.NewPlaybookHandler(playbookService, logger)
 	commLogHandler := handlers.NewCommLogHandler(commLogService) // Corrected to match handler constructor
 noteHandler := handlers.NewNoteHandler(noteService)
 eventHandler := handlers.NewEventHandler(eventService)
-	tempHandler := handlers.NewTempHandler(tempService, logger)
+	invitationHandler := handlers.NewInvitationHandler(invitationService, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, logger)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, logger)
+	workers := health.NewWorkers(logger)
+	healthHandler := handlers.NewHealthHandler(db, migrator, workers, logger)
 	// Router
 	router := api.NewRouter(
 		cfg, // Pass the entire config object
@@ -100,55 +220,37 @@ eventHandler := handlers.NewEventHandler(eventService)
 		dealHandler,
 		reportHandler,
 		taskHandler,
+		taskCommentHandler,
+		taskChecklistHandler,
+		taskBulkHandler,
+		playbookHandler,
 		commLogHandler,
 		noteHandler,
 		eventHandler,
-		tempHandler,
+		invitationHandler,
+		apiKeyHandler,
+		apiKeyService,
+		auditHandler,
+		notificationHandler,
+		idempotencyService,
+		dealService,
+		leadService,
+		appMetrics,
+		healthHandler,
+		limiter,
 	)
 
-	// --- DATA MIGRATION ---
-    users, err := userRepo.GetAll(context.Background())
-    if err != nil {
-        logger.Error("could not get all users", "error", err)
-    }
-    if len(users) > 0 {
-        defaultUser := users[0]
-        contacts, err := contactRepo.GetAll(context.Background())
-        if err != nil {
-            logger.Error("could not get all contacts", "error", err)
-        }
-        for _, contact := range contacts {
-            if contact.CreatedBy == nil {
-                logger.Info("updating contact with nil created_by", "contact_id", contact.ID)
-                err := contactRepo.UpdateCreatedBy(context.Background(), contact.ID, defaultUser.ID)
-                if err != nil {
-                    logger.Error("could not update contact", "contact_id", contact.ID, "error", err)
-                }
-            }
-        }
-    }
-
 	router.Get("/reports/my-sales", reportHandler.GetMySalesReport)
 	
 	
-    // --- PASTE THIS ENTIRE BLOCK OF CODE HERE ---
-	logger.Info("--- Registered Routes ---")
 	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-		fmt.Printf("%s \t %s\n", method, route)
+		logger.Debug("route registered", "method", method, "route", route)
 		return nil
 	}
-if err := chi.Walk(router, walkFunc); err != nil {
+	if err := chi.Walk(router, walkFunc); err != nil {
 		logger.Error("failed to walk routes", "error", err)
 	}
-	logger.Info("-------------------------")
-    // --- END OF THE BLOCK TO PASTE ---
-
-
-
 
-
-
-	
 	// --- Create and Start the HTTP Server ---
 	srv := &http.Server{
 		Addr:    cfg.Server.Port,
@@ -163,11 +265,73 @@ if err := chi.Walk(router, walkFunc); err != nil {
 		}
 	}()
 
+	// --- Background Workers ---
+	// Workers run until workerCtx is cancelled at shutdown; /readyz fails if
+	// any of them stops early.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Stored Idempotency-Key responses are only replayed within the configured
+	// window; drop the expired ones periodically.
+	workers.Go(workerCtx, "idempotency-purge", func(ctx context.Context) {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := idempotencyService.PurgeExpired(ctx); err != nil {
+					logger.Error("failed to purge expired idempotency keys", "error", err)
+				}
+			}
+		}
+	})
+
+	// Shared rate limit buckets that have refilled are the same as missing
+	// ones; drop them so the table stays small.
+	if rateLimitRepo != nil {
+		workers.Go(workerCtx, "rate-limit-purge", func(ctx context.Context) {
+			ticker := time.NewTicker(10 * time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if _, err := rateLimitRepo.DeleteIdle(ctx, limiter.IdleAfter()); err != nil {
+						logger.Error("failed to purge idle rate limit buckets", "error", err)
+					}
+				}
+			}
+		})
+	}
+
+	// Lead and deal gauges are computed from the database, so refresh them on
+	// an interval rather than on every scrape.
+	workers.Go(workerCtx, "metrics-refresh", func(ctx context.Context) {
+		appMetrics.RunBusinessRefresh(ctx, leadRepo, dealRepo, cfg.Metrics.RefreshInterval, logger)
+	})
+
+	// Task reminders, overdue marking and daily digests. Every instance may
+	// run this; notifications are deduplicated in the database.
+	if cfg.Reminders.Enabled {
+		workers.Go(workerCtx, "task-reminders", reminderService.Run)
+	}
+
+	// SLA escalation of tasks left open too long. Like reminders it may run
+	// on every instance; each breach is recorded and escalated once.
+	if cfg.SLA.Enabled && len(cfg.SLA.Types) > 0 {
+		workers.Go(workerCtx, "task-sla", taskSLAService.Run)
+	}
+
 	// --- Graceful Shutdown Logic ---
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Warn("shutdown signal received, starting graceful shutdown")
+	healthHandler.SetDraining()
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -176,6 +340,27 @@ if err := chi.Walk(router, walkFunc); err != nil {
 		logger.Error("server shutdown failed", "error", err)
 		os.Exit(1)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
 
 	logger.Info("server exited gracefully")
-}
\ No newline at end of file
+}
+
+// loadRoleIDs fills in cfg.Roles from the roles table.
+func loadRoleIDs(ctx context.Context, admin postgres.AdminRepository, cfg *config.Config) error {
+	for name, dst := range map[string]*int{
+		"Sales_Agent": &cfg.Roles.SalesAgentID,
+		"Reception":   &cfg.Roles.ReceptionID,
+	} {
+		id, err := admin.GetRoleID(ctx, name)
+		if err != nil {
+			return err
+		}
+		if id == 0 {
+			return fmt.Errorf("role %q is missing from the roles table (run `crmctl seed`)", name)
+		}
+		*dst = id
+	}
+	return nil
+}
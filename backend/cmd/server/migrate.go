@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crm-project/db/migrations"
+	"crm-project/internal/config"
+	"crm-project/internal/migrate"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = `usage: server [flags] migrate <command>
+
+commands:
+  up         apply all pending migrations
+  down       roll back the most recent migration
+  status     list migrations and whether they are applied
+  to N       migrate up or down to version N (0 rolls back everything)`
+
+// runMigrate implements the `server migrate` subcommand and returns the
+// process exit code.
+func runMigrate(loader *config.Loader, args []string, logger *slog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := loader.Load(logger)
+	if err == nil {
+		err = cfg.ValidateDatabase()
+	}
+	if err != nil {
+		logger.Error("could not load configuration", "error", err)
+		return 1
+	}
+	db, err := sqlx.Connect("pgx", cfg.Database.URL)
+	if err != nil {
+		logger.Error("could not connect to database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		logger.Error("could not load migrations", "error", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		err = migrator.To(ctx, version)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		logger.Error("migration failed", "command", args[0], "error", err)
+		return 1
+	}
+	return 0
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Unknown:
+			state = "unknown"
+		case s.Drifted:
+			state = "drifted"
+		case s.Applied:
+			state = "applied"
+		}
+		appliedAt := ""
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -6,4 +6,6 @@ ALTER TABLE events DROP CONSTRAINT IF EXISTS fk_event_lead;
 ALTER TABLE tasks DROP COLUMN IF EXISTS deal_id;
 ALTER TABLE tasks DROP COLUMN IF EXISTS lead_id;
 ALTER TABLE events DROP COLUMN IF EXISTS deal_id;
-ALTER TABLE events DROP COLUMN IF EXISTS lead_id;
\ No newline at end of file
+ALTER TABLE events DROP COLUMN IF EXISTS lead_id;
+
+DROP TABLE IF EXISTS events;
@@ -1,3 +1,20 @@
+-- The events table is created here because this migration and 000012 alter it;
+-- 000013 only re-creates it if it is missing.
+CREATE TABLE IF NOT EXISTS events (
+    event_id SERIAL PRIMARY KEY,
+    event_name VARCHAR(255) NOT NULL,
+    event_description TEXT,
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time TIMESTAMPTZ NOT NULL,
+    location TEXT,
+    organizer_id INT NOT NULL, -- The user_id of the person who created the event
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_event_organizer
+        FOREIGN KEY(organizer_id)
+        REFERENCES users(user_id)
+);
+
 -- Add optional columns to link tasks and events directly to leads and deals
 ALTER TABLE tasks ADD COLUMN lead_id INT;
 ALTER TABLE tasks ADD COLUMN deal_id INT;
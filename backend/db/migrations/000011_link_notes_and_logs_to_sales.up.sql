@@ -2,9 +2,9 @@
 -- and communication_logs tables to create a unified activity timeline.
 
 -- Add columns to the 'notes' table
-ALTER TABLE notes ADD COLUMN contact_id INT;
-ALTER TABLE notes ADD COLUMN lead_id INT;
-ALTER TABLE notes ADD COLUMN deal_id INT;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS contact_id INT;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS lead_id INT;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS deal_id INT;
 
 -- Add columns to the 'communication_logs' table
 ALTER TABLE communication_logs ADD COLUMN lead_id INT;
@@ -13,6 +13,11 @@ ALTER TABLE communication_logs ADD COLUMN deal_id INT;
 
 -- Add foreign key constraints for the new columns.
 -- We use ON DELETE SET NULL to preserve the activity history even if a lead/deal is deleted.
+-- 000008 already added the notes constraints without it, so they are replaced.
+
+ALTER TABLE notes DROP CONSTRAINT IF EXISTS fk_note_contact;
+ALTER TABLE notes DROP CONSTRAINT IF EXISTS fk_note_lead;
+ALTER TABLE notes DROP CONSTRAINT IF EXISTS fk_note_deal;
 
 ALTER TABLE notes
 ADD CONSTRAINT fk_note_contact
@@ -1 +1 @@
-DROP TABLE IF EXISTS events;
\ No newline at end of file
+-- The events table is created by 000010 and dropped by its down migration.
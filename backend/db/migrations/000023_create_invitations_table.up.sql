@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    invitation_id SERIAL PRIMARY KEY,
+    email VARCHAR(255), -- Optional: if set, only this address may redeem the invite
+    role_id INT NOT NULL,
+    created_by INT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    used_by INT,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_invitation_role
+        FOREIGN KEY(role_id)
+        REFERENCES roles(role_id),
+    CONSTRAINT fk_invitation_created_by
+        FOREIGN KEY(created_by)
+        REFERENCES users(user_id),
+    CONSTRAINT fk_invitation_used_by
+        FOREIGN KEY(used_by)
+        REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_changes (
+    role_change_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL, -- The user whose role was changed
+    old_role_id INT NOT NULL,
+    new_role_id INT NOT NULL,
+    changed_by INT NOT NULL, -- The manager who made the change
+    reason TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_role_change_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(user_id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_change_changed_by
+        FOREIGN KEY(changed_by)
+        REFERENCES users(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_changes_user_id ON role_changes(user_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    api_key_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL, -- The user the key acts as
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL, -- First characters of the key, shown in listings
+    key_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the full key; the key itself is never stored
+    scopes TEXT NOT NULL, -- Comma-separated, e.g. contacts:read,leads:write
+    expires_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_api_key_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
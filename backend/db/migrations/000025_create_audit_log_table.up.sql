@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    audit_id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor_user_id INT, -- NULL for changes made by the system (e.g. background jobs)
+    api_key_id INT, -- Set when the change was made with a personal API key
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INT NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before JSONB, -- Changed fields before the change; NULL on create
+    after JSONB, -- Changed fields after the change; NULL on delete
+    request_id VARCHAR(100)
+);
+
+-- No foreign keys on purpose: audit entries must outlive the users and records they describe.
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at);
+
+-- The audit log is append-only.
+CREATE OR REPLACE FUNCTION audit_log_reject_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_reject_modification();
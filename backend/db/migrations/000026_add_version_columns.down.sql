@@ -0,0 +1,8 @@
+ALTER TABLE communication_logs DROP COLUMN IF EXISTS version;
+ALTER TABLE notes DROP COLUMN IF EXISTS version;
+ALTER TABLE events DROP COLUMN IF EXISTS version;
+ALTER TABLE tasks DROP COLUMN IF EXISTS version;
+ALTER TABLE properties DROP COLUMN IF EXISTS version;
+ALTER TABLE deals DROP COLUMN IF EXISTS version;
+ALTER TABLE leads DROP COLUMN IF EXISTS version;
+ALTER TABLE contacts DROP COLUMN IF EXISTS version;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INT NOT NULL, -- Keys are scoped to the user that sent them
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_method VARCHAR(10) NOT NULL,
+    request_path TEXT NOT NULL,
+    request_hash CHAR(64) NOT NULL, -- SHA-256 of method, path and body; a reused key must match
+    status_code INT, -- NULL while the original request is still in progress
+    response_headers JSONB,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key),
+    CONSTRAINT fk_idempotency_key_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,11 @@
+-- Token buckets shared by all server instances when rate_limit.store is
+-- postgres. A missing row means a full bucket, so rows can be deleted once
+-- they have had time to refill.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key VARCHAR(255) PRIMARY KEY, -- Route group and user or client IP, e.g. "reports:user:7"
+    tokens DOUBLE PRECISION NOT NULL, -- Tokens left as of updated_at
+    allowed BOOLEAN NOT NULL, -- Whether the last request took a token
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
@@ -0,0 +1,25 @@
+-- Recurring tasks. A series holds the recurrence rule and the fields copied
+-- into each occurrence; the occurrences themselves are ordinary tasks.
+CREATE TABLE IF NOT EXISTS task_series (
+    series_id SERIAL PRIMARY KEY,
+    rrule TEXT NOT NULL, -- RFC 5545 RRULE without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO
+    dtstart TIMESTAMPTZ NOT NULL, -- The rule is evaluated from here
+    start_occurrence INT NOT NULL DEFAULT 1, -- Occurrence number due at dtstart; changes when future occurrences are edited
+    until_deal_closed BOOLEAN NOT NULL DEFAULT FALSE,
+    task_name VARCHAR(255) NOT NULL,
+    task_description TEXT,
+    assigned_to INT NOT NULL REFERENCES users(user_id),
+    lead_id INT REFERENCES leads(lead_id) ON DELETE SET NULL,
+    deal_id INT REFERENCES deals(deal_id) ON DELETE SET NULL,
+    ended_at TIMESTAMPTZ, -- No further occurrences once set
+    created_by INT REFERENCES users(user_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS series_id INT REFERENCES task_series(series_id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence INT;
+
+-- Completing an occurrence twice at once must not create the next one twice.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_series_occurrence ON tasks(series_id, occurrence) WHERE series_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_tasks_open_due_date;
+DROP TABLE IF EXISTS notifications;
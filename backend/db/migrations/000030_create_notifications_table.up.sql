@@ -0,0 +1,20 @@
+-- In-app notifications, written by the task reminder scheduler. dedup_key
+-- names the event a notification is about (e.g. one reminder for one due
+-- date), so running the scheduler on several instances, or again after a
+-- restart, notifies users only once.
+CREATE TABLE IF NOT EXISTS notifications (
+    notification_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL, -- task_reminder, task_overdue or task_digest
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    task_id INT REFERENCES tasks(task_id) ON DELETE CASCADE,
+    dedup_key VARCHAR(255) UNIQUE,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+
+-- The scheduler looks up open tasks by due date every minute.
+CREATE INDEX IF NOT EXISTS idx_tasks_open_due_date ON tasks(due_date) WHERE status <> 'Completed' AND deleted_at IS NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS task_checklist_items;
+DROP TABLE IF EXISTS task_comment_edits;
+DROP TABLE IF EXISTS task_comments;
+ALTER TABLE tasks DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,48 @@
+-- Who created a task. Comments and checklists are shared between the
+-- assignee and the creator. Tasks created before this migration have none.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_by INT REFERENCES users(user_id) ON DELETE SET NULL;
+
+-- Threaded comments on tasks. Deleted comments are kept, without their body,
+-- so replies to them still have a parent.
+CREATE TABLE IF NOT EXISTS task_comments (
+    comment_id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+    parent_id INT REFERENCES task_comments(comment_id) ON DELETE CASCADE, -- The comment this one replies to
+    author_id INT REFERENCES users(user_id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ,
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_comments_task ON task_comments(task_id, created_at);
+
+-- Earlier bodies of edited comments, one row per edit.
+CREATE TABLE IF NOT EXISTS task_comment_edits (
+    edit_id SERIAL PRIMARY KEY,
+    comment_id INT NOT NULL REFERENCES task_comments(comment_id) ON DELETE CASCADE,
+    body TEXT NOT NULL, -- The body as it was before the edit
+    edited_by INT REFERENCES users(user_id) ON DELETE SET NULL,
+    edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_comment_edits_comment ON task_comment_edits(comment_id, edited_at);
+
+-- Checklist sub-items. A task cannot be completed while a required item is
+-- not done.
+CREATE TABLE IF NOT EXISTS task_checklist_items (
+    item_id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL,
+    completed_at TIMESTAMPTZ,
+    completed_by INT REFERENCES users(user_id) ON DELETE SET NULL,
+    created_by INT REFERENCES users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_checklist_items_task ON task_checklist_items(task_id, position);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS playbook_runs;
+DROP TABLE IF EXISTS playbook_tasks;
+DROP TABLE IF EXISTS playbooks;
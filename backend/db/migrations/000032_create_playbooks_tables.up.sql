@@ -0,0 +1,44 @@
+-- Task playbooks. When a deal enters a stage, or a lead reaches a status,
+-- every active playbook for it creates its templated tasks.
+CREATE TABLE IF NOT EXISTS playbooks (
+    playbook_id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    deal_stage_id INT REFERENCES deal_stages(stage_id) ON DELETE CASCADE,
+    lead_status_id INT REFERENCES lead_statuses(status_id) ON DELETE CASCADE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT REFERENCES users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    -- A playbook is triggered by a deal stage or by a lead status, not both.
+    CHECK ((deal_stage_id IS NULL) <> (lead_status_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_playbooks_deal_stage ON playbooks(deal_stage_id) WHERE active;
+CREATE INDEX IF NOT EXISTS idx_playbooks_lead_status ON playbooks(lead_status_id) WHERE active;
+
+-- The tasks a playbook creates.
+CREATE TABLE IF NOT EXISTS playbook_tasks (
+    playbook_task_id SERIAL PRIMARY KEY,
+    playbook_id INT NOT NULL REFERENCES playbooks(playbook_id) ON DELETE CASCADE,
+    task_name VARCHAR(255) NOT NULL,
+    task_description TEXT,
+    due_in_days INT NOT NULL DEFAULT 0 CHECK (due_in_days >= 0), -- Days after the stage or status change
+    assign_to INT REFERENCES users(user_id) ON DELETE SET NULL, -- NULL assigns the deal or lead owner
+    position INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_playbook_tasks_playbook ON playbook_tasks(playbook_id, position);
+
+-- Each playbook runs at most once per deal or lead, so moving a deal back
+-- and forth between stages does not create its tasks again.
+CREATE TABLE IF NOT EXISTS playbook_runs (
+    run_id SERIAL PRIMARY KEY,
+    playbook_id INT NOT NULL REFERENCES playbooks(playbook_id) ON DELETE CASCADE,
+    deal_id INT REFERENCES deals(deal_id) ON DELETE CASCADE,
+    lead_id INT REFERENCES leads(lead_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_playbook_runs_deal ON playbook_runs(playbook_id, deal_id) WHERE deal_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_playbook_runs_lead ON playbook_runs(playbook_id, lead_id) WHERE lead_id IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_open_task_type;
+DROP TABLE IF EXISTS task_sla_breaches;
+ALTER TABLE task_series DROP COLUMN IF EXISTS task_type;
+ALTER TABLE tasks DROP COLUMN IF EXISTS task_type;
@@ -0,0 +1,22 @@
+-- Task types select the SLA a task is held to (see the sla section of the
+-- configuration). Occurrences of a recurring task take the type of the series.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS task_type VARCHAR(50);
+ALTER TABLE task_series ADD COLUMN IF NOT EXISTS task_type VARCHAR(50);
+
+-- Tasks left open past their SLA, one row per task. The row is written when
+-- the task is escalated, so the SLA scheduler escalates each task only once
+-- however many instances run it.
+CREATE TABLE IF NOT EXISTS task_sla_breaches (
+    breach_id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL UNIQUE REFERENCES tasks(task_id) ON DELETE CASCADE,
+    task_type VARCHAR(50) NOT NULL,
+    assigned_to INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE, -- The agent the task was assigned to when it breached
+    escalation VARCHAR(20) NOT NULL, -- notify or reassign
+    reassigned_to INT REFERENCES users(user_id) ON DELETE SET NULL,
+    due_by TIMESTAMPTZ NOT NULL, -- When the SLA ran out
+    breached_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_sla_breaches_assigned_to ON task_sla_breaches(assigned_to, breached_at);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_open_task_type ON tasks(task_type, created_at) WHERE task_type IS NOT NULL AND status <> 'Completed' AND deleted_at IS NULL;
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"crm-project/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// parentFromRequest returns the deal or lead that a nested activity route
+// (/deals/{dealId}/... or /leads/{leadId}/...) refers to.
+func parentFromRequest(r *http.Request) (service.ActivityParent, error) {
+	if raw := chi.URLParam(r, "dealId"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			return service.ActivityParent{}, errors.New("Invalid deal ID")
+		}
+		return service.DealParent(id), nil
+	}
+	if raw := chi.URLParam(r, "leadId"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			return service.ActivityParent{}, errors.New("Invalid lead ID")
+		}
+		return service.LeadParent(id), nil
+	}
+	return service.ActivityParent{}, errors.New("Missing deal or lead ID")
+}
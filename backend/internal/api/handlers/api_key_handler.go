@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"crm-project/internal/dto"
+	"crm-project/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+	logger  *slog.Logger
+}
+
+func NewAPIKeyHandler(s *service.APIKeyService, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{service: s, logger: logger}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "invalid create API key request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	created, err := h.service.CreateAPIKey(ctx, req)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to create API key", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "API key created successfully", "api_key_id", created.APIKey.ID)
+	// The response holds the only copy of the raw key; keep it out of caches
+	// and stored idempotent responses.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *APIKeyHandler) GetMyAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	keys, err := h.service.GetMyAPIKeys(ctx)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to get API keys", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	h.logger.DebugContext(r.Context(), "retrieved API keys", "count", len(keys))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "keyId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+	if err := h.service.RevokeAPIKey(ctx, id); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to revoke API key", "api_key_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "API key revoked successfully", "api_key_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
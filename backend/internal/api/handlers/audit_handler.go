@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"crm-project/internal/models"
+	"crm-project/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+	logger  *slog.Logger
+}
+
+func NewAuditHandler(s *service.AuditService, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{service: s, logger: logger}
+}
+
+// GetAuditLog lists audit entries. Supported query parameters: entity_type,
+// entity_id, user_id, from, to (RFC 3339 or YYYY-MM-DD), limit and offset.
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	filter := models.AuditFilter{EntityType: q.Get("entity_type")}
+	var err error
+	if filter.EntityID, err = optionalInt(q.Get("entity_id")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid entity_id")
+		return
+	}
+	if filter.ActorUserID, err = optionalInt(q.Get("user_id")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user_id")
+		return
+	}
+	if filter.Limit, err = optionalInt(q.Get("limit")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	if filter.Offset, err = optionalInt(q.Get("offset")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+	if filter.From, err = optionalTime(q.Get("from"), false); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid from date")
+		return
+	}
+	if filter.To, err = optionalTime(q.Get("to"), true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid to date")
+		return
+	}
+
+	entries, err := h.service.GetAuditLog(ctx, filter)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to get audit log", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.DebugContext(r.Context(), "retrieved audit log", "count", len(entries))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func optionalInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return n, nil
+}
+
+// optionalTime parses an RFC 3339 timestamp or a plain date. A plain date used
+// as an upper bound includes the whole day.
+func optionalTime(s string, endOfDay bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
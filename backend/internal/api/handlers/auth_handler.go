@@ -35,14 +35,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid login request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid login request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	token, roleID, err := h.service.LoginUser(ctx, req.Username, req.Password)
 	if err != nil {
-		h.logger.Warn("failed login attempt", "username", req.Username)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.logger.WarnContext(r.Context(), "failed login attempt", "username", req.Username)
+		respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
@@ -50,25 +50,25 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	response := LoginResponse{Token: token, RoleID: roleID}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("failed to encode login response", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to encode login response", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal server error")
 	}
-	h.logger.Info("login response sent", "response", response)
+	h.logger.InfoContext(r.Context(), "user logged in", "role_id", roleID)
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var req dto.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid register request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid register request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	_, err := h.service.RegisterUser(ctx, &req)
 	if err != nil {
-		h.logger.Error("failed to register user", "error", err, "username", req.Username)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.WarnContext(r.Context(), "register request rejected", "error", err, "username", req.Username)
+		respondWithServiceError(w, err)
 		return
 	}
 
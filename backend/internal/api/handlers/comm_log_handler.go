@@ -49,6 +49,7 @@ type CommLogResponse struct {
     InteractionType string     `json:"interaction_type"`
     Notes           *string    `json:"notes,omitempty"`
     CreatedAt       *time.Time `json:"created_at,omitempty"`
+    Version         int        `json:"version"`
 }
 
 // Helper function to convert models.CommLog to CommLogResponse
@@ -63,18 +64,19 @@ func convertCommLogToResponse(log *models.CommLog) CommLogResponse {
         InteractionType: log.InteractionType,
         Notes:           log.Notes,
         CreatedAt:       log.CreatedAt,
+        Version:         log.Version,
     }
     return response
 }
 
 // GetAllCommLogs handles GET /api/v1/comm-logs
 func (h *CommLogHandler) GetAllCommLogs(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetAllCommLogs called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "GetAllCommLogs called", "method", r.Method, "url", r.URL.Path)
 
-    logs, err := h.commLogService.GetAllCommLogs()
+    logs, err := h.commLogService.GetAllCommLogs(r.Context())
     if err != nil {
-        slog.Error("Failed to get all communication logs", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get communication logs: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get all communication logs", "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -83,13 +85,13 @@ func (h *CommLogHandler) GetAllCommLogs(w http.ResponseWriter, r *http.Request)
         logResponses[i] = convertCommLogToResponse(&log)
     }
 
-    slog.Info("Successfully retrieved all communication logs", "count", len(logs))
+    slog.InfoContext(r.Context(), "Successfully retrieved all communication logs", "count", len(logs))
     respondWithJSON(w, http.StatusOK, logResponses)
 }
 
 // CreateCommLog handles POST /api/v1/comm-logs
 func (h *CommLogHandler) CreateCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("CreateCommLog called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "CreateCommLog called", "method", r.Method, "url", r.URL.Path)
     userID, err := getUserIDFromContext(r.Context())
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -98,7 +100,7 @@ func (h *CommLogHandler) CreateCommLog(w http.ResponseWriter, r *http.Request) {
 
     var req CreateCommLogRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -115,40 +117,41 @@ func (h *CommLogHandler) CreateCommLog(w http.ResponseWriter, r *http.Request) {
         CreatedAt:       &time.Time{},
     }
 
-    if err := h.commLogService.CreateCommLog(log); err != nil {
-        slog.Error("Failed to create communication log", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to create communication log: "+err.Error())
+    if err := h.commLogService.CreateCommLog(r.Context(), log); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to create communication log", "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully created communication log", "logID", log.ID)
+    slog.InfoContext(r.Context(), "Successfully created communication log", "logID", log.ID)
     respondWithJSON(w, http.StatusCreated, convertCommLogToResponse(log))
 }
 
 // GetCommLogByID handles GET /api/v1/comm-logs/{logId}
 func (h *CommLogHandler) GetCommLogByID(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetCommLogByID called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "GetCommLogByID called", "method", r.Method, "url", r.URL.Path)
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
-    log, err := h.commLogService.GetCommLogByID(logID)
+    log, err := h.commLogService.GetCommLogByID(r.Context(), logID)
     if err != nil {
-        slog.Error("Failed to get communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Communication log not found: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully retrieved communication log", "logID", logID)
+    slog.InfoContext(r.Context(), "Successfully retrieved communication log", "logID", logID)
+    setETag(w, log.Version)
     respondWithJSON(w, http.StatusOK, convertCommLogToResponse(log))
 }
 
 // UpdateCommLog handles PUT /api/v1/comm-logs/{logId}
 func (h *CommLogHandler) UpdateCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("UpdateCommLog called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "UpdateCommLog called", "method", r.Method, "url", r.URL.Path)
     userID, err := getUserIDFromContext(r.Context())
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -157,14 +160,20 @@ func (h *CommLogHandler) UpdateCommLog(w http.ResponseWriter, r *http.Request) {
 
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
     var req UpdateCommLogRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -177,78 +186,66 @@ func (h *CommLogHandler) UpdateCommLog(w http.ResponseWriter, r *http.Request) {
         InteractionDate: req.InteractionDate,
         InteractionType: req.InteractionType,
         Notes:           req.Notes,
+        Version:         version,
     }
 
-    if err := h.commLogService.UpdateCommLog(log); err != nil {
-        slog.Error("Failed to update communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to update communication log: "+err.Error())
+    if err := h.commLogService.UpdateCommLog(r.Context(), log); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to update communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    updatedLog, err := h.commLogService.GetCommLogByID(logID)
+    updatedLog, err := h.commLogService.GetCommLogByID(r.Context(), logID)
     if err != nil {
-        slog.Error("Failed to fetch updated communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get updated communication log: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to fetch updated communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully updated communication log", "logID", logID)
+    slog.InfoContext(r.Context(), "Successfully updated communication log", "logID", logID)
     respondWithJSON(w, http.StatusOK, convertCommLogToResponse(updatedLog))
 }
 
 // DeleteCommLog handles DELETE /api/v1/comm-logs/{logId}
 func (h *CommLogHandler) DeleteCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("DeleteCommLog called", "method", r.Method, "url", r.URL.Path)
-    userID, err := getUserIDFromContext(r.Context())
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "Authentication required")
-        return
-    }
-
+    slog.InfoContext(r.Context(), "DeleteCommLog called", "method", r.Method, "url", r.URL.Path)
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
-    existingLog, err := h.commLogService.GetCommLogByID(logID)
+    version, err := ifMatchVersion(r)
     if err != nil {
-        slog.Error("Communication log not found", "logID", logID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Communication log not found: "+err.Error())
-        return
-    }
-
-    if existingLog.UserID != userID {
-        slog.Warn("Unauthorized to delete communication log", "userID", userID, "logUserID", existingLog.UserID)
-        respondWithError(w, http.StatusForbidden, "You can only delete your own communication logs")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    if err := h.commLogService.DeleteCommLog(logID); err != nil {
-        slog.Error("Failed to delete communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to delete communication log: "+err.Error())
+    if err := h.commLogService.DeleteCommLog(r.Context(), logID, version); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to delete communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully deleted communication log", "logID", logID)
+    slog.InfoContext(r.Context(), "Successfully deleted communication log", "logID", logID)
     respondWithJSON(w, http.StatusOK, map[string]string{"message": "Communication log deleted successfully"})
 }
 
 // GetLogsForContact handles GET /api/v1/contacts/{contactId}/comm-logs
 func (h *CommLogHandler) GetLogsForContact(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetLogsForContact called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "GetLogsForContact called", "method", r.Method, "url", r.URL.Path)
     contactID, err := strconv.Atoi(chi.URLParam(r, "contactId"))
     if err != nil {
-        slog.Error("Invalid contact ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid contact ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid contact ID")
         return
     }
 
-    logs, err := h.commLogService.GetCommLogsByContactID(contactID)
+    logs, err := h.commLogService.GetCommLogsByContactID(r.Context(), contactID)
     if err != nil {
-        slog.Error("Failed to get communication logs for contact", "contactID", contactID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get communication logs: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get communication logs for contact", "contactID", contactID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -257,13 +254,13 @@ func (h *CommLogHandler) GetLogsForContact(w http.ResponseWriter, r *http.Reques
         logResponses[i] = convertCommLogToResponse(&log)
     }
 
-    slog.Info("Successfully retrieved communication logs for contact", "contactID", contactID, "count", len(logs))
+    slog.InfoContext(r.Context(), "Successfully retrieved communication logs for contact", "contactID", contactID, "count", len(logs))
     respondWithJSON(w, http.StatusOK, logResponses)
 }
 
 // CreateContactCommLog handles POST /api/v1/contacts/{contactId}/comm-logs
 func (h *CommLogHandler) CreateContactCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("CreateContactCommLog called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "CreateContactCommLog called", "method", r.Method, "url", r.URL.Path)
     userID, err := getUserIDFromContext(r.Context())
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -272,14 +269,14 @@ func (h *CommLogHandler) CreateContactCommLog(w http.ResponseWriter, r *http.Req
 
     contactID, err := strconv.Atoi(chi.URLParam(r, "contactId"))
     if err != nil {
-        slog.Error("Invalid contact ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid contact ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid contact ID")
         return
     }
 
     var req CreateCommLogRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -296,19 +293,19 @@ func (h *CommLogHandler) CreateContactCommLog(w http.ResponseWriter, r *http.Req
         CreatedAt:       &time.Time{},
     }
 
-    if err := h.commLogService.CreateContactCommLog(log); err != nil {
-        slog.Error("Failed to create contact communication log", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to create communication log: "+err.Error())
+    if err := h.commLogService.CreateContactCommLog(r.Context(), log); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to create contact communication log", "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully created contact communication log", "logID", log.ID, "contactID", contactID)
+    slog.InfoContext(r.Context(), "Successfully created contact communication log", "logID", log.ID, "contactID", contactID)
     respondWithJSON(w, http.StatusCreated, convertCommLogToResponse(log))
 }
 
 // UpdateContactCommLog handles PUT /api/v1/contacts/{contactId}/comm-logs/{logId}
 func (h *CommLogHandler) UpdateContactCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("UpdateContactCommLog called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "UpdateContactCommLog called", "method", r.Method, "url", r.URL.Path)
     userID, err := getUserIDFromContext(r.Context())
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -317,21 +314,27 @@ func (h *CommLogHandler) UpdateContactCommLog(w http.ResponseWriter, r *http.Req
 
     contactID, err := strconv.Atoi(chi.URLParam(r, "contactId"))
     if err != nil {
-        slog.Error("Invalid contact ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid contact ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid contact ID")
         return
     }
 
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
     var req UpdateCommLogRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -344,91 +347,72 @@ func (h *CommLogHandler) UpdateContactCommLog(w http.ResponseWriter, r *http.Req
         InteractionDate: req.InteractionDate,
         InteractionType: req.InteractionType,
         Notes:           req.Notes,
+        Version:         version,
     }
 
-    if err := h.commLogService.UpdateContactCommLog(log); err != nil {
-        slog.Error("Failed to update contact communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to update communication log: "+err.Error())
+    if err := h.commLogService.UpdateContactCommLog(r.Context(), log); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to update contact communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    updatedLog, err := h.commLogService.GetCommLogByID(logID)
+    updatedLog, err := h.commLogService.GetCommLogByID(r.Context(), logID)
     if err != nil {
-        slog.Error("Failed to fetch updated communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get updated communication log: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to fetch updated communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully updated contact communication log", "logID", logID, "contactID", contactID)
+    slog.InfoContext(r.Context(), "Successfully updated contact communication log", "logID", logID, "contactID", contactID)
     respondWithJSON(w, http.StatusOK, convertCommLogToResponse(updatedLog))
 }
 
 // DeleteContactCommLog handles DELETE /api/v1/contacts/{contactId}/comm-logs/{logId}
 func (h *CommLogHandler) DeleteContactCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("DeleteContactCommLog called", "method", r.Method, "url", r.URL.Path)
-    userID, err := getUserIDFromContext(r.Context())
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "Authentication required")
-        return
-    }
-
+    slog.InfoContext(r.Context(), "DeleteContactCommLog called", "method", r.Method, "url", r.URL.Path)
     contactID, err := strconv.Atoi(chi.URLParam(r, "contactId"))
     if err != nil {
-        slog.Error("Invalid contact ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid contact ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid contact ID")
         return
     }
 
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
-    existingLog, err := h.commLogService.GetCommLogByID(logID)
+    version, err := ifMatchVersion(r)
     if err != nil {
-        slog.Error("Communication log not found", "logID", logID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Communication log not found: "+err.Error())
-        return
-    }
-
-    if existingLog.ContactID == nil || (existingLog.ContactID != nil && *existingLog.ContactID != contactID) {
-        slog.Warn("Communication log does not belong to contact", "logID", logID, "contactID", contactID)
-        respondWithError(w, http.StatusNotFound, "Communication log not found for this contact")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    if existingLog.UserID != userID {
-        slog.Warn("Unauthorized to delete communication log", "userID", userID, "logUserID", existingLog.UserID)
-        respondWithError(w, http.StatusForbidden, "You can only delete your own communication logs")
+    if err := h.commLogService.DeleteContactCommLog(r.Context(), contactID, logID, version); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to delete contact communication log", "logID", logID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    if err := h.commLogService.DeleteContactCommLog(logID); err != nil {
-        slog.Error("Failed to delete contact communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to delete communication log: "+err.Error())
-        return
-    }
-
-    slog.Info("Successfully deleted contact communication log", "logID", logID, "contactID", contactID)
+    slog.InfoContext(r.Context(), "Successfully deleted contact communication log", "logID", logID, "contactID", contactID)
     respondWithJSON(w, http.StatusOK, map[string]string{"message": "Communication log deleted successfully"})
 }
 
-// GetDealCommLogs handles GET /api/v1/deals/{dealId}/comm-logs
-func (h *CommLogHandler) GetDealCommLogs(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetDealCommLogs called", "method", r.Method, "url", r.URL.Path)
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+// GetCommLogsForParent handles GET /api/v1/deals/{dealId}/comm-logs and
+// GET /api/v1/leads/{leadId}/comm-logs
+func (h *CommLogHandler) GetCommLogsForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    logs, err := h.commLogService.GetCommLogsByDealID(dealID)
+    logs, err := h.commLogService.GetCommLogsForParent(r.Context(), parent)
     if err != nil {
-        slog.Error("Failed to get deal communication logs", "dealID", dealID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get deal communication logs: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get communication logs", "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -437,29 +421,27 @@ func (h *CommLogHandler) GetDealCommLogs(w http.ResponseWriter, r *http.Request)
         logResponses[i] = convertCommLogToResponse(&log)
     }
 
-    slog.Info("Successfully retrieved deal communication logs", "dealID", dealID, "count", len(logs))
     respondWithJSON(w, http.StatusOK, logResponses)
 }
 
-// CreateDealCommLog handles POST /api/v1/deals/{dealId}/comm-logs
-func (h *CommLogHandler) CreateDealCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("CreateDealCommLog called", "method", r.Method, "url", r.URL.Path)
+// CreateCommLogForParent handles POST /api/v1/deals/{dealId}/comm-logs and
+// POST /api/v1/leads/{leadId}/comm-logs
+func (h *CommLogHandler) CreateCommLogForParent(w http.ResponseWriter, r *http.Request) {
     userID, err := getUserIDFromContext(r.Context())
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
         return
     }
 
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+    parent, err := parentFromRequest(r)
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    var req CreateCommLogRequest
+    var req UpdateCommLogRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -468,49 +450,77 @@ func (h *CommLogHandler) CreateDealCommLog(w http.ResponseWriter, r *http.Reques
     log := &models.CommLog{
         ContactID:       req.ContactID,
         UserID:          userID,
-        DealID:          &dealID,
         InteractionDate: req.InteractionDate,
         InteractionType: req.InteractionType,
         Notes:           req.Notes,
-        CreatedAt:       &time.Time{},
     }
 
-    if err := h.commLogService.CreateDealCommLog(log); err != nil {
-        slog.Error("Failed to create deal communication log", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to create deal communication log: "+err.Error())
+    if err := h.commLogService.CreateCommLogForParent(r.Context(), parent, log); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to create communication log", "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully created deal communication log", "logID", log.ID, "dealID", dealID)
+    slog.InfoContext(r.Context(), "Successfully created communication log", "logID", log.ID, "parent", parent)
     respondWithJSON(w, http.StatusCreated, convertCommLogToResponse(log))
 }
 
-// UpdateDealCommLog handles PUT /api/v1/deals/{dealId}/comm-logs/{logId}
-func (h *CommLogHandler) UpdateDealCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("UpdateDealCommLog called", "method", r.Method, "url", r.URL.Path)
+// GetCommLogForParent handles GET /api/v1/deals/{dealId}/comm-logs/{logId} and
+// GET /api/v1/leads/{leadId}/comm-logs/{logId}
+func (h *CommLogHandler) GetCommLogForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
+        return
+    }
+
+    log, err := h.commLogService.GetCommLogForParent(r.Context(), parent, logID)
+    if err != nil {
+        respondWithServiceError(w, err)
+        return
+    }
+
+    setETag(w, log.Version)
+    respondWithJSON(w, http.StatusOK, convertCommLogToResponse(log))
+}
+
+// UpdateCommLogForParent handles PUT /api/v1/deals/{dealId}/comm-logs/{logId}
+// and PUT /api/v1/leads/{leadId}/comm-logs/{logId}
+func (h *CommLogHandler) UpdateCommLogForParent(w http.ResponseWriter, r *http.Request) {
     userID, err := getUserIDFromContext(r.Context())
     if err != nil {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
         return
     }
 
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+    parent, err := parentFromRequest(r)
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
     var req UpdateCommLogRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -520,77 +530,57 @@ func (h *CommLogHandler) UpdateDealCommLog(w http.ResponseWriter, r *http.Reques
         ID:              logID,
         ContactID:       req.ContactID,
         UserID:          userID,
-        DealID:          &dealID,
         InteractionDate: req.InteractionDate,
         InteractionType: req.InteractionType,
         Notes:           req.Notes,
+        Version:         version,
     }
 
-    if err := h.commLogService.UpdateDealCommLog(log); err != nil {
-        slog.Error("Failed to update deal communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to update deal communication log: "+err.Error())
+    if err := h.commLogService.UpdateCommLogForParent(r.Context(), parent, log); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to update communication log", "logID", logID, "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    updatedLog, err := h.commLogService.GetCommLogByID(logID)
+    updatedLog, err := h.commLogService.GetCommLogByID(r.Context(), logID)
     if err != nil {
-        slog.Error("Failed to fetch updated communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get updated communication log: "+err.Error())
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully updated deal communication log", "logID", logID, "dealID", dealID)
+    slog.InfoContext(r.Context(), "Successfully updated communication log", "logID", logID, "parent", parent)
+    setETag(w, updatedLog.Version)
     respondWithJSON(w, http.StatusOK, convertCommLogToResponse(updatedLog))
 }
 
-// DeleteDealCommLog handles DELETE /api/v1/deals/{dealId}/comm-logs/{logId}
-func (h *CommLogHandler) DeleteDealCommLog(w http.ResponseWriter, r *http.Request) {
-    slog.Info("DeleteDealCommLog called", "method", r.Method, "url", r.URL.Path)
-    userID, err := getUserIDFromContext(r.Context())
-    if err != nil {
-        respondWithError(w, http.StatusUnauthorized, "Authentication required")
-        return
-    }
-
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+// DeleteCommLogForParent handles DELETE /api/v1/deals/{dealId}/comm-logs/{logId}
+// and DELETE /api/v1/leads/{leadId}/comm-logs/{logId}
+func (h *CommLogHandler) DeleteCommLogForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
     logID, err := strconv.Atoi(chi.URLParam(r, "logId"))
     if err != nil {
-        slog.Error("Invalid communication log ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid communication log ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid communication log ID")
         return
     }
 
-    existingLog, err := h.commLogService.GetCommLogByID(logID)
+    version, err := ifMatchVersion(r)
     if err != nil {
-        slog.Error("Communication log not found", "logID", logID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Communication log not found: "+err.Error())
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    if existingLog.DealID == nil || *existingLog.DealID != dealID {
-        slog.Warn("Communication log does not belong to deal", "logID", logID, "dealID", dealID)
-        respondWithError(w, http.StatusNotFound, "Communication log not found for this deal")
+    if err := h.commLogService.DeleteCommLogForParent(r.Context(), parent, logID, version); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to delete communication log", "logID", logID, "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    if existingLog.UserID != userID {
-        slog.Warn("Unauthorized to delete communication log", "userID", userID, "logUserID", existingLog.UserID)
-        respondWithError(w, http.StatusForbidden, "You can only delete your own communication logs")
-        return
-    }
-
-    if err := h.commLogService.DeleteDealCommLog(logID); err != nil {
-        slog.Error("Failed to delete deal communication log", "logID", logID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to delete deal communication log: "+err.Error())
-        return
-    }
-
-    slog.Info("Successfully deleted deal communication log", "logID", logID, "dealID", dealID)
-    respondWithJSON(w, http.StatusOK, map[string]string{"message": "Deal communication log deleted successfully"})
-}
\ No newline at end of file
+    slog.InfoContext(r.Context(), "Successfully deleted communication log", "logID", logID, "parent", parent)
+    respondWithJSON(w, http.StatusOK, map[string]string{"message": "Communication log deleted successfully"})
+}
@@ -4,7 +4,6 @@ package handlers
 import (
 	"crm-project/internal/service"
 	"encoding/json"
-	"log"
 	"net/http"
 	"crm-project/internal/models"
 	"github.com/go-chi/chi/v5"
@@ -35,13 +34,13 @@ func (h *ContactHandler) GetAllContacts(w http.ResponseWriter, r *http.Request)
 
 	contacts, err := h.service.GetAllContacts(ctx)
 	if err != nil {
-		h.logger.Error("error getting all contacts", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "error getting all contacts", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
 	// The handler's job is to format the response correctly.
-	h.logger.Debug("retrieved all contacts", "count", len(contacts))
+	h.logger.DebugContext(r.Context(), "retrieved all contacts", "count", len(contacts))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(contacts)
 
@@ -62,17 +61,15 @@ func (h *ContactHandler) CreateContact(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&newContact)
 	if err != nil {
 		// If there's an error in the JSON, it's a client error.
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// 2. Call the service layer with the decoded data.
 	newID, err := h.service.CreateContact(ctx, newContact)
 	if err != nil {
-		// Our service layer validation might return an error.
-		// A real app would check the type of error and return a more specific status code.
-		log.Printf("Error creating contact: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest) // Send back the validation error message
+		h.logger.WarnContext(ctx, "failed to create contact", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -94,26 +91,27 @@ func (h *ContactHandler) GetContactByID(w http.ResponseWriter, r *http.Request)
 	// 1. Get the URL parameter using the CORRECT name "contactId" from our router.
 	idStr := chi.URLParam(r, "contactId")
 
-	h.logger.Info("GetContactByID called with idStr:", "id", idStr) // Added log
+	h.logger.InfoContext(r.Context(), "GetContactByID called with idStr:", "id", idStr) // Added log
 
 	// 2. Safely convert the string to an integer and HANDLE THE ERROR.
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		// This will catch cases where the ID is not a number, or is missing.
-		h.logger.Warn("invalid contact ID in URL", "raw_id", idStr, "error", err)
-		http.Error(w, "Invalid contact ID format", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid contact ID in URL", "raw_id", idStr, "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID format")
 		return
 	}
 
 	// 3. Call the service with the now-validated ID.
 	contact, err := h.service.GetContactByID(ctx, id)
 	if err != nil {
-		h.logger.Warn("contact not found", "contact_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.logger.WarnContext(r.Context(), "contact not found", "contact_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 	
-	h.logger.Debug("retrieved contact by id", "contact_id", id)
+	h.logger.DebugContext(r.Context(), "retrieved contact by id", "contact_id", id)
+	setETag(w, contact.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(contact)
 }
@@ -126,46 +124,90 @@ func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "contactId") // <-- THE FIX
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid contact ID format", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID format")
 		return
 	}
 
 	var contactToUpdate models.Contact
 	if err := json.NewDecoder(r.Body).Decode(&contactToUpdate); err != nil {
-		h.logger.Warn("invalid update contact request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid update contact request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if version != 0 {
+		contactToUpdate.Version = version
+	}
 	
 	err = h.service.UpdateContact(ctx, id, contactToUpdate)
 	if err != nil {
-		h.logger.Error("failed to update contact", "contact_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError) // Or be more specific based on the error
+		h.logger.ErrorContext(r.Context(), "failed to update contact", "contact_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 	
-	h.logger.Info("contact updated successfully", "contact_id", id)
+	h.logger.InfoContext(r.Context(), "contact updated successfully", "contact_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 
+// PatchContact handles PATCH /api/v1/contacts/{contactId} with a JSON merge patch.
+func (h *ContactHandler) PatchContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "contactId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	contact, err := h.service.PatchContact(ctx, id, patch, version)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to patch contact", "contact_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "contact patched successfully", "contact_id", id)
+	setETag(w, contact.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
 // Replace the DeleteContact function in contact_handler.go with this:
 func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	idStr := chi.URLParam(r, "contactId") // <-- THE FIX
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid contact ID format", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID format")
 		return
 	}
 	
-	err = h.service.DeleteContact(ctx, id)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.service.DeleteContact(ctx, id, version)
 	if err != nil {
-		h.logger.Error("failed to delete contact", "contact_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to delete contact", "contact_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 	
-	h.logger.Info("contact deleted successfully", "contact_id", id)
+	h.logger.InfoContext(r.Context(), "contact deleted successfully", "contact_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
\ No newline at end of file
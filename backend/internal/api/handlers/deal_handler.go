@@ -26,15 +26,15 @@ func (h *DealHandler) CreateDeal(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		h.logger.Error("claims not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		h.logger.ErrorContext(r.Context(), "claims not found in context")
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var newDeal models.Deal
 	if err := json.NewDecoder(r.Body).Decode(&newDeal); err != nil {
-		h.logger.Warn("invalid create deal request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid create deal request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -55,11 +55,11 @@ func (h *DealHandler) CreateDeal(w http.ResponseWriter, r *http.Request) {
 
 	newID, err := h.service.CreateDeal(ctx, newDeal)
 	if err != nil {
-		h.logger.Error("failed to create deal", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "failed to create deal", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("deal created successfully", "deal_id", newID, "created_by", newDeal.CreatedBy.Int64)
+	h.logger.InfoContext(r.Context(), "deal created successfully", "deal_id", newID, "created_by", newDeal.CreatedBy.Int64)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]int{"id": newID})
@@ -69,18 +69,18 @@ func (h *DealHandler) GetAllDeals(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		h.logger.Error("claims not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		h.logger.ErrorContext(r.Context(), "claims not found in context")
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	deals, err := h.service.GetAllDeals(ctx, claims.UserID, claims.RoleID)
 	if err != nil {
-		h.logger.Error("failed to get all deals", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to get all deals", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	h.logger.Debug("retrieved all deals", "count", len(deals), "user_id", claims.UserID, "role_id", claims.RoleID)
+	h.logger.DebugContext(r.Context(), "retrieved all deals", "count", len(deals), "user_id", claims.UserID, "role_id", claims.RoleID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(deals)
 }
@@ -89,27 +89,24 @@ func (h *DealHandler) GetDealByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		h.logger.Error("claims not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		h.logger.ErrorContext(r.Context(), "claims not found in context")
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid deal ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
 		return
 	}
 	deal, err := h.service.GetDealByID(ctx, id, claims.UserID, claims.RoleID)
 	if err != nil {
-		h.logger.Warn("deal not found or unauthorized", "deal_id", id, "user_id", claims.UserID, "role_id", claims.RoleID, "error", err)
-		if err.Error() == "unauthorized" {
-			http.Error(w, "Unauthorized to view this deal", http.StatusForbidden)
-		} else {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		}
+		h.logger.WarnContext(r.Context(), "deal not found or unauthorized", "deal_id", id, "user_id", claims.UserID, "role_id", claims.RoleID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Debug("retrieved deal by id", "deal_id", id, "user_id", claims.UserID)
+	h.logger.DebugContext(r.Context(), "retrieved deal by id", "deal_id", id, "user_id", claims.UserID)
+	setETag(w, deal.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(deal)
 }
@@ -118,60 +115,102 @@ func (h *DealHandler) UpdateDeal(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		h.logger.Error("claims not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		h.logger.ErrorContext(r.Context(), "claims not found in context")
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid deal ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
 		return
 	}
 	var d models.Deal
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
-		h.logger.Warn("invalid update deal request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid update deal request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if version != 0 {
+		d.Version = version
+	}
 	err = h.service.UpdateDeal(ctx, id, d, claims.UserID, claims.RoleID)
 	if err != nil {
-		h.logger.Error("failed to update deal", "deal_id", id, "user_id", claims.UserID, "role_id", claims.RoleID, "error", err)
-		if err.Error() == "unauthorized" {
-			http.Error(w, "Unauthorized to update this deal", http.StatusForbidden)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		h.logger.ErrorContext(r.Context(), "failed to update deal", "deal_id", id, "user_id", claims.UserID, "role_id", claims.RoleID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("deal updated successfully", "deal_id", id, "user_id", claims.UserID)
+	h.logger.InfoContext(r.Context(), "deal updated successfully", "deal_id", id, "user_id", claims.UserID)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// PatchDeal handles PATCH /api/v1/deals/{id} with a JSON merge patch.
+func (h *DealHandler) PatchDeal(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		h.logger.ErrorContext(r.Context(), "claims not found in context")
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	deal, err := h.service.PatchDeal(ctx, id, patch, version, claims.UserID, claims.RoleID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to patch deal", "deal_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "deal patched successfully", "deal_id", id)
+	setETag(w, deal.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deal)
+}
+
 func (h *DealHandler) DeleteDeal(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		h.logger.Error("claims not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		h.logger.ErrorContext(r.Context(), "claims not found in context")
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid deal ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
 		return
 	}
-	err = h.service.DeleteDeal(ctx, id, claims.UserID, claims.RoleID)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		h.logger.Error("failed to delete deal", "deal_id", id, "user_id", claims.UserID, "role_id", claims.RoleID, "error", err)
-		if err.Error() == "unauthorized" {
-			http.Error(w, "Unauthorized to delete this deal", http.StatusForbidden)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = h.service.DeleteDeal(ctx, id, version, claims.UserID, claims.RoleID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to delete deal", "deal_id", id, "user_id", claims.UserID, "role_id", claims.RoleID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("deal deleted successfully", "deal_id", id, "user_id", claims.UserID)
+	h.logger.InfoContext(r.Context(), "deal deleted successfully", "deal_id", id, "user_id", claims.UserID)
 	w.WriteHeader(http.StatusNoContent)
 }
\ No newline at end of file
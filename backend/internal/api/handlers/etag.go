@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETags are the entity's row version, e.g. "3". A client that sends the ETag
+// back in If-Match on PUT or DELETE only succeeds if nobody else has modified
+// the record in the meantime.
+
+// setETag sets the ETag header for a versioned entity.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion parses the If-Match header into an entity version. It returns
+// 0, which skips the version check, when the header is absent or "*".
+func ifMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag := strings.TrimPrefix(header, "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match header: %q", header)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header: %q", header)
+	}
+	return version, nil
+}
@@ -53,6 +53,7 @@ type EventResponse struct {
 	DealID           *int       `json:"deal_id,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+	Version          int        `json:"version"`
 }
 
 // Helper function to convert models.Event to EventResponse
@@ -69,18 +70,19 @@ func convertEventToResponse(event *models.Event) EventResponse {
 		DealID:           event.DealID,
 		CreatedAt:        event.CreatedAt,
 		UpdatedAt:        event.UpdatedAt,
+		Version:          event.Version,
 	}
 	return response
 }
 
 // GetAllEvents handles GET /api/v1/events
 func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
-	slog.Info("GetAllEvents called", "method", r.Method, "url", r.URL.Path)
+	slog.InfoContext(r.Context(), "GetAllEvents called", "method", r.Method, "url", r.URL.Path)
 
-	events, err := h.eventService.GetAllEvents()
+	events, err := h.eventService.GetAllEvents(r.Context())
 	if err != nil {
-		slog.Error("Failed to get all events", "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to get events: "+err.Error())
+		slog.ErrorContext(r.Context(), "Failed to get all events", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -89,13 +91,13 @@ func (h *EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
 		eventResponses[i] = convertEventToResponse(&event)
 	}
 
-	slog.Info("Successfully retrieved all events", "count", len(events))
+	slog.InfoContext(r.Context(), "Successfully retrieved all events", "count", len(events))
 	respondWithJSON(w, http.StatusOK, eventResponses)
 }
 
 // CreateEvent handles POST /api/v1/events
 func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	slog.Info("CreateEvent called", "method", r.Method, "url", r.URL.Path)
+	slog.InfoContext(r.Context(), "CreateEvent called", "method", r.Method, "url", r.URL.Path)
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -104,7 +106,7 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
 	var req CreateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request body", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
@@ -122,40 +124,41 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:        time.Now(),
 	}
 
-	if err := h.eventService.CreateEvent(event); err != nil {
-		slog.Error("Failed to create event", "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create event: "+err.Error())
+	if err := h.eventService.CreateEvent(r.Context(), event); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create event", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully created event", "eventID", event.ID)
+	slog.InfoContext(r.Context(), "Successfully created event", "eventID", event.ID)
 	respondWithJSON(w, http.StatusCreated, convertEventToResponse(event))
 }
 
 // GetEventByID handles GET /api/v1/events/{eventId}
 func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
-	slog.Info("GetEventByID called", "method", r.Method, "url", r.URL.Path)
+	slog.InfoContext(r.Context(), "GetEventByID called", "method", r.Method, "url", r.URL.Path)
 	eventID, err := strconv.Atoi(chi.URLParam(r, "eventId"))
 	if err != nil {
-		slog.Error("Invalid event ID", "error", err)
+		slog.ErrorContext(r.Context(), "Invalid event ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
-	event, err := h.eventService.GetEventByID(eventID)
+	event, err := h.eventService.GetEventByID(r.Context(), eventID)
 	if err != nil {
-		slog.Error("Failed to get event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusNotFound, "Event not found: "+err.Error())
+		slog.ErrorContext(r.Context(), "Failed to get event", "eventID", eventID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully retrieved event", "eventID", eventID)
+	slog.InfoContext(r.Context(), "Successfully retrieved event", "eventID", eventID)
+	setETag(w, event.Version)
 	respondWithJSON(w, http.StatusOK, convertEventToResponse(event))
 }
 
 // UpdateEvent handles PUT /api/v1/events/{eventId}
 func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
-	slog.Info("UpdateEvent called", "method", r.Method, "url", r.URL.Path)
+	slog.InfoContext(r.Context(), "UpdateEvent called", "method", r.Method, "url", r.URL.Path)
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -164,14 +167,20 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 
 	eventID, err := strconv.Atoi(chi.URLParam(r, "eventId"))
 	if err != nil {
-		slog.Error("Invalid event ID", "error", err)
+		slog.ErrorContext(r.Context(), "Invalid event ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req UpdateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request body", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
@@ -185,78 +194,66 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		EndTime:          req.EndTime,
 		Location:         req.Location,
 		OrganizerID:      userID,
+		Version:          version,
 	}
 
-	if err := h.eventService.UpdateEvent(event); err != nil {
-		slog.Error("Failed to update event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to update event: "+err.Error())
+	if err := h.eventService.UpdateEvent(r.Context(), event); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update event", "eventID", eventID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	updatedEvent, err := h.eventService.GetEventByID(eventID)
+	updatedEvent, err := h.eventService.GetEventByID(r.Context(), eventID)
 	if err != nil {
-		slog.Error("Failed to fetch updated event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to get updated event: "+err.Error())
+		slog.ErrorContext(r.Context(), "Failed to fetch updated event", "eventID", eventID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully updated event", "eventID", eventID)
+	slog.InfoContext(r.Context(), "Successfully updated event", "eventID", eventID)
 	respondWithJSON(w, http.StatusOK, convertEventToResponse(updatedEvent))
 }
 
 // DeleteEvent handles DELETE /api/v1/events/{eventId}
 func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
-	slog.Info("DeleteEvent called", "method", r.Method, "url", r.URL.Path)
-	userID, err := getUserIDFromContext(r.Context())
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
-		return
-	}
-
+	slog.InfoContext(r.Context(), "DeleteEvent called", "method", r.Method, "url", r.URL.Path)
 	eventID, err := strconv.Atoi(chi.URLParam(r, "eventId"))
 	if err != nil {
-		slog.Error("Invalid event ID", "error", err)
+		slog.ErrorContext(r.Context(), "Invalid event ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
-	existingEvent, err := h.eventService.GetEventByID(eventID)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		slog.Error("Event not found", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusNotFound, "Event not found: "+err.Error())
-		return
-	}
-
-	if existingEvent.OrganizerID != userID {
-		slog.Warn("Unauthorized to delete event", "userID", userID, "eventOrganizerID", existingEvent.OrganizerID)
-		respondWithError(w, http.StatusForbidden, "You can only delete your own events")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := h.eventService.DeleteEvent(eventID); err != nil {
-		slog.Error("Failed to delete event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete event: "+err.Error())
+	if err := h.eventService.DeleteEvent(r.Context(), eventID, version); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete event", "eventID", eventID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully deleted event", "eventID", eventID)
+	slog.InfoContext(r.Context(), "Successfully deleted event", "eventID", eventID)
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Event deleted successfully"})
 }
 
 // GetEventsForUser handles GET /api/v1/users/{userId}/events
 func (h *EventHandler) GetEventsForUser(w http.ResponseWriter, r *http.Request) {
-	slog.Info("GetEventsForUser called", "method", r.Method, "url", r.URL.Path)
+	slog.InfoContext(r.Context(), "GetEventsForUser called", "method", r.Method, "url", r.URL.Path)
 	userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
 	if err != nil {
-		slog.Error("Invalid user ID", "error", err)
+		slog.ErrorContext(r.Context(), "Invalid user ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	events, err := h.eventService.GetEventsForUser(userID)
+	events, err := h.eventService.GetEventsForUser(r.Context(), userID)
 	if err != nil {
-		slog.Error("Failed to get user events", "userID", userID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to get user events: "+err.Error())
+		slog.ErrorContext(r.Context(), "Failed to get user events", "userID", userID, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -265,24 +262,23 @@ func (h *EventHandler) GetEventsForUser(w http.ResponseWriter, r *http.Request)
 		eventResponses[i] = convertEventToResponse(&event)
 	}
 
-	slog.Info("Successfully retrieved user events", "userID", userID, "count", len(events))
+	slog.InfoContext(r.Context(), "Successfully retrieved user events", "userID", userID, "count", len(events))
 	respondWithJSON(w, http.StatusOK, eventResponses)
 }
 
-// GetDealEvents handles GET /api/v1/deals/{dealId}/events
-func (h *EventHandler) GetDealEvents(w http.ResponseWriter, r *http.Request) {
-	slog.Info("GetDealEvents called", "method", r.Method, "url", r.URL.Path)
-	dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+// GetEventsForParent handles GET /api/v1/deals/{dealId}/events and
+// GET /api/v1/leads/{leadId}/events
+func (h *EventHandler) GetEventsForParent(w http.ResponseWriter, r *http.Request) {
+	parent, err := parentFromRequest(r)
 	if err != nil {
-		slog.Error("Invalid deal ID", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	events, err := h.eventService.GetEventsByDealID(dealID)
+	events, err := h.eventService.GetEventsForParent(r.Context(), parent)
 	if err != nil {
-		slog.Error("Failed to get deal events", "dealID", dealID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to get deal events: "+err.Error())
+		slog.ErrorContext(r.Context(), "Failed to get events", "parent", parent, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -291,29 +287,27 @@ func (h *EventHandler) GetDealEvents(w http.ResponseWriter, r *http.Request) {
 		eventResponses[i] = convertEventToResponse(&event)
 	}
 
-	slog.Info("Successfully retrieved deal events", "dealID", dealID, "count", len(events))
 	respondWithJSON(w, http.StatusOK, eventResponses)
 }
 
-// CreateDealEvent handles POST /api/v1/deals/{dealId}/events
-func (h *EventHandler) CreateDealEvent(w http.ResponseWriter, r *http.Request) {
-	slog.Info("CreateDealEvent called", "method", r.Method, "url", r.URL.Path)
+// CreateEventForParent handles POST /api/v1/deals/{dealId}/events and
+// POST /api/v1/leads/{leadId}/events
+func (h *EventHandler) CreateEventForParent(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Authentication required")
 		return
 	}
 
-	dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+	parent, err := parentFromRequest(r)
 	if err != nil {
-		slog.Error("Invalid deal ID", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	var req CreateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request body", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
@@ -326,46 +320,75 @@ func (h *EventHandler) CreateDealEvent(w http.ResponseWriter, r *http.Request) {
 		EndTime:          req.EndTime,
 		Location:         req.Location,
 		OrganizerID:      userID,
-		DealID:           &dealID,
 		CreatedAt:        time.Now(),
 	}
 
-	if err := h.eventService.CreateDealEvent(event); err != nil {
-		slog.Error("Failed to create deal event", "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create deal event: "+err.Error())
+	if err := h.eventService.CreateEventForParent(r.Context(), parent, event); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create event", "parent", parent, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully created deal event", "eventID", event.ID, "dealID", dealID)
+	slog.InfoContext(r.Context(), "Successfully created event", "eventID", event.ID, "parent", parent)
 	respondWithJSON(w, http.StatusCreated, convertEventToResponse(event))
 }
 
-// UpdateDealEvent handles PUT /api/v1/deals/{dealId}/events/{eventId}
-func (h *EventHandler) UpdateDealEvent(w http.ResponseWriter, r *http.Request) {
-	slog.Info("UpdateDealEvent called", "method", r.Method, "url", r.URL.Path)
+// GetEventForParent handles GET /api/v1/deals/{dealId}/events/{eventId} and
+// GET /api/v1/leads/{leadId}/events/{eventId}
+func (h *EventHandler) GetEventForParent(w http.ResponseWriter, r *http.Request) {
+	parent, err := parentFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	eventID, err := strconv.Atoi(chi.URLParam(r, "eventId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	event, err := h.eventService.GetEventForParent(r.Context(), parent, eventID)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	setETag(w, event.Version)
+	respondWithJSON(w, http.StatusOK, convertEventToResponse(event))
+}
+
+// UpdateEventForParent handles PUT /api/v1/deals/{dealId}/events/{eventId} and
+// PUT /api/v1/leads/{leadId}/events/{eventId}
+func (h *EventHandler) UpdateEventForParent(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Authentication required")
 		return
 	}
 
-	dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+	parent, err := parentFromRequest(r)
 	if err != nil {
-		slog.Error("Invalid deal ID", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	eventID, err := strconv.Atoi(chi.URLParam(r, "eventId"))
 	if err != nil {
-		slog.Error("Invalid event ID", "error", err)
+		slog.ErrorContext(r.Context(), "Invalid event ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req UpdateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request body", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
@@ -379,74 +402,54 @@ func (h *EventHandler) UpdateDealEvent(w http.ResponseWriter, r *http.Request) {
 		EndTime:          req.EndTime,
 		Location:         req.Location,
 		OrganizerID:      userID,
-		DealID:           &dealID,
+		Version:          version,
 	}
 
-	if err := h.eventService.UpdateDealEvent(event); err != nil {
-		slog.Error("Failed to update deal event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to update deal event: "+err.Error())
+	if err := h.eventService.UpdateEventForParent(r.Context(), parent, event); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update event", "eventID", eventID, "parent", parent, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	updatedEvent, err := h.eventService.GetEventByID(eventID)
+	updatedEvent, err := h.eventService.GetEventByID(r.Context(), eventID)
 	if err != nil {
-		slog.Error("Failed to fetch updated event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to get updated event: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully updated deal event", "eventID", eventID, "dealID", dealID)
+	slog.InfoContext(r.Context(), "Successfully updated event", "eventID", eventID, "parent", parent)
+	setETag(w, updatedEvent.Version)
 	respondWithJSON(w, http.StatusOK, convertEventToResponse(updatedEvent))
 }
 
-// DeleteDealEvent handles DELETE /api/v1/deals/{dealId}/events/{eventId}
-func (h *EventHandler) DeleteDealEvent(w http.ResponseWriter, r *http.Request) {
-	slog.Info("DeleteDealEvent called", "method", r.Method, "url", r.URL.Path)
-	userID, err := getUserIDFromContext(r.Context())
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
-		return
-	}
-
-	dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+// DeleteEventForParent handles DELETE /api/v1/deals/{dealId}/events/{eventId}
+// and DELETE /api/v1/leads/{leadId}/events/{eventId}
+func (h *EventHandler) DeleteEventForParent(w http.ResponseWriter, r *http.Request) {
+	parent, err := parentFromRequest(r)
 	if err != nil {
-		slog.Error("Invalid deal ID", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	eventID, err := strconv.Atoi(chi.URLParam(r, "eventId"))
 	if err != nil {
-		slog.Error("Invalid event ID", "error", err)
+		slog.ErrorContext(r.Context(), "Invalid event ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
-	existingEvent, err := h.eventService.GetEventByID(eventID)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		slog.Error("Event not found", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusNotFound, "Event not found: "+err.Error())
-		return
-	}
-
-	if existingEvent.DealID == nil || *existingEvent.DealID != dealID {
-		slog.Warn("Event does not belong to deal", "eventID", eventID, "dealID", dealID)
-		respondWithError(w, http.StatusNotFound, "Event not found for this deal")
-		return
-	}
-
-	if existingEvent.OrganizerID != userID {
-		slog.Warn("Unauthorized to delete event", "userID", userID, "eventOrganizerID", existingEvent.OrganizerID)
-		respondWithError(w, http.StatusForbidden, "You can only delete your own events")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := h.eventService.DeleteEvent(eventID); err != nil {
-		slog.Error("Failed to delete deal event", "eventID", eventID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete deal event: "+err.Error())
+	if err := h.eventService.DeleteEventForParent(r.Context(), parent, eventID, version); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete event", "eventID", eventID, "parent", parent, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully deleted deal event", "eventID", eventID, "dealID", dealID)
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Deal event deleted successfully"})
-}
\ No newline at end of file
+	slog.InfoContext(r.Context(), "Successfully deleted event", "eventID", eventID, "parent", parent)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Event deleted successfully"})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"crm-project/internal/buildinfo"
+)
+
+// readyCheckTimeout bounds each dependency check made by /readyz so a hung
+// database makes the instance unready instead of hanging the probe.
+const readyCheckTimeout = 2 * time.Second
+
+// Pinger is satisfied by *sqlx.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// SchemaChecker is satisfied by *migrate.Migrator.
+type SchemaChecker interface {
+	Check(ctx context.Context) error
+	Current(ctx context.Context) (int, error)
+	Latest() int
+}
+
+// WorkerMonitor is satisfied by *health.Workers.
+type WorkerMonitor interface {
+	Stopped() []string
+}
+
+// HealthHandler serves the unauthenticated probes used by load balancers and
+// deploy scripts.
+type HealthHandler struct {
+	db       Pinger
+	schema   SchemaChecker
+	workers  WorkerMonitor
+	logger   *slog.Logger
+	draining atomic.Bool
+}
+
+func NewHealthHandler(db Pinger, schema SchemaChecker, workers WorkerMonitor, logger *slog.Logger) *HealthHandler {
+	return &HealthHandler{db: db, schema: schema, workers: workers, logger: logger}
+}
+
+// SetDraining makes /readyz fail so the load balancer stops sending new
+// requests while the server shuts down.
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Healthz reports that the process is up and serving HTTP. It checks no
+// dependencies, so a database outage does not get the instance restarted.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+type readyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Readyz reports whether the instance should receive traffic: the database
+// answers, the schema matches this build's migrations and every background
+// worker is still running. Failing checks answer 503 with the reason.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	resp := readyResponse{Status: "ok", Checks: map[string]string{}}
+	fail := func(check, reason string) {
+		resp.Status = "unavailable"
+		resp.Checks[check] = reason
+	}
+
+	if h.draining.Load() {
+		fail("server", "shutting down")
+	}
+	if err := h.db.PingContext(ctx); err != nil {
+		h.logger.WarnContext(r.Context(), "readiness: database ping failed", "error", err)
+		fail("database", "unreachable")
+	} else {
+		resp.Checks["database"] = "ok"
+		if err := h.schema.Check(ctx); err != nil {
+			h.logger.WarnContext(r.Context(), "readiness: schema check failed", "error", err)
+			fail("migrations", err.Error())
+		} else {
+			resp.Checks["migrations"] = "ok"
+		}
+	}
+	if stopped := h.workers.Stopped(); len(stopped) > 0 {
+		fail("workers", "stopped: "+strings.Join(stopped, ", "))
+	} else {
+		resp.Checks["workers"] = "ok"
+	}
+
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, status, resp)
+}
+
+type versionResponse struct {
+	buildinfo.Info
+	SchemaVersion int `json:"schema_version"`
+	SchemaLatest  int `json:"schema_latest"`
+}
+
+// Version reports the running build and database schema version. The schema
+// version is 0 if the database cannot be reached.
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	resp := versionResponse{Info: buildinfo.Get(), SchemaLatest: h.schema.Latest()}
+	current, err := h.schema.Current(ctx)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "version: could not read schema version", "error", err)
+	}
+	resp.SchemaVersion = current
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"crm-project/internal/dto"
+	"crm-project/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type InvitationHandler struct {
+	service *service.InvitationService
+	logger  *slog.Logger
+}
+
+func NewInvitationHandler(s *service.InvitationService, logger *slog.Logger) *InvitationHandler {
+	return &InvitationHandler{service: s, logger: logger}
+}
+
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req dto.CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "invalid create invitation request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	created, err := h.service.CreateInvitation(ctx, req)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to create invitation", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "invitation created successfully", "invitation_id", created.Invitation.ID)
+	// The response holds the raw invite token and accept link; keep it out of
+	// caches and stored idempotent responses.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *InvitationHandler) GetAllInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	invitations, err := h.service.GetAllInvitations(ctx)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to get invitations", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	h.logger.DebugContext(r.Context(), "retrieved all invitations", "count", len(invitations))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "invitationId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+	if err := h.service.RevokeInvitation(ctx, id); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to revoke invitation", "invitation_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "invitation revoked successfully", "invitation_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
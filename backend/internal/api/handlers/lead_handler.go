@@ -25,17 +25,17 @@ func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var newLead models.Lead
 	if err := json.NewDecoder(r.Body).Decode(&newLead); err != nil {
-		h.logger.Warn("invalid create lead request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid create lead request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	newID, err := h.service.CreateLead(ctx, newLead)
 	if err != nil {
-		h.logger.Error("failed to create lead", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "failed to create lead", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("lead created successfully", "lead_id", newID)
+	h.logger.InfoContext(r.Context(), "lead created successfully", "lead_id", newID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]int{"id": newID})
@@ -45,11 +45,11 @@ func (h *LeadHandler) GetAllLeads(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	leads, err := h.service.GetAllLeads(ctx)
 	if err != nil {
-		h.logger.Error("failed to get all leads", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to get all leads", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	h.logger.Debug("retrieved all leads", "count", len(leads))
+	h.logger.DebugContext(r.Context(), "retrieved all leads", "count", len(leads))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(leads)
 }
@@ -58,16 +58,17 @@ func (h *LeadHandler) GetLeadByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid lead ID")
 		return
 	}
 	lead, err := h.service.GetLeadByID(ctx, id)
 	if err != nil {
-		h.logger.Warn("lead not found", "lead_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.logger.WarnContext(r.Context(), "lead not found", "lead_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Debug("retrieved lead by id", "lead_id", id)
+	h.logger.DebugContext(r.Context(), "retrieved lead by id", "lead_id", id)
+	setETag(w, lead.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(lead)
 }
@@ -76,38 +77,81 @@ func (h *LeadHandler) UpdateLead(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid lead ID")
 		return
 	}
 	var l models.Lead
 	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
-		h.logger.Warn("invalid update lead request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid update lead request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if version != 0 {
+		l.Version = version
+	}
 	err = h.service.UpdateLead(ctx, id, l)
 	if err != nil {
-		h.logger.Error("failed to update lead", "lead_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to update lead", "lead_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("lead updated successfully", "lead_id", id)
+	h.logger.InfoContext(r.Context(), "lead updated successfully", "lead_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// PatchLead handles PATCH /api/v1/leads/{id} with a JSON merge patch.
+func (h *LeadHandler) PatchLead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	lead, err := h.service.PatchLead(ctx, id, patch, version)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to patch lead", "lead_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "lead patched successfully", "lead_id", id)
+	setETag(w, lead.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lead)
+}
+
 func (h *LeadHandler) DeleteLead(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	err = h.service.DeleteLead(ctx, id)
+	err = h.service.DeleteLead(ctx, id, version)
 	if err != nil {
-		h.logger.Error("failed to delete lead", "lead_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to delete lead", "lead_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("lead deleted successfully", "lead_id", id)
+	h.logger.InfoContext(r.Context(), "lead deleted successfully", "lead_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
\ No newline at end of file
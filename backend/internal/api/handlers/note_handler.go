@@ -8,7 +8,6 @@ import (
 	"strconv"
     "log/slog"
 
-	"time"
 	"crm-project/internal/models"
 	"crm-project/internal/service"
 	"crm-project/internal/util"
@@ -47,6 +46,7 @@ type NoteResponse struct {
 	DealID    *int   `json:"deal_id,omitempty"`
 	CreatedAt string `json:"created_at,omitempty"`
 	UpdatedAt string `json:"updated_at,omitempty"`
+	Version   int    `json:"version"`
 }
 
 // Helper function to get user ID from context
@@ -63,15 +63,10 @@ func respondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		slog.Error("failed to encode response", "error", err)
 	}
 }
 
-// Helper function to respond with error
-func respondWithError(w http.ResponseWriter, statusCode int, message string) {
-	respondWithJSON(w, statusCode, map[string]string{"error": message})
-}
-
 // Helper function to extract URL parameter
 func getURLParam(r *http.Request, param string) string {
 	// Implement based on your router (chi, gorilla/mux, etc.)
@@ -92,6 +87,7 @@ func convertNoteToResponse(note *models.Note) NoteResponse {
 		ContactID: note.ContactID,
 		LeadID:    note.LeadID,
 		DealID:    note.DealID,
+		Version:   note.Version,
 	}
 	
 	// Format timestamps if they exist
@@ -132,15 +128,15 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		Content:   req.Content,
 	}
 
-	if err := h.noteService.CreateNote(note); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create note: "+err.Error())
+	if err := h.noteService.CreateNote(r.Context(), note); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
 	// Get the created note with complete data including timestamps
-	createdNote, err := h.noteService.GetNoteByID(note.ID)
+	createdNote, err := h.noteService.GetNoteByID(r.Context(), note.ID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get created note: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -155,9 +151,9 @@ func (h *NoteHandler) GetContactNotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	notes, err := h.noteService.GetNotesByContactID(contactID)
+	notes, err := h.noteService.GetNotesByContactID(r.Context(), contactID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get notes: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -184,9 +180,9 @@ func (h *NoteHandler) GetNoteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	note, err := h.noteService.GetNoteByID(noteID)
+	note, err := h.noteService.GetNoteByID(r.Context(), noteID)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Note not found: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -196,6 +192,7 @@ func (h *NoteHandler) GetNoteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, note.Version)
 	respondWithJSON(w, http.StatusOK, convertNoteToResponse(note))
 }
 
@@ -220,9 +217,9 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify the note exists and belongs to the contact
-	existingNote, err := h.noteService.GetNoteByID(noteID)
+	existingNote, err := h.noteService.GetNoteByID(r.Context(), noteID)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Note not found: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -231,6 +228,12 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req UpdateNoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
@@ -242,17 +245,18 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 		ID:      noteID,
 		UserID:  userID,
 		Content: req.Content,
+		Version: version,
 	}
 
-	if err := h.noteService.UpdateNote(note); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update note: "+err.Error())
+	if err := h.noteService.UpdateNote(r.Context(), note); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
 	// Get the updated note to return complete data
-	updatedNote, err := h.noteService.GetNoteByID(noteID)
+	updatedNote, err := h.noteService.GetNoteByID(r.Context(), noteID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get updated note: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -261,12 +265,6 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 
 // DeleteNote handles DELETE /api/v1/contacts/{contactId}/notes/{noteId}
 func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
-	userID, err := getUserIDFromContext(r.Context())
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
-		return
-	}
-
 	noteID, err := strconv.Atoi(getURLParam(r, "noteId"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
@@ -280,9 +278,9 @@ func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify the note exists and belongs to the contact
-	existingNote, err := h.noteService.GetNoteByID(noteID)
+	existingNote, err := h.noteService.GetNoteByID(r.Context(), noteID)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Note not found: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -291,14 +289,14 @@ func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify the user owns the note (optional security check)
-	if existingNote.UserID != userID {
-		respondWithError(w, http.StatusForbidden, "You can only delete your own notes")
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := h.noteService.DeleteNote(noteID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete note: "+err.Error())
+	if err := h.noteService.DeleteNote(r.Context(), noteID, version); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -313,9 +311,9 @@ func (h *NoteHandler) GetUserNotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	notes, err := h.noteService.GetNotesByUserID(userID)
+	notes, err := h.noteService.GetNotesByUserID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get user notes: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -328,156 +326,122 @@ func (h *NoteHandler) GetUserNotes(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, noteResponses)
 }
 
+// GetNotesForParent handles GET /api/v1/deals/{dealId}/notes and
+// GET /api/v1/leads/{leadId}/notes
+func (h *NoteHandler) GetNotesForParent(w http.ResponseWriter, r *http.Request) {
+	parent, err := parentFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	notes, err := h.noteService.GetNotesForParent(r.Context(), parent)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get notes", "parent", parent, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
 
+	noteResponses := make([]NoteResponse, len(notes))
+	for i, note := range notes {
+		noteResponses[i] = convertNoteToResponse(&note)
+	}
 
+	respondWithJSON(w, http.StatusOK, noteResponses)
+}
 
-
-
-
-
-
-
-
-
-
-
-
-
-
-// CreateDealNote handles POST /api/v1/deals/{dealId}/notes
-func (h *NoteHandler) CreateDealNote(w http.ResponseWriter, r *http.Request) {
-	slog.Info("CreateDealNote called",
-		"method", r.Method,
-		"url", r.URL.Path,
-		"dealID", chi.URLParam(r, "dealId"))
-
-	// Temporarily bypass userID check to test route
-	// userID, err := getUserIDFromContext(r.Context())
-	// if err != nil {
-	// 	respondWithError(w, http.StatusUnauthorized, "Authentication required")
-	// 	return
-	// }
-	// slog.Debug("Authenticated user", "userID", userID)
-
-	// Get dealId from URL
-	dealIDStr := chi.URLParam(r, "dealId")
-	dealIDInt, err := strconv.Atoi(dealIDStr)
+// CreateNoteForParent handles POST /api/v1/deals/{dealId}/notes and
+// POST /api/v1/leads/{leadId}/notes
+func (h *NoteHandler) CreateNoteForParent(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
-		slog.Error("Invalid deal ID", "dealID", dealIDStr, "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		respondWithError(w, http.StatusUnauthorized, "Authentication required")
 		return
 	}
-	dealID := &dealIDInt // Pointer, because models.Note.DealID is *int
 
-	// Parse request body
-	var req struct {
-		Content string `json:"content"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request body", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	parent, err := parentFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer func() {
-		if err := r.Body.Close(); err != nil {
-			slog.Warn("Failed to close request body", "error", err)
-		}
-	}()
-	slog.Debug("Parsed request body", "content", req.Content)
 
-	// Validate request
-	if req.Content == "" {
-		slog.Error("Content is empty")
-		respondWithError(w, http.StatusBadRequest, "Content is required")
+	var req UpdateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	defer r.Body.Close()
 
-	now := time.Now()
 	note := &models.Note{
-		Content:   req.Content,
-		DealID:    dealID,
-		UserID:    0, // Temporary placeholder since auth is bypassed
-		CreatedAt: now,
-		UpdatedAt: &now,
+		UserID:  userID,
+		Content: req.Content,
 	}
 
-	// Insert note into database
-	if err := h.noteService.CreateNote(note); err != nil {
-		slog.Error("Failed to create deal note", "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create deal note: "+err.Error())
+	if err := h.noteService.CreateNoteForParent(r.Context(), parent, note); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create note", "parent", parent, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	// Fetch the created note
-	createdNote, err := h.noteService.GetNoteByID(note.ID)
+	createdNote, err := h.noteService.GetNoteByID(r.Context(), note.ID)
 	if err != nil {
-		slog.Error("Failed to fetch created note", "noteID", note.ID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to fetch created note: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
-	slog.Info("Successfully created deal note", "noteID", createdNote.ID, "dealID", dealIDInt)
+	slog.InfoContext(r.Context(), "Successfully created note", "noteID", createdNote.ID, "parent", parent)
 	respondWithJSON(w, http.StatusCreated, convertNoteToResponse(createdNote))
 }
 
+// GetNoteForParent handles GET /api/v1/deals/{dealId}/notes/{noteId} and
+// GET /api/v1/leads/{leadId}/notes/{noteId}
+func (h *NoteHandler) GetNoteForParent(w http.ResponseWriter, r *http.Request) {
+	parent, err := parentFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	noteID, err := strconv.Atoi(getURLParam(r, "noteId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
 
+	note, err := h.noteService.GetNoteForParent(r.Context(), parent, noteID)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
 
+	setETag(w, note.Version)
+	respondWithJSON(w, http.StatusOK, convertNoteToResponse(note))
+}
 
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-// UpdateDealNote handles PUT /deals/{dealId}/notes/{noteId}
-func (h *NoteHandler) UpdateDealNote(w http.ResponseWriter, r *http.Request) {
+// UpdateNoteForParent handles PUT /api/v1/deals/{dealId}/notes/{noteId} and
+// PUT /api/v1/leads/{leadId}/notes/{noteId}
+func (h *NoteHandler) UpdateNoteForParent(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Authentication required")
 		return
 	}
 
-	noteID, err := strconv.Atoi(getURLParam(r, "noteId"))
+	parent, err := parentFromRequest(r)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	dealID, err := strconv.Atoi(getURLParam(r, "dealId"))
+	noteID, err := strconv.Atoi(getURLParam(r, "noteId"))
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
 		return
 	}
 
-	existingNote, err := h.noteService.GetNoteByID(noteID)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Note not found: "+err.Error())
-		return
-	}
-
-	if existingNote.DealID == nil || *existingNote.DealID != dealID {
-		respondWithError(w, http.StatusNotFound, "Note not found for this deal")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -492,27 +456,30 @@ func (h *NoteHandler) UpdateDealNote(w http.ResponseWriter, r *http.Request) {
 		ID:      noteID,
 		UserID:  userID,
 		Content: req.Content,
+		Version: version,
 	}
 
-	if err := h.noteService.UpdateDealNote(note); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update deal note: "+err.Error())
+	if err := h.noteService.UpdateNoteForParent(r.Context(), parent, note); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
-	updatedNote, err := h.noteService.GetNoteByID(noteID)
+	updatedNote, err := h.noteService.GetNoteByID(r.Context(), noteID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get updated deal note: "+err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
+	setETag(w, updatedNote.Version)
 	respondWithJSON(w, http.StatusOK, convertNoteToResponse(updatedNote))
 }
 
-// DeleteDealNote handles DELETE /deals/{dealId}/notes/{noteId}
-func (h *NoteHandler) DeleteDealNote(w http.ResponseWriter, r *http.Request) {
-	userID, err := getUserIDFromContext(r.Context())
+// DeleteNoteForParent handles DELETE /api/v1/deals/{dealId}/notes/{noteId} and
+// DELETE /api/v1/leads/{leadId}/notes/{noteId}
+func (h *NoteHandler) DeleteNoteForParent(w http.ResponseWriter, r *http.Request) {
+	parent, err := parentFromRequest(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -522,103 +489,16 @@ func (h *NoteHandler) DeleteDealNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dealID, err := strconv.Atoi(getURLParam(r, "dealId"))
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
-		return
-	}
-
-	existingNote, err := h.noteService.GetNoteByID(noteID)
+	version, err := ifMatchVersion(r)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Note not found: "+err.Error())
-		return
-	}
-
-	if existingNote.DealID == nil || *existingNote.DealID != dealID {
-		respondWithError(w, http.StatusNotFound, "Note not found for this deal")
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if existingNote.UserID != userID {
-		respondWithError(w, http.StatusForbidden, "You can only delete your own notes")
+	if err := h.noteService.DeleteNoteForParent(r.Context(), parent, noteID, version); err != nil {
+		respondWithServiceError(w, err)
 		return
 	}
 
-	if err := h.noteService.DeleteDealNote(noteID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete deal note: "+err.Error())
-		return
-	}
-
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Deal note deleted successfully"})
-}
-
-
-
-
-// GetDealNoteByID handles GET /deals/{dealId}/notes/{noteId}
-func (h *NoteHandler) GetDealNoteByID(w http.ResponseWriter, r *http.Request) {
-    dealIDStr := chi.URLParam(r, "dealId")
-    noteIDStr := chi.URLParam(r, "noteId")
-
-    dealID, err := strconv.Atoi(dealIDStr)
-    if err != nil {
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
-        return
-    }
-
-    noteID, err := strconv.Atoi(noteIDStr)
-    if err != nil {
-        respondWithError(w, http.StatusBadRequest, "Invalid note ID")
-        return
-    }
-
-    note, err := h.noteService.GetNoteByID(noteID)
-    if err != nil {
-        respondWithError(w, http.StatusNotFound, "Note not found: "+err.Error())
-        return
-    }
-
-    // Verify the note belongs to this deal
-    if note.DealID == nil || *note.DealID != dealID {
-        respondWithError(w, http.StatusNotFound, "Note not found for this deal")
-        return
-    }
-
-    respondWithJSON(w, http.StatusOK, convertNoteToResponse(note))
-}
-
-
-
-
-
-
-
-// GetDealNotes handles GET /api/v1/deals/{dealId}/notes
-func (h *NoteHandler) GetDealNotes(w http.ResponseWriter, r *http.Request) {
-	slog.Info("GetDealNotes called", "method", r.Method, "url", r.URL.Path)
-	dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
-	if err != nil {
-		slog.Error("Invalid deal ID", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
-		return
-	}
-
-	notes, err := h.noteService.GetNotesByDealID(dealID)
-	if err != nil {
-		slog.Error("Failed to get deal notes", "dealID", dealID, "error", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to get deal notes: "+err.Error())
-		return
-	}
-
-	noteResponses := make([]NoteResponse, len(notes))
-	for i, note := range notes {
-		noteResponses[i] = convertNoteToResponse(&note)
-	}
-
-	slog.Info("Successfully retrieved deal notes", "dealID", dealID, "count", len(notes))
-	respondWithJSON(w, http.StatusOK, noteResponses)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Note deleted successfully"})
 }
-
-
-
-
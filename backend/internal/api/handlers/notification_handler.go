@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"crm-project/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type NotificationHandler struct {
+	service *service.NotificationService
+	logger  *slog.Logger
+}
+
+func NewNotificationHandler(s *service.NotificationService, logger *slog.Logger) *NotificationHandler {
+	return &NotificationHandler{service: s, logger: logger}
+}
+
+// GetMyNotifications lists the current user's notifications, newest first.
+// With ?unread=true only unread ones are returned.
+func (h *NotificationHandler) GetMyNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	unreadOnly := false
+	if v := r.URL.Query().Get("unread"); v != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid unread flag")
+			return
+		}
+	}
+
+	notifications, err := h.service.GetMyNotifications(ctx, unreadOnly)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get notifications", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, notifications)
+}
+
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "notificationId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+	if err := h.service.MarkRead(ctx, id); err != nil {
+		h.logger.WarnContext(ctx, "failed to mark notification read", "notification_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	marked, err := h.service.MarkAllRead(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to mark notifications read", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]int64{"marked": marked})
+}
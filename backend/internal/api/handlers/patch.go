@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// maxPatchBytes bounds the size of a merge patch document.
+const maxPatchBytes = 1 << 20
+
+// readMergePatch reads a JSON Merge Patch (RFC 7396) request body. Plain
+// application/json is accepted as well. On failure it writes the error
+// response and returns false.
+func readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			respondWithError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+			return nil, false
+		}
+	}
+	defer r.Body.Close()
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+	return patch, true
+}
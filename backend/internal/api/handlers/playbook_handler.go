@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"crm-project/internal/models"
+	"crm-project/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PlaybookRequest is the body of a new or replaced playbook. Task templates
+// are created in the order given.
+type PlaybookRequest struct {
+	Name         string                `json:"name"`
+	DealStageID  *int                  `json:"deal_stage_id,omitempty"`
+	LeadStatusID *int                  `json:"lead_status_id,omitempty"`
+	Active       *bool                 `json:"active,omitempty"` // Defaults to true
+	Tasks        []models.PlaybookTask `json:"tasks"`
+}
+
+func (req PlaybookRequest) playbook() *models.Playbook {
+	p := &models.Playbook{
+		Name:         req.Name,
+		DealStageID:  req.DealStageID,
+		LeadStatusID: req.LeadStatusID,
+		Active:       req.Active == nil || *req.Active,
+		Tasks:        req.Tasks,
+	}
+	for i := range p.Tasks {
+		p.Tasks[i].ID = 0
+	}
+	return p
+}
+
+type PlaybookHandler struct {
+	service *service.PlaybookService
+	logger  *slog.Logger
+}
+
+func NewPlaybookHandler(s *service.PlaybookService, logger *slog.Logger) *PlaybookHandler {
+	return &PlaybookHandler{service: s, logger: logger}
+}
+
+func (h *PlaybookHandler) GetPlaybooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	playbooks, err := h.service.GetPlaybooks(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get playbooks", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, playbooks)
+}
+
+func (h *PlaybookHandler) GetPlaybook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid playbook ID")
+		return
+	}
+	p, err := h.service.GetPlaybook(ctx, id)
+	if err != nil {
+		h.logger.WarnContext(ctx, "failed to get playbook", "playbook_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	setETag(w, p.Version)
+	respondWithJSON(w, http.StatusOK, p)
+}
+
+func (h *PlaybookHandler) CreatePlaybook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req PlaybookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	p := req.playbook()
+	if err := h.service.CreatePlaybook(ctx, p); err != nil {
+		h.logger.WarnContext(ctx, "failed to create playbook", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "playbook created", "playbook_id", p.ID)
+	setETag(w, p.Version)
+	respondWithJSON(w, http.StatusCreated, p)
+}
+
+func (h *PlaybookHandler) UpdatePlaybook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid playbook ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req PlaybookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	p := req.playbook()
+	p.ID = id
+	p.Version = version
+	if err := h.service.UpdatePlaybook(ctx, p); err != nil {
+		h.logger.WarnContext(ctx, "failed to update playbook", "playbook_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	setETag(w, p.Version)
+	respondWithJSON(w, http.StatusOK, p)
+}
+
+func (h *PlaybookHandler) DeletePlaybook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid playbook ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.DeletePlaybook(ctx, id, version); err != nil {
+		h.logger.WarnContext(ctx, "failed to delete playbook", "playbook_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "playbook deleted", "playbook_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
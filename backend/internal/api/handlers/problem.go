@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"crm-project/internal/service"
+	"crm-project/internal/util"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Every error response from
+// the API uses this shape.
+type Problem struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title"`
+	Status int               `json:"status"`
+	Detail string            `json:"detail,omitempty"`
+	Errors []util.FieldError `json:"errors,omitempty"`
+}
+
+// WriteProblem writes a problem+json response with the given status and detail.
+func WriteProblem(w http.ResponseWriter, status int, detail string) {
+	writeProblem(w, Problem{Status: status, Detail: detail})
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	p = p.withDefaults()
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// withDefaults fills in the type and title of p if they are not set.
+func (p Problem) withDefaults() Problem {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	return p
+}
+
+// Helper function to respond with error
+func respondWithError(w http.ResponseWriter, statusCode int, message string) {
+	WriteProblem(w, statusCode, message)
+}
+
+// WriteServiceError maps an error returned by the service layer to a problem
+// response. Errors that are not domain errors are logged and reported as a
+// generic 500 so internal details are not leaked to the client.
+func WriteServiceError(w http.ResponseWriter, err error) {
+	writeProblem(w, problemForServiceError(err))
+}
+
+// problemForServiceError maps an error returned by the service layer to the
+// problem that reports it.
+func problemForServiceError(err error) Problem {
+	var (
+		notFound   *service.NotFoundError
+		forbidden  *service.ForbiddenError
+		validation *service.ValidationError
+		conflict   *service.ConflictError
+	)
+	switch {
+	case errors.As(err, &validation):
+		return Problem{Status: http.StatusBadRequest, Detail: validation.Message, Errors: validation.Fields}
+	case errors.As(err, &forbidden):
+		return Problem{Status: http.StatusForbidden, Detail: forbidden.Message}
+	case errors.As(err, &notFound):
+		return Problem{Status: http.StatusNotFound, Detail: notFound.Message}
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		return Problem{Status: http.StatusNotFound, Detail: "the requested resource was not found"}
+	case errors.As(err, &conflict):
+		return Problem{Status: http.StatusConflict, Detail: conflict.Message}
+	case errors.Is(err, service.ErrVersionConflict):
+		return Problem{Status: http.StatusPreconditionFailed, Detail: "the record was modified by someone else; reload it and try again"}
+	case errors.Is(err, service.ErrInvalidPatch):
+		return Problem{Status: http.StatusBadRequest, Detail: err.Error()}
+	default:
+		slog.Error("unhandled service error", "error", err)
+		return Problem{Status: http.StatusInternalServerError, Detail: "an internal error occurred"}
+	}
+}
+
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	WriteServiceError(w, err)
+}
@@ -8,7 +8,6 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"strings" 
 	"github.com/go-chi/chi/v5"
 )
 
@@ -25,17 +24,17 @@ func (h *PropertyHandler) CreateProperty(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	var newProperty models.Property
 	if err := json.NewDecoder(r.Body).Decode(&newProperty); err != nil {
-		h.logger.Warn("invalid create property request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid create property request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	newID, err := h.service.CreateProperty(ctx, newProperty)
 	if err != nil {
-		h.logger.Error("failed to create property", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest) // Assuming validation errors
+		h.logger.ErrorContext(r.Context(), "failed to create property", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("property created successfully", "property_id", newID)
+	h.logger.InfoContext(r.Context(), "property created successfully", "property_id", newID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]int{"id": newID})
@@ -45,11 +44,11 @@ func (h *PropertyHandler) GetAllProperties(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 	properties, err := h.service.GetAllProperties(ctx)
 	if err != nil {
-		h.logger.Error("failed to get all properties", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to get all properties", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	h.logger.Debug("retrieved all properties", "count", len(properties))
+	h.logger.DebugContext(r.Context(), "retrieved all properties", "count", len(properties))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(properties)
 }
@@ -58,17 +57,18 @@ func (h *PropertyHandler) GetPropertyByID(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "propertyId"))
 	if err != nil {
-		h.logger.Error("invalid property ID format", "id_param", chi.URLParam(r, "propertyId"), "error", err)
-		http.Error(w, "Invalid property ID", http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "invalid property ID format", "id_param", chi.URLParam(r, "propertyId"), "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid property ID")
 		return
 	}
 	property, err := h.service.GetPropertyByID(ctx, id)
 	if err != nil {
-		h.logger.Warn("property not found", "property_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.logger.WarnContext(r.Context(), "property not found", "property_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Debug("retrieved property by id", "property_id", id)
+	h.logger.DebugContext(r.Context(), "retrieved property by id", "property_id", id)
+	setETag(w, property.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(property)
 }
@@ -77,52 +77,89 @@ func (h *PropertyHandler) GetPropertyByID(w http.ResponseWriter, r *http.Request
 func (h *PropertyHandler) UpdateProperty(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	idStr := chi.URLParam(r, "propertyId")
-	h.logger.Debug("UpdateProperty called", "id_param", idStr)
+	h.logger.DebugContext(r.Context(), "UpdateProperty called", "id_param", idStr)
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("invalid property ID format for update", "id_param", idStr, "error", err)
-		http.Error(w, "Invalid property ID format", http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "invalid property ID format for update", "id_param", idStr, "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid property ID format")
 		return
 	}
 	var p models.Property
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		h.logger.Warn("invalid update property request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid update property request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if version != 0 {
+		p.Version = version
+	}
 	err = h.service.UpdateProperty(ctx, id, p)
 	if err != nil {
-		h.logger.Error("failed to update property", "property_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to update property", "property_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("property updated successfully", "property_id", id)
+	h.logger.InfoContext(r.Context(), "property updated successfully", "property_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// PatchProperty handles PATCH /api/v1/properties/{propertyId} with a JSON merge patch.
+func (h *PropertyHandler) PatchProperty(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "propertyId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid property ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	property, err := h.service.PatchProperty(ctx, id, patch, version)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to patch property", "property_id", id, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "property patched successfully", "property_id", id)
+	setETag(w, property.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(property)
+}
+
 // Replace the DeleteProperty function in property_handler.go with this:
 func (h *PropertyHandler) DeleteProperty(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	idStr := chi.URLParam(r, "propertyId")
-	h.logger.Debug("DeleteProperty called", "id_param", idStr)
+	h.logger.DebugContext(r.Context(), "DeleteProperty called", "id_param", idStr)
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("invalid property ID format for delete", "id_param", idStr, "error", err)
-		http.Error(w, "Invalid property ID format", http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "invalid property ID format for delete", "id_param", idStr, "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid property ID format")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	err = h.service.DeleteProperty(ctx, id)
+	err = h.service.DeleteProperty(ctx, id, version)
 	if err != nil {
-		// Also check for foreign key constraint errors
-		if strings.Contains(err.Error(), "violates foreign key constraint") {
-			h.logger.Warn("attempted to delete property with dependent leads/deals", "property_id", id)
-			http.Error(w, "Cannot delete property: it is linked to existing leads or deals.", http.StatusConflict) // 409 Conflict is a good code here
-			return
-		}
-		h.logger.Error("failed to delete property", "property_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to delete property", "property_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("property deleted successfully", "property_id", id)
+	h.logger.InfoContext(r.Context(), "property deleted successfully", "property_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
\ No newline at end of file
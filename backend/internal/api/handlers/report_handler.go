@@ -22,7 +22,7 @@ func (h *ReportHandler) GetEmployeeLeadReport(w http.ResponseWriter, r *http.Req
 	report, err := h.service.GenerateEmployeeLeadReport(ctx)
 	if err != nil {
 		// The service already logged the specific error
-		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -33,8 +33,8 @@ func (h *ReportHandler) GetSourceLeadReport(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	report, err := h.service.GetSourceLeadReport(ctx)
 	if err != nil {
-		h.logger.Error("failed to generate source lead report", "error", err)
-		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to generate source lead report", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -45,8 +45,8 @@ func (h *ReportHandler) GetEmployeeSalesReport(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 	report, err := h.service.GetEmployeeSalesReport(ctx)
 	if err != nil {
-		h.logger.Error("failed to generate employee sales report", "error", err)
-		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to generate employee sales report", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -57,8 +57,8 @@ func (h *ReportHandler) GetSourceSalesReport(w http.ResponseWriter, r *http.Requ
 	ctx := r.Context()
 	report, err := h.service.GetSourceSalesReport(ctx)
 	if err != nil {
-		h.logger.Error("failed to generate source sales report", "error", err)
-		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to generate source sales report", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -69,8 +69,8 @@ func (h *ReportHandler) GetMySalesReport(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	report, err := h.service.GetMySalesReport(ctx)
 	if err != nil {
-		h.logger.Error("failed to generate personal sales report", "error", err)
-		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to generate personal sales report", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -81,10 +81,21 @@ func (h *ReportHandler) GetDealsPipelineReport(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 	report, err := h.service.GetDealsPipelineReport(ctx)
 	if err != nil {
-		h.logger.Error("failed to generate deals pipeline report", "error", err)
-		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to generate deals pipeline report", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate report")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(report)
-}
\ No newline at end of file
+}
+
+// GetSLABreachReport handles GET /api/v1/reports/sla-breaches
+func (h *ReportHandler) GetSLABreachReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.GetSLABreachReport(r.Context())
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to generate SLA breach report", "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"crm-project/internal/models"
+	"crm-project/internal/service"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Outcomes of a bulk operation for one task.
+const (
+	bulkItemApplied    = "applied"
+	bulkItemFailed     = "failed"
+	bulkItemRolledBack = "rolled_back" // The task was fine but another one failed
+)
+
+// BulkTaskRequest is the body of a bulk task operation. It selects the tasks
+// either by ids or by filter; the other fields are the change, and which of
+// them are used depends on the operation.
+type BulkTaskRequest struct {
+	IDs        []int              `json:"ids,omitempty"`
+	Filter     *models.TaskFilter `json:"filter,omitempty"`
+	AssignedTo int                `json:"assigned_to,omitempty"` // reassign
+	DueDate    string             `json:"due_date,omitempty"`    // reschedule, to this date
+	ShiftDays  int                `json:"shift_days,omitempty"`  // reschedule, by this many days
+	Status     string             `json:"status,omitempty"`      // status
+}
+
+// BulkTaskResponse reports the outcome of a bulk task operation. Applied is
+// false if any task failed, in which case no task was changed.
+type BulkTaskResponse struct {
+	Applied bool                   `json:"applied"`
+	Results []BulkTaskItemResponse `json:"results"`
+}
+
+// BulkTaskItemResponse is the outcome of a bulk operation for one task. Task
+// is the task as it is now if the operation was applied and did not delete
+// it; Error says why a failed task could not be changed.
+type BulkTaskItemResponse struct {
+	ID     int           `json:"id"`
+	Status string        `json:"status"`
+	Task   *TaskResponse `json:"task,omitempty"`
+	Error  *Problem      `json:"error,omitempty"`
+}
+
+type TaskBulkHandler struct {
+	service *service.TaskBulkService
+	logger  *slog.Logger
+}
+
+func NewTaskBulkHandler(s *service.TaskBulkService, logger *slog.Logger) *TaskBulkHandler {
+	return &TaskBulkHandler{service: s, logger: logger}
+}
+
+// ReassignTasks assigns the selected tasks to assigned_to.
+func (h *TaskBulkHandler) ReassignTasks(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBulkTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	results, err := h.service.Reassign(r.Context(), req.selection(), req.AssignedTo)
+	h.respond(w, r, "reassign", results, err)
+}
+
+// RescheduleTasks moves the selected tasks to due_date or by shift_days days.
+func (h *TaskBulkHandler) RescheduleTasks(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBulkTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	var dueDate *time.Time
+	if req.DueDate != "" {
+		parsed, err := parseDueDate(req.DueDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		dueDate = &parsed
+	}
+	results, err := h.service.Reschedule(r.Context(), req.selection(), dueDate, req.ShiftDays)
+	h.respond(w, r, "reschedule", results, err)
+}
+
+// SetTasksStatus sets the status of the selected tasks.
+func (h *TaskBulkHandler) SetTasksStatus(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBulkTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	results, err := h.service.SetStatus(r.Context(), req.selection(), req.Status)
+	h.respond(w, r, "set status", results, err)
+}
+
+// DeleteTasks soft deletes the selected tasks.
+func (h *TaskBulkHandler) DeleteTasks(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBulkTaskRequest(w, r)
+	if !ok {
+		return
+	}
+	results, err := h.service.Delete(r.Context(), req.selection())
+	h.respond(w, r, "delete", results, err)
+}
+
+func decodeBulkTaskRequest(w http.ResponseWriter, r *http.Request) (BulkTaskRequest, bool) {
+	var req BulkTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return req, false
+	}
+	return req, true
+}
+
+func (req BulkTaskRequest) selection() service.TaskSelection {
+	return service.TaskSelection{IDs: req.IDs, Filter: req.Filter}
+}
+
+// respond writes the results of a bulk operation: 200 if it was applied, or
+// 422 with the failed tasks if nothing was changed.
+func (h *TaskBulkHandler) respond(w http.ResponseWriter, r *http.Request, op string, results []service.BulkTaskResult, err error) {
+	ctx := r.Context()
+	applied := err == nil
+	if err != nil && !errors.Is(err, service.ErrBulkNotApplied) {
+		h.logger.WarnContext(ctx, "bulk task operation failed", "operation", op, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	resp := BulkTaskResponse{Applied: applied, Results: make([]BulkTaskItemResponse, len(results))}
+	for i, res := range results {
+		item := BulkTaskItemResponse{ID: res.ID, Status: bulkItemApplied}
+		switch {
+		case res.Err != nil:
+			p := problemForServiceError(res.Err).withDefaults()
+			item.Status, item.Error = bulkItemFailed, &p
+		case !applied:
+			item.Status = bulkItemRolledBack
+		case res.Task != nil:
+			task := convertTaskToResponse(res.Task)
+			item.Task = &task
+		}
+		resp.Results[i] = item
+	}
+
+	if !applied {
+		h.logger.InfoContext(ctx, "bulk task operation rolled back", "operation", op, "tasks", len(results))
+		respondWithJSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
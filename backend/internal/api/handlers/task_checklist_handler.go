@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crm-project/internal/models"
+	"crm-project/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ChecklistItemRequest is the body of a new or updated checklist item. A
+// position of 0 puts a new item last and leaves an existing one in place.
+type ChecklistItemRequest struct {
+	Title     string `json:"title"`
+	Required  bool   `json:"required"`
+	Position  int    `json:"position,omitempty"`
+	Completed bool   `json:"completed"`
+}
+
+type TaskChecklistHandler struct {
+	service *service.TaskChecklistService
+	logger  *slog.Logger
+}
+
+func NewTaskChecklistHandler(s *service.TaskChecklistService, logger *slog.Logger) *TaskChecklistHandler {
+	return &TaskChecklistHandler{service: s, logger: logger}
+}
+
+// GetTaskChecklist lists the checklist items of a task in position order.
+func (h *TaskChecklistHandler) GetTaskChecklist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	items, err := h.service.GetChecklist(ctx, taskID)
+	if err != nil {
+		h.logger.WarnContext(ctx, "failed to get task checklist", "task_id", taskID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, items)
+}
+
+func (h *TaskChecklistHandler) CreateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req ChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	item := &models.ChecklistItem{Title: req.Title, Required: req.Required, Position: req.Position}
+	if err := h.service.AddItem(ctx, taskID, item, req.Completed); err != nil {
+		h.logger.WarnContext(ctx, "failed to create checklist item", "task_id", taskID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "checklist item created", "task_id", taskID, "item_id", item.ID)
+	setETag(w, item.Version)
+	respondWithJSON(w, http.StatusCreated, item)
+}
+
+func (h *TaskChecklistHandler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid checklist item ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req ChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	item := &models.ChecklistItem{ID: itemID, Title: req.Title, Required: req.Required, Position: req.Position, Version: version}
+	if err := h.service.UpdateItem(ctx, taskID, item, req.Completed); err != nil {
+		h.logger.WarnContext(ctx, "failed to update checklist item", "task_id", taskID, "item_id", itemID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	setETag(w, item.Version)
+	respondWithJSON(w, http.StatusOK, item)
+}
+
+func (h *TaskChecklistHandler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid checklist item ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.DeleteItem(ctx, taskID, itemID, version); err != nil {
+		h.logger.WarnContext(ctx, "failed to delete checklist item", "task_id", taskID, "item_id", itemID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "checklist item deleted", "task_id", taskID, "item_id", itemID)
+	w.WriteHeader(http.StatusNoContent)
+}
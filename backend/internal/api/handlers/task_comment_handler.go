@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"crm-project/internal/models"
+	"crm-project/internal/service"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TaskCommentRequest is the body of a new or edited task comment. ParentID
+// is only read when posting; a comment cannot be moved to another thread.
+type TaskCommentRequest struct {
+	Body     string `json:"body"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+type TaskCommentHandler struct {
+	service *service.TaskCommentService
+	logger  *slog.Logger
+}
+
+func NewTaskCommentHandler(s *service.TaskCommentService, logger *slog.Logger) *TaskCommentHandler {
+	return &TaskCommentHandler{service: s, logger: logger}
+}
+
+// GetTaskComments lists the comments on a task, oldest first.
+func (h *TaskCommentHandler) GetTaskComments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	comments, err := h.service.GetComments(ctx, taskID)
+	if err != nil {
+		h.logger.WarnContext(ctx, "failed to get task comments", "task_id", taskID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, comments)
+}
+
+func (h *TaskCommentHandler) CreateTaskComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req TaskCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	comment := &models.TaskComment{Body: req.Body, ParentID: req.ParentID}
+	if err := h.service.AddComment(ctx, taskID, comment); err != nil {
+		h.logger.WarnContext(ctx, "failed to create task comment", "task_id", taskID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "task comment created", "task_id", taskID, "comment_id", comment.ID)
+	setETag(w, comment.Version)
+	respondWithJSON(w, http.StatusCreated, comment)
+}
+
+func (h *TaskCommentHandler) UpdateTaskComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req TaskCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	comment := &models.TaskComment{ID: commentID, Body: req.Body, Version: version}
+	if err := h.service.UpdateComment(ctx, taskID, comment); err != nil {
+		h.logger.WarnContext(ctx, "failed to update task comment", "task_id", taskID, "comment_id", commentID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	setETag(w, comment.Version)
+	respondWithJSON(w, http.StatusOK, comment)
+}
+
+func (h *TaskCommentHandler) DeleteTaskComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.DeleteComment(ctx, taskID, commentID, version); err != nil {
+		h.logger.WarnContext(ctx, "failed to delete task comment", "task_id", taskID, "comment_id", commentID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "task comment deleted", "task_id", taskID, "comment_id", commentID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTaskCommentHistory lists the earlier bodies of an edited comment.
+func (h *TaskCommentHandler) GetTaskCommentHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+	edits, err := h.service.GetCommentHistory(ctx, taskID, commentID)
+	if err != nil {
+		h.logger.WarnContext(ctx, "failed to get task comment history", "task_id", taskID, "comment_id", commentID, "error", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, edits)
+}
+
+// taskIDParam reads the {id} of a /tasks/{id}/... route, answering 400 if it
+// is not a number.
+func taskIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid task ID")
+		return 0, false
+	}
+	return taskID, true
+}
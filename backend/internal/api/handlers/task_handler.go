@@ -31,6 +31,8 @@ type CreateTaskRequest struct {
     AssignedTo      *int       `json:"assigned_to,omitempty"` // Added this line
     LeadID          *int       `json:"lead_id,omitempty"`
     DealID          *int       `json:"deal_id,omitempty"`
+    TaskType        *string    `json:"task_type,omitempty"`  // Selects the SLA the task is held to
+    Recurrence      *models.Recurrence `json:"recurrence,omitempty"` // Makes the task the first of a recurring series
 }
 
 // UpdateTaskRequest represents the request body for updating a task
@@ -40,6 +42,8 @@ type UpdateTaskRequest struct {
     DueDate         string     `json:"due_date"`
     Status          string     `json:"status"`
     AssignedTo      *int       `json:"assigned_to,omitempty"` // Added this line
+    TaskType        *string    `json:"task_type,omitempty"`  // Omit to keep the current type, "" to clear it
+    Recurrence      *models.Recurrence `json:"recurrence,omitempty"` // New rule for future occurrences; needs scope=future
 }
 
 // TaskResponse represents the response structure for tasks
@@ -52,8 +56,13 @@ type TaskResponse struct {
     AssignedTo      int        `json:"assigned_to"`
     LeadID          *int       `json:"lead_id,omitempty"`
     DealID          *int       `json:"deal_id,omitempty"`
+    CreatedBy       int        `json:"created_by,omitempty"`
     CreatedAt       time.Time  `json:"created_at"`
     UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+    Version         int        `json:"version"`
+    SeriesID        *int       `json:"series_id,omitempty"`
+    Occurrence      *int       `json:"occurrence,omitempty"`
+    TaskType        *string    `json:"task_type,omitempty"`
 }
 
 // parseDueDate parses a date string in various formats into a time.Time object.
@@ -88,15 +97,27 @@ func convertTaskToResponse(task *models.Task) TaskResponse {
         AssignedTo:      task.AssignedTo,
         LeadID:          task.LeadID,
         DealID:          task.DealID,
+        CreatedBy:       task.CreatedBy,
         CreatedAt:       task.CreatedAt,
         UpdatedAt:       task.UpdatedAt,
+        Version:         task.Version,
+        SeriesID:        task.SeriesID,
+        Occurrence:      task.Occurrence,
+        TaskType:        task.TaskType,
     }
     return response
 }
 
+// editScope reads the scope query parameter of an edit or delete, which says
+// whether it applies to this occurrence of a recurring task only or to the
+// future ones too.
+func editScope(r *http.Request) (service.EditScope, error) {
+    return service.ParseEditScope(r.URL.Query().Get("scope"))
+}
+
 // GetAllTasks handles GET /api/v1/tasks
 func (h *TaskHandler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetAllTasks called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "GetAllTasks called", "method", r.Method, "url", r.URL.Path)
 
     claims, ok := util.GetClaimsFromContext(r.Context())
     if !ok {
@@ -111,8 +132,8 @@ func (h *TaskHandler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 
     tasks, err := h.taskService.GetAllTasks(r.Context(), filterUserID)
     if err != nil {
-        slog.Error("Failed to get all tasks", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get tasks: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get all tasks", "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -121,13 +142,13 @@ func (h *TaskHandler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
         taskResponses[i] = convertTaskToResponse(&task)
     }
 
-    slog.Info("Successfully retrieved all tasks", "count", len(tasks))
+    slog.InfoContext(r.Context(), "Successfully retrieved all tasks", "count", len(tasks))
     respondWithJSON(w, http.StatusOK, taskResponses)
 }
 
 // CreateTask handles POST /api/v1/tasks
 func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
-    slog.Info("CreateTask called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "CreateTask called", "method", r.Method, "url", r.URL.Path)
     claims, ok := util.GetClaimsFromContext(r.Context())
     if !ok {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -138,7 +159,7 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
     var req CreateTaskRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -146,7 +167,7 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
     parsedDueDate, err := parseDueDate(req.DueDate)
     if err != nil {
-        slog.Error("Failed to parse due date", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to parse due date", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid due date format. Expected YYYY-MM-DD")
         return
     }
@@ -170,24 +191,25 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
         AssignedTo:      assignedToUserID,
         LeadID:          req.LeadID,
         DealID:          req.DealID,
+        TaskType:        req.TaskType,
         CreatedAt:       time.Now(),
     }
 
     // ... (rest of the code)
 
-    if _, err := h.taskService.CreateTask(r.Context(), task); err != nil {
-        slog.Error("Failed to create task", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to create task: "+err.Error())
+    if _, err := h.taskService.CreateTask(r.Context(), task, req.Recurrence); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to create task", "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully created task", "taskID", task.ID)
+    slog.InfoContext(r.Context(), "Successfully created task", "taskID", task.ID)
     respondWithJSON(w, http.StatusCreated, convertTaskToResponse(task))
 }
 
 // GetTaskByID handles GET /api/v1/tasks/{id}
 func (h *TaskHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetTaskByID called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "GetTaskByID called", "method", r.Method, "url", r.URL.Path)
     claims, ok := util.GetClaimsFromContext(r.Context())
     if !ok {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -196,31 +218,32 @@ func (h *TaskHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 
     taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
     if err != nil {
-        slog.Error("Invalid task ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid task ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid task ID")
         return
     }
 
     task, err := h.taskService.GetTaskByID(r.Context(), taskID)
     if err != nil {
-        slog.Error("Failed to get task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Task not found: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get task", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    if claims.RoleID == util.RoleSalesAgent && task.AssignedTo != claims.UserID {
-        slog.Warn("Sales agent attempted to view unassigned task", "userID", claims.UserID, "taskID", taskID, "taskAssignedTo", task.AssignedTo)
-        respondWithError(w, http.StatusForbidden, "You can only view tasks assigned to you")
+    if claims.RoleID == util.RoleSalesAgent && task.AssignedTo != claims.UserID && task.CreatedBy != claims.UserID {
+        slog.WarnContext(r.Context(), "Sales agent attempted to view unassigned task", "userID", claims.UserID, "taskID", taskID, "taskAssignedTo", task.AssignedTo)
+        respondWithError(w, http.StatusForbidden, "You can only view tasks assigned to you or created by you")
         return
     }
 
-    slog.Info("Successfully retrieved task", "taskID", task.ID)
+    slog.InfoContext(r.Context(), "Successfully retrieved task", "taskID", task.ID)
+    setETag(w, task.Version)
     respondWithJSON(w, http.StatusOK, convertTaskToResponse(task))
 }
 
 // UpdateTask handles PUT /api/v1/tasks/{id}
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
-    slog.Info("UpdateTask called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "UpdateTask called", "method", r.Method, "url", r.URL.Path)
     claims, ok := util.GetClaimsFromContext(r.Context())
     if !ok {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -229,14 +252,26 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
     taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
     if err != nil {
-        slog.Error("Invalid task ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid task ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid task ID")
         return
     }
 
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    scope, err := editScope(r)
+    if err != nil {
+        respondWithServiceError(w, err)
+        return
+    }
+
     var req UpdateTaskRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -244,15 +279,15 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
     parsedDueDate, err := parseDueDate(req.DueDate)
     if err != nil {
-        slog.Error("Failed to parse due date", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to parse due date", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid due date format. Expected YYYY-MM-DD")
         return
     }
 
     existingTask, err := h.taskService.GetTaskByID(r.Context(), taskID)
     if err != nil {
-        slog.Error("Task not found", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Task not found: "+err.Error())
+        slog.ErrorContext(r.Context(), "Task not found", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -260,13 +295,13 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
     if claims.RoleID == util.RoleSalesAgent {
         if existingTask.AssignedTo != claims.UserID {
-            slog.Warn("Sales agent attempted to update unassigned task", "userID", claims.UserID, "taskAssignedTo", existingTask.AssignedTo)
+            slog.WarnContext(r.Context(), "Sales agent attempted to update unassigned task", "userID", claims.UserID, "taskAssignedTo", existingTask.AssignedTo)
             respondWithError(w, http.StatusForbidden, "You can only update tasks assigned to you")
             return
         }
         // Sales agents cannot change who the task is assigned to
         if req.AssignedTo != nil && *req.AssignedTo != existingTask.AssignedTo {
-            slog.Warn("Sales agent attempted to reassign task", "userID", claims.UserID, "taskAssignedTo", existingTask.AssignedTo, "requestedAssignedTo", *req.AssignedTo)
+            slog.WarnContext(r.Context(), "Sales agent attempted to reassign task", "userID", claims.UserID, "taskAssignedTo", existingTask.AssignedTo, "requestedAssignedTo", *req.AssignedTo)
             respondWithError(w, http.StatusForbidden, "Sales agents cannot reassign tasks")
             return
         }
@@ -276,6 +311,12 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
         }
     }
 
+    taskType := existingTask.TaskType
+    if req.TaskType != nil {
+        taskType = req.TaskType
+    }
+
+    // The request cannot change what the task is linked to, so it keeps its links.
     task := &models.Task{
         ID:              taskID,
         TaskName:        req.TaskName,
@@ -283,28 +324,73 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
         DueDate:         parsedDueDate,
         Status:          req.Status,
         AssignedTo:      assignedToUserID,
+        LeadID:          existingTask.LeadID,
+        DealID:          existingTask.DealID,
+        TaskType:        taskType,
+        Version:         version,
     }
 
-    if err := h.taskService.UpdateTask(r.Context(), task); err != nil {
-        slog.Error("Failed to update task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to update task: "+err.Error())
+    if err := h.taskService.UpdateTask(r.Context(), task, scope, req.Recurrence); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to update task", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
     updatedTask, err := h.taskService.GetTaskByID(r.Context(), taskID)
     if err != nil {
-        slog.Error("Failed to fetch updated task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get updated task: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to fetch updated task", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully updated task", "taskID", task.ID)
+    slog.InfoContext(r.Context(), "Successfully updated task", "taskID", task.ID)
     respondWithJSON(w, http.StatusOK, convertTaskToResponse(updatedTask))
 }
 
+// PatchTask handles PATCH /api/v1/tasks/{id} with a JSON merge patch.
+// Sales agents may change the status and details of their own tasks but not
+// who they are assigned to.
+func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+    slog.InfoContext(r.Context(), "PatchTask called", "method", r.Method, "url", r.URL.Path)
+    taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
+    if err != nil {
+        slog.ErrorContext(r.Context(), "Invalid task ID", "error", err)
+        respondWithError(w, http.StatusBadRequest, "Invalid task ID")
+        return
+    }
+
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    scope, err := editScope(r)
+    if err != nil {
+        respondWithServiceError(w, err)
+        return
+    }
+
+    patch, ok := readMergePatch(w, r)
+    if !ok {
+        return
+    }
+
+    task, err := h.taskService.PatchTask(r.Context(), taskID, patch, version, scope)
+    if err != nil {
+        slog.ErrorContext(r.Context(), "Failed to patch task", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
+        return
+    }
+
+    slog.InfoContext(r.Context(), "Successfully patched task", "taskID", taskID)
+    setETag(w, task.Version)
+    respondWithJSON(w, http.StatusOK, convertTaskToResponse(task))
+}
+
 // DeleteTask handles DELETE /api/v1/tasks/{id}
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
-    slog.Info("DeleteTask called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "DeleteTask called", "method", r.Method, "url", r.URL.Path)
     claims, ok := util.GetClaimsFromContext(r.Context())
     if !ok {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -312,39 +398,51 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
     }
 
     if claims.RoleID == util.RoleSalesAgent {
-        slog.Warn("Sales agent attempted to delete task", "userID", claims.UserID)
+        slog.WarnContext(r.Context(), "Sales agent attempted to delete task", "userID", claims.UserID)
         respondWithError(w, http.StatusForbidden, "Sales agents are not allowed to delete tasks")
         return
     }
 
     taskID, err := strconv.Atoi(chi.URLParam(r, "id"))
     if err != nil {
-        slog.Error("Invalid task ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid task ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid task ID")
         return
     }
 
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    scope, err := editScope(r)
+    if err != nil {
+        respondWithServiceError(w, err)
+        return
+    }
+
     // Check if the task exists before attempting to delete
     _, err = h.taskService.GetTaskByID(r.Context(), taskID)
     if err != nil {
-        slog.Error("Task not found", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Task not found: "+err.Error())
+        slog.ErrorContext(r.Context(), "Task not found", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    if err := h.taskService.DeleteTask(r.Context(), taskID); err != nil {
-        slog.Error("Failed to delete task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to delete task: "+err.Error())
+    if err := h.taskService.DeleteTask(r.Context(), taskID, version, scope); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to delete task", "taskID", taskID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully deleted task", "taskID", taskID)
+    slog.InfoContext(r.Context(), "Successfully deleted task", "taskID", taskID)
     respondWithJSON(w, http.StatusOK, map[string]string{"message": "Task deleted successfully"})
 }
 
 // GetTasksForUser handles GET /api/v1/users/{userId}/tasks
 func (h *TaskHandler) GetTasksForUser(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetTasksForUser called", "method", r.Method, "url", r.URL.Path)
+    slog.InfoContext(r.Context(), "GetTasksForUser called", "method", r.Method, "url", r.URL.Path)
     claims, ok := util.GetClaimsFromContext(r.Context())
     if !ok {
         respondWithError(w, http.StatusUnauthorized, "Authentication required")
@@ -358,15 +456,15 @@ func (h *TaskHandler) GetTasksForUser(w http.ResponseWriter, r *http.Request) {
     }
 
     if claims.RoleID == util.RoleSalesAgent && claims.UserID != userID {
-        slog.Warn("Sales agent attempted to view tasks for another user", "userID", claims.UserID, "requestedUserID", userID)
+        slog.WarnContext(r.Context(), "Sales agent attempted to view tasks for another user", "userID", claims.UserID, "requestedUserID", userID)
         respondWithError(w, http.StatusForbidden, "You can only view your own tasks")
         return
     }
 
     tasks, err := h.taskService.GetTasksForUser(r.Context(), userID)
     if err != nil {
-        slog.Error("Failed to get user tasks", "userID", userID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get user tasks: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get user tasks", "userID", userID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -375,24 +473,23 @@ func (h *TaskHandler) GetTasksForUser(w http.ResponseWriter, r *http.Request) {
         taskResponses[i] = convertTaskToResponse(&task)
     }
 
-    slog.Info("Successfully retrieved user tasks", "userID", userID, "count", len(tasks))
+    slog.InfoContext(r.Context(), "Successfully retrieved user tasks", "userID", userID, "count", len(tasks))
     respondWithJSON(w, http.StatusOK, taskResponses)
 }
 
-// GetDealTasks handles GET /api/v1/deals/{dealId}/tasks
-func (h *TaskHandler) GetDealTasks(w http.ResponseWriter, r *http.Request) {
-    slog.Info("GetDealTasks called", "method", r.Method, "url", r.URL.Path)
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+// GetTasksForParent handles GET /api/v1/deals/{dealId}/tasks and
+// GET /api/v1/leads/{leadId}/tasks
+func (h *TaskHandler) GetTasksForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    tasks, err := h.taskService.GetTasksByDealID(r.Context(), dealID)
+    tasks, err := h.taskService.GetTasksForParent(r.Context(), parent)
     if err != nil {
-        slog.Error("Failed to get deal tasks", "dealID", dealID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get deal tasks: "+err.Error())
+        slog.ErrorContext(r.Context(), "Failed to get tasks", "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
@@ -401,29 +498,21 @@ func (h *TaskHandler) GetDealTasks(w http.ResponseWriter, r *http.Request) {
         taskResponses[i] = convertTaskToResponse(&task)
     }
 
-    slog.Info("Successfully retrieved deal tasks", "dealID", dealID, "count", len(tasks))
     respondWithJSON(w, http.StatusOK, taskResponses)
 }
 
-// CreateDealTask handles POST /api/v1/deals/{dealId}/tasks
-func (h *TaskHandler) CreateDealTask(w http.ResponseWriter, r *http.Request) {
-    slog.Info("CreateDealTask called", "method", r.Method, "url", r.URL.Path)
-    claims, ok := util.GetClaimsFromContext(r.Context())
-    if !ok {
-        respondWithError(w, http.StatusUnauthorized, "Authentication required")
-        return
-    }
-
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+// CreateTaskForParent handles POST /api/v1/deals/{dealId}/tasks and
+// POST /api/v1/leads/{leadId}/tasks
+func (h *TaskHandler) CreateTaskForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
     var req CreateTaskRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -431,68 +520,89 @@ func (h *TaskHandler) CreateDealTask(w http.ResponseWriter, r *http.Request) {
 
     parsedDueDate, err := parseDueDate(req.DueDate)
     if err != nil {
-        slog.Error("Failed to parse due date", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to parse due date", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid due date format. Expected YYYY-MM-DD")
         return
     }
 
-    assignedToUserID := claims.UserID // Default to the user creating the task
-
-    if claims.RoleID == util.RoleReception {
-        if req.AssignedTo != nil {
-            assignedToUserID = *req.AssignedTo
-        }
-    } else if claims.RoleID == util.RoleSalesAgent {
-        // Sales agents can only assign tasks to themselves
-        assignedToUserID = claims.UserID
-    }
-
     task := &models.Task{
         TaskName:        req.TaskName,
         TaskDescription: req.TaskDescription,
         DueDate:         parsedDueDate,
         Status:          req.Status,
-        AssignedTo:      assignedToUserID,
-        DealID:          &dealID,
+        TaskType:        req.TaskType,
         CreatedAt:       time.Now(),
     }
+    if req.AssignedTo != nil {
+        task.AssignedTo = *req.AssignedTo
+    }
 
-    if _, err := h.taskService.CreateDealTask(r.Context(), task); err != nil {
-        slog.Error("Failed to create deal task", "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to create deal task: "+err.Error())
+    if _, err := h.taskService.CreateTaskForParent(r.Context(), parent, task, req.Recurrence); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to create task", "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully created deal task", "taskID", task.ID, "dealID", dealID)
+    slog.InfoContext(r.Context(), "Successfully created task", "taskID", task.ID, "parent", parent)
     respondWithJSON(w, http.StatusCreated, convertTaskToResponse(task))
 }
 
-// UpdateDealTask handles PUT /api/v1/deals/{dealId}/tasks/{taskId}
-func (h *TaskHandler) UpdateDealTask(w http.ResponseWriter, r *http.Request) {
-    slog.Info("UpdateDealTask called", "method", r.Method, "url", r.URL.Path)
-    claims, ok := util.GetClaimsFromContext(r.Context())
-    if !ok {
-        respondWithError(w, http.StatusUnauthorized, "Authentication required")
+// GetTaskForParent handles GET /api/v1/deals/{dealId}/tasks/{taskId} and
+// GET /api/v1/leads/{leadId}/tasks/{taskId}
+func (h *TaskHandler) GetTaskForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+    taskID, err := strconv.Atoi(chi.URLParam(r, "taskId"))
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        respondWithError(w, http.StatusBadRequest, "Invalid task ID")
+        return
+    }
+
+    task, err := h.taskService.GetTaskForParent(r.Context(), parent, taskID)
+    if err != nil {
+        respondWithServiceError(w, err)
+        return
+    }
+
+    setETag(w, task.Version)
+    respondWithJSON(w, http.StatusOK, convertTaskToResponse(task))
+}
+
+// UpdateTaskForParent handles PUT /api/v1/deals/{dealId}/tasks/{taskId} and
+// PUT /api/v1/leads/{leadId}/tasks/{taskId}
+func (h *TaskHandler) UpdateTaskForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
     taskID, err := strconv.Atoi(chi.URLParam(r, "taskId"))
     if err != nil {
-        slog.Error("Invalid task ID", "error", err)
+        slog.ErrorContext(r.Context(), "Invalid task ID", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid task ID")
         return
     }
 
+    version, err := ifMatchVersion(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    scope, err := editScope(r)
+    if err != nil {
+        respondWithServiceError(w, err)
+        return
+    }
+
     var req UpdateTaskRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        slog.Error("Failed to decode request body", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid request payload")
         return
     }
@@ -500,116 +610,122 @@ func (h *TaskHandler) UpdateDealTask(w http.ResponseWriter, r *http.Request) {
 
     parsedDueDate, err := parseDueDate(req.DueDate)
     if err != nil {
-        slog.Error("Failed to parse due date", "error", err)
+        slog.ErrorContext(r.Context(), "Failed to parse due date", "error", err)
         respondWithError(w, http.StatusBadRequest, "Invalid due date format. Expected YYYY-MM-DD")
         return
     }
 
-    existingTask, err := h.taskService.GetTaskByID(r.Context(), taskID)
+    existingTask, err := h.taskService.GetTaskForParent(r.Context(), parent, taskID)
     if err != nil {
-        slog.Error("Task not found", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Task not found: "+err.Error())
+        respondWithServiceError(w, err)
         return
     }
 
-    assignedToUserID := existingTask.AssignedTo // Default to existing assigned user
-
-    if claims.RoleID == util.RoleSalesAgent {
-        if existingTask.AssignedTo != claims.UserID {
-            slog.Warn("Sales agent attempted to update unassigned deal task", "userID", claims.UserID, "taskAssignedTo", existingTask.AssignedTo)
-            respondWithError(w, http.StatusForbidden, "You can only update deal tasks assigned to you")
-            return
-        }
-        // Sales agents cannot change who the task is assigned to
-        if req.AssignedTo != nil && *req.AssignedTo != existingTask.AssignedTo {
-            slog.Warn("Sales agent attempted to reassign deal task", "userID", claims.UserID, "taskAssignedTo", existingTask.AssignedTo, "requestedAssignedTo", *req.AssignedTo)
-            respondWithError(w, http.StatusForbidden, "Sales agents cannot reassign deal tasks")
-            return
-        }
-    } else if claims.RoleID == util.RoleReception {
-        if req.AssignedTo != nil {
-            assignedToUserID = *req.AssignedTo
-        }
-    }
-
     task := &models.Task{
         ID:              taskID,
         TaskName:        req.TaskName,
         TaskDescription: req.TaskDescription,
         DueDate:         parsedDueDate,
         Status:          req.Status,
-        AssignedTo:      assignedToUserID,
-        DealID:          &dealID,
+        AssignedTo:      existingTask.AssignedTo,
+        TaskType:        existingTask.TaskType,
+        Version:         version,
+    }
+    if req.AssignedTo != nil {
+        task.AssignedTo = *req.AssignedTo
+    }
+    if req.TaskType != nil {
+        task.TaskType = req.TaskType
     }
 
-    if err := h.taskService.UpdateDealTask(r.Context(), task); err != nil {
-        slog.Error("Failed to update deal task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to update deal task: "+err.Error())
+    if err := h.taskService.UpdateTaskForParent(r.Context(), parent, task, scope, req.Recurrence); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to update task", "taskID", taskID, "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
     updatedTask, err := h.taskService.GetTaskByID(r.Context(), taskID)
     if err != nil {
-        slog.Error("Failed to fetch updated task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to get updated task: "+err.Error())
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully updated deal task", "taskID", taskID, "dealID", dealID)
+    slog.InfoContext(r.Context(), "Successfully updated task", "taskID", taskID, "parent", parent)
+    setETag(w, updatedTask.Version)
     respondWithJSON(w, http.StatusOK, convertTaskToResponse(updatedTask))
 }
 
-// DeleteDealTask handles DELETE /api/v1/deals/{dealId}/tasks/{taskId}
-func (h *TaskHandler) DeleteDealTask(w http.ResponseWriter, r *http.Request) {
-    slog.Info("DeleteDealTask called", "method", r.Method, "url", r.URL.Path)
-    claims, ok := util.GetClaimsFromContext(r.Context())
-    if !ok {
-        respondWithError(w, http.StatusUnauthorized, "Authentication required")
+// DeleteTaskForParent handles DELETE /api/v1/deals/{dealId}/tasks/{taskId} and
+// DELETE /api/v1/leads/{leadId}/tasks/{taskId}
+func (h *TaskHandler) DeleteTaskForParent(w http.ResponseWriter, r *http.Request) {
+    parent, err := parentFromRequest(r)
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+    taskID, err := strconv.Atoi(chi.URLParam(r, "taskId"))
     if err != nil {
-        slog.Error("Invalid deal ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid deal ID")
+        slog.ErrorContext(r.Context(), "Invalid task ID", "error", err)
+        respondWithError(w, http.StatusBadRequest, "Invalid task ID")
         return
     }
 
-    taskID, err := strconv.Atoi(chi.URLParam(r, "taskId"))
+    version, err := ifMatchVersion(r)
     if err != nil {
-        slog.Error("Invalid task ID", "error", err)
-        respondWithError(w, http.StatusBadRequest, "Invalid task ID")
+        respondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    existingTask, err := h.taskService.GetTaskByID(r.Context(), taskID)
+    scope, err := editScope(r)
     if err != nil {
-        slog.Error("Task not found", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusNotFound, "Task not found: "+err.Error())
+        respondWithServiceError(w, err)
         return
     }
 
-    if existingTask.DealID == nil || *existingTask.DealID != dealID {
-        slog.Warn("Task does not belong to deal", "taskID", taskID, "dealID", dealID)
-        respondWithError(w, http.StatusNotFound, "Task not found for this deal")
+    if err := h.taskService.DeleteTaskForParent(r.Context(), parent, taskID, version, scope); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to delete task", "taskID", taskID, "parent", parent, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    if claims.RoleID == util.RoleSalesAgent {
-        slog.Warn("Sales agent attempted to delete deal task", "userID", claims.UserID)
-        respondWithError(w, http.StatusForbidden, "Sales agents are not allowed to delete deal tasks")
+    slog.InfoContext(r.Context(), "Successfully deleted task", "taskID", taskID, "parent", parent)
+    respondWithJSON(w, http.StatusOK, map[string]string{"message": "Task deleted successfully"})
+}
+
+// GetTaskSeries handles GET /api/v1/tasks/series/{seriesId}
+func (h *TaskHandler) GetTaskSeries(w http.ResponseWriter, r *http.Request) {
+    seriesID, err := strconv.Atoi(chi.URLParam(r, "seriesId"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "Invalid series ID")
+        return
+    }
+
+    series, err := h.taskService.GetTaskSeries(r.Context(), seriesID)
+    if err != nil {
+        respondWithServiceError(w, err)
         return
     }
 
-    // Reception can delete any deal task
-    // No additional checks needed for reception as they have full control
+    setETag(w, series.Version)
+    respondWithJSON(w, http.StatusOK, series)
+}
+
+// EndTaskSeries handles DELETE /api/v1/tasks/series/{seriesId}. The series
+// stops creating occurrences; existing tasks are kept.
+func (h *TaskHandler) EndTaskSeries(w http.ResponseWriter, r *http.Request) {
+    seriesID, err := strconv.Atoi(chi.URLParam(r, "seriesId"))
+    if err != nil {
+        respondWithError(w, http.StatusBadRequest, "Invalid series ID")
+        return
+    }
 
-    if err := h.taskService.DeleteDealTask(r.Context(), taskID); err != nil {
-        slog.Error("Failed to delete deal task", "taskID", taskID, "error", err)
-        respondWithError(w, http.StatusInternalServerError, "Failed to delete deal task: "+err.Error())
+    if err := h.taskService.EndTaskSeries(r.Context(), seriesID); err != nil {
+        slog.ErrorContext(r.Context(), "Failed to end task series", "seriesID", seriesID, "error", err)
+        respondWithServiceError(w, err)
         return
     }
 
-    slog.Info("Successfully deleted deal task", "taskID", taskID, "dealID", dealID)
-    respondWithJSON(w, http.StatusOK, map[string]string{"message": "Deal task deleted successfully"})
-}
\ No newline at end of file
+    slog.InfoContext(r.Context(), "Successfully ended task series", "seriesID", seriesID)
+    respondWithJSON(w, http.StatusOK, map[string]string{"message": "Task series ended"})
+}
@@ -4,7 +4,6 @@ package handlers
 import (
 	"crm-project/internal/dto"
 	"crm-project/internal/service"
-	"crm-project/internal/util"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -26,25 +25,19 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var req dto.CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid create user request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid create user request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	newID, err := h.service.CreateUser(ctx, req)
 	if err != nil {
-		if _, ok := err.(*util.ValidationError); ok {
-			h.logger.Warn("create user validation failed", "error", err, "request", req)
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		} else {
-			h.logger.Error("failed to create user", "error", err)
-			// A real app might check for specific DB errors, like unique constraint violation
-			http.Error(w, "Could not create user", http.StatusInternalServerError)
-		}
+		h.logger.WarnContext(r.Context(), "failed to create user", "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	h.logger.Info("user created successfully", "user_id", newID)
+	h.logger.InfoContext(r.Context(), "user created successfully", "user_id", newID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]int{"id": newID})
@@ -54,11 +47,11 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	users, err := h.service.GetAllUsers(ctx)
 	if err != nil {
-		h.logger.Error("failed to get all users", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to get all users", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	h.logger.Debug("retrieved all users", "count", len(users))
+	h.logger.DebugContext(r.Context(), "retrieved all users", "count", len(users))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
@@ -67,16 +60,16 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 	user, err := h.service.GetUserByID(ctx, id)
 	if err != nil {
-		h.logger.Warn("user not found", "user_id", id, "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.logger.WarnContext(r.Context(), "user not found", "user_id", id, "error", err)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Debug("retrieved user by id", "user_id", id)
+	h.logger.DebugContext(r.Context(), "retrieved user by id", "user_id", id)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
@@ -85,30 +78,25 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
 	var req dto.UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid update user request body", "error", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.WarnContext(r.Context(), "invalid update user request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	err = h.service.UpdateUser(ctx, id, req)
 	if err != nil {
-		if _, ok := err.(*util.ValidationError); ok {
-			h.logger.Warn("update user validation failed", "error", err, "user_id", id)
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		} else {
-			h.logger.Error("failed to update user", "error", err, "user_id", id)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		h.logger.WarnContext(r.Context(), "failed to update user", "error", err, "user_id", id)
+		respondWithServiceError(w, err)
 		return
 	}
 
-	h.logger.Info("user updated successfully", "user_id", id)
+	h.logger.InfoContext(r.Context(), "user updated successfully", "user_id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -116,15 +104,58 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 	err = h.service.DeleteUser(ctx, id)
 	if err != nil {
-		h.logger.Error("failed to delete user", "error", err, "user_id", id)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to delete user", "error", err, "user_id", id)
+		respondWithServiceError(w, err)
 		return
 	}
-	h.logger.Info("user deleted successfully", "user_id", id)
+	h.logger.InfoContext(r.Context(), "user deleted successfully", "user_id", id)
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+func (h *UserHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req dto.ChangeRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WarnContext(r.Context(), "invalid change role request body", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err = h.service.ChangeUserRole(ctx, id, req)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "failed to change user role", "error", err, "user_id", id)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "user role changed successfully", "user_id", id, "role_id", req.RoleID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) GetRoleChanges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	changes, err := h.service.GetRoleChanges(ctx, id)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to get role changes", "error", err, "user_id", id)
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crm-project/internal/api/handlers"
+	"crm-project/internal/models"
+	"crm-project/internal/service"
+	"crm-project/internal/util"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	// maxIdempotentBodyBytes bounds the request body buffered for hashing.
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with an idempotent response.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// IdempotencyStore records responses to requests sent with an Idempotency-Key.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, userID int, key, method, path string, body []byte) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, userID int, key string, statusCode int, headers map[string]string, body []byte) error
+	Release(ctx context.Context, userID int, key string) error
+}
+
+// IdempotencyMiddleware makes POST requests that carry an Idempotency-Key
+// header safe to retry. The first request with a key is processed normally
+// and its response stored; a retry with the same key and body gets the stored
+// response back, marked with "Idempotent-Replayed: true". Server errors and
+// responses marked "Cache-Control: no-store" (e.g. ones carrying secrets) are
+// not stored, so the request is processed again. Must run after
+// AuthMiddleware, since keys are scoped to the authenticated user.
+func IdempotencyMiddleware(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" || store == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				handlers.WriteProblem(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+				return
+			}
+			claims, ok := util.GetClaimsFromContext(r.Context())
+			if !ok {
+				handlers.WriteProblem(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				handlers.WriteProblem(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			stored, err := store.Begin(ctx, claims.UserID, key, r.Method, r.URL.Path, body)
+			switch {
+			case errors.Is(err, service.ErrIdempotencyKeyReused):
+				handlers.WriteProblem(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+				return
+			case errors.Is(err, service.ErrIdempotencyKeyInFlight):
+				w.Header().Set("Retry-After", "1")
+				handlers.WriteProblem(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "failed to check idempotency key", "user_id", claims.UserID, "error", err)
+				handlers.WriteProblem(w, http.StatusInternalServerError, "Internal Server Error")
+				return
+			case stored != nil:
+				replayResponse(w, stored)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// Release the key if the handler panicked or failed, so the
+				// client's retry is processed again.
+				if !completed {
+					if err := store.Release(context.WithoutCancel(ctx), claims.UserID, key); err != nil {
+						slog.ErrorContext(r.Context(), "failed to release idempotency key", "user_id", claims.UserID, "error", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError || rec.Header().Get("Cache-Control") == "no-store" {
+				return
+			}
+			headers := map[string]string{}
+			for _, h := range replayedHeaders {
+				if v := rec.Header().Get(h); v != "" {
+					headers[h] = v
+				}
+			}
+			if err := store.Complete(context.WithoutCancel(ctx), claims.UserID, key, rec.status, headers, rec.body.Bytes()); err != nil {
+				slog.ErrorContext(r.Context(), "failed to store idempotent response", "user_id", claims.UserID, "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replayResponse(w http.ResponseWriter, stored *models.IdempotencyRecord) {
+	for h, v := range stored.ResponseHeaders {
+		w.Header().Set(h, v)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(stored.ResponseBody)))
+	w.WriteHeader(*stored.StatusCode)
+	w.Write(stored.ResponseBody)
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"crm-project/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader carries the request ID in both directions: a proxy may set
+// it on the request, and it is always echoed on the response so a client can
+// quote it in a bug report.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits what is accepted from clients, since the value ends up
+// in every log line for the request.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+// RequestLogger assigns each request an ID, stores it in the request context
+// so every log record made while handling it carries the ID, and logs one
+// line per request with its route, status, latency and user.
+func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(id) {
+				id = newRequestID()
+			}
+			ctx := logging.WithRequestID(r.Context(), id)
+			w.Header().Set(RequestIDHeader, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				switch {
+				case status >= 500:
+					level = slog.LevelError
+				case status >= 400:
+					level = slog.LevelWarn
+				}
+				route := ""
+				if rctx := chi.RouteContext(ctx); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+				logger.LogAttrs(ctx, level, "request completed",
+					slog.String("method", r.Method),
+					slog.String("route", route),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
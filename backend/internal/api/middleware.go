@@ -3,41 +3,60 @@ package api
 
 import (
 	"context"
+	"crm-project/internal/api/handlers"
 	"crm-project/internal/dto"   // <-- Import shared DTOs
+	"crm-project/internal/logging"
+	"crm-project/internal/models"
 	"crm-project/internal/util"  // <-- Import shared utils
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware creates a middleware that verifies the JWT token.
-// In internal/api/middleware.go
+// APIKeyAuthenticator resolves a raw API key to the claims of its owner.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*dto.Claims, error)
+}
 
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// AuthMiddleware creates a middleware that verifies the JWT token.
+// Requests may instead carry a personal API key, either as
+// "Authorization: ApiKey <key>" or in the X-API-Key header. API key requests
+// are additionally limited to the scopes granted to the key.
+func AuthMiddleware(jwtSecret string, apiKeys APIKeyAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-			slog.Info("AuthMiddleware called",
+			slog.DebugContext(r.Context(), "AuthMiddleware called",
                 "method", r.Method,
-                "url", r.URL.Path,
-                "authHeader", r.Header.Get("Authorization"))
-				
+                "url", r.URL.Path)
+
 			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				slog.Warn("authorization header is missing")
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			rawAPIKey := r.Header.Get("X-API-Key")
+			if authHeader == "" && rawAPIKey == "" {
+				slog.WarnContext(r.Context(), "authorization header is missing")
+				handlers.WriteProblem(w, http.StatusUnauthorized, "Authorization header required")
 				return
 			}
 
 			headerParts := strings.Split(authHeader, " ")
+			if rawAPIKey == "" && len(headerParts) == 2 && headerParts[0] == "ApiKey" {
+				rawAPIKey = headerParts[1]
+			}
+			if rawAPIKey != "" {
+				authenticateAPIKey(apiKeys, rawAPIKey, next, w, r)
+				return
+			}
+
 			if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-				slog.Warn("invalid authorization header format")
-				http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+				slog.WarnContext(r.Context(), "invalid authorization header format")
+				handlers.WriteProblem(w, http.StatusUnauthorized, "Invalid Authorization header format")
 				return
 			}
 			tokenString := headerParts[1]
@@ -54,32 +73,61 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 
 			if err != nil {
 				if errors.Is(err, jwt.ErrTokenExpired) {
-					slog.Warn("token validation failed: token is expired", "error", err)
+					slog.WarnContext(r.Context(), "token validation failed: token is expired", "error", err)
 				} else {
-					slog.Warn("token validation failed", "error", err)
+					slog.WarnContext(r.Context(), "token validation failed", "error", err)
 				}
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				handlers.WriteProblem(w, http.StatusUnauthorized, "Invalid token")
 				return
 			}
 
-			if !token.Valid {
-				slog.Warn("token was parsed but is not valid")
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+			if !token.Valid || claims.UserID == 0 {
+				slog.WarnContext(r.Context(), "token was parsed but is not valid")
+				handlers.WriteProblem(w, http.StatusUnauthorized, "Invalid token")
 				return
 			}
 			
-			slog.Debug("token is valid", "user_id", claims.UserID, "role_id", claims.RoleID)
+			slog.DebugContext(r.Context(), "token is valid", "user_id", claims.UserID, "role_id", claims.RoleID)
+			logging.SetUserID(r.Context(), claims.UserID)
 			ctx := util.AddClaimsToContext(r.Context(), claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticateAPIKey serves r as the owner of rawKey if the key is valid and
+// its scopes cover the requested route.
+func authenticateAPIKey(apiKeys APIKeyAuthenticator, rawKey string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if apiKeys == nil {
+		handlers.WriteProblem(w, http.StatusUnauthorized, "API keys are not supported")
+		return
+	}
+
+	claims, err := apiKeys.Authenticate(r.Context(), rawKey)
+	if err != nil {
+		slog.WarnContext(r.Context(), "API key validation failed", "error", err)
+		handlers.WriteProblem(w, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+
+	resource, action := util.RequestScope(r)
+	if resource == "" || !util.ScopeAllows(claims.Scopes, resource, action) {
+		slog.WarnContext(r.Context(), "API key scope does not allow request", "api_key_id", claims.APIKeyID, "resource", resource, "action", action)
+		handlers.WriteProblem(w, http.StatusForbidden, "Forbidden: API key does not have the required scope.")
+		return
+	}
+
+	slog.DebugContext(r.Context(), "API key is valid", "api_key_id", claims.APIKeyID, "user_id", claims.UserID, "role_id", claims.RoleID)
+	logging.SetUserID(r.Context(), claims.UserID)
+	ctx := util.AddClaimsToContext(r.Context(), claims)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // TimeoutMiddleware adds a request timeout.
 func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			slog.Info("TimeoutMiddleware called",
+			slog.DebugContext(r.Context(), "TimeoutMiddleware called",
                 "method", r.Method,
                 "url", r.URL.Path)
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
@@ -96,8 +144,8 @@ func AuthorizeRole(allowedRoleIDs ...int) func(http.Handler) http.Handler {
 			// Get claims from the context using our new utility function.
 			claims, ok := util.GetClaimsFromContext(r.Context())
 			if !ok {
-				slog.Error("could not get claims from context in AuthorizeRole middleware")
-				http.Error(w, "Not authorized", http.StatusForbidden)
+				slog.ErrorContext(r.Context(), "could not get claims from context in AuthorizeRole middleware")
+				handlers.WriteProblem(w, http.StatusForbidden, "Not authorized")
 				return
 			}
 
@@ -110,12 +158,71 @@ func AuthorizeRole(allowedRoleIDs ...int) func(http.Handler) http.Handler {
 			}
 
 			if !isAllowed {
-				slog.Warn("user forbidden from accessing route", "user_id", claims.UserID, "user_role", claims.RoleID, "required_roles", allowedRoleIDs)
-				http.Error(w, "Forbidden: You do not have the necessary permissions.", http.StatusForbidden)
+				slog.WarnContext(r.Context(), "user forbidden from accessing route", "user_id", claims.UserID, "user_role", claims.RoleID, "required_roles", allowedRoleIDs)
+				handlers.WriteProblem(w, http.StatusForbidden, "Forbidden: You do not have the necessary permissions.")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DealAccessChecker loads a deal on behalf of a user, failing if the user may
+// not view it. It is implemented by service.DealService.
+type DealAccessChecker interface {
+	GetDealByID(ctx context.Context, dealID int, userID int, roleID int) (*models.Deal, error)
+}
+
+// LeadAccessChecker loads a lead for the user in the context, failing if the
+// user may not view it. It is implemented by service.LeadService.
+type LeadAccessChecker interface {
+	GetLeadByID(ctx context.Context, id int) (*models.Lead, error)
+}
+
+// RequireDealAccess guards routes nested under /deals/{dealId}: the request is
+// only passed on if the user may view the deal, using the same rules as
+// GET /deals/{id}.
+func RequireDealAccess(deals DealAccessChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := util.GetClaimsFromContext(r.Context())
+			if !ok {
+				handlers.WriteProblem(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+			dealID, err := strconv.Atoi(chi.URLParam(r, "dealId"))
+			if err != nil {
+				handlers.WriteProblem(w, http.StatusBadRequest, "Invalid deal ID")
+				return
+			}
+			if _, err := deals.GetDealByID(r.Context(), dealID, claims.UserID, claims.RoleID); err != nil {
+				slog.WarnContext(r.Context(), "deal access denied", "user_id", claims.UserID, "deal_id", dealID, "error", err)
+				handlers.WriteServiceError(w, err)
 				return
 			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
+// RequireLeadAccess guards routes nested under /leads/{leadId}: the request is
+// only passed on if the user may view the lead, using the same rules as
+// GET /leads/{id}.
+func RequireLeadAccess(leads LeadAccessChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			leadID, err := strconv.Atoi(chi.URLParam(r, "leadId"))
+			if err != nil {
+				handlers.WriteProblem(w, http.StatusBadRequest, "Invalid lead ID")
+				return
+			}
+			if _, err := leads.GetLeadByID(r.Context(), leadID); err != nil {
+				slog.WarnContext(r.Context(), "lead access denied", "lead_id", leadID, "error", err)
+				handlers.WriteServiceError(w, err)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"crm-project/internal/api/handlers"
+	"crm-project/internal/ratelimit"
+	"crm-project/internal/util"
+)
+
+// Response headers describing the limit that applied to a request, following
+// the IETF RateLimit header fields draft.
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimiter throttles requests per route group. Authenticated requests are
+// counted per user, others per client IP.
+type RateLimiter struct {
+	store      ratelimit.Store
+	limits     map[string]ratelimit.Limit
+	trustProxy bool
+	logger     *slog.Logger
+}
+
+// NewRateLimiter creates a RateLimiter. With trustProxy the client IP is taken
+// from the last X-Forwarded-For entry, which is the address the load balancer
+// saw; only enable it when the server cannot be reached directly.
+func NewRateLimiter(store ratelimit.Store, limits map[string]ratelimit.Limit, trustProxy bool, logger *slog.Logger) *RateLimiter {
+	return &RateLimiter{store: store, limits: limits, trustProxy: trustProxy, logger: logger}
+}
+
+// Limit returns a middleware applying the limit configured for group. It must
+// run after AuthMiddleware to count authenticated requests per user. When
+// several groups apply, the innermost one sets the RateLimit-* headers. A nil
+// RateLimiter or a group without a limit lets every request through.
+func (l *RateLimiter) Limit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		limit, ok := l.limits[group]
+		if !ok {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			key := group + ":" + l.clientKey(r)
+			res, err := l.store.Take(ctx, key, limit)
+			if err != nil {
+				// Failing open keeps the API available if the store is down.
+				l.logger.ErrorContext(ctx, "rate limit store failed", "group", group, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set(rateLimitLimitHeader, strconv.Itoa(res.Limit))
+			h.Set(rateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+			h.Set(rateLimitResetHeader, ceilSeconds(res.Reset))
+			if !res.Allowed {
+				l.logger.WarnContext(ctx, "rate limit exceeded", "group", group, "key", key)
+				h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				handlers.WriteProblem(w, http.StatusTooManyRequests, "Rate limit exceeded, retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IdleAfter is how long a bucket of any group takes to refill completely;
+// stores may forget buckets that have been idle that long.
+func (l *RateLimiter) IdleAfter() time.Duration {
+	var idle time.Duration
+	for _, limit := range l.limits {
+		idle = max(idle, limit.FullAfter())
+	}
+	return idle
+}
+
+// clientKey identifies who a request counts against.
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if claims, ok := util.GetClaimsFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(claims.UserID)
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			hops := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats d as whole seconds, rounded up so clients that wait that
+// long are not refused again.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
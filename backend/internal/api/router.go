@@ -2,8 +2,11 @@
 package api
 
 import (
+	"log/slog"
+
 	"crm-project/internal/api/handlers"
 	"crm-project/internal/config"
+	"crm-project/internal/metrics"
 	"crm-project/internal/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,45 +24,96 @@ func NewRouter(
 	dealHandler *handlers.DealHandler,
 	reportHandler *handlers.ReportHandler,
 	taskHandler *handlers.TaskHandler,
+	taskCommentHandler *handlers.TaskCommentHandler,
+	taskChecklistHandler *handlers.TaskChecklistHandler,
+	taskBulkHandler *handlers.TaskBulkHandler,
+	playbookHandler *handlers.PlaybookHandler,
 	commLogHandler *handlers.CommLogHandler,
 	noteHandler *handlers.NoteHandler,
 	eventHandler *handlers.EventHandler,
-	tempHandler *handlers.TempHandler,
+	invitationHandler *handlers.InvitationHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
+	apiKeys APIKeyAuthenticator,
+	auditHandler *handlers.AuditHandler,
+	notificationHandler *handlers.NotificationHandler,
+	idempotency IdempotencyStore,
+	deals DealAccessChecker,
+	leads LeadAccessChecker,
+	m *metrics.Metrics,
+	healthHandler *handlers.HealthHandler,
+	limiter *RateLimiter,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	r.Use(TracingMiddleware)
+	r.Use(RequestLogger(slog.Default()))
+	r.Use(m.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "If-Match", "Idempotency-Key", RequestIDHeader, "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"Link", "ETag", RequestIDHeader, rateLimitLimitHeader, rateLimitRemainingHeader, rateLimitResetHeader, "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// Temporary route to grant reception role
-	r.Post("/temp/grant-reception-role", tempHandler.GrantReceptionRole)
+	// Prometheus scrape endpoint. It is unauthenticated, so restrict access to
+	// it at the network level.
+	r.Handle("/metrics", m.Handler())
+
+	// Probes for load balancers and deploy scripts (unauthenticated)
+	r.Get("/healthz", healthHandler.Healthz)
+	r.Get("/readyz", healthHandler.Readyz)
+	r.Get("/version", healthHandler.Version)
 
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Post("/auth/login", authHandler.Login)
-		r.Post("/auth/register", authHandler.Register)
+		// Anonymous, so limited per client IP
+		r.Group(func(r chi.Router) {
+			r.Use(limiter.Limit(config.RateLimitGroupAuth))
+			r.Post("/auth/login", authHandler.Login)
+			r.Post("/auth/register", authHandler.Register)
+		})
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
-			r.Use(AuthMiddleware(jwtSecret))
+			r.Use(AuthMiddleware(jwtSecret, apiKeys))
+			r.Use(limiter.Limit(config.RateLimitGroupAPI))
+			// Retried POSTs with the same Idempotency-Key replay the first response
+			r.Use(IdempotencyMiddleware(idempotency))
 
 			// User Routes
 			r.Get("/users", userHandler.GetAllUsers)
 			r.Post("/users", userHandler.CreateUser)
 			r.Get("/users/{userId}", userHandler.GetUserByID)
 
+			// Personal API keys (own keys only; not reachable with an API key)
+			r.Get("/api-keys", apiKeyHandler.GetMyAPIKeys)
+			r.Post("/api-keys", apiKeyHandler.CreateAPIKey)
+			r.Delete("/api-keys/{keyId}", apiKeyHandler.RevokeAPIKey)
+
+			// Own in-app notifications (task reminders and digests)
+			r.Get("/notifications", notificationHandler.GetMyNotifications)
+			r.Post("/notifications/read-all", notificationHandler.MarkAllNotificationsRead)
+			r.Post("/notifications/{notificationId}/read", notificationHandler.MarkNotificationRead)
+
+			// User onboarding and role administration (Reception only)
+			r.Group(func(r chi.Router) {
+				r.Use(AuthorizeRole(util.RoleReception))
+				r.Get("/invitations", invitationHandler.GetAllInvitations)
+				r.Post("/invitations", invitationHandler.CreateInvitation)
+				r.Delete("/invitations/{invitationId}", invitationHandler.RevokeInvitation)
+				r.Put("/users/{userId}/role", userHandler.ChangeUserRole)
+				r.Get("/users/{userId}/role-changes", userHandler.GetRoleChanges)
+				r.Get("/audit-log", auditHandler.GetAuditLog)
+			})
+
 			// Contact Routes
 			r.Get("/contacts", contactHandler.GetAllContacts)
 			r.Post("/contacts", contactHandler.CreateContact)
 			r.Get("/contacts/{contactId}", contactHandler.GetContactByID)
 			r.Put("/contacts/{contactId}", contactHandler.UpdateContact)
+			r.Patch("/contacts/{contactId}", contactHandler.PatchContact)
 			r.Delete("/contacts/{contactId}", contactHandler.DeleteContact)
 
 			// Property Routes
@@ -67,6 +121,7 @@ func NewRouter(
 			r.Post("/properties", propertyHandler.CreateProperty)
 			r.Get("/properties/{propertyId}", propertyHandler.GetPropertyByID)
 			r.Put("/properties/{propertyId}", propertyHandler.UpdateProperty)
+			r.Patch("/properties/{propertyId}", propertyHandler.PatchProperty)
 			r.Delete("/properties/{propertyId}", propertyHandler.DeleteProperty)
 
 			// Lead Routes
@@ -74,6 +129,7 @@ func NewRouter(
 			r.Post("/leads", leadHandler.CreateLead)
 			r.Get("/leads/{id}", leadHandler.GetLeadByID)
 			r.Put("/leads/{id}", leadHandler.UpdateLead)
+			r.Patch("/leads/{id}", leadHandler.PatchLead)
 			r.Delete("/leads/{id}", leadHandler.DeleteLead)
 
 			// Deal Routes
@@ -81,6 +137,7 @@ func NewRouter(
 			r.Post("/deals", dealHandler.CreateDeal)
 			r.Get("/deals/{id}", dealHandler.GetDealByID)
 			r.Put("/deals/{id}", dealHandler.UpdateDeal)
+			r.Patch("/deals/{id}", dealHandler.PatchDeal)
 			r.Delete("/deals/{id}", dealHandler.DeleteDeal)
 
 			// Task Routes
@@ -90,13 +147,41 @@ func NewRouter(
 				r.Get("/tasks", taskHandler.GetAllTasks)
 				r.Get("/tasks/{id}", taskHandler.GetTaskByID)
 				r.Put("/tasks/{id}", taskHandler.UpdateTask)
+				r.Patch("/tasks/{id}", taskHandler.PatchTask)
+				r.Get("/tasks/series/{seriesId}", taskHandler.GetTaskSeries)
+				r.Delete("/tasks/series/{seriesId}", taskHandler.EndTaskSeries)
+				r.Post("/tasks/bulk/reschedule", taskBulkHandler.RescheduleTasks)
+				r.Post("/tasks/bulk/status", taskBulkHandler.SetTasksStatus)
+
+				// Comments and checklists, shared by the assignee and the creator
+				r.Get("/tasks/{id}/comments", taskCommentHandler.GetTaskComments)
+				r.Post("/tasks/{id}/comments", taskCommentHandler.CreateTaskComment)
+				r.Put("/tasks/{id}/comments/{commentId}", taskCommentHandler.UpdateTaskComment)
+				r.Delete("/tasks/{id}/comments/{commentId}", taskCommentHandler.DeleteTaskComment)
+				r.Get("/tasks/{id}/comments/{commentId}/history", taskCommentHandler.GetTaskCommentHistory)
+				r.Get("/tasks/{id}/checklist", taskChecklistHandler.GetTaskChecklist)
+				r.Post("/tasks/{id}/checklist", taskChecklistHandler.CreateChecklistItem)
+				r.Put("/tasks/{id}/checklist/{itemId}", taskChecklistHandler.UpdateChecklistItem)
+				r.Delete("/tasks/{id}/checklist/{itemId}", taskChecklistHandler.DeleteChecklistItem)
 			})
 
-			// Only Reception can create and delete tasks
+			// Only Reception can create and delete tasks and reassign them in bulk
 			r.Group(func(r chi.Router) {
 				r.Use(AuthorizeRole(util.RoleReception))
 				r.Post("/tasks", taskHandler.CreateTask)
 				r.Delete("/tasks/{id}", taskHandler.DeleteTask)
+				r.Post("/tasks/bulk/reassign", taskBulkHandler.ReassignTasks)
+				r.Post("/tasks/bulk/delete", taskBulkHandler.DeleteTasks)
+			})
+
+			// Task playbooks run on deal stage and lead status changes (Reception only)
+			r.Group(func(r chi.Router) {
+				r.Use(AuthorizeRole(util.RoleReception))
+				r.Get("/playbooks", playbookHandler.GetPlaybooks)
+				r.Post("/playbooks", playbookHandler.CreatePlaybook)
+				r.Get("/playbooks/{id}", playbookHandler.GetPlaybook)
+				r.Put("/playbooks/{id}", playbookHandler.UpdatePlaybook)
+				r.Delete("/playbooks/{id}", playbookHandler.DeletePlaybook)
 			})
 
 			// Note Routes
@@ -125,19 +210,63 @@ func NewRouter(
 			r.Put("/contacts/{contactId}/comm-logs/{logId}", commLogHandler.UpdateContactCommLog)
 			r.Delete("/contacts/{contactId}/comm-logs/{logId}", commLogHandler.DeleteContactCommLog)
 
+			// Deal and Lead sub-resources. Only users who can view the parent
+			// deal or lead get through; per-record rules still apply.
+			r.Group(func(r chi.Router) {
+				r.Use(RequireDealAccess(deals))
+				mountActivityRoutes(r, "/deals/{dealId}", noteHandler, taskHandler, eventHandler, commLogHandler)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(RequireLeadAccess(leads))
+				mountActivityRoutes(r, "/leads/{leadId}", noteHandler, taskHandler, eventHandler, commLogHandler)
+			})
+
 			// User-specific Notes and Events
 			r.Get("/users/{userId}/notes", noteHandler.GetUserNotes)
 			r.Get("/users/{userId}/events", eventHandler.GetEventsForUser)
 
-			// Report Routes
-			r.Get("/reports/employee-leads", reportHandler.GetEmployeeLeadReport)
-			r.Get("/reports/employee-sales", reportHandler.GetEmployeeSalesReport)
-			r.Get("/reports/source-leads", reportHandler.GetSourceLeadReport)
-			r.Get("/reports/source-sales", reportHandler.GetSourceSalesReport)
-			r.Get("/reports/my-sales", reportHandler.GetMySalesReport)
-			r.Get("/reports/deals-pipeline", reportHandler.GetDealsPipelineReport)
+			// Report Routes. Reports query every agent's records, so they
+			// get a tighter limit on top of the general one.
+			r.Group(func(r chi.Router) {
+				r.Use(limiter.Limit(config.RateLimitGroupReports))
+				r.Get("/reports/employee-leads", reportHandler.GetEmployeeLeadReport)
+				r.Get("/reports/employee-sales", reportHandler.GetEmployeeSalesReport)
+				r.Get("/reports/source-leads", reportHandler.GetSourceLeadReport)
+				r.Get("/reports/source-sales", reportHandler.GetSourceSalesReport)
+				r.Get("/reports/my-sales", reportHandler.GetMySalesReport)
+				r.Get("/reports/deals-pipeline", reportHandler.GetDealsPipelineReport)
+				r.Get("/reports/sla-breaches", reportHandler.GetSLABreachReport)
+			})
 		})
 	})
 
 	return r
 }
+
+// mountActivityRoutes registers the notes, tasks, events and communication
+// logs filed under a parent record, e.g. prefix "/deals/{dealId}".
+func mountActivityRoutes(r chi.Router, prefix string, notes *handlers.NoteHandler, tasks *handlers.TaskHandler, events *handlers.EventHandler, commLogs *handlers.CommLogHandler) {
+	r.Get(prefix+"/notes", notes.GetNotesForParent)
+	r.Post(prefix+"/notes", notes.CreateNoteForParent)
+	r.Get(prefix+"/notes/{noteId}", notes.GetNoteForParent)
+	r.Put(prefix+"/notes/{noteId}", notes.UpdateNoteForParent)
+	r.Delete(prefix+"/notes/{noteId}", notes.DeleteNoteForParent)
+
+	r.Get(prefix+"/tasks", tasks.GetTasksForParent)
+	r.Post(prefix+"/tasks", tasks.CreateTaskForParent)
+	r.Get(prefix+"/tasks/{taskId}", tasks.GetTaskForParent)
+	r.Put(prefix+"/tasks/{taskId}", tasks.UpdateTaskForParent)
+	r.Delete(prefix+"/tasks/{taskId}", tasks.DeleteTaskForParent)
+
+	r.Get(prefix+"/events", events.GetEventsForParent)
+	r.Post(prefix+"/events", events.CreateEventForParent)
+	r.Get(prefix+"/events/{eventId}", events.GetEventForParent)
+	r.Put(prefix+"/events/{eventId}", events.UpdateEventForParent)
+	r.Delete(prefix+"/events/{eventId}", events.DeleteEventForParent)
+
+	r.Get(prefix+"/comm-logs", commLogs.GetCommLogsForParent)
+	r.Post(prefix+"/comm-logs", commLogs.CreateCommLogForParent)
+	r.Get(prefix+"/comm-logs/{logId}", commLogs.GetCommLogForParent)
+	r.Put(prefix+"/comm-logs/{logId}", commLogs.UpdateCommLogForParent)
+	r.Delete(prefix+"/comm-logs/{logId}", commLogs.DeleteCommLogForParent)
+}
@@ -0,0 +1,54 @@
+// Package buildinfo reports which build of the server is running. Release
+// builds set the variables with the linker:
+//
+//	go build -ldflags "-X crm-project/internal/buildinfo.Version=v1.4.0 \
+//	  -X crm-project/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X crm-project/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// Builds without ldflags fall back to the VCS information the Go toolchain
+// embeds when building inside a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	Modified  bool   `json:"modified"` // Built from a working tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information, preferring values set with -ldflags.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
@@ -2,18 +2,33 @@
 package config
 
 import (
-	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"crm-project/internal/logging"
+	"crm-project/internal/notify"
+	"crm-project/internal/ratelimit"
+	"crm-project/internal/tracing"
 
 	"gopkg.in/yaml.v3"
-	"github.com/jackc/pgx/v5/stdlib"
 )
 
-func init() {
-	sql.Register("postgres", &stdlib.Driver{})
-}
+// DefaultPath is the configuration file read when neither -config nor
+// CRM_CONFIG names another one. Unlike an explicitly named file it may be
+// missing, so the server can be configured from the environment alone.
+const DefaultPath = "config.yml"
+
+// minJWTSecretLength is the shortest signing key Validate accepts. HS256 keys
+// shorter than the 256-bit hash output weaken the signature.
+const minJWTSecretLength = 32
 
 // Config struct matches the structure of our config.yml file
 type Config struct {
@@ -21,56 +36,537 @@ type Config struct {
 		Port string `yaml:"port"`
 	} `yaml:"server"`
 	Database struct {
-		URL string `yaml:"url"`
+		URL     string `yaml:"url"`
+		URLFile string `yaml:"url_file"` // Read the URL from this file instead, e.g. a mounted secret
 	} `yaml:"database"`
 	Auth struct { // <-- ADD THIS
-		JWTSecret string `yaml:"jwt_secret"`
+		JWTSecret     string        `yaml:"jwt_secret"`
+		JWTSecretFile string        `yaml:"jwt_secret_file"` // Read the secret from this file instead
+		InviteURL     string        `yaml:"invite_url"`      // Frontend signup page the invite token is appended to
+		InviteTTL     time.Duration `yaml:"invite_ttl"`      // How long an invite link stays valid
 	} `yaml:"auth"`
+	Idempotency struct {
+		TTL time.Duration `yaml:"ttl"` // How long a stored response is replayed for a retried Idempotency-Key
+	} `yaml:"idempotency"`
+	Metrics struct {
+		RefreshInterval time.Duration `yaml:"refresh_interval"` // How often the lead and deal gauges are recomputed
+	} `yaml:"metrics"`
+	Tracing struct {
+		Exporter     string  `yaml:"exporter"`      // none, otlp or file
+		OTLPEndpoint string  `yaml:"otlp_endpoint"` // host:port of the collector; defaults to OTEL_EXPORTER_OTLP_ENDPOINT
+		File         string  `yaml:"file"`          // Where the file exporter appends spans
+		SampleRatio  float64 `yaml:"sample_ratio"`  // Fraction of traces recorded, (0, 1]
+	} `yaml:"tracing"`
+	RateLimit struct {
+		Enabled    bool                      `yaml:"enabled"`
+		Store      string                    `yaml:"store"`       // memory (per instance) or postgres (shared by all instances)
+		TrustProxy bool                      `yaml:"trust_proxy"` // Key anonymous clients by the last X-Forwarded-For address
+		Groups     map[string]RateLimitGroup `yaml:"groups"`      // Limits per route group; see RateLimitGroups
+	} `yaml:"rate_limit"`
+	Reminders struct {
+		Enabled    bool            `yaml:"enabled"`
+		Interval   time.Duration   `yaml:"interval"`    // How often due dates are checked
+		Offsets    []time.Duration `yaml:"offsets"`     // Remind the assignee this long before a task is due
+		DigestHour int             `yaml:"digest_hour"` // Hour of the day (0-23) the daily digest is sent at
+		Timezone   string          `yaml:"timezone"`    // IANA time zone of digest_hour and of times in messages
+	} `yaml:"reminders"`
+	SLA struct {
+		Enabled  bool               `yaml:"enabled"`
+		Interval time.Duration      `yaml:"interval"` // How often open tasks are checked against their SLA
+		Types    map[string]SLARule `yaml:"types"`    // SLA per task type; tasks of other types, or with none, have no SLA
+	} `yaml:"sla"`
+	Email struct {
+		Sender string `yaml:"sender"` // none or file
+		File   string `yaml:"file"`   // mbox file the file sender appends to
+		From   string `yaml:"from"`   // Sender address of outgoing email
+	} `yaml:"email"`
+	Logging struct {
+		Level string `yaml:"level"` // debug, info, warn or error
+		PII   string `yaml:"pii"`   // What to do with emails and phone numbers: mask, redact or plain
+	} `yaml:"logging"`
 	Roles struct {
-		SalesAgentID int `yaml:"-"` // Not from YAML, populated from DB
-		ReceptionID  int `yaml:"-"` // Not from YAML, populated from DB
+		SalesAgentID int `yaml:"-"` // Not from YAML, resolved from the roles table at startup
+		ReceptionID  int `yaml:"-"` // Not from YAML, resolved from the roles table at startup
 	} `yaml:"-"`
 }
 
-// Load reads the config.yml file and returns a Config struct
-func Load(path string, logger *slog.Logger) (*Config, error) {
-	logger.Info("loading configuration", "path", path)
-	data, err := os.ReadFile(path)
-	if err != nil {
+// Rate limit stores accepted in rate_limit.store.
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+)
+
+// Route groups that can be given their own rate limit. Requests are counted
+// per user once authenticated and per client IP before that.
+const (
+	RateLimitGroupAuth    = "auth"    // Login and registration, keyed by IP
+	RateLimitGroupAPI     = "api"     // Every authenticated request
+	RateLimitGroupReports = "reports" // Report endpoints, counted on top of api
+)
+
+// RateLimitGroups lists the groups in the order they are documented.
+var RateLimitGroups = []string{RateLimitGroupAuth, RateLimitGroupAPI, RateLimitGroupReports}
+
+// RateLimitGroup is a token bucket: Burst requests may arrive at once, after
+// which RequestsPerMinute are allowed.
+type RateLimitGroup struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	Burst             int `yaml:"burst"`
+}
+
+// Escalations accepted in sla.types.*.escalate.
+const (
+	SLAEscalateNotify   = "notify"   // Tell the task creator
+	SLAEscalateReassign = "reassign" // Assign the task to its creator, and tell both
+)
+
+// SLARule is how long a task of one type may stay open, and what happens once
+// it has been open longer.
+type SLARule struct {
+	Within   time.Duration `yaml:"within"`   // Measured from when the task was created
+	Escalate string        `yaml:"escalate"` // notify (the default) or reassign
+}
+
+// defaults returns the configuration used for anything no layer sets.
+func defaults() Config {
+	var cfg Config
+	cfg.Server.Port = ":8080"
+	cfg.Auth.InviteTTL = 72 * time.Hour
+	cfg.Idempotency.TTL = 24 * time.Hour
+	cfg.Metrics.RefreshInterval = time.Minute
+	cfg.Tracing.Exporter = tracing.ExporterNone
+	cfg.Tracing.File = "traces.jsonl"
+	cfg.Tracing.SampleRatio = 1
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.Store = RateLimitStoreMemory
+	cfg.RateLimit.Groups = map[string]RateLimitGroup{
+		RateLimitGroupAuth:    {RequestsPerMinute: 10, Burst: 5},
+		RateLimitGroupAPI:     {RequestsPerMinute: 600, Burst: 100},
+		RateLimitGroupReports: {RequestsPerMinute: 30, Burst: 10},
+	}
+	cfg.Reminders.Enabled = true
+	cfg.Reminders.Interval = time.Minute
+	cfg.Reminders.Offsets = []time.Duration{24 * time.Hour, time.Hour}
+	cfg.Reminders.DigestHour = 8
+	cfg.Reminders.Timezone = "UTC"
+	cfg.SLA.Enabled = true
+	cfg.SLA.Interval = 5 * time.Minute
+	cfg.SLA.Types = map[string]SLARule{}
+	cfg.Email.Sender = notify.SenderNone
+	cfg.Email.File = "outbox.mbox"
+	cfg.Email.From = "crm@localhost"
+	cfg.Logging.Level = "info"
+	cfg.Logging.PII = string(logging.PIIMask)
+	return cfg
+}
+
+// Loader builds a Config from, in increasing order of precedence, built-in
+// defaults, the YAML file, CRM_* environment variables and command-line flags.
+type Loader struct {
+	fs          *flag.FlagSet
+	path        string
+	port        string
+	databaseURL string
+	inviteURL   string
+	logLevel    string
+}
+
+// NewLoader registers the configuration flags on fs. Call Load after fs has
+// been parsed. The JWT secret deliberately has no flag, since command lines
+// are visible to every user on the host.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{fs: fs}
+	fs.StringVar(&l.path, "config", "", "path to the configuration file (default $CRM_CONFIG or "+DefaultPath+")")
+	fs.StringVar(&l.port, "port", "", "address to listen on, overrides server.port")
+	fs.StringVar(&l.databaseURL, "database-url", "", "Postgres connection URL, overrides database.url")
+	fs.StringVar(&l.inviteURL, "invite-url", "", "frontend signup page, overrides auth.invite_url")
+	fs.StringVar(&l.logLevel, "log-level", "", "debug, info, warn or error, overrides logging.level")
+	return l
+}
+
+// Load merges the configuration layers. It does not validate the result; the
+// server calls Validate, while tools that only need the database call
+// ValidateDatabase.
+func (l *Loader) Load(logger *slog.Logger) (*Config, error) {
+	cfg := defaults()
+
+	path, explicit := l.path, true
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	if path == "" {
+		path, explicit = DefaultPath, false
+	}
+	if err := loadFile(&cfg, path, explicit, logger); err != nil {
 		return nil, err
 	}
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := loadEnv(&cfg); err != nil {
 		return nil, err
 	}
+	l.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = l.port
+		case "database-url":
+			cfg.Database.URL = l.databaseURL
+		case "invite-url":
+			cfg.Auth.InviteURL = l.inviteURL
+		case "log-level":
+			cfg.Logging.Level = l.logLevel
+		}
+	})
+	return &cfg, nil
+}
 
-	logger.Info("Database URL from config", "url", cfg.Database.URL)
-	// Establish database connection to fetch role IDs
-	db, err := sql.Open("postgres", cfg.Database.URL)
+// loadFile merges the YAML file at path into cfg. A missing file is only an
+// error if the operator named it.
+func loadFile(cfg *Config, path string, explicit bool, logger *slog.Logger) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		logger.Info("no configuration file, using defaults and environment", "path", path)
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return fmt.Errorf("reading configuration file: %w", err)
 	}
-	defer db.Close()
+	logger.Info("loading configuration", "path", path)
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	var file Config
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if file.Database.URLFile != "" {
+		if file.Database.URL != "" {
+			return fmt.Errorf("%s: set only one of database.url and database.url_file", path)
+		}
+		if file.Database.URL, err = readSecret(file.Database.URLFile); err != nil {
+			return fmt.Errorf("database.url_file: %w", err)
+		}
+	}
+	if file.Auth.JWTSecretFile != "" {
+		if file.Auth.JWTSecret != "" {
+			return fmt.Errorf("%s: set only one of auth.jwt_secret and auth.jwt_secret_file", path)
+		}
+		if file.Auth.JWTSecret, err = readSecret(file.Auth.JWTSecretFile); err != nil {
+			return fmt.Errorf("auth.jwt_secret_file: %w", err)
+		}
 	}
 
-	// Fetch Sales_Agent role ID
-	err = db.QueryRow("SELECT role_id FROM roles WHERE role_name = $1", "Sales_Agent").Scan(&cfg.Roles.SalesAgentID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch Sales_Agent role ID: %w", err)
+	setString(&cfg.Server.Port, file.Server.Port)
+	setString(&cfg.Database.URL, file.Database.URL)
+	setString(&cfg.Auth.JWTSecret, file.Auth.JWTSecret)
+	setString(&cfg.Auth.InviteURL, file.Auth.InviteURL)
+	setString(&cfg.Tracing.Exporter, file.Tracing.Exporter)
+	setString(&cfg.Tracing.OTLPEndpoint, file.Tracing.OTLPEndpoint)
+	setString(&cfg.Tracing.File, file.Tracing.File)
+	if file.Tracing.SampleRatio != 0 {
+		cfg.Tracing.SampleRatio = file.Tracing.SampleRatio
+	}
+	setString(&cfg.Logging.Level, file.Logging.Level)
+	setString(&cfg.Logging.PII, file.Logging.PII)
+	if file.Auth.InviteTTL != 0 {
+		cfg.Auth.InviteTTL = file.Auth.InviteTTL
+	}
+	if file.Idempotency.TTL != 0 {
+		cfg.Idempotency.TTL = file.Idempotency.TTL
+	}
+	if file.Metrics.RefreshInterval != 0 {
+		cfg.Metrics.RefreshInterval = file.Metrics.RefreshInterval
+	}
+	setString(&cfg.Email.Sender, file.Email.Sender)
+	setString(&cfg.Email.File, file.Email.File)
+	setString(&cfg.Email.From, file.Email.From)
+	if err := loadRateLimit(cfg, data); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := loadReminders(cfg, data); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := loadSLA(cfg, data); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadRateLimit merges the rate_limit section. It is decoded separately so an
+// explicit "enabled: false" can be told apart from a missing key, and so a
+// group that sets only one field keeps the default for the other.
+func loadRateLimit(cfg *Config, data []byte) error {
+	var file struct {
+		RateLimit struct {
+			Enabled    *bool                     `yaml:"enabled"`
+			Store      string                    `yaml:"store"`
+			TrustProxy *bool                     `yaml:"trust_proxy"`
+			Groups     map[string]RateLimitGroup `yaml:"groups"`
+		} `yaml:"rate_limit"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	rl := file.RateLimit
+	if rl.Enabled != nil {
+		cfg.RateLimit.Enabled = *rl.Enabled
+	}
+	if rl.TrustProxy != nil {
+		cfg.RateLimit.TrustProxy = *rl.TrustProxy
+	}
+	setString(&cfg.RateLimit.Store, rl.Store)
+	for name, g := range rl.Groups {
+		merged := cfg.RateLimit.Groups[name]
+		if g.RequestsPerMinute != 0 {
+			merged.RequestsPerMinute = g.RequestsPerMinute
+		}
+		if g.Burst != 0 {
+			merged.Burst = g.Burst
+		}
+		cfg.RateLimit.Groups[name] = merged
+	}
+	return nil
+}
+
+// loadReminders merges the reminders section. Like rate_limit it is decoded
+// separately, since "enabled: false" and "digest_hour: 0" are meaningful.
+// Offsets, when given, replace the default list.
+func loadReminders(cfg *Config, data []byte) error {
+	var file struct {
+		Reminders struct {
+			Enabled    *bool           `yaml:"enabled"`
+			Interval   time.Duration   `yaml:"interval"`
+			Offsets    []time.Duration `yaml:"offsets"`
+			DigestHour *int            `yaml:"digest_hour"`
+			Timezone   string          `yaml:"timezone"`
+		} `yaml:"reminders"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	rem := file.Reminders
+	if rem.Enabled != nil {
+		cfg.Reminders.Enabled = *rem.Enabled
+	}
+	if rem.Interval != 0 {
+		cfg.Reminders.Interval = rem.Interval
+	}
+	if rem.Offsets != nil {
+		cfg.Reminders.Offsets = rem.Offsets
+	}
+	if rem.DigestHour != nil {
+		cfg.Reminders.DigestHour = *rem.DigestHour
+	}
+	setString(&cfg.Reminders.Timezone, rem.Timezone)
+	return nil
+}
+
+// loadSLA merges the sla section, decoded separately for "enabled: false".
+// Types, when given, replace the default (empty) set.
+func loadSLA(cfg *Config, data []byte) error {
+	var file struct {
+		SLA struct {
+			Enabled  *bool              `yaml:"enabled"`
+			Interval time.Duration      `yaml:"interval"`
+			Types    map[string]SLARule `yaml:"types"`
+		} `yaml:"sla"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	sla := file.SLA
+	if sla.Enabled != nil {
+		cfg.SLA.Enabled = *sla.Enabled
+	}
+	if sla.Interval != 0 {
+		cfg.SLA.Interval = sla.Interval
+	}
+	if sla.Types != nil {
+		cfg.SLA.Types = make(map[string]SLARule, len(sla.Types))
+		for name, rule := range sla.Types {
+			if rule.Escalate == "" {
+				rule.Escalate = SLAEscalateNotify
+			}
+			cfg.SLA.Types[name] = rule
+		}
 	}
+	return nil
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
 
-	// Fetch Reception role ID
-	err = db.QueryRow("SELECT role_id FROM roles WHERE role_name = $1", "Reception").Scan(&cfg.Roles.ReceptionID)
+// readSecret returns the contents of a secret file without the trailing
+// newline editors and `kubectl create secret --from-file` tend to leave.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch Reception role ID: %w", err)
+		return "", err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
+
+// Validate checks everything the server needs and reports all problems at
+// once, naming the setting and how to provide it.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Port == "" {
+		errs = append(errs, errors.New("server.port is required (or set CRM_SERVER_PORT / -port)"))
+	}
+	if err := c.ValidateDatabase(); err != nil {
+		errs = append(errs, err)
+	}
+	switch {
+	case c.Auth.JWTSecret == "":
+		errs = append(errs, errors.New("auth.jwt_secret is required (or set CRM_AUTH_JWT_SECRET / CRM_AUTH_JWT_SECRET_FILE)"))
+	case len(c.Auth.JWTSecret) < minJWTSecretLength:
+		errs = append(errs, fmt.Errorf("auth.jwt_secret must be at least %d characters long", minJWTSecretLength))
+	}
+	if c.Auth.InviteURL != "" {
+		if u, err := url.Parse(c.Auth.InviteURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("auth.invite_url %q must be an absolute URL", c.Auth.InviteURL))
+		}
+	}
+	if c.Auth.InviteTTL <= 0 {
+		errs = append(errs, errors.New("auth.invite_ttl must be positive"))
 	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl must be positive"))
+	}
+	if c.Metrics.RefreshInterval <= 0 {
+		errs = append(errs, errors.New("metrics.refresh_interval must be positive"))
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP:
+	case tracing.ExporterFile:
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing.file is required with the file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q must be none, otlp or file", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio <= 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be greater than 0 and at most 1"))
+	}
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case RateLimitStoreMemory, RateLimitStorePostgres:
+		default:
+			errs = append(errs, fmt.Errorf("rate_limit.store %q must be memory or postgres", c.RateLimit.Store))
+		}
+		for name, g := range c.RateLimit.Groups {
+			if !slices.Contains(RateLimitGroups, name) {
+				errs = append(errs, fmt.Errorf("rate_limit.groups.%s is not a route group (use %s)", name, strings.Join(RateLimitGroups, ", ")))
+				continue
+			}
+			if g.RequestsPerMinute <= 0 || g.Burst <= 0 {
+				errs = append(errs, fmt.Errorf("rate_limit.groups.%s: requests_per_minute and burst must be positive", name))
+			}
+		}
+	}
+	if c.Reminders.Enabled {
+		if c.Reminders.Interval <= 0 {
+			errs = append(errs, errors.New("reminders.interval must be positive"))
+		}
+		for _, offset := range c.Reminders.Offsets {
+			if offset <= 0 {
+				errs = append(errs, fmt.Errorf("reminders.offsets: %s is not positive", offset))
+			}
+		}
+		if c.Reminders.DigestHour < 0 || c.Reminders.DigestHour > 23 {
+			errs = append(errs, errors.New("reminders.digest_hour must be between 0 and 23"))
+		}
+		if _, err := time.LoadLocation(c.Reminders.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("reminders.timezone: %w", err))
+		}
+	}
+	if c.SLA.Enabled {
+		if c.SLA.Interval <= 0 {
+			errs = append(errs, errors.New("sla.interval must be positive"))
+		}
+		for name, rule := range c.SLA.Types {
+			if strings.TrimSpace(name) != name || name == "" || len(name) > 50 {
+				errs = append(errs, fmt.Errorf("sla.types: %q is not a valid task type", name))
+				continue
+			}
+			if rule.Within <= 0 {
+				errs = append(errs, fmt.Errorf("sla.types.%s.within must be positive", name))
+			}
+			switch rule.Escalate {
+			case SLAEscalateNotify, SLAEscalateReassign:
+			default:
+				errs = append(errs, fmt.Errorf("sla.types.%s.escalate %q must be notify or reassign", name, rule.Escalate))
+			}
+		}
+	}
+	switch c.Email.Sender {
+	case notify.SenderNone:
+	case notify.SenderFile:
+		if c.Email.File == "" {
+			errs = append(errs, errors.New("email.file is required with the file sender"))
+		}
+		if c.Email.From == "" {
+			errs = append(errs, errors.New("email.from is required to send email"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("email.sender %q must be none or file", c.Email.Sender))
+	}
+	if err := c.ValidateLogging(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ValidateDatabase checks only the database settings, for tools such as the
+// migrate command and crmctl that never serve requests.
+func (c *Config) ValidateDatabase() error {
+	if c.Database.URL == "" {
+		return errors.New("database.url is required (or set CRM_DATABASE_URL / CRM_DATABASE_URL_FILE / -database-url)")
+	}
+	return nil
+}
 
-	logger.Info("configuration loaded successfully",
-		"SalesAgentRoleID", cfg.Roles.SalesAgentID,
-		"ReceptionRoleID", cfg.Roles.ReceptionID)
+// ValidateLogging checks the logging settings.
+func (c *Config) ValidateLogging() error {
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		return fmt.Errorf("logging.level: %w", err)
+	}
+	if _, err := logging.ParsePIIMode(c.Logging.PII); err != nil {
+		return fmt.Errorf("logging.pii: %w", err)
+	}
+	return nil
+}
 
-	return &cfg, nil
-}
\ No newline at end of file
+// Logger returns the application logger configured by the logging section.
+// The settings must have passed ValidateLogging.
+func (c *Config) Logger(w io.Writer) *slog.Logger {
+	level, _ := logging.ParseLevel(c.Logging.Level)
+	pii, _ := logging.ParsePIIMode(c.Logging.PII)
+	return logging.New(w, logging.Options{Level: level, PII: pii})
+}
+
+// RateLimits returns the token bucket for each route group. The settings must
+// have passed Validate.
+func (c *Config) RateLimits() map[string]ratelimit.Limit {
+	limits := make(map[string]ratelimit.Limit, len(c.RateLimit.Groups))
+	for name, g := range c.RateLimit.Groups {
+		limits[name] = ratelimit.PerMinute(g.RequestsPerMinute, g.Burst)
+	}
+	return limits
+}
+
+// ReminderLocation returns the time zone reminders and digests are computed
+// in. The settings must have passed Validate.
+func (c *Config) ReminderLocation() *time.Location {
+	loc, err := time.LoadLocation(c.Reminders.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix is prepended to every environment variable the loader reads.
+const envPrefix = "CRM_"
+
+// envVar maps one environment variable onto a Config field. Secret settings
+// also accept <name>_FILE, naming a file to read the value from.
+type envVar struct {
+	name   string
+	secret bool
+	set    func(cfg *Config, v string) error
+}
+
+var envVars = []envVar{
+	{name: "SERVER_PORT", set: func(c *Config, v string) error { c.Server.Port = v; return nil }},
+	{name: "DATABASE_URL", secret: true, set: func(c *Config, v string) error { c.Database.URL = v; return nil }},
+	{name: "AUTH_JWT_SECRET", secret: true, set: func(c *Config, v string) error { c.Auth.JWTSecret = v; return nil }},
+	{name: "AUTH_INVITE_URL", set: func(c *Config, v string) error { c.Auth.InviteURL = v; return nil }},
+	{name: "AUTH_INVITE_TTL", set: durationSetter(func(c *Config) *time.Duration { return &c.Auth.InviteTTL })},
+	{name: "IDEMPOTENCY_TTL", set: durationSetter(func(c *Config) *time.Duration { return &c.Idempotency.TTL })},
+	{name: "METRICS_REFRESH_INTERVAL", set: durationSetter(func(c *Config) *time.Duration { return &c.Metrics.RefreshInterval })},
+	{name: "TRACING_EXPORTER", set: func(c *Config, v string) error { c.Tracing.Exporter = v; return nil }},
+	{name: "TRACING_OTLP_ENDPOINT", set: func(c *Config, v string) error { c.Tracing.OTLPEndpoint = v; return nil }},
+	{name: "TRACING_FILE", set: func(c *Config, v string) error { c.Tracing.File = v; return nil }},
+	{name: "TRACING_SAMPLE_RATIO", set: func(c *Config, v string) error {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		c.Tracing.SampleRatio = ratio
+		return nil
+	}},
+	{name: "RATE_LIMIT_ENABLED", set: boolSetter(func(c *Config) *bool { return &c.RateLimit.Enabled })},
+	{name: "RATE_LIMIT_STORE", set: func(c *Config, v string) error { c.RateLimit.Store = v; return nil }},
+	{name: "RATE_LIMIT_TRUST_PROXY", set: boolSetter(func(c *Config) *bool { return &c.RateLimit.TrustProxy })},
+	{name: "REMINDERS_ENABLED", set: boolSetter(func(c *Config) *bool { return &c.Reminders.Enabled })},
+	{name: "REMINDERS_INTERVAL", set: durationSetter(func(c *Config) *time.Duration { return &c.Reminders.Interval })},
+	{name: "REMINDERS_OFFSETS", set: func(c *Config, v string) error {
+		var offsets []time.Duration
+		for _, field := range strings.Split(v, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(field))
+			if err != nil {
+				return err
+			}
+			offsets = append(offsets, d)
+		}
+		c.Reminders.Offsets = offsets
+		return nil
+	}},
+	{name: "REMINDERS_DIGEST_HOUR", set: func(c *Config, v string) error {
+		hour, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		c.Reminders.DigestHour = hour
+		return nil
+	}},
+	{name: "REMINDERS_TIMEZONE", set: func(c *Config, v string) error { c.Reminders.Timezone = v; return nil }},
+	{name: "SLA_ENABLED", set: boolSetter(func(c *Config) *bool { return &c.SLA.Enabled })},
+	{name: "SLA_INTERVAL", set: durationSetter(func(c *Config) *time.Duration { return &c.SLA.Interval })},
+	{name: "EMAIL_SENDER", set: func(c *Config, v string) error { c.Email.Sender = v; return nil }},
+	{name: "EMAIL_FILE", set: func(c *Config, v string) error { c.Email.File = v; return nil }},
+	{name: "EMAIL_FROM", set: func(c *Config, v string) error { c.Email.From = v; return nil }},
+	{name: "LOG_LEVEL", set: func(c *Config, v string) error { c.Logging.Level = v; return nil }},
+	{name: "LOG_PII", set: func(c *Config, v string) error { c.Logging.PII = v; return nil }},
+}
+
+func durationSetter(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func boolSetter(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+// loadEnv applies the CRM_* environment variables that are set to cfg.
+func loadEnv(cfg *Config) error {
+	for _, ev := range envVars {
+		name := envPrefix + ev.name
+		value, ok := os.LookupEnv(name)
+		if ev.secret {
+			if path, fromFile := os.LookupEnv(name + "_FILE"); fromFile {
+				if ok {
+					return fmt.Errorf("set only one of %s and %s_FILE", name, name)
+				}
+				secret, err := readSecret(path)
+				if err != nil {
+					return fmt.Errorf("%s_FILE: %w", name, err)
+				}
+				value, ok = secret, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := ev.set(cfg, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -5,11 +5,13 @@ import "github.com/golang-jwt/jwt/v5" // <-- ADD THIS IMPORT
 
 // --- User Request DTOs ---
 
+// RegisterRequest is used for self-registration. The role is taken from the
+// invitation, never from the request body.
 type RegisterRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=50"`
-	Password string `json:"password" validate:"required,min=8"`
-	Email    string `json:"email"    validate:"required,email"`
-	RoleID   int    `json:"role_id"  validate:"required,oneof=1 2"`
+	Username    string `json:"username"     validate:"required,min=3,max=50"`
+	Password    string `json:"password"     validate:"required,min=8"`
+	Email       string `json:"email"        validate:"required,email"`
+	InviteToken string `json:"invite_token" validate:"required"`
 }
 
 type CreateUserRequest struct {
@@ -19,11 +21,33 @@ type CreateUserRequest struct {
 	RoleID   int    `json:"role_id"  validate:"required,oneof=1 2"`
 }
 
+// UpdateUserRequest deliberately has no role field; roles are changed through
+// ChangeRoleRequest so that every change is audited.
 type UpdateUserRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email"    validate:"required,email"`
 	Password string `json:"password" validate:"omitempty,min=8"`
-	RoleID   int    `json:"role_id"  validate:"required,oneof=1 2"`
+}
+
+type ChangeRoleRequest struct {
+	RoleID int    `json:"role_id" validate:"required,oneof=1 2"`
+	Reason string `json:"reason"  validate:"max=500"`
+}
+
+// --- Invitation Request DTOs ---
+
+type CreateInvitationRequest struct {
+	Email  string `json:"email"   validate:"omitempty,email"`
+	RoleID int    `json:"role_id" validate:"required,oneof=1 2"`
+}
+
+// --- API Key Request DTOs ---
+
+// CreateAPIKeyRequest creates a personal API key. ExpiresInDays defaults to 90.
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"            validate:"required,max=100"`
+	Scopes        []string `json:"scopes"          validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
 }
 
 // --- JWT Claims DTO ---
@@ -33,5 +57,18 @@ type Claims struct {
 	RoleID int `json:"role_id"`
 	Username string `json:"username"` 
 
+	// APIKeyID and Scopes are only set when the request was authenticated
+	// with an API key instead of a login token.
+	APIKeyID int      `json:"-"`
+	Scopes   []string `json:"-"`
+
 	jwt.RegisteredClaims
-}
\ No newline at end of file
+}
+
+// InviteClaims is the payload of a signed invitation link. It is signed with a
+// key derived from the JWT secret, so it can never pass as a login token.
+type InviteClaims struct {
+	InvitationID int `json:"invitation_id"`
+
+	jwt.RegisteredClaims
+}
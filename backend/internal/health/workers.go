@@ -0,0 +1,65 @@
+// Package health tracks the server's background workers so readiness checks
+// can tell whether they are still running.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// Workers starts background goroutines and remembers which of them have
+// stopped. A worker is expected to run until its context is cancelled; one
+// that returns early or panics is reported by Stopped.
+type Workers struct {
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewWorkers creates an empty registry.
+func NewWorkers(logger *slog.Logger) *Workers {
+	return &Workers{logger: logger, running: make(map[string]bool)}
+}
+
+// Go runs fn in a new goroutine under name. A panic in fn is logged and the
+// worker marked stopped instead of crashing the server.
+func (w *Workers) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	w.set(name, true)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				w.logger.Error("background worker panicked", "worker", name, "panic", fmt.Sprint(p))
+			}
+			if ctx.Err() == nil {
+				w.logger.Error("background worker stopped", "worker", name)
+			}
+			w.set(name, false)
+		}()
+		fn(ctx)
+	}()
+}
+
+func (w *Workers) set(name string, running bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running[name] = running
+}
+
+// Stopped returns the names of the workers that are no longer running, in
+// alphabetical order.
+func (w *Workers) Stopped() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var stopped []string
+	for name, running := range w.running {
+		if !running {
+			stopped = append(stopped, name)
+		}
+	}
+	sort.Strings(stopped)
+	return stopped
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type requestKey struct{}
+
+// requestInfo is shared by everything handling one request. The user is only
+// known once authentication has run, further down the middleware chain than
+// where the request is started, so it is filled in later through the pointer.
+type requestInfo struct {
+	id     string
+	userID atomic.Int64
+}
+
+// WithRequestID returns a context whose log records carry the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &requestInfo{id: id})
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request in ctx. It is
+// visible to every context derived from the one WithRequestID returned,
+// including those created before the call.
+func SetUserID(ctx context.Context, userID int) {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		info.userID.Store(int64(userID))
+	}
+}
+
+// UserID returns the user recorded with SetUserID, or 0.
+func UserID(ctx context.Context) int {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		return int(info.userID.Load())
+	}
+	return 0
+}
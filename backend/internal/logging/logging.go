@@ -0,0 +1,51 @@
+// Package logging builds the application's slog logger. Records logged with a
+// request context carry that request's ID and user, and attribute values are
+// redacted so tokens, passwords and personal data do not end up in log files.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// PIIMode says what happens to email addresses and phone numbers in logs.
+// Credentials are always redacted whatever the mode.
+type PIIMode string
+
+const (
+	PIIMask   PIIMode = "mask"   // keep enough to tell values apart: j***@example.com, ********44
+	PIIRedact PIIMode = "redact" // replace the whole value
+	PIIPlain  PIIMode = "plain"  // log as is; for local debugging only
+)
+
+// Options configures New.
+type Options struct {
+	Level slog.Leveler
+	PII   PIIMode
+}
+
+// New returns a JSON logger writing to w.
+func New(w io.Writer, opts Options) *slog.Logger {
+	inner := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: opts.Level})
+	return slog.New(newHandler(inner, opts.PII))
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) to a slog.Level.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// ParsePIIMode validates a PII mode name.
+func ParsePIIMode(s string) (PIIMode, error) {
+	switch mode := PIIMode(strings.ToLower(s)); mode {
+	case PIIMask, PIIRedact, PIIPlain:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown PII mode %q (want mask, redact or plain)", s)
+}
@@ -0,0 +1,197 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are substrings of attribute keys whose values are never logged.
+// Keys ending in "_id" (api_key_id) name a record, not a secret, and are kept.
+var secretKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "api_key", "apikey", "hash"}
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	bearerPattern = regexp.MustCompile(`(?i)\b(Bearer|ApiKey)\s+[A-Za-z0-9._~+/=\-]+`)
+)
+
+// handler adds the request ID, user and trace ID from the context to every
+// record and redacts attribute values before passing the record on.
+type handler struct {
+	next slog.Handler
+	pii  PIIMode
+}
+
+func newHandler(next slog.Handler, pii PIIMode) *handler {
+	if pii == "" {
+		pii = PIIMask
+	}
+	return &handler{next: next, pii: pii}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.scrub(r.Message), r.PC)
+	hasUser := false
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "user_id" {
+			hasUser = true
+		}
+		out.AddAttrs(h.redact(a))
+		return true
+	})
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		out.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		out.AddAttrs(slog.String("request_id", info.id))
+		if uid := info.userID.Load(); uid != 0 && !hasUser {
+			out.AddAttrs(slog.Int64("user_id", uid))
+		}
+	}
+	return h.next.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.redact(a)
+	}
+	return &handler{next: h.next.WithAttrs(clean), pii: h.pii}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), pii: h.pii}
+}
+
+// redact applies the policy to one attribute: credentials by key are replaced,
+// emails and phones by key are masked according to the PII mode, and free
+// text is scrubbed of bearer tokens and email addresses.
+func (h *handler) redact(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, g := range group {
+			clean[i] = h.redact(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(clean...)}
+	}
+
+	key := strings.ToLower(a.Key)
+	if isSecretKey(key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch {
+	case strings.Contains(key, "email"):
+		return slog.String(a.Key, h.maskEmail(a.Value.String()))
+	case strings.Contains(key, "phone"):
+		return slog.String(a.Key, h.maskPhone(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, h.scrub(err.Error()))
+		}
+		return slog.Any(a.Key, h.redactJSON(a.Key, toJSON(a.Value.Any())))
+	}
+	return a
+}
+
+// isSecretKey reports whether values logged under the lower-cased key are
+// credentials.
+func isSecretKey(key string) bool {
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) && !strings.HasSuffix(key, "_id") {
+			return true
+		}
+	}
+	return false
+}
+
+// toJSON converts a struct, map, slice or pointer to its JSON form (maps,
+// slices, strings, numbers and bools) so redactJSON can apply the policy to
+// each field by its JSON name. A value that cannot be encoded is not logged.
+func toJSON(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return redacted
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return redacted
+	}
+	return out
+}
+
+// redactJSON applies the policy to a value from toJSON logged under key,
+// field by field.
+func (h *handler) redactJSON(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = h.redactJSON(k, e)
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = h.redactJSON(key, e)
+		}
+		return v
+	case string:
+		return h.redact(slog.String(key, v)).Value.String()
+	}
+	if isSecretKey(strings.ToLower(key)) {
+		return redacted
+	}
+	return v
+}
+
+// scrub removes credentials and email addresses from free text such as
+// messages and error strings.
+func (h *handler) scrub(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "$1 "+redacted)
+	if h.pii != PIIPlain {
+		s = emailPattern.ReplaceAllStringFunc(s, h.maskEmail)
+	}
+	return s
+}
+
+func (h *handler) maskEmail(email string) string {
+	switch h.pii {
+	case PIIPlain:
+		return email
+	case PIIRedact:
+		return redacted
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return redacted
+	}
+	return local[:1] + "***@" + domain
+}
+
+func (h *handler) maskPhone(phone string) string {
+	switch h.pii {
+	case PIIPlain:
+		return phone
+	case PIIRedact:
+		return redacted
+	}
+	if len(phone) <= 2 {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
@@ -0,0 +1,165 @@
+// Package metrics exposes the server's Prometheus metrics: HTTP traffic per
+// route, database pool statistics, login outcomes and business gauges that
+// are refreshed from the database on an interval.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crm-project/internal/repository/postgres"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crm"
+
+// Metrics owns a registry and the collectors registered in it.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	logins       *prometheus.CounterVec
+
+	openLeads   *prometheus.GaugeVec
+	deals       *prometheus.GaugeVec
+	dealAmount  *prometheus.GaugeVec
+	refreshedAt prometheus.Gauge
+}
+
+// New creates the collectors and registers them, together with the Go runtime,
+// process and database pool collectors, in a fresh registry.
+func New(db *sqlx.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route pattern, method and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by outcome: success, invalid_credentials or error.",
+		}, []string{"outcome"}),
+		openLeads: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "open_leads",
+			Help:      "Leads that are neither Converted nor Lost, by status.",
+		}, []string{"status"}),
+		deals: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "deals",
+			Help:      "Deals by pipeline stage.",
+		}, []string{"stage"}),
+		dealAmount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "deal_amount",
+			Help:      "Sum of deal amounts by pipeline stage.",
+		}, []string{"stage"}),
+		refreshedAt: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "business_metrics_refreshed_timestamp_seconds",
+			Help:      "Unix time the business gauges were last refreshed successfully.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db.DB, "crm"),
+		m.httpRequests, m.httpDuration, m.logins,
+		m.openLeads, m.deals, m.dealAmount, m.refreshedAt,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware counts and times requests. It labels them with the chi route
+// pattern rather than the path so IDs do not create a series per record;
+// requests that match no route are labelled "unmatched".
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RecordLogin counts a login attempt. It implements service.LoginRecorder.
+func (m *Metrics) RecordLogin(outcome string) {
+	m.logins.WithLabelValues(outcome).Inc()
+}
+
+// RefreshBusiness updates the lead and deal gauges from the database. Label
+// sets that no longer appear, such as a deleted stage, are dropped.
+func (m *Metrics) RefreshBusiness(ctx context.Context, leads postgres.LeadRepository, deals postgres.DealRepository) error {
+	leadRows, err := leads.GetOpenLeadCountsByStatus(ctx)
+	if err != nil {
+		return err
+	}
+	dealRows, err := deals.GetDealsPipelineReport(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.openLeads.Reset()
+	for _, row := range leadRows {
+		m.openLeads.WithLabelValues(row.Status).Set(float64(row.Count))
+	}
+	m.deals.Reset()
+	m.dealAmount.Reset()
+	for _, row := range dealRows {
+		m.deals.WithLabelValues(row.StageName).Set(float64(row.DealCount))
+		m.dealAmount.WithLabelValues(row.StageName).Set(row.TotalAmount)
+	}
+	m.refreshedAt.SetToCurrentTime()
+	return nil
+}
+
+// RunBusinessRefresh calls RefreshBusiness immediately and then every
+// interval until ctx is cancelled. Failures are logged and the previous values
+// kept until the next attempt.
+func (m *Metrics) RunBusinessRefresh(ctx context.Context, leads postgres.LeadRepository, deals postgres.DealRepository, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.RefreshBusiness(ctx, leads, deals); err != nil && ctx.Err() == nil {
+			logger.Error("failed to refresh business metrics", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
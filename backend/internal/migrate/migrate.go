@@ -0,0 +1,473 @@
+// Package migrate applies the numbered SQL migrations embedded in the binary
+// and records them in the schema_migrations table.
+//
+// Each migration runs in its own transaction together with the row that
+// records it, so a failed migration leaves nothing half applied. The SHA-256 of
+// every applied up script is stored; if the embedded file changes afterwards
+// the runner refuses to continue until the drift is resolved.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lockID is the Postgres advisory lock held while migrations run, so two
+// servers started at the same time cannot apply the same migration twice.
+const lockID int64 = 0x63726d5f6d696772 // "crm_migr"
+
+var (
+	// ErrOutOfDate is returned by Check when embedded migrations have not
+	// been applied.
+	ErrOutOfDate = errors.New("database schema is out of date")
+	// ErrDrift is returned when applied migrations no longer match the
+	// embedded files, or the database has migrations this binary does not
+	// know about.
+	ErrDrift = errors.New("applied migrations do not match the embedded files")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up, hex encoded
+}
+
+// Status describes one migration that is embedded, applied, or both.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Drifted   bool // Applied from a file whose contents have since changed
+	Unknown   bool // Applied, but not embedded in this binary
+}
+
+type appliedRow struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Load reads NNNNNN_name.up.sql and NNNNNN_name.down.sql files from fsys and
+// returns them ordered by version. Versions do not have to be contiguous.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// New creates a Migrator for the migrations in fsys.
+func New(db *sqlx.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Latest returns the highest embedded migration version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// Status lists every embedded or applied migration, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+func (m *Migrator) status(applied map[int]appliedRow) []Status {
+	var statuses []Status
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			if !row.AppliedAt.IsZero() {
+				at := row.AppliedAt
+				s.AppliedAt = &at
+			}
+			s.Drifted = row.Checksum != mig.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	for _, row := range applied {
+		if _, ok := m.find(row.Version); !ok {
+			at := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &at, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// verify returns ErrDrift if any applied migration was changed or is not
+// embedded in this binary.
+func (m *Migrator) verify(applied map[int]appliedRow) error {
+	var problems []string
+	for _, s := range m.status(applied) {
+		switch {
+		case s.Drifted:
+			problems = append(problems, fmt.Sprintf("%d_%s was modified after it was applied", s.Version, s.Name))
+		case s.Unknown:
+			problems = append(problems, fmt.Sprintf("%d_%s is applied but not embedded in this binary", s.Version, s.Name))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrDrift, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Check reports whether the database is fully migrated. The server calls it
+// on startup and refuses to run against an out-of-date or drifted schema.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.loadApplied(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(applied); err != nil {
+		return err
+	}
+	var pending []string
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, strconv.Itoa(mig.Version))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending migrations %s; run `server migrate up`", ErrOutOfDate, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Current returns the highest applied migration version, or 0 if none is.
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	applied, err := m.loadApplied(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for v := range applied {
+		current = max(current, v)
+	}
+	return current, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn, applied map[int]appliedRow) error {
+		latest := 0
+		for v := range applied {
+			latest = max(latest, v)
+		}
+		if latest == 0 {
+			m.logger.Info("no migrations to roll back")
+			return nil
+		}
+		mig, _ := m.find(latest)
+		return m.rollback(ctx, conn, mig)
+	})
+}
+
+// To migrates up or down so that exactly the embedded migrations with a
+// version up to and including target are applied. A target of 0 rolls back
+// everything.
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if _, ok := m.find(target); !ok && target != 0 {
+		return fmt.Errorf("no migration with version %d", target)
+	}
+	return m.withLock(ctx, func(conn *sqlx.Conn, applied map[int]appliedRow) error {
+		var down, up []Migration
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if mig := m.migrations[i]; mig.Version > target {
+				if _, ok := applied[mig.Version]; ok {
+					down = append(down, mig)
+				}
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= target {
+				up = append(up, mig)
+			}
+		}
+		if len(down) == 0 && len(up) == 0 {
+			m.logger.Info("schema is up to date", "version", target)
+			return nil
+		}
+
+		for _, mig := range down {
+			if err := m.rollback(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+		for _, mig := range up {
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	m.logger.Info("applying migration", "version", mig.Version, "name", mig.Name)
+	return inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			mig.Version, mig.Name, mig.Checksum)
+		return err
+	})
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	m.logger.Info("rolling back migration", "version", mig.Version, "name", mig.Name)
+	return inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if strings.TrimSpace(mig.Down) != "" {
+			if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+				return fmt.Errorf("rolling back migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+		return err
+	})
+}
+
+// withLock takes the migration lock on a dedicated connection, makes sure the
+// schema_migrations table exists, checks for drift and then runs fn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn, applied map[int]appliedRow) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	applied, err := m.loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(applied); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// queryer is the part of *sqlx.DB and *sqlx.Conn used to read the migration
+// table.
+type queryer interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// legacyVersion reports the version recorded by golang-migrate, which the
+// project used before the runner was built in. Its schema_migrations table has
+// a single (version, dirty) row.
+func legacyVersion(ctx context.Context, q queryer) (version int, isLegacy bool, err error) {
+	err = q.GetContext(ctx, &isLegacy, `SELECT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'schema_migrations' AND column_name = 'dirty')`)
+	if err != nil || !isLegacy {
+		return 0, false, err
+	}
+
+	var row struct {
+		Version int  `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	err = q.GetContext(ctx, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, true, nil
+	}
+	if err != nil {
+		return 0, true, err
+	}
+	if row.Dirty {
+		return 0, true, fmt.Errorf("golang-migrate left migration %d dirty; fix the schema by hand and clear the dirty flag first", row.Version)
+	}
+	return row.Version, true, nil
+}
+
+// loadApplied returns the applied migrations by version. A database that was
+// migrated with golang-migrate is reported as having every embedded migration
+// up to its recorded version applied.
+func (m *Migrator) loadApplied(ctx context.Context, q queryer) (map[int]appliedRow, error) {
+	applied := make(map[int]appliedRow)
+
+	version, isLegacy, err := legacyVersion(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if isLegacy {
+		for _, mig := range m.migrations {
+			if mig.Version <= version {
+				applied[mig.Version] = appliedRow{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum}
+			}
+		}
+		return applied, nil
+	}
+
+	var exists bool
+	if err := q.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	var rows []appliedRow
+	if err := q.SelectContext(ctx, &rows, `SELECT version, name, checksum, applied_at FROM schema_migrations`); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// ensureTable creates schema_migrations, taking over the history of a
+// golang-migrate table if there is one. The old table is kept as
+// schema_migrations_legacy.
+func (m *Migrator) ensureTable(ctx context.Context, conn *sqlx.Conn) error {
+	version, isLegacy, err := legacyVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if isLegacy {
+			m.logger.Info("adopting golang-migrate history", "version", version)
+			if _, err := tx.ExecContext(ctx, `ALTER TABLE schema_migrations RENAME TO schema_migrations_legacy`); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+		if err != nil || !isLegacy {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
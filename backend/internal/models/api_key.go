@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIKey is a personal access key used by scripts and integrations instead of
+// a login session. Only a hash of the key is stored.
+type APIKey struct {
+	ID         int        `db:"api_key_id"   json:"id"`
+	UserID     int        `db:"user_id"      json:"user_id"`
+	Name       string     `db:"name"         json:"name"`
+	KeyPrefix  string     `db:"key_prefix"   json:"key_prefix"`
+	KeyHash    string     `db:"key_hash"     json:"-"`
+	Scopes     Scopes     `db:"scopes"       json:"scopes"`
+	ExpiresAt  time.Time  `db:"expires_at"   json:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at"   json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at"   json:"created_at"`
+}
+
+// Scopes is a list of permission scopes stored as a comma-separated column.
+type Scopes []string
+
+// Value implements driver.Valuer.
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+// Scan implements sql.Scanner.
+func (s *Scopes) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case nil:
+		*s = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Scopes", src)
+	}
+	if raw == "" {
+		*s = Scopes{}
+		return nil
+	}
+	*s = strings.Split(raw, ",")
+	return nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEntry is one row of the append-only audit log. Before and After only
+// hold the fields that changed; on create Before is nil, on delete After is nil.
+type AuditEntry struct {
+	ID          int64        `db:"audit_id"      json:"id"`
+	OccurredAt  time.Time    `db:"occurred_at"   json:"occurred_at"`
+	ActorUserID *int         `db:"actor_user_id" json:"actor_user_id,omitempty"`
+	APIKeyID    *int         `db:"api_key_id"    json:"api_key_id,omitempty"`
+	EntityType  string       `db:"entity_type"   json:"entity_type"`
+	EntityID    int          `db:"entity_id"     json:"entity_id"`
+	Action      string       `db:"action"        json:"action"`
+	Before      AuditChanges `db:"before"        json:"before,omitempty"`
+	After       AuditChanges `db:"after"         json:"after,omitempty"`
+	RequestID   *string      `db:"request_id"    json:"request_id,omitempty"`
+}
+
+// AuditFilter narrows an audit log query. Zero values are ignored.
+type AuditFilter struct {
+	EntityType  string
+	EntityID    int
+	ActorUserID int
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+// AuditChanges is a set of field values stored as a JSONB column.
+type AuditChanges map[string]interface{}
+
+// Value implements driver.Valuer.
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (c *AuditChanges) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into AuditChanges", src)
+	}
+	return json.Unmarshal(raw, c)
+}
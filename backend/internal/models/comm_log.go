@@ -15,4 +15,5 @@ type CommLog struct {
     CreatedAt       *time.Time `db:"created_at"       json:"created_at,omitempty"`
     UpdatedAt       *time.Time `db:"updated_at"       json:"updated_at,omitempty"` // New field
     DeletedAt       *time.Time `db:"deleted_at"       json:"deleted_at,omitempty"`
+    Version         int        `db:"version"          json:"version"`
 }
\ No newline at end of file
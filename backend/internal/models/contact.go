@@ -18,4 +18,5 @@ type Contact struct {
 	CreatedAt      time.Time  `db:"created_at"      json:"created_at"`
 	UpdatedAt      *time.Time `db:"updated_at"      json:"updated_at,omitempty"` // New field
 	CreatedBy      *int       `db:"created_by" json:"created_by,omitempty"`
+	Version        int        `db:"version"         json:"version"`
 }
\ No newline at end of file
@@ -19,4 +19,5 @@ type Deal struct {
 	CreatedAt   time.Time     `db:"created_at"   json:"created_at"`
 	UpdatedAt   time.Time     `db:"updated_at"   json:"updated_at"`
 	CreatedBy   sql.NullInt64 `db:"created_by"   json:"created_by"`
+	Version     int           `db:"version"      json:"version"`
 }
\ No newline at end of file
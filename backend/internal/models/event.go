@@ -16,4 +16,5 @@ type Event struct {
 	CreatedAt        time.Time  `db:"created_at"         json:"created_at"`
 	UpdatedAt        *time.Time `db:"updated_at"         json:"updated_at,omitempty"`   // Nullable for consistency
 	DeletedAt        *time.Time `db:"deleted_at"         json:"deleted_at,omitempty"`   // For soft deletes
+	Version          int        `db:"version"            json:"version"`
 }
\ No newline at end of file
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// IdempotencyRecord remembers a create request sent with an Idempotency-Key
+// header so that a retry of the same request gets the original response
+// instead of creating a duplicate. StatusCode is nil while the original
+// request is still being processed.
+type IdempotencyRecord struct {
+	UserID          int           `db:"user_id"`
+	Key             string        `db:"idempotency_key"`
+	Method          string        `db:"request_method"`
+	Path            string        `db:"request_path"`
+	RequestHash     string        `db:"request_hash"`
+	StatusCode      *int          `db:"status_code"`
+	ResponseHeaders StoredHeaders `db:"response_headers"`
+	ResponseBody    []byte        `db:"response_body"`
+	CreatedAt       time.Time     `db:"created_at"`
+	ExpiresAt       time.Time     `db:"expires_at"`
+}
+
+// StoredHeaders is a set of HTTP response headers stored as a JSONB column.
+type StoredHeaders map[string]string
+
+// Value implements driver.Valuer.
+func (h StoredHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (h *StoredHeaders) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case nil:
+		*h = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into StoredHeaders", src)
+	}
+	return json.Unmarshal(raw, h)
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Invitation is a single-use onboarding invite issued by a manager. The role is
+// fixed when the invite is created, so new users cannot pick their own role.
+type Invitation struct {
+	ID        int        `db:"invitation_id" json:"id"`
+	Email     *string    `db:"email"         json:"email,omitempty"` // If set, only this address may register with the invite
+	RoleID    int        `db:"role_id"       json:"role_id"`
+	CreatedBy int        `db:"created_by"    json:"created_by"`
+	ExpiresAt time.Time  `db:"expires_at"    json:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"       json:"used_at,omitempty"`
+	UsedBy    *int       `db:"used_by"       json:"used_by,omitempty"`
+	RevokedAt *time.Time `db:"revoked_at"    json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at"    json:"created_at"`
+}
+
+// RoleChange records a single change of a user's role and who made it.
+type RoleChange struct {
+	ID        int       `db:"role_change_id" json:"id"`
+	UserID    int       `db:"user_id"        json:"user_id"`
+	OldRoleID int       `db:"old_role_id"    json:"old_role_id"`
+	NewRoleID int       `db:"new_role_id"    json:"new_role_id"`
+	ChangedBy int       `db:"changed_by"     json:"changed_by"`
+	Reason    *string   `db:"reason"         json:"reason,omitempty"`
+	ChangedAt time.Time `db:"changed_at"     json:"changed_at"`
+}
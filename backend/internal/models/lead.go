@@ -14,4 +14,5 @@ type Lead struct {
 	Notes       *string    `db:"notes"         json:"notes,omitempty"` // Nullable
 	CreatedAt   time.Time  `db:"created_at"    json:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at"    json:"updated_at"`
+	Version     int        `db:"version"       json:"version"`
 }
\ No newline at end of file
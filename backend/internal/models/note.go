@@ -15,4 +15,5 @@ type Note struct {
 	CreatedAt time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at" json:"updated_at,omitempty"` // Use pointer for nullable updated_at
 	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // New field
+	Version   int        `db:"version" json:"version"`
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Kinds of notification.
+const (
+	NotificationTaskReminder   = "task_reminder"   // A task is due soon
+	NotificationTaskOverdue    = "task_overdue"    // A task was marked Overdue
+	NotificationTaskDigest     = "task_digest"     // Daily summary of a user's open tasks
+	NotificationTaskSLABreach  = "task_sla_breach" // A task the user created stayed open past its SLA
+	NotificationTaskReassigned = "task_reassigned" // A task was taken from the user after it breached its SLA
+)
+
+// Notification is an in-app message for one user.
+type Notification struct {
+	ID        int        `db:"notification_id" json:"id"`
+	UserID    int        `db:"user_id"         json:"user_id"`
+	Kind      string     `db:"kind"            json:"kind"`
+	Title     string     `db:"title"           json:"title"`
+	Body      string     `db:"body"            json:"body"`
+	TaskID    *int       `db:"task_id"         json:"task_id,omitempty"`
+	DedupKey  *string    `db:"dedup_key"       json:"-"` // At most one notification is stored per key
+	ReadAt    *time.Time `db:"read_at"         json:"read_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at"      json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Playbook is a set of task templates that is applied when a deal enters a
+// stage or a lead reaches a status. Exactly one of DealStageID and
+// LeadStatusID is set.
+type Playbook struct {
+	ID           int            `db:"playbook_id"    json:"id"`
+	Name         string         `db:"name"           json:"name"`
+	DealStageID  *int           `db:"deal_stage_id"  json:"deal_stage_id,omitempty"`
+	LeadStatusID *int           `db:"lead_status_id" json:"lead_status_id,omitempty"`
+	Active       bool           `db:"active"         json:"active"`
+	Tasks        []PlaybookTask `db:"-"              json:"tasks"`
+	CreatedBy    int            `db:"created_by"     json:"created_by"`
+	CreatedAt    time.Time      `db:"created_at"     json:"created_at"`
+	UpdatedAt    time.Time      `db:"updated_at"     json:"updated_at"`
+	Version      int            `db:"version"        json:"version"`
+}
+
+// PlaybookTask is the template of a task a playbook creates.
+type PlaybookTask struct {
+	ID              int     `db:"playbook_task_id" json:"id"`
+	PlaybookID      int     `db:"playbook_id"      json:"-"`
+	TaskName        string  `db:"task_name"        json:"task_name"`
+	TaskDescription *string `db:"task_description" json:"task_description,omitempty"`
+	DueInDays       int     `db:"due_in_days"      json:"due_in_days"`         // The task is due this many days after the change
+	AssignTo        *int    `db:"assign_to"        json:"assign_to,omitempty"` // Nil assigns the deal or lead owner
+	Position        int     `db:"position"         json:"position"`
+}
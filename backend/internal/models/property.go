@@ -15,4 +15,5 @@ type Property struct {
 	Status         string    `db:"status"          json:"status"`
 	CreatedAt      time.Time `db:"created_at"      json:"created_at"`
 	UpdatedAt      time.Time `db:"updated_at"      json:"updated_at"`
+	Version        int       `db:"version"         json:"version"`
 }
\ No newline at end of file
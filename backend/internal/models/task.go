@@ -16,4 +16,8 @@ type Task struct {
     UpdatedAt       *time.Time `db:"updated_at"       json:"updated_at,omitempty"`
     DeletedAt       *time.Time `db:"deleted_at"       json:"deleted_at,omitempty"`
     CreatedBy       int        `db:"created_by"       json:"created_by"`
+    Version         int        `db:"version"          json:"version"`
+    SeriesID        *int       `db:"series_id"        json:"series_id,omitempty"`  // Set for occurrences of a recurring task
+    Occurrence      *int       `db:"occurrence"       json:"occurrence,omitempty"` // 1 for the first task of the series, 2 for the next, ...
+    TaskType        *string    `db:"task_type"        json:"task_type,omitempty"`  // Selects the SLA the task is held to
 }
\ No newline at end of file
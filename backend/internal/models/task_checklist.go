@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ChecklistItem is a sub-item of a task. A task cannot be completed while
+// one of its required items is not done.
+type ChecklistItem struct {
+	ID          int        `db:"item_id"      json:"id"`
+	TaskID      int        `db:"task_id"      json:"task_id"`
+	Title       string     `db:"title"        json:"title"`
+	Required    bool       `db:"required"     json:"required"`
+	Position    int        `db:"position"     json:"position"` // Items are listed in ascending position
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	CompletedBy *int       `db:"completed_by" json:"completed_by,omitempty"`
+	CreatedBy   int        `db:"created_by"   json:"created_by"`
+	CreatedAt   time.Time  `db:"created_at"   json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"   json:"updated_at"`
+	Version     int        `db:"version"      json:"version"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// TaskComment is a comment on a task. A comment with a ParentID replies to
+// another comment on the same task. Deleted comments keep their place in the
+// thread but lose their body.
+type TaskComment struct {
+	ID        int        `db:"comment_id" json:"id"`
+	TaskID    int        `db:"task_id"    json:"task_id"`
+	ParentID  *int       `db:"parent_id"  json:"parent_id,omitempty"`
+	AuthorID  int        `db:"author_id"  json:"author_id"`
+	Body      string     `db:"body"       json:"body"`
+	Edited    bool       `db:"edited"     json:"edited"` // The body was changed after the comment was posted
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version   int        `db:"version"    json:"version"`
+}
+
+// TaskCommentEdit records the body a comment had before one of its edits.
+type TaskCommentEdit struct {
+	ID        int       `db:"edit_id"    json:"id"`
+	CommentID int       `db:"comment_id" json:"comment_id"`
+	Body      string    `db:"body"       json:"body"`
+	EditedBy  int       `db:"edited_by"  json:"edited_by"`
+	EditedAt  time.Time `db:"edited_at"  json:"edited_at"`
+}
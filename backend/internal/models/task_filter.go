@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TaskFilter selects the open and completed tasks (not deleted ones) a bulk
+// operation applies to. Zero values are ignored; the conditions that are set
+// must all match.
+type TaskFilter struct {
+	AssignedTo int        `json:"assigned_to,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	TaskType   string     `json:"task_type,omitempty"`
+	LeadID     int        `json:"lead_id,omitempty"`
+	DealID     int        `json:"deal_id,omitempty"`
+	DueAfter   *time.Time `json:"due_after,omitempty"`  // Due at or after this time
+	DueBefore  *time.Time `json:"due_before,omitempty"` // Due before this time
+}
+
+// IsEmpty reports whether f sets no condition, and so would match every task.
+func (f TaskFilter) IsEmpty() bool {
+	return f == TaskFilter{}
+}
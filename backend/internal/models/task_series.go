@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// TaskSeries is a recurring task. Only the current occurrence exists as a
+// task; completing it creates the next one from the template fields below,
+// due at the next date the recurrence rule produces.
+type TaskSeries struct {
+	ID              int        `db:"series_id"         json:"id"`
+	RRule           string     `db:"rrule"             json:"rrule"`             // RFC 5545 recurrence rule, e.g. FREQ=WEEKLY;BYDAY=MO;COUNT=8
+	DTStart         time.Time  `db:"dtstart"           json:"dtstart"`           // Due date the rule is evaluated from
+	StartOccurrence int        `db:"start_occurrence"  json:"start_occurrence"`  // Occurrence due at DTStart; later ones follow the rule
+	UntilDealClosed bool       `db:"until_deal_closed" json:"until_deal_closed"` // Stop once the linked deal is Closed-Won or Closed-Lost
+	TaskName        string     `db:"task_name"         json:"task_name"`
+	TaskDescription *string    `db:"task_description"  json:"task_description,omitempty"`
+	AssignedTo      int        `db:"assigned_to"       json:"assigned_to"`
+	LeadID          *int       `db:"lead_id"           json:"lead_id,omitempty"`
+	DealID          *int       `db:"deal_id"           json:"deal_id,omitempty"`
+	TaskType        *string    `db:"task_type"         json:"task_type,omitempty"`
+	EndedAt         *time.Time `db:"ended_at"          json:"ended_at,omitempty"` // Set once no further occurrences will be created
+	CreatedBy       int        `db:"created_by"        json:"created_by"`
+	CreatedAt       time.Time  `db:"created_at"        json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"        json:"updated_at"`
+	Version         int        `db:"version"           json:"version"`
+}
+
+// Recurrence is how a client asks for a task to repeat.
+type Recurrence struct {
+	RRule           string `json:"rrule"`
+	UntilDealClosed bool   `json:"until_deal_closed,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// TaskSLABreach records that a task stayed open longer than the SLA of its
+// type, and how it was escalated.
+type TaskSLABreach struct {
+	ID           int       `db:"breach_id"     json:"id"`
+	TaskID       int       `db:"task_id"       json:"task_id"`
+	TaskType     string    `db:"task_type"     json:"task_type"`
+	AssignedTo   int       `db:"assigned_to"   json:"assigned_to"` // The agent the task was assigned to when the SLA ran out
+	Escalation   string    `db:"escalation"    json:"escalation"`  // notify or reassign
+	ReassignedTo *int      `db:"reassigned_to" json:"reassigned_to,omitempty"`
+	DueBy        time.Time `db:"due_by"        json:"due_by"` // When the SLA ran out
+	BreachedAt   time.Time `db:"breached_at"   json:"breached_at"`
+}
+
+// SLABreachReport counts SLA breaches per agent.
+type SLABreachReport struct {
+	Rows  []SLABreachReportRow `json:"rows"`
+	Total SLABreachSummary     `json:"total"`
+}
+
+// SLABreachReportRow holds the breaches of the tasks assigned to one agent.
+type SLABreachReportRow struct {
+	EmployeeID   int    `db:"employee_id"   json:"employee_id"`
+	EmployeeName string `db:"employee_name" json:"employee_name"`
+	SLABreachSummary
+}
+
+// SLABreachSummary holds breach counts, for one agent or for all of them.
+type SLABreachSummary struct {
+	Breaches   int `db:"breaches"   json:"breaches"`
+	Reassigned int `db:"reassigned" json:"reassigned"` // Breaches escalated by taking the task away from the agent
+	StillOpen  int `db:"still_open" json:"still_open"` // Breached tasks not completed yet
+}
@@ -0,0 +1,102 @@
+// Package notify delivers notifications outside the application. Email goes
+// through an EmailSender, so a real mail service can be plugged in without
+// touching the code that decides what to send.
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Email senders accepted by NewEmailSender.
+const (
+	SenderNone = "none" // emails are dropped; notifications are in-app only
+	SenderFile = "file" // emails are appended to a local mbox file
+)
+
+// Email is a plain-text message to one recipient.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailSender delivers email. Implementations must be safe for concurrent use.
+type EmailSender interface {
+	Send(ctx context.Context, msg Email) error
+}
+
+// NewEmailSender returns the sender named by kind. file is only used by the
+// file sender.
+func NewEmailSender(kind, file, from string) (EmailSender, error) {
+	switch kind {
+	case "", SenderNone:
+		return NopSender{}, nil
+	case SenderFile:
+		return NewFileSender(file, from), nil
+	}
+	return nil, fmt.Errorf("unknown email sender %q", kind)
+}
+
+// NopSender drops every email.
+type NopSender struct{}
+
+// Send does nothing.
+func (NopSender) Send(ctx context.Context, msg Email) error {
+	return nil
+}
+
+// FileSender appends each email to a file in mbox format, standing in for a
+// mail service during development. The file can be opened with most mail
+// clients or simply read.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+	from string
+	now  func() time.Time
+}
+
+// NewFileSender creates a FileSender appending to path, creating it if needed.
+func NewFileSender(path, from string) *FileSender {
+	return &FileSender{path: path, from: from, now: time.Now}
+}
+
+// Send appends msg to the file.
+func (s *FileSender) Send(ctx context.Context, msg Email) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "From %s %s\n", s.from, now.UTC().Format(time.ANSIC))
+	fmt.Fprintf(w, "From: %s\nTo: %s\nSubject: %s\nDate: %s\n", s.from, msg.To, headerValue(msg.Subject), now.Format(time.RFC1123Z))
+	fmt.Fprintf(w, "Content-Type: text/plain; charset=utf-8\n\n")
+	for _, line := range strings.Split(strings.TrimRight(msg.Body, "\n"), "\n") {
+		// mbox separates messages with "From " lines, so quote body lines
+		// that would look like one.
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// headerValue keeps a header on one line.
+func headerValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
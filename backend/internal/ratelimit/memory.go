@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so
+// with several servers behind a load balancer a client effectively gets the
+// limit once per instance; use the Postgres store there.
+type MemoryStore struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Take takes a token from the bucket for key, creating a full one if needed.
+func (s *MemoryStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = refill(l, b.tokens, now.Sub(b.updated))
+	b.updated = now
+	b.limit = l
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return NewResult(l, b.tokens, allowed), nil
+}
+
+// sweep drops buckets that are full again, which bounds memory to the clients
+// seen recently. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.limit.FullAfter() {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,68 @@
+// Package ratelimit implements token-bucket rate limiting. Each key (a route
+// group plus a user or client IP) has a bucket holding up to Burst tokens
+// that refills at Rate tokens per second; a request takes one token and is
+// refused when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is the size and refill rate of a bucket.
+type Limit struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Bucket capacity, i.e. how many requests may arrive at once
+}
+
+// PerMinute returns a limit of n requests per minute with the given burst.
+func PerMinute(n, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// FullAfter is how long an untouched bucket takes to refill completely. A
+// bucket idle for longer can be forgotten, since a new one starts full.
+func (l Limit) FullAfter() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Limit      int           // Bucket capacity
+	Remaining  int           // Whole tokens left after this request
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token is available; zero if Allowed
+}
+
+// NewResult describes a bucket holding tokens after a request was allowed or
+// refused. Stores use it so every store reports the same numbers.
+func NewResult(l Limit, tokens float64, allowed bool) Result {
+	tokens = math.Max(0, math.Min(tokens, float64(l.Burst)))
+	res := Result{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(tokens),
+		Reset:     secondsToDuration((float64(l.Burst) - tokens) / l.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = secondsToDuration((1 - tokens) / l.Rate)
+	}
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps the buckets. Take must be atomic per key, since several requests
+// for the same key may arrive at once.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+// refill returns the tokens in a bucket that held tokens elapsed ago.
+func refill(l Limit, tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.Rate)
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"crm-project/internal/repository/postgres"
+)
+
+// AdminRepo is an in-memory postgres.AdminRepository.
+type AdminRepo struct {
+	s *Store
+}
+
+// NewAdminRepo creates a new AdminRepo backed by s.
+func NewAdminRepo(s *Store) *AdminRepo {
+	return &AdminRepo{s: s}
+}
+
+// SeedLookups adds any postgres.LookupSeeds rows that are missing and returns
+// how many rows were added to each table.
+func (r *AdminRepo) SeedLookups(ctx context.Context) (map[string]int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tables := map[string]map[int]string{
+		"roles":          r.s.roles,
+		"property_types": r.s.propertyTypes,
+		"lead_sources":   r.s.leadSources,
+		"lead_statuses":  r.s.leadStatuses,
+		"deal_stages":    r.s.dealStages,
+	}
+	added := make(map[string]int64, len(postgres.LookupSeeds))
+	for _, seed := range postgres.LookupSeeds {
+		table := tables[seed.Table]
+		for _, name := range seed.Names {
+			if lookupID(table, name) == 0 {
+				table[r.s.nextID(seed.Table)] = name
+				added[seed.Table]++
+			}
+		}
+	}
+	return added, nil
+}
+
+// GetRoleID returns the ID of the role with the given name, or 0 if there is
+// none.
+func (r *AdminRepo) GetRoleID(ctx context.Context, roleName string) (int, error) {
+	return r.s.RoleID(roleName), nil
+}
+
+// ReassignRecords moves the contacts, leads and deals owned by one user, their
+// open tasks and their upcoming events to another user.
+func (r *AdminRepo) ReassignRecords(ctx context.Context, fromUserID, toUserID int) (*postgres.ReassignCounts, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	var counts postgres.ReassignCounts
+	for id, c := range r.s.contacts {
+		if c.CreatedBy != nil && *c.CreatedBy == fromUserID {
+			c.CreatedBy = intPtr(toUserID)
+			c.UpdatedAt = timePtr(now)
+			c.Version++
+			r.s.contacts[id] = c
+			counts.Contacts++
+		}
+	}
+	for id, l := range r.s.leads {
+		if l.AssignedTo == fromUserID {
+			l.AssignedTo = toUserID
+			l.UpdatedAt = now
+			l.Version++
+			r.s.leads[id] = l
+			counts.Leads++
+		}
+	}
+	for id, d := range r.s.deals {
+		if d.CreatedBy.Valid && d.CreatedBy.Int64 == int64(fromUserID) {
+			d.CreatedBy = sql.NullInt64{Int64: int64(toUserID), Valid: true}
+			d.UpdatedAt = now
+			d.Version++
+			r.s.deals[id] = d
+			counts.Deals++
+		}
+	}
+	for id, t := range r.s.tasks {
+		if t.AssignedTo == fromUserID && t.Status != "Completed" {
+			t.AssignedTo = toUserID
+			t.UpdatedAt = timePtr(now)
+			t.Version++
+			r.s.tasks[id] = t
+			counts.Tasks++
+		}
+	}
+	for id, e := range r.s.events {
+		if e.OrganizerID == fromUserID && e.StartTime.After(now) {
+			e.OrganizerID = toUserID
+			e.UpdatedAt = timePtr(now)
+			e.Version++
+			r.s.events[id] = e
+			counts.Events++
+		}
+	}
+	return &counts, nil
+}
+
+// BackfillContactCreatedBy sets created_by on every contact that has none.
+func (r *AdminRepo) BackfillContactCreatedBy(ctx context.Context, userID int) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	var n int64
+	for id, c := range r.s.contacts {
+		if c.CreatedBy == nil {
+			c.CreatedBy = intPtr(userID)
+			c.UpdatedAt = timePtr(now)
+			c.Version++
+			r.s.contacts[id] = c
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// APIKeyRepo is an in-memory postgres.APIKeyRepository.
+type APIKeyRepo struct {
+	s *Store
+}
+
+// NewAPIKeyRepo creates a new APIKeyRepo backed by s.
+func NewAPIKeyRepo(s *Store) *APIKeyRepo {
+	return &APIKeyRepo{s: s}
+}
+
+// Create inserts a new API key and returns its ID.
+func (r *APIKeyRepo) Create(ctx context.Context, k models.APIKey) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, other := range r.s.apiKeys {
+		if other.KeyHash == k.KeyHash {
+			return 0, uniqueViolation("api_keys", "api_keys_key_hash_key")
+		}
+	}
+	k.ID = r.s.nextID("api_keys")
+	k.LastUsedAt, k.RevokedAt = nil, nil
+	k.CreatedAt = r.s.now()
+	r.s.apiKeys[k.ID] = k
+	return k.ID, nil
+}
+
+// GetByID retrieves a single API key. It returns nil, nil if it does not exist.
+func (r *APIKeyRepo) GetByID(ctx context.Context, id int) (*models.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	k, ok := r.s.apiKeys[id]
+	if !ok {
+		return nil, nil
+	}
+	return &k, nil
+}
+
+// GetAllForUser retrieves all API keys owned by a user, newest first.
+func (r *APIKeyRepo) GetAllForUser(ctx context.Context, userID int) ([]models.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.apiKeys, func(k models.APIKey) bool {
+		return k.UserID == userID
+	}, func(a, b models.APIKey) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	}), nil
+}
+
+// GetActiveByHash looks up an unrevoked, unexpired key by the hash of its
+// secret. It returns nil, nil if no such key exists.
+func (r *APIKeyRepo) GetActiveByHash(ctx context.Context, keyHash string) (*postgres.APIKeyOwner, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	now := r.s.now()
+	for _, k := range r.s.apiKeys {
+		if k.KeyHash != keyHash || k.RevokedAt != nil || !k.ExpiresAt.After(now) {
+			continue
+		}
+		u, ok := r.s.users[k.UserID]
+		if !ok {
+			return nil, nil
+		}
+		return &postgres.APIKeyOwner{APIKey: k, Username: u.Username, RoleID: u.RoleID}, nil
+	}
+	return nil, nil
+}
+
+// TouchLastUsed records that a key was used. Like the Postgres repository it
+// only moves the timestamp forward once per minute.
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	k, ok := r.s.apiKeys[id]
+	if !ok {
+		return nil
+	}
+	now := r.s.now()
+	if k.LastUsedAt == nil || k.LastUsedAt.Before(now.Add(-time.Minute)) {
+		k.LastUsedAt = timePtr(now)
+		r.s.apiKeys[id] = k
+	}
+	return nil
+}
+
+// Revoke marks a key as revoked.
+func (r *APIKeyRepo) Revoke(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	k, ok := r.s.apiKeys[id]
+	if !ok || k.RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	k.RevokedAt = timePtr(r.s.now())
+	r.s.apiKeys[id] = k
+	return nil
+}
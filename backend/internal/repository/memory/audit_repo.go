@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"crm-project/internal/models"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditRepo is an in-memory postgres.AuditRepository.
+type AuditRepo struct {
+	s *Store
+}
+
+// NewAuditRepo creates a new AuditRepo backed by s.
+func NewAuditRepo(s *Store) *AuditRepo {
+	return &AuditRepo{s: s}
+}
+
+// Insert appends an entry to the audit log.
+func (r *AuditRepo) Insert(ctx context.Context, e models.AuditEntry) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e.ID = int64(r.s.nextID("audit_log"))
+	e.OccurredAt = r.s.now()
+	r.s.auditLog = append(r.s.auditLog, e)
+	return nil
+}
+
+// Find returns audit entries matching the filter, newest first.
+func (r *AuditRepo) Find(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var entries []models.AuditEntry
+	for _, e := range r.s.auditLog {
+		if f.EntityType != "" && e.EntityType != f.EntityType {
+			continue
+		}
+		if f.EntityID > 0 && e.EntityID != f.EntityID {
+			continue
+		}
+		if f.ActorUserID > 0 && (e.ActorUserID == nil || *e.ActorUserID != f.ActorUserID) {
+			continue
+		}
+		if f.From != nil && e.OccurredAt.Before(*f.From) {
+			continue
+		}
+		if f.To != nil && !e.OccurredAt.Before(*f.To) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].OccurredAt.Equal(entries[j].OccurredAt) {
+			return entries[i].OccurredAt.After(entries[j].OccurredAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	if f.Offset >= len(entries) {
+		return nil, nil
+	}
+	entries = entries[max(f.Offset, 0):]
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// CommLogRepo is an in-memory postgres.CommLogRepository.
+type CommLogRepo struct {
+	s *Store
+}
+
+// NewCommLogRepository creates a new CommLogRepo backed by s.
+func NewCommLogRepository(s *Store) *CommLogRepo {
+	return &CommLogRepo{s: s}
+}
+
+func errCommLogNotFound() error {
+	return fmt.Errorf("communication log %w", postgres.ErrNotFound)
+}
+
+// liveCommLogs returns the logs that are not soft deleted and are accepted by
+// keep, most recent interaction first. The caller must hold the lock.
+func (r *CommLogRepo) liveCommLogs(keep func(models.CommLog) bool) []models.CommLog {
+	return sortedValues(r.s.commLogs, func(l models.CommLog) bool {
+		return l.DeletedAt == nil && (keep == nil || keep(l))
+	}, func(a, b models.CommLog) bool {
+		return newestFirst(a.InteractionDate, a.ID, b.InteractionDate, b.ID)
+	})
+}
+
+// CreateCommLog creates a new communication log
+func (r *CommLogRepo) CreateCommLog(ctx context.Context, log *models.CommLog) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	log.ID = r.s.nextID("communication_logs")
+	log.CreatedAt = timePtr(now)
+	log.UpdatedAt = timePtr(now)
+	log.DeletedAt = nil
+	log.Version = 1
+	r.s.commLogs[log.ID] = *log
+	return nil
+}
+
+// GetCommLogByID retrieves a communication log by ID
+func (r *CommLogRepo) GetCommLogByID(ctx context.Context, id int) (*models.CommLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	l, ok := r.s.commLogs[id]
+	if !ok || l.DeletedAt != nil {
+		return nil, errCommLogNotFound()
+	}
+	return &l, nil
+}
+
+// GetCommLogsByDealID retrieves all communication logs for a deal
+func (r *CommLogRepo) GetCommLogsByDealID(ctx context.Context, dealID int) ([]models.CommLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveCommLogs(func(l models.CommLog) bool {
+		return l.DealID != nil && *l.DealID == dealID
+	}), nil
+}
+
+// GetCommLogsByLeadID retrieves all communication logs for a lead
+func (r *CommLogRepo) GetCommLogsByLeadID(ctx context.Context, leadID int) ([]models.CommLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveCommLogs(func(l models.CommLog) bool {
+		return l.LeadID != nil && *l.LeadID == leadID
+	}), nil
+}
+
+// GetCommLogsByContactID retrieves all communication logs for a contact
+func (r *CommLogRepo) GetCommLogsByContactID(ctx context.Context, contactID int) ([]models.CommLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveCommLogs(func(l models.CommLog) bool {
+		return l.ContactID != nil && *l.ContactID == contactID
+	}), nil
+}
+
+// GetAllCommLogs retrieves all communication logs
+func (r *CommLogRepo) GetAllCommLogs(ctx context.Context) ([]models.CommLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveCommLogs(nil), nil
+}
+
+// GetCommLogsForUser retrieves the communication logs recorded by a user
+func (r *CommLogRepo) GetCommLogsForUser(ctx context.Context, userID int) ([]models.CommLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveCommLogs(func(l models.CommLog) bool {
+		return l.UserID == userID
+	}), nil
+}
+
+// UpdateCommLog updates an existing communication log
+func (r *CommLogRepo) UpdateCommLog(ctx context.Context, log *models.CommLog) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.commLogs[log.ID]
+	if !ok || existing.DeletedAt != nil {
+		return errCommLogNotFound()
+	}
+	if log.Version != 0 && log.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	updated := existing
+	updated.ContactID = log.ContactID
+	updated.UserID = log.UserID
+	updated.LeadID = log.LeadID
+	updated.DealID = log.DealID
+	updated.InteractionDate = log.InteractionDate
+	updated.InteractionType = log.InteractionType
+	updated.Notes = log.Notes
+	updated.UpdatedAt = timePtr(r.s.now())
+	updated.Version = existing.Version + 1
+	r.s.commLogs[log.ID] = updated
+	log.CreatedAt = updated.CreatedAt
+	log.Version = updated.Version
+	return nil
+}
+
+// DeleteCommLog soft deletes a communication log. If expectedVersion is not 0
+// the log is only deleted if it is still at that version.
+func (r *CommLogRepo) DeleteCommLog(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.commLogs[id]
+	if !ok || existing.DeletedAt != nil {
+		return errCommLogNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.DeletedAt = timePtr(r.s.now())
+	r.s.commLogs[id] = existing
+	return nil
+}
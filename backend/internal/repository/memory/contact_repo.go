@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// ContactRepo is an in-memory postgres.ContactRepository.
+type ContactRepo struct {
+	s *Store
+}
+
+// NewContactRepo creates a new ContactRepo backed by s.
+func NewContactRepo(s *Store) *ContactRepo {
+	return &ContactRepo{s: s}
+}
+
+// duplicateContact reports the unique constraint c would violate, ignoring the
+// row with c's own ID. The caller must hold the lock.
+func (r *ContactRepo) duplicateContact(c models.Contact) error {
+	for _, other := range r.s.contacts {
+		if other.ID == c.ID {
+			continue
+		}
+		if other.PrimaryPhone == c.PrimaryPhone {
+			return uniqueViolation("contacts", "contacts_primary_phone_key")
+		}
+		if c.Email != nil && other.Email != nil && *other.Email == *c.Email {
+			return uniqueViolation("contacts", "contacts_email_key")
+		}
+	}
+	return nil
+}
+
+// Create inserts a new contact and returns its ID.
+func (r *ContactRepo) Create(ctx context.Context, c models.Contact) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.duplicateContact(c); err != nil {
+		return 0, err
+	}
+	c.ID = r.s.nextID("contacts")
+	c.CreatedAt = r.s.now()
+	c.UpdatedAt = timePtr(c.CreatedAt)
+	c.Version = 1
+	r.s.contacts[c.ID] = c
+	return c.ID, nil
+}
+
+// GetAll retrieves all contacts, newest first.
+func (r *ContactRepo) GetAll(ctx context.Context) ([]models.Contact, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.contacts, nil, func(a, b models.Contact) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	}), nil
+}
+
+// GetAllForUser retrieves the contacts created by a user, newest first.
+func (r *ContactRepo) GetAllForUser(ctx context.Context, userID int) ([]models.Contact, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.contacts, func(c models.Contact) bool {
+		return c.CreatedBy != nil && *c.CreatedBy == userID
+	}, func(a, b models.Contact) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	}), nil
+}
+
+// GetByID retrieves a single contact. It returns nil, nil if it does not exist.
+func (r *ContactRepo) GetByID(ctx context.Context, id int) (*models.Contact, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.contacts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// Update modifies an existing contact. The creator is never changed here; use
+// UpdateCreatedBy for that.
+func (r *ContactRepo) Update(ctx context.Context, contact models.Contact) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.contacts[contact.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if contact.Version != 0 && contact.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	if err := r.duplicateContact(contact); err != nil {
+		return err
+	}
+	contact.CreatedAt = existing.CreatedAt
+	contact.CreatedBy = existing.CreatedBy
+	contact.UpdatedAt = timePtr(r.s.now())
+	contact.Version = existing.Version + 1
+	r.s.contacts[contact.ID] = contact
+	return nil
+}
+
+// Delete removes a contact. If expectedVersion is not 0 the contact is only
+// deleted if it is still at that version.
+func (r *ContactRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.contacts[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	for _, l := range r.s.leads {
+		if l.ContactID == id {
+			return foreignKeyViolation("contacts", "fk_contact", "leads")
+		}
+	}
+	delete(r.s.contacts, id)
+	return nil
+}
+
+// UpdateCreatedBy updates the created_by field of a contact.
+func (r *ContactRepo) UpdateCreatedBy(ctx context.Context, contactID int, createdBy int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.contacts[contactID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	c.CreatedBy = intPtr(createdBy)
+	r.s.contacts[contactID] = c
+	return nil
+}
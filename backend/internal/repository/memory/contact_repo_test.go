@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+func TestContactRepoVersion(t *testing.T) {
+	ctx := context.Background()
+	r := NewContactRepo(NewStore())
+	id, err := r.Create(ctx, models.Contact{FirstName: "Ada", LastName: "Lovelace", PrimaryPhone: "+100"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	c, err := r.GetByID(ctx, id)
+	if err != nil || c == nil {
+		t.Fatalf("GetByID: %v, %v", c, err)
+	}
+
+	c.FirstName = "Augusta"
+	if err := r.Update(ctx, *c); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := r.Update(ctx, *c); !errors.Is(err, postgres.ErrVersionConflict) {
+		t.Fatalf("Update with a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if err := r.Delete(ctx, id, c.Version); !errors.Is(err, postgres.ErrVersionConflict) {
+		t.Fatalf("Delete with a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if err := r.Delete(ctx, id, c.Version+1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if got, err := r.GetByID(ctx, id); got != nil || err != nil {
+		t.Errorf("GetByID of a deleted contact: got %v, %v, want nil, nil", got, err)
+	}
+	if err := r.Update(ctx, *c); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update of a deleted contact: got %v, want sql.ErrNoRows", err)
+	}
+}
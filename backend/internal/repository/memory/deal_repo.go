@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// DealRepo is an in-memory postgres.DealRepository.
+type DealRepo struct {
+	s *Store
+}
+
+// NewDealRepo creates a new DealRepo backed by s.
+func NewDealRepo(s *Store) *DealRepo {
+	return &DealRepo{s: s}
+}
+
+func dealsByID(a, b models.Deal) bool {
+	return a.ID < b.ID
+}
+
+// Create inserts a new deal and returns its ID.
+func (r *DealRepo) Create(ctx context.Context, d models.Deal) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	d.ID = r.s.nextID("deals")
+	if d.DealStatus == "" {
+		d.DealStatus = "Pending"
+	}
+	d.CreatedAt = r.s.now()
+	d.UpdatedAt = d.CreatedAt
+	d.DealDate = d.CreatedAt
+	d.Version = 1
+	r.s.deals[d.ID] = d
+	return d.ID, nil
+}
+
+// GetAll retrieves all deals.
+func (r *DealRepo) GetAll(ctx context.Context) ([]models.Deal, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.deals, nil, dealsByID), nil
+}
+
+// GetAllForUser retrieves the deals created by a user.
+func (r *DealRepo) GetAllForUser(ctx context.Context, userID int) ([]models.Deal, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.deals, func(d models.Deal) bool {
+		return d.CreatedBy.Valid && d.CreatedBy.Int64 == int64(userID)
+	}, dealsByID), nil
+}
+
+// GetByID retrieves a single deal. It returns nil, nil if it does not exist.
+func (r *DealRepo) GetByID(ctx context.Context, id int) (*models.Deal, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	d, ok := r.s.deals[id]
+	if !ok {
+		return nil, nil
+	}
+	return &d, nil
+}
+
+// Update modifies an existing deal.
+func (r *DealRepo) Update(ctx context.Context, d models.Deal) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.deals[d.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if d.Version != 0 && d.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	d.CreatedAt = existing.CreatedAt
+	d.CreatedBy = existing.CreatedBy
+	d.DealDate = existing.DealDate
+	d.UpdatedAt = r.s.now()
+	d.Version = existing.Version + 1
+	r.s.deals[d.ID] = d
+	return nil
+}
+
+// Delete removes a deal. If expectedVersion is not 0 the deal is only deleted
+// if it is still at that version.
+func (r *DealRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.deals[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	delete(r.s.deals, id)
+	return nil
+}
+
+// salesRow accumulates one group of a sales report.
+type salesRow struct {
+	count int
+	total float64
+}
+
+// groupDeals groups the deals accepted by keep under the name returned by key,
+// skipping deals for which key reports false. The caller must hold the lock.
+func (r *DealRepo) groupDeals(keep func(models.Deal, models.Lead) bool, key func(models.Deal, models.Lead) (string, bool)) map[string]*salesRow {
+	groups := make(map[string]*salesRow)
+	for _, d := range r.s.deals {
+		l, ok := r.s.leads[d.LeadID]
+		if !ok {
+			continue
+		}
+		if keep != nil && !keep(d, l) {
+			continue
+		}
+		name, ok := key(d, l)
+		if !ok {
+			continue
+		}
+		g := groups[name]
+		if g == nil {
+			g = &salesRow{}
+			groups[name] = g
+		}
+		g.count++
+		g.total += d.DealAmount
+	}
+	return groups
+}
+
+// employeeSales builds an employee sales report from the deals accepted by
+// keep, largest total first. The caller must hold the lock.
+func (r *DealRepo) employeeSales(keep func(models.Deal, models.Lead) bool) []postgres.EmployeeSalesReportRow {
+	groups := r.groupDeals(keep, func(d models.Deal, l models.Lead) (string, bool) {
+		u, ok := r.s.users[l.AssignedTo]
+		return u.Username, ok
+	})
+	var rows []postgres.EmployeeSalesReportRow
+	for name, g := range groups {
+		rows = append(rows, postgres.EmployeeSalesReportRow{EmployeeName: name, NumberOfSales: g.count, TotalSalesAmount: g.total})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].TotalSalesAmount > rows[j].TotalSalesAmount })
+	return rows
+}
+
+// GetEmployeeSalesReport aggregates sales data per employee.
+func (r *DealRepo) GetEmployeeSalesReport(ctx context.Context) ([]postgres.EmployeeSalesReportRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.employeeSales(nil), nil
+}
+
+// GetEmployeeSalesReportForUser aggregates the Closed-Won deals of a single
+// employee.
+func (r *DealRepo) GetEmployeeSalesReportForUser(ctx context.Context, userID int) ([]postgres.EmployeeSalesReportRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.employeeSales(func(d models.Deal, l models.Lead) bool {
+		return d.DealStatus == "Closed-Won" && l.AssignedTo == userID
+	}), nil
+}
+
+// GetSourceSalesReport aggregates sales data per lead source.
+func (r *DealRepo) GetSourceSalesReport(ctx context.Context) ([]postgres.SourceSalesReportRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	groups := r.groupDeals(nil, func(d models.Deal, l models.Lead) (string, bool) {
+		name, ok := r.s.leadSources[l.SourceID]
+		return name, ok
+	})
+	var rows []postgres.SourceSalesReportRow
+	for name, g := range groups {
+		rows = append(rows, postgres.SourceSalesReportRow{SourceName: name, NumberOfSales: g.count, TotalSalesAmount: g.total})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].TotalSalesAmount > rows[j].TotalSalesAmount })
+	return rows, nil
+}
+
+// GetDealsPipelineReport aggregates deal data by stage, in stage order.
+func (r *DealRepo) GetDealsPipelineReport(ctx context.Context) ([]postgres.DealsPipelineReportRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	byStage := make(map[int]*salesRow)
+	for _, d := range r.s.deals {
+		if _, ok := r.s.dealStages[d.StageID]; !ok {
+			continue
+		}
+		g := byStage[d.StageID]
+		if g == nil {
+			g = &salesRow{}
+			byStage[d.StageID] = g
+		}
+		g.count++
+		g.total += d.DealAmount
+	}
+	stageIDs := make([]int, 0, len(byStage))
+	for id := range byStage {
+		stageIDs = append(stageIDs, id)
+	}
+	sort.Ints(stageIDs)
+	var rows []postgres.DealsPipelineReportRow
+	for _, id := range stageIDs {
+		g := byStage[id]
+		rows = append(rows, postgres.DealsPipelineReportRow{StageName: r.s.dealStages[id], DealCount: g.count, TotalAmount: g.total})
+	}
+	return rows, nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// EventRepo is an in-memory postgres.EventRepository.
+type EventRepo struct {
+	s *Store
+}
+
+// NewEventRepository creates a new EventRepo backed by s.
+func NewEventRepository(s *Store) *EventRepo {
+	return &EventRepo{s: s}
+}
+
+func errEventNotFound() error {
+	return fmt.Errorf("event %w", postgres.ErrNotFound)
+}
+
+// liveEvents returns the events that are not soft deleted and are accepted by
+// keep, earliest start first. The caller must hold the lock.
+func (r *EventRepo) liveEvents(keep func(models.Event) bool) []models.Event {
+	return sortedValues(r.s.events, func(e models.Event) bool {
+		return e.DeletedAt == nil && (keep == nil || keep(e))
+	}, func(a, b models.Event) bool {
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.Before(b.StartTime)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// CreateEvent creates a new event
+func (r *EventRepo) CreateEvent(ctx context.Context, event *models.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	event.ID = r.s.nextID("events")
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+	}
+	event.UpdatedAt = timePtr(now)
+	event.DeletedAt = nil
+	event.Version = 1
+	r.s.events[event.ID] = *event
+	return nil
+}
+
+// GetEventByID retrieves an event by ID
+func (r *EventRepo) GetEventByID(ctx context.Context, id int) (*models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	e, ok := r.s.events[id]
+	if !ok || e.DeletedAt != nil {
+		return nil, errEventNotFound()
+	}
+	return &e, nil
+}
+
+// GetEventsByDealID retrieves all events for a deal
+func (r *EventRepo) GetEventsByDealID(ctx context.Context, dealID int) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveEvents(func(e models.Event) bool {
+		return e.DealID != nil && *e.DealID == dealID
+	}), nil
+}
+
+// GetEventsByLeadID retrieves all events for a lead
+func (r *EventRepo) GetEventsByLeadID(ctx context.Context, leadID int) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveEvents(func(e models.Event) bool {
+		return e.LeadID != nil && *e.LeadID == leadID
+	}), nil
+}
+
+// GetAllEvents retrieves all events
+func (r *EventRepo) GetAllEvents(ctx context.Context) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveEvents(nil), nil
+}
+
+// GetEventsForUser retrieves the events organized by a user
+func (r *EventRepo) GetEventsForUser(ctx context.Context, userID int) ([]models.Event, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveEvents(func(e models.Event) bool {
+		return e.OrganizerID == userID
+	}), nil
+}
+
+// UpdateEvent updates an existing event
+func (r *EventRepo) UpdateEvent(ctx context.Context, event *models.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.events[event.ID]
+	if !ok || existing.DeletedAt != nil {
+		return errEventNotFound()
+	}
+	if event.Version != 0 && event.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	updated := existing
+	updated.EventName = event.EventName
+	updated.EventDescription = event.EventDescription
+	updated.StartTime = event.StartTime
+	updated.EndTime = event.EndTime
+	updated.Location = event.Location
+	updated.OrganizerID = event.OrganizerID
+	updated.LeadID = event.LeadID
+	updated.DealID = event.DealID
+	updated.UpdatedAt = timePtr(r.s.now())
+	updated.Version = existing.Version + 1
+	r.s.events[event.ID] = updated
+	event.UpdatedAt = updated.UpdatedAt
+	event.Version = updated.Version
+	return nil
+}
+
+// DeleteEvent soft deletes an event. If expectedVersion is not 0 the event is
+// only deleted if it is still at that version.
+func (r *EventRepo) DeleteEvent(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.events[id]
+	if !ok || existing.DeletedAt != nil {
+		return errEventNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.DeletedAt = timePtr(r.s.now())
+	r.s.events[id] = existing
+	return nil
+}
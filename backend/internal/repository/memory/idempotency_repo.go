@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+
+	"crm-project/internal/models"
+)
+
+// idempotencyKey is the primary key of the idempotency_keys table.
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+// IdempotencyRepo is an in-memory postgres.IdempotencyRepository.
+type IdempotencyRepo struct {
+	s *Store
+}
+
+// NewIdempotencyRepo creates a new IdempotencyRepo backed by s.
+func NewIdempotencyRepo(s *Store) *IdempotencyRepo {
+	return &IdempotencyRepo{s: s}
+}
+
+// Reserve claims a key for an in-progress request. An expired record with the
+// same key is replaced. It returns false if a live record already exists.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, rec models.IdempotencyRecord) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	k := idempotencyKey{userID: rec.UserID, key: rec.Key}
+	now := r.s.now()
+	if existing, ok := r.s.idempotency[k]; ok && existing.ExpiresAt.After(now) {
+		return false, nil
+	}
+	r.s.idempotency[k] = models.IdempotencyRecord{
+		UserID:      rec.UserID,
+		Key:         rec.Key,
+		Method:      rec.Method,
+		Path:        rec.Path,
+		RequestHash: rec.RequestHash,
+		CreatedAt:   now,
+		ExpiresAt:   rec.ExpiresAt,
+	}
+	return true, nil
+}
+
+// Get returns the record for a key, or nil if there is none.
+func (r *IdempotencyRepo) Get(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rec, ok := r.s.idempotency[idempotencyKey{userID: userID, key: key}]
+	if !ok {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// Complete stores the response of the request that reserved a key.
+func (r *IdempotencyRepo) Complete(ctx context.Context, userID int, key string, statusCode int, headers models.StoredHeaders, body []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	k := idempotencyKey{userID: userID, key: key}
+	rec, ok := r.s.idempotency[k]
+	if !ok {
+		return nil
+	}
+	rec.StatusCode = intPtr(statusCode)
+	rec.ResponseHeaders = headers
+	rec.ResponseBody = append([]byte(nil), body...)
+	r.s.idempotency[k] = rec
+	return nil
+}
+
+// Release removes an in-progress reservation so the request can be retried.
+func (r *IdempotencyRepo) Release(ctx context.Context, userID int, key string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	k := idempotencyKey{userID: userID, key: key}
+	if rec, ok := r.s.idempotency[k]; ok && rec.StatusCode == nil {
+		delete(r.s.idempotency, k)
+	}
+	return nil
+}
+
+// DeleteExpired removes all expired records and returns how many were removed.
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	var n int64
+	for k, rec := range r.s.idempotency {
+		if !rec.ExpiresAt.After(now) {
+			delete(r.s.idempotency, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// InvitationRepo is an in-memory postgres.InvitationRepository.
+type InvitationRepo struct {
+	s *Store
+}
+
+// NewInvitationRepo creates a new InvitationRepo backed by s.
+func NewInvitationRepo(s *Store) *InvitationRepo {
+	return &InvitationRepo{s: s}
+}
+
+// Create inserts a new invitation and returns its ID.
+func (r *InvitationRepo) Create(ctx context.Context, inv models.Invitation) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inv.ID = r.s.nextID("invitations")
+	inv.UsedAt, inv.UsedBy, inv.RevokedAt = nil, nil, nil
+	inv.CreatedAt = r.s.now()
+	r.s.invitations[inv.ID] = inv
+	return inv.ID, nil
+}
+
+// GetByID retrieves a single invitation. It returns nil, nil if it does not exist.
+func (r *InvitationRepo) GetByID(ctx context.Context, id int) (*models.Invitation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	inv, ok := r.s.invitations[id]
+	if !ok {
+		return nil, nil
+	}
+	return &inv, nil
+}
+
+// GetAll retrieves all invitations, newest first.
+func (r *InvitationRepo) GetAll(ctx context.Context) ([]models.Invitation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.invitations, nil, func(a, b models.Invitation) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	}), nil
+}
+
+// Revoke marks an unused invitation as revoked.
+func (r *InvitationRepo) Revoke(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inv, ok := r.s.invitations[id]
+	if !ok || inv.UsedAt != nil || inv.RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	inv.RevokedAt = timePtr(r.s.now())
+	r.s.invitations[id] = inv
+	return nil
+}
+
+// Redeem creates the user and marks the invitation as used. It returns
+// postgres.ErrInvitationUnavailable if the invitation is missing, used,
+// revoked or expired.
+func (r *InvitationRepo) Redeem(ctx context.Context, invitationID int, user models.User) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inv, ok := r.s.invitations[invitationID]
+	now := r.s.now()
+	if !ok || inv.UsedAt != nil || inv.RevokedAt != nil || !inv.ExpiresAt.After(now) {
+		return 0, postgres.ErrInvitationUnavailable
+	}
+	newUserID, err := r.s.insertUser(user)
+	if err != nil {
+		return 0, err
+	}
+	inv.UsedAt = timePtr(now)
+	inv.UsedBy = intPtr(newUserID)
+	r.s.invitations[invitationID] = inv
+	return newUserID, nil
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// LeadRepo is an in-memory postgres.LeadRepository.
+type LeadRepo struct {
+	s *Store
+}
+
+// NewLeadRepo creates a new LeadRepo backed by s.
+func NewLeadRepo(s *Store) *LeadRepo {
+	return &LeadRepo{s: s}
+}
+
+// Create inserts a new lead and returns its ID.
+func (r *LeadRepo) Create(ctx context.Context, l models.Lead) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	l.ID = r.s.nextID("leads")
+	l.CreatedAt = r.s.now()
+	l.UpdatedAt = l.CreatedAt
+	l.Version = 1
+	r.s.leads[l.ID] = l
+	return l.ID, nil
+}
+
+// GetAll retrieves all leads, newest first.
+func (r *LeadRepo) GetAll(ctx context.Context) ([]models.Lead, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.leads, nil, leadsNewestFirst), nil
+}
+
+// GetAllLeadsForUser retrieves the leads assigned to a user, newest first.
+func (r *LeadRepo) GetAllLeadsForUser(ctx context.Context, userID int) ([]models.Lead, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.leads, func(l models.Lead) bool {
+		return l.AssignedTo == userID
+	}, leadsNewestFirst), nil
+}
+
+func leadsNewestFirst(a, b models.Lead) bool {
+	return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+}
+
+// GetByID retrieves a single lead. It returns nil, nil if it does not exist.
+func (r *LeadRepo) GetByID(ctx context.Context, id int) (*models.Lead, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	l, ok := r.s.leads[id]
+	if !ok {
+		return nil, nil
+	}
+	return &l, nil
+}
+
+// Update modifies an existing lead.
+func (r *LeadRepo) Update(ctx context.Context, l models.Lead) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.leads[l.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if l.Version != 0 && l.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	l.CreatedAt = existing.CreatedAt
+	l.UpdatedAt = r.s.now()
+	l.Version = existing.Version + 1
+	r.s.leads[l.ID] = l
+	return nil
+}
+
+// Delete removes a lead. If expectedVersion is not 0 the lead is only deleted
+// if it is still at that version.
+func (r *LeadRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.leads[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	for _, d := range r.s.deals {
+		if d.LeadID == id {
+			return foreignKeyViolation("leads", "fk_lead", "deals")
+		}
+	}
+	delete(r.s.leads, id)
+	return nil
+}
+
+// isOpen reports whether a lead's status is neither Converted nor Lost. The
+// caller must hold the lock.
+func (r *LeadRepo) isOpen(l models.Lead) bool {
+	status := r.s.leadStatuses[l.StatusID]
+	return status != "Converted" && status != "Lost"
+}
+
+// CheckForOpenLeadByContactID reports whether a contact has a lead that is
+// neither Converted nor Lost.
+func (r *LeadRepo) CheckForOpenLeadByContactID(ctx context.Context, contactID int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, l := range r.s.leads {
+		if l.ContactID == contactID && r.isOpen(l) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetLeadCountsByUserID counts a user's leads by status.
+func (r *LeadRepo) GetLeadCountsByUserID(ctx context.Context, userID int) (*postgres.LeadStatusCounts, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var counts postgres.LeadStatusCounts
+	for _, l := range r.s.leads {
+		if l.AssignedTo != userID {
+			continue
+		}
+		switch r.s.leadStatuses[l.StatusID] {
+		case "New":
+			counts.New++
+		case "Contacted":
+			counts.Contacted++
+		case "Qualified":
+			counts.Qualified++
+		case "Converted":
+			counts.Converted++
+		case "Lost":
+			counts.Lost++
+		}
+	}
+	return &counts, nil
+}
+
+// GetSourceLeadReport lists leads with their contact, source, assignee and
+// status, newest first. Like the SQL join, leads missing any of these are left
+// out.
+func (r *LeadRepo) GetSourceLeadReport(ctx context.Context) ([]postgres.SourceLeadReportRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var rows []postgres.SourceLeadReportRow
+	for _, l := range sortedValues(r.s.leads, nil, leadsNewestFirst) {
+		contact, ok := r.s.contacts[l.ContactID]
+		if !ok {
+			continue
+		}
+		source, ok := r.s.leadSources[l.SourceID]
+		if !ok {
+			continue
+		}
+		user, ok := r.s.users[l.AssignedTo]
+		if !ok {
+			continue
+		}
+		status, ok := r.s.leadStatuses[l.StatusID]
+		if !ok {
+			continue
+		}
+		rows = append(rows, postgres.SourceLeadReportRow{
+			LeadDate:         l.CreatedAt,
+			ContactName:      contact.FirstName + " " + contact.LastName,
+			ContactPhone:     contact.PrimaryPhone,
+			ContactEmail:     contact.Email,
+			LeadSource:       source,
+			AssignedEmployee: user.Username,
+			LeadStatus:       status,
+		})
+	}
+	return rows, nil
+}
+
+// GetOpenLeadCountsByStatus counts the leads in every status except Converted
+// and Lost, in status ID order, including statuses without leads.
+func (r *LeadRepo) GetOpenLeadCountsByStatus(ctx context.Context) ([]postgres.LeadStatusCountRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := make(map[int]int)
+	for _, l := range r.s.leads {
+		counts[l.StatusID]++
+	}
+	var rows []postgres.LeadStatusCountRow
+	for _, id := range slices.Sorted(maps.Keys(r.s.leadStatuses)) {
+		name := r.s.leadStatuses[id]
+		if name == "Converted" || name == "Lost" {
+			continue
+		}
+		rows = append(rows, postgres.LeadStatusCountRow{Status: name, Count: counts[id]})
+	}
+	return rows, nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// NoteRepo is an in-memory postgres.NoteRepository.
+type NoteRepo struct {
+	s *Store
+}
+
+// NewNoteRepository creates a new NoteRepo backed by s.
+func NewNoteRepository(s *Store) *NoteRepo {
+	return &NoteRepo{s: s}
+}
+
+func errNoteNotFound() error {
+	return fmt.Errorf("note %w", postgres.ErrNotFound)
+}
+
+// liveNotes returns the notes that are not soft deleted and are accepted by
+// keep, newest first. The caller must hold the lock.
+func (r *NoteRepo) liveNotes(keep func(models.Note) bool) []models.Note {
+	return sortedValues(r.s.notes, func(n models.Note) bool {
+		return n.DeletedAt == nil && keep(n)
+	}, func(a, b models.Note) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+}
+
+// CreateNote creates a new note
+func (r *NoteRepo) CreateNote(ctx context.Context, note *models.Note) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	note.ID = r.s.nextID("notes")
+	note.CreatedAt = now
+	note.UpdatedAt = timePtr(now)
+	note.DeletedAt = nil
+	note.Version = 1
+	r.s.notes[note.ID] = *note
+	return nil
+}
+
+// GetNoteByID retrieves a note by its ID
+func (r *NoteRepo) GetNoteByID(ctx context.Context, id int) (*models.Note, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	n, ok := r.s.notes[id]
+	if !ok || n.DeletedAt != nil {
+		return nil, errNoteNotFound()
+	}
+	return &n, nil
+}
+
+// GetNotesByContactID retrieves all notes for a contact
+func (r *NoteRepo) GetNotesByContactID(ctx context.Context, contactID int) ([]models.Note, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveNotes(func(n models.Note) bool {
+		return n.ContactID != nil && *n.ContactID == contactID
+	}), nil
+}
+
+// GetNotesByUserID retrieves all notes written by a user
+func (r *NoteRepo) GetNotesByUserID(ctx context.Context, userID int) ([]models.Note, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveNotes(func(n models.Note) bool {
+		return n.UserID == userID
+	}), nil
+}
+
+// GetNotesByDealID retrieves all notes for a deal
+func (r *NoteRepo) GetNotesByDealID(ctx context.Context, dealID int) ([]models.Note, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveNotes(func(n models.Note) bool {
+		return n.DealID != nil && *n.DealID == dealID
+	}), nil
+}
+
+// GetNotesByLeadID retrieves all notes for a lead
+func (r *NoteRepo) GetNotesByLeadID(ctx context.Context, leadID int) ([]models.Note, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveNotes(func(n models.Note) bool {
+		return n.LeadID != nil && *n.LeadID == leadID
+	}), nil
+}
+
+// UpdateNote updates the content of an existing note
+func (r *NoteRepo) UpdateNote(ctx context.Context, note *models.Note) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.notes[note.ID]
+	if !ok || existing.DeletedAt != nil {
+		return errNoteNotFound()
+	}
+	if note.Version != 0 && note.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.Content = note.Content
+	existing.UpdatedAt = timePtr(r.s.now())
+	existing.Version++
+	r.s.notes[note.ID] = existing
+	note.UpdatedAt = existing.UpdatedAt
+	note.Version = existing.Version
+	return nil
+}
+
+// DeleteNote soft deletes a note. If expectedVersion is not 0 the note is only
+// deleted if it is still at that version.
+func (r *NoteRepo) DeleteNote(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.notes[id]
+	if !ok || existing.DeletedAt != nil {
+		return errNoteNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.DeletedAt = timePtr(r.s.now())
+	r.s.notes[id] = existing
+	return nil
+}
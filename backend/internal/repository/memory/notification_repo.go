@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"crm-project/internal/models"
+)
+
+// NotificationRepo is an in-memory postgres.NotificationRepository.
+type NotificationRepo struct {
+	s *Store
+}
+
+// NewNotificationRepo creates a new NotificationRepo backed by s.
+func NewNotificationRepo(s *Store) *NotificationRepo {
+	return &NotificationRepo{s: s}
+}
+
+// Create inserts a notification and fills in its ID and creation time. If
+// n.DedupKey is set and a notification with that key exists it returns false
+// and leaves n untouched.
+func (r *NotificationRepo) Create(ctx context.Context, n *models.Notification) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if n.DedupKey != nil {
+		for _, other := range r.s.notifications {
+			if other.DedupKey != nil && *other.DedupKey == *n.DedupKey {
+				return false, nil
+			}
+		}
+	}
+	n.ID = r.s.nextID("notifications")
+	n.ReadAt = nil
+	n.CreatedAt = r.s.now()
+	r.s.notifications[n.ID] = *n
+	return true, nil
+}
+
+// GetAllForUser returns a user's most recent notifications, newest first.
+func (r *NotificationRepo) GetAllForUser(ctx context.Context, userID int, unreadOnly bool, limit int) ([]models.Notification, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := sortedValues(r.s.notifications, func(n models.Notification) bool {
+		return n.UserID == userID && (!unreadOnly || n.ReadAt == nil)
+	}, func(a, b models.Notification) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// MarkRead marks one of a user's notifications as read. Marking it again is
+// not an error. It returns sql.ErrNoRows if the user has no such notification.
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n, ok := r.s.notifications[id]
+	if !ok || n.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if n.ReadAt == nil {
+		n.ReadAt = timePtr(r.s.now())
+		r.s.notifications[id] = n
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user as read and returns
+// how many there were.
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	var marked int64
+	for id, n := range r.s.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = timePtr(now)
+			r.s.notifications[id] = n
+			marked++
+		}
+	}
+	return marked, nil
+}
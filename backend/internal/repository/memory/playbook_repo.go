@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// playbookRunKey identifies the deal or lead a playbook has run for; one of
+// dealID and leadID is 0.
+type playbookRunKey struct {
+	playbookID int
+	dealID     int
+	leadID     int
+}
+
+// PlaybookRepo is an in-memory postgres.PlaybookRepository.
+type PlaybookRepo struct {
+	s *Store
+}
+
+// NewPlaybookRepo creates a new PlaybookRepo backed by s.
+func NewPlaybookRepo(s *Store) *PlaybookRepo {
+	return &PlaybookRepo{s: s}
+}
+
+func errPlaybookNotFound() error {
+	return fmt.Errorf("playbook %w", postgres.ErrNotFound)
+}
+
+// Create inserts a playbook with its task templates and fills in their IDs,
+// timestamps and version.
+func (r *PlaybookRepo) Create(ctx context.Context, p *models.Playbook) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.checkTrigger(p); err != nil {
+		return err
+	}
+	now := r.s.now()
+	p.ID = r.s.nextID("playbooks")
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	p.Version = 1
+	r.setTasks(p)
+	r.s.playbooks[p.ID] = clonePlaybook(*p)
+	return nil
+}
+
+// GetByID returns a playbook with its task templates, or nil if there is
+// none.
+func (r *PlaybookRepo) GetByID(ctx context.Context, id int) (*models.Playbook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.playbooks[id]
+	if !ok {
+		return nil, nil
+	}
+	p = clonePlaybook(p)
+	return &p, nil
+}
+
+// GetAll returns every playbook with its task templates, ordered by name.
+func (r *PlaybookRepo) GetAll(ctx context.Context) ([]models.Playbook, error) {
+	return r.selectPlaybooks(nil), nil
+}
+
+// GetActiveForDealStage returns the active playbooks triggered by a deal
+// entering a stage.
+func (r *PlaybookRepo) GetActiveForDealStage(ctx context.Context, stageID int) ([]models.Playbook, error) {
+	return r.selectPlaybooks(func(p models.Playbook) bool {
+		return p.Active && p.DealStageID != nil && *p.DealStageID == stageID
+	}), nil
+}
+
+// GetActiveForLeadStatus returns the active playbooks triggered by a lead
+// reaching a status.
+func (r *PlaybookRepo) GetActiveForLeadStatus(ctx context.Context, statusID int) ([]models.Playbook, error) {
+	return r.selectPlaybooks(func(p models.Playbook) bool {
+		return p.Active && p.LeadStatusID != nil && *p.LeadStatusID == statusID
+	}), nil
+}
+
+// Update saves a playbook and replaces its task templates. If p.Version is
+// not 0 the playbook is only updated if it is still at that version.
+func (r *PlaybookRepo) Update(ctx context.Context, p *models.Playbook) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.playbooks[p.ID]
+	if !ok {
+		return errPlaybookNotFound()
+	}
+	if p.Version != 0 && p.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	if err := r.checkTrigger(p); err != nil {
+		return err
+	}
+	p.CreatedBy = existing.CreatedBy
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = r.s.now()
+	p.Version = existing.Version + 1
+	r.setTasks(p)
+	r.s.playbooks[p.ID] = clonePlaybook(*p)
+	return nil
+}
+
+// Delete removes a playbook. Tasks it created are kept. If expectedVersion
+// is not 0 the playbook is only deleted if it is still at that version.
+func (r *PlaybookRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p, ok := r.s.playbooks[id]
+	if !ok {
+		return errPlaybookNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != p.Version {
+		return postgres.ErrVersionConflict
+	}
+	delete(r.s.playbooks, id)
+	for key := range r.s.playbookRuns {
+		if key.playbookID == id {
+			delete(r.s.playbookRuns, key)
+		}
+	}
+	return nil
+}
+
+// StartRun records that a playbook runs for a deal or a lead (exactly one of
+// dealID and leadID is set). It returns false if it has run for it before.
+func (r *PlaybookRepo) StartRun(ctx context.Context, playbookID int, dealID, leadID *int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := playbookRunKey{playbookID: playbookID}
+	if dealID != nil {
+		key.dealID = *dealID
+	} else if leadID != nil {
+		key.leadID = *leadID
+	}
+	if _, ok := r.s.playbookRuns[key]; ok {
+		return false, nil
+	}
+	r.s.playbookRuns[key] = r.s.now()
+	return true, nil
+}
+
+// checkTrigger reports a foreign key violation for a deal stage or lead
+// status that does not exist. The caller must hold the lock.
+func (r *PlaybookRepo) checkTrigger(p *models.Playbook) error {
+	if p.DealStageID != nil {
+		if _, ok := r.s.dealStages[*p.DealStageID]; !ok {
+			return foreignKeyViolation("deal_stages", "playbooks_deal_stage_id_fkey", "playbooks")
+		}
+	}
+	if p.LeadStatusID != nil {
+		if _, ok := r.s.leadStatuses[*p.LeadStatusID]; !ok {
+			return foreignKeyViolation("lead_statuses", "playbooks_lead_status_id_fkey", "playbooks")
+		}
+	}
+	return nil
+}
+
+// setTasks assigns IDs to the task templates of p. The caller must hold the
+// write lock.
+func (r *PlaybookRepo) setTasks(p *models.Playbook) {
+	for i := range p.Tasks {
+		p.Tasks[i].ID = r.s.nextID("playbook_tasks")
+		p.Tasks[i].PlaybookID = p.ID
+	}
+	if p.Tasks == nil {
+		p.Tasks = []models.PlaybookTask{}
+	}
+}
+
+func (r *PlaybookRepo) selectPlaybooks(keep func(models.Playbook) bool) []models.Playbook {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := sortedValues(r.s.playbooks, keep, func(a, b models.Playbook) bool {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c < 0
+		}
+		return a.ID < b.ID
+	})
+	for i := range out {
+		out[i] = clonePlaybook(out[i])
+	}
+	return out
+}
+
+// clonePlaybook copies p so the stored task templates are not shared with
+// callers. Templates are ordered by position.
+func clonePlaybook(p models.Playbook) models.Playbook {
+	p.Tasks = slices.Clone(p.Tasks)
+	slices.SortStableFunc(p.Tasks, func(a, b models.PlaybookTask) int {
+		if a.Position != b.Position {
+			return a.Position - b.Position
+		}
+		return a.ID - b.ID
+	})
+	return p
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// PropertyRepo is an in-memory postgres.PropertyRepository.
+type PropertyRepo struct {
+	s *Store
+}
+
+// NewPropertyRepo creates a new PropertyRepo backed by s.
+func NewPropertyRepo(s *Store) *PropertyRepo {
+	return &PropertyRepo{s: s}
+}
+
+// Create inserts a new property and returns its ID.
+func (r *PropertyRepo) Create(ctx context.Context, p models.Property) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p.ID = r.s.nextID("properties")
+	p.CreatedAt = r.s.now()
+	p.UpdatedAt = p.CreatedAt
+	p.Version = 1
+	r.s.properties[p.ID] = p
+	return p.ID, nil
+}
+
+// GetAll retrieves all properties, newest first.
+func (r *PropertyRepo) GetAll(ctx context.Context) ([]models.Property, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.properties, nil, func(a, b models.Property) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	}), nil
+}
+
+// GetByID retrieves a single property. It returns nil, nil if it does not exist.
+func (r *PropertyRepo) GetByID(ctx context.Context, id int) (*models.Property, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.properties[id]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// Update modifies an existing property.
+func (r *PropertyRepo) Update(ctx context.Context, p models.Property) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.properties[p.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if p.Version != 0 && p.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = r.s.now()
+	p.Version = existing.Version + 1
+	r.s.properties[p.ID] = p
+	return nil
+}
+
+// Delete removes a property. If expectedVersion is not 0 the property is only
+// deleted if it is still at that version.
+func (r *PropertyRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.properties[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	for _, l := range r.s.leads {
+		if l.PropertyID != nil && *l.PropertyID == id {
+			return foreignKeyViolation("properties", "fk_property", "leads")
+		}
+	}
+	for _, d := range r.s.deals {
+		if d.PropertyID == id {
+			return foreignKeyViolation("properties", "fk_deal_property", "deals")
+		}
+	}
+	delete(r.s.properties, id)
+	return nil
+}
+
+// SiteExists checks if a site with the given ID exists.
+func (r *PropertyRepo) SiteExists(ctx context.Context, siteID int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	_, ok := r.s.sites[siteID]
+	return ok, nil
+}
+
+// PropertyTypeExists checks if a property type with the given ID exists.
+func (r *PropertyRepo) PropertyTypeExists(ctx context.Context, propertyTypeID int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	_, ok := r.s.propertyTypes[propertyTypeID]
+	return ok, nil
+}
+
+// IsPropertyInOpenLeadOrDeal checks if a property is already part of an active
+// sales process.
+func (r *PropertyRepo) IsPropertyInOpenLeadOrDeal(ctx context.Context, propertyID int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, l := range r.s.leads {
+		if l.PropertyID == nil || *l.PropertyID != propertyID {
+			continue
+		}
+		status, ok := r.s.leadStatuses[l.StatusID]
+		if ok && status != "Converted" && status != "Lost" {
+			return true, nil
+		}
+	}
+	for _, d := range r.s.deals {
+		if d.PropertyID == propertyID && d.DealStatus != "Closed-Won" && d.DealStatus != "Closed-Lost" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
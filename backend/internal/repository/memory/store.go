@@ -0,0 +1,238 @@
+// Package memory provides in-memory implementations of the repository
+// interfaces in package postgres. They keep everything in maps guarded by a
+// single lock, so they are safe for concurrent use, and they report errors the
+// same way the Postgres repositories do (nil results, sql.ErrNoRows,
+// postgres.ErrNotFound, postgres.ErrVersionConflict, foreign key violations).
+// They are meant for unit testing services without a database.
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Lookup rows seeded by NewStore. The IDs match the order the migrations
+// insert them in, so code that relies on e.g. Sales_Agent being role 1 behaves
+// the same.
+var (
+	seedRoles         = []string{"Sales_Agent", "Reception"}
+	seedPropertyTypes = []string{"Apartment", "Villa", "Office", "Townhouse"}
+	seedLeadSources   = []string{"Website Inquiry", "Phone Call", "Social Media", "Referral"}
+	seedLeadStatuses  = []string{"New", "Contacted", "Qualified", "Converted", "Lost"}
+	seedDealStages    = []string{"Prospecting", "Qualification", "Negotiation", "Closing"}
+)
+
+// Store holds the tables shared by the in-memory repositories. Repositories
+// created from the same Store see each other's data, which is needed for
+// queries that join tables (reports, open lead checks, API key owners).
+type Store struct {
+	mu  sync.RWMutex
+	now func() time.Time
+
+	ids map[string]int
+
+	roles         map[int]string
+	sites         map[int]string
+	propertyTypes map[int]string
+	leadSources   map[int]string
+	leadStatuses  map[int]string
+	dealStages    map[int]string
+
+	users            map[int]models.User
+	roleChanges      []models.RoleChange
+	contacts         map[int]models.Contact
+	properties       map[int]models.Property
+	leads            map[int]models.Lead
+	deals            map[int]models.Deal
+	tasks            map[int]models.Task
+	taskSeries       map[int]models.TaskSeries
+	notifications    map[int]models.Notification
+	taskComments     map[int]models.TaskComment
+	taskCommentEdits map[int]models.TaskCommentEdit
+	checklistItems   map[int]models.ChecklistItem
+	playbooks        map[int]models.Playbook
+	playbookRuns     map[playbookRunKey]time.Time
+	slaBreaches      map[int]models.TaskSLABreach
+	notes            map[int]models.Note
+	events           map[int]models.Event
+	commLogs         map[int]models.CommLog
+	invitations      map[int]models.Invitation
+	apiKeys          map[int]models.APIKey
+	auditLog         []models.AuditEntry
+	idempotency      map[idempotencyKey]models.IdempotencyRecord
+}
+
+// NewStore returns an empty store with the lookup tables seeded the same way
+// the migrations seed them. Sites are not seeded; add them with AddSite.
+func NewStore() *Store {
+	s := &Store{
+		now:              time.Now,
+		ids:              make(map[string]int),
+		roles:            make(map[int]string),
+		sites:            make(map[int]string),
+		propertyTypes:    make(map[int]string),
+		leadSources:      make(map[int]string),
+		leadStatuses:     make(map[int]string),
+		dealStages:       make(map[int]string),
+		users:            make(map[int]models.User),
+		contacts:         make(map[int]models.Contact),
+		properties:       make(map[int]models.Property),
+		leads:            make(map[int]models.Lead),
+		deals:            make(map[int]models.Deal),
+		tasks:            make(map[int]models.Task),
+		taskSeries:       make(map[int]models.TaskSeries),
+		notifications:    make(map[int]models.Notification),
+		taskComments:     make(map[int]models.TaskComment),
+		taskCommentEdits: make(map[int]models.TaskCommentEdit),
+		checklistItems:   make(map[int]models.ChecklistItem),
+		playbooks:        make(map[int]models.Playbook),
+		playbookRuns:     make(map[playbookRunKey]time.Time),
+		slaBreaches:      make(map[int]models.TaskSLABreach),
+		notes:            make(map[int]models.Note),
+		events:           make(map[int]models.Event),
+		commLogs:         make(map[int]models.CommLog),
+		invitations:      make(map[int]models.Invitation),
+		apiKeys:          make(map[int]models.APIKey),
+		idempotency:      make(map[idempotencyKey]models.IdempotencyRecord),
+	}
+	seed := func(table string, dst map[int]string, names []string) {
+		for _, name := range names {
+			dst[s.nextID(table)] = name
+		}
+	}
+	seed("roles", s.roles, seedRoles)
+	seed("property_types", s.propertyTypes, seedPropertyTypes)
+	seed("lead_sources", s.leadSources, seedLeadSources)
+	seed("lead_statuses", s.leadStatuses, seedLeadStatuses)
+	seed("deal_stages", s.dealStages, seedDealStages)
+	return s
+}
+
+// SetClock replaces the function the store uses to read the current time.
+// Tests use it to control timestamps and expiry.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// AddSite adds a row to the sites lookup table and returns its ID.
+func (s *Store) AddSite(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID("sites")
+	s.sites[id] = name
+	return id
+}
+
+// nextID returns the next value of a table's ID sequence. The caller must hold
+// the write lock.
+func (s *Store) nextID(table string) int {
+	s.ids[table]++
+	return s.ids[table]
+}
+
+// RoleID returns the ID of the role with the given name, or 0 if there is none.
+func (s *Store) RoleID(name string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return lookupID(s.roles, name)
+}
+
+func lookupID(table map[int]string, name string) int {
+	for id, n := range table {
+		if n == name {
+			return id
+		}
+	}
+	return 0
+}
+
+// foreignKeyViolation builds the error Postgres reports when a row that other
+// rows still reference is deleted, so postgres.IsForeignKeyViolation and
+// message-based checks treat it the same way.
+func foreignKeyViolation(table, constraint, referencedBy string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23503",
+		Message:        fmt.Sprintf("update or delete on table %q violates foreign key constraint %q on table %q", table, constraint, referencedBy),
+		TableName:      referencedBy,
+		ConstraintName: constraint,
+	}
+}
+
+// uniqueViolation builds the error Postgres reports when an insert or update
+// would duplicate a unique column.
+func uniqueViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// sortedValues returns the values of m ordered by less.
+func sortedValues[T any](m map[int]T, keep func(T) bool, less func(a, b T) bool) []T {
+	out := make([]T, 0, len(m))
+	for _, v := range m {
+		if keep == nil || keep(v) {
+			out = append(out, v)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
+
+// newestFirst orders rows by creation time descending, breaking ties by the
+// higher ID so rows created in the same instant keep insertion order.
+func newestFirst(aCreated time.Time, aID int, bCreated time.Time, bID int) bool {
+	if !aCreated.Equal(bCreated) {
+		return aCreated.After(bCreated)
+	}
+	return aID > bID
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// Compile-time checks that the in-memory repositories and transaction manager
+// satisfy the interfaces.
+var (
+	_ postgres.ContactRepository       = (*ContactRepo)(nil)
+	_ postgres.LeadRepository          = (*LeadRepo)(nil)
+	_ postgres.DealRepository          = (*DealRepo)(nil)
+	_ postgres.PropertyRepository      = (*PropertyRepo)(nil)
+	_ postgres.UserRepository          = (*UserRepo)(nil)
+	_ postgres.InvitationRepository    = (*InvitationRepo)(nil)
+	_ postgres.APIKeyRepository        = (*APIKeyRepo)(nil)
+	_ postgres.AuditRepository         = (*AuditRepo)(nil)
+	_ postgres.IdempotencyRepository   = (*IdempotencyRepo)(nil)
+	_ postgres.TaskRepository          = (*TaskRepo)(nil)
+	_ postgres.TaskSeriesRepository    = (*TaskSeriesRepo)(nil)
+	_ postgres.TaskReminderRepository  = (*TaskReminderRepo)(nil)
+	_ postgres.TaskSLARepository       = (*TaskSLARepo)(nil)
+	_ postgres.TaskBulkRepository      = (*TaskBulkRepo)(nil)
+	_ postgres.NotificationRepository  = (*NotificationRepo)(nil)
+	_ postgres.TaskCommentRepository   = (*TaskCommentRepo)(nil)
+	_ postgres.TaskChecklistRepository = (*TaskChecklistRepo)(nil)
+	_ postgres.PlaybookRepository      = (*PlaybookRepo)(nil)
+	_ postgres.NoteRepository          = (*NoteRepo)(nil)
+	_ postgres.EventRepository         = (*EventRepo)(nil)
+	_ postgres.CommLogRepository       = (*CommLogRepo)(nil)
+	_ postgres.AdminRepository         = (*AdminRepo)(nil)
+	_ postgres.Transactor              = (*TxManager)(nil)
+)
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+
+	"crm-project/internal/models"
+)
+
+// TaskBulkRepo is an in-memory postgres.TaskBulkRepository.
+type TaskBulkRepo struct {
+	tasks *TaskRepo
+}
+
+// NewTaskBulkRepo creates a new TaskBulkRepo backed by s.
+func NewTaskBulkRepo(s *Store) *TaskBulkRepo {
+	return &TaskBulkRepo{tasks: NewTaskRepository(s)}
+}
+
+// GetByIDs returns the tasks with the given IDs that exist and are not
+// deleted.
+func (r *TaskBulkRepo) GetByIDs(ctx context.Context, ids []int) ([]models.Task, error) {
+	r.tasks.s.mu.RLock()
+	defer r.tasks.s.mu.RUnlock()
+	tasks := []models.Task{}
+	for _, id := range ids {
+		if t, ok := r.tasks.s.tasks[id]; ok && t.DeletedAt == nil {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+// FindIDs returns the IDs of up to limit tasks that are not deleted and
+// match f, ordered by due date.
+func (r *TaskBulkRepo) FindIDs(ctx context.Context, f models.TaskFilter, limit int) ([]int, error) {
+	r.tasks.s.mu.RLock()
+	defer r.tasks.s.mu.RUnlock()
+	tasks := r.tasks.liveTasks(func(t models.Task) bool {
+		return (f.AssignedTo == 0 || t.AssignedTo == f.AssignedTo) &&
+			(f.Status == "" || t.Status == f.Status) &&
+			(f.TaskType == "" || t.TaskType != nil && *t.TaskType == f.TaskType) &&
+			(f.LeadID == 0 || t.LeadID != nil && *t.LeadID == f.LeadID) &&
+			(f.DealID == 0 || t.DealID != nil && *t.DealID == f.DealID) &&
+			(f.DueAfter == nil || !t.DueDate.Before(*f.DueAfter)) &&
+			(f.DueBefore == nil || t.DueDate.Before(*f.DueBefore))
+	})
+	ids := []int{}
+	for _, t := range tasks {
+		if len(ids) == limit {
+			break
+		}
+		ids = append(ids, t.ID)
+	}
+	return ids, nil
+}
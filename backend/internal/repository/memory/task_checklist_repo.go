@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// TaskChecklistRepo is an in-memory postgres.TaskChecklistRepository.
+type TaskChecklistRepo struct {
+	s *Store
+}
+
+// NewTaskChecklistRepo creates a new TaskChecklistRepo backed by s.
+func NewTaskChecklistRepo(s *Store) *TaskChecklistRepo {
+	return &TaskChecklistRepo{s: s}
+}
+
+func errChecklistItemNotFound() error {
+	return fmt.Errorf("checklist item %w", postgres.ErrNotFound)
+}
+
+// Create inserts a checklist item and fills in its ID, timestamps and
+// version. An item without a position goes after the task's last item.
+func (r *TaskChecklistRepo) Create(ctx context.Context, item *models.ChecklistItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if item.Position == 0 {
+		for _, other := range r.s.checklistItems {
+			if other.TaskID == item.TaskID && other.Position > item.Position {
+				item.Position = other.Position
+			}
+		}
+		item.Position++
+	}
+	now := r.s.now()
+	item.ID = r.s.nextID("task_checklist_items")
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	item.Version = 1
+	r.s.checklistItems[item.ID] = *item
+	return nil
+}
+
+// GetByID returns a checklist item, or nil if there is none.
+func (r *TaskChecklistRepo) GetByID(ctx context.Context, id int) (*models.ChecklistItem, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	item, ok := r.s.checklistItems[id]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// GetByTaskID returns the checklist of a task in position order.
+func (r *TaskChecklistRepo) GetByTaskID(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.checklistItems, func(item models.ChecklistItem) bool {
+		return item.TaskID == taskID
+	}, func(a, b models.ChecklistItem) bool {
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.ID < b.ID
+	}), nil
+}
+
+// Update saves a checklist item. If item.Version is not 0 the item is only
+// updated if it is still at that version.
+func (r *TaskChecklistRepo) Update(ctx context.Context, item *models.ChecklistItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.checklistItems[item.ID]
+	if !ok {
+		return errChecklistItemNotFound()
+	}
+	if item.Version != 0 && item.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.Title = item.Title
+	existing.Required = item.Required
+	existing.Position = item.Position
+	existing.CompletedAt = item.CompletedAt
+	existing.CompletedBy = item.CompletedBy
+	existing.UpdatedAt = r.s.now()
+	existing.Version++
+	r.s.checklistItems[item.ID] = existing
+	item.UpdatedAt = existing.UpdatedAt
+	item.Version = existing.Version
+	return nil
+}
+
+// Delete removes a checklist item. If expectedVersion is not 0 the item is
+// only deleted if it is still at that version.
+func (r *TaskChecklistRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	item, ok := r.s.checklistItems[id]
+	if !ok {
+		return errChecklistItemNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != item.Version {
+		return postgres.ErrVersionConflict
+	}
+	delete(r.s.checklistItems, id)
+	return nil
+}
+
+// CountOpenRequired returns how many required items of a task's checklist
+// are not done.
+func (r *TaskChecklistRepo) CountOpenRequired(ctx context.Context, taskID int) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	count := 0
+	for _, item := range r.s.checklistItems {
+		if item.TaskID == taskID && item.Required && item.CompletedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// TaskCommentRepo is an in-memory postgres.TaskCommentRepository.
+type TaskCommentRepo struct {
+	s *Store
+}
+
+// NewTaskCommentRepo creates a new TaskCommentRepo backed by s.
+func NewTaskCommentRepo(s *Store) *TaskCommentRepo {
+	return &TaskCommentRepo{s: s}
+}
+
+func errTaskCommentNotFound() error {
+	return fmt.Errorf("task comment %w", postgres.ErrNotFound)
+}
+
+// withEdited sets c.Edited from the edit history. The caller must hold the
+// lock.
+func (r *TaskCommentRepo) withEdited(c models.TaskComment) models.TaskComment {
+	c.Edited = false
+	for _, e := range r.s.taskCommentEdits {
+		if e.CommentID == c.ID {
+			c.Edited = true
+			break
+		}
+	}
+	return c
+}
+
+// Create inserts a comment and fills in its ID, timestamps and version.
+func (r *TaskCommentRepo) Create(ctx context.Context, c *models.TaskComment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	c.ID = r.s.nextID("task_comments")
+	c.Edited = false
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	c.DeletedAt = nil
+	c.Version = 1
+	r.s.taskComments[c.ID] = *c
+	return nil
+}
+
+// GetByID returns a comment, deleted or not, or nil if there is none.
+func (r *TaskCommentRepo) GetByID(ctx context.Context, id int) (*models.TaskComment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.taskComments[id]
+	if !ok {
+		return nil, nil
+	}
+	c = r.withEdited(c)
+	return &c, nil
+}
+
+// GetByTaskID returns the comments on a task, deleted ones included, oldest
+// first.
+func (r *TaskCommentRepo) GetByTaskID(ctx context.Context, taskID int) ([]models.TaskComment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := sortedValues(r.s.taskComments, func(c models.TaskComment) bool {
+		return c.TaskID == taskID
+	}, func(a, b models.TaskComment) bool {
+		return newestFirst(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+	for i := range out {
+		out[i] = r.withEdited(out[i])
+	}
+	return out, nil
+}
+
+// Update saves the body of a comment that has not been deleted. If c.Version
+// is not 0 the comment is only updated if it is still at that version.
+func (r *TaskCommentRepo) Update(ctx context.Context, c *models.TaskComment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.taskComments[c.ID]
+	if !ok || existing.DeletedAt != nil {
+		return errTaskCommentNotFound()
+	}
+	if c.Version != 0 && c.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.Body = c.Body
+	existing.UpdatedAt = r.s.now()
+	existing.Version++
+	r.s.taskComments[c.ID] = existing
+	c.UpdatedAt = existing.UpdatedAt
+	c.Version = existing.Version
+	return nil
+}
+
+// Delete marks a comment deleted and clears its body and edit history. If
+// expectedVersion is not 0 the comment is only deleted if it is still at that
+// version.
+func (r *TaskCommentRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.taskComments[id]
+	if !ok || c.DeletedAt != nil {
+		return errTaskCommentNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != c.Version {
+		return postgres.ErrVersionConflict
+	}
+	now := r.s.now()
+	c.Body = ""
+	c.DeletedAt = timePtr(now)
+	c.UpdatedAt = now
+	c.Version++
+	r.s.taskComments[id] = c
+	for editID, e := range r.s.taskCommentEdits {
+		if e.CommentID == id {
+			delete(r.s.taskCommentEdits, editID)
+		}
+	}
+	return nil
+}
+
+// AddEdit records the body a comment had before an edit and fills in the
+// edit's ID and time.
+func (r *TaskCommentRepo) AddEdit(ctx context.Context, e *models.TaskCommentEdit) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.taskComments[e.CommentID]; !ok {
+		return foreignKeyViolation("task_comments", "task_comment_edits_comment_id_fkey", "task_comment_edits")
+	}
+	e.ID = r.s.nextID("task_comment_edits")
+	e.EditedAt = r.s.now()
+	r.s.taskCommentEdits[e.ID] = *e
+	return nil
+}
+
+// GetEdits returns the edit history of a comment, oldest first.
+func (r *TaskCommentRepo) GetEdits(ctx context.Context, commentID int) ([]models.TaskCommentEdit, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return sortedValues(r.s.taskCommentEdits, func(e models.TaskCommentEdit) bool {
+		return e.CommentID == commentID
+	}, func(a, b models.TaskCommentEdit) bool {
+		return newestFirst(b.EditedAt, b.ID, a.EditedAt, a.ID)
+	}), nil
+}
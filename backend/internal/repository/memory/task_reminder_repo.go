@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"crm-project/internal/models"
+)
+
+// TaskReminderRepo is an in-memory postgres.TaskReminderRepository.
+type TaskReminderRepo struct {
+	tasks *TaskRepo
+}
+
+// NewTaskReminderRepo creates a new TaskReminderRepo backed by s.
+func NewTaskReminderRepo(s *Store) *TaskReminderRepo {
+	return &TaskReminderRepo{tasks: NewTaskRepository(s)}
+}
+
+// GetOpenTasksDueBefore returns the tasks that are not completed or deleted
+// and are due before the given time, including overdue ones, ordered by due
+// date.
+func (r *TaskReminderRepo) GetOpenTasksDueBefore(ctx context.Context, before time.Time) ([]models.Task, error) {
+	r.tasks.s.mu.RLock()
+	defer r.tasks.s.mu.RUnlock()
+	return r.tasks.liveTasks(func(t models.Task) bool {
+		return t.Status != "Completed" && t.DueDate.Before(before)
+	}), nil
+}
+
+// MarkOverdue sets the status of the pending tasks due before now to Overdue
+// and returns them as updated.
+func (r *TaskReminderRepo) MarkOverdue(ctx context.Context, now time.Time) ([]models.Task, error) {
+	s := r.tasks.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	overdue := r.tasks.liveTasks(func(t models.Task) bool {
+		return t.Status == "Pending" && t.DueDate.Before(now)
+	})
+	updatedAt := s.now()
+	for i := range overdue {
+		t := &overdue[i]
+		t.Status = "Overdue"
+		t.UpdatedAt = timePtr(updatedAt)
+		t.Version++
+		s.tasks[t.ID] = *t
+	}
+	return overdue, nil
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// TaskRepo is an in-memory postgres.TaskRepository.
+type TaskRepo struct {
+	s *Store
+}
+
+// NewTaskRepository creates a new TaskRepo backed by s.
+func NewTaskRepository(s *Store) *TaskRepo {
+	return &TaskRepo{s: s}
+}
+
+func errTaskNotFound() error {
+	return fmt.Errorf("task %w", postgres.ErrNotFound)
+}
+
+// liveTasks returns the tasks that are not soft deleted and are accepted by
+// keep, earliest due date first. The caller must hold the lock.
+func (r *TaskRepo) liveTasks(keep func(models.Task) bool) []models.Task {
+	return sortedValues(r.s.tasks, func(t models.Task) bool {
+		return t.DeletedAt == nil && (keep == nil || keep(t))
+	}, func(a, b models.Task) bool {
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.Before(b.DueDate)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// CreateTask creates a new task
+func (r *TaskRepo) CreateTask(ctx context.Context, task *models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	task.ID = r.s.nextID("tasks")
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now
+	}
+	task.UpdatedAt = timePtr(now)
+	task.DeletedAt = nil
+	task.Version = 1
+	r.s.tasks[task.ID] = *task
+	return nil
+}
+
+// GetTaskByID retrieves a task by ID
+func (r *TaskRepo) GetTaskByID(ctx context.Context, id int) (*models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	t, ok := r.s.tasks[id]
+	if !ok || t.DeletedAt != nil {
+		return nil, errTaskNotFound()
+	}
+	return &t, nil
+}
+
+// GetTasksByDealID retrieves all tasks for a specific deal
+func (r *TaskRepo) GetTasksByDealID(ctx context.Context, dealID int) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveTasks(func(t models.Task) bool {
+		return t.DealID != nil && *t.DealID == dealID
+	}), nil
+}
+
+// GetTasksByDealIDForUser retrieves the tasks for a deal assigned to a user
+func (r *TaskRepo) GetTasksByDealIDForUser(ctx context.Context, dealID int, userID int) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveTasks(func(t models.Task) bool {
+		return t.DealID != nil && *t.DealID == dealID && t.AssignedTo == userID
+	}), nil
+}
+
+// GetTasksByLeadID retrieves all tasks for a specific lead
+func (r *TaskRepo) GetTasksByLeadID(ctx context.Context, leadID int) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveTasks(func(t models.Task) bool {
+		return t.LeadID != nil && *t.LeadID == leadID
+	}), nil
+}
+
+// GetTasksByLeadIDForUser retrieves the tasks for a lead assigned to a user
+func (r *TaskRepo) GetTasksByLeadIDForUser(ctx context.Context, leadID int, userID int) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveTasks(func(t models.Task) bool {
+		return t.LeadID != nil && *t.LeadID == leadID && t.AssignedTo == userID
+	}), nil
+}
+
+// GetAllTasks retrieves all tasks
+func (r *TaskRepo) GetAllTasks(ctx context.Context) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveTasks(nil), nil
+}
+
+// GetTasksForUser retrieves tasks for a specific user
+func (r *TaskRepo) GetTasksForUser(ctx context.Context, userID int) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.liveTasks(func(t models.Task) bool {
+		return t.AssignedTo == userID
+	}), nil
+}
+
+// UpdateTask updates an existing task
+func (r *TaskRepo) UpdateTask(ctx context.Context, task *models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.tasks[task.ID]
+	if !ok || existing.DeletedAt != nil {
+		return errTaskNotFound()
+	}
+	if task.Version != 0 && task.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	updated := existing
+	updated.TaskName = task.TaskName
+	updated.TaskDescription = task.TaskDescription
+	updated.DueDate = task.DueDate
+	updated.Status = task.Status
+	updated.AssignedTo = task.AssignedTo
+	updated.LeadID = task.LeadID
+	updated.DealID = task.DealID
+	updated.TaskType = task.TaskType
+	updated.UpdatedAt = timePtr(r.s.now())
+	updated.Version = existing.Version + 1
+	r.s.tasks[task.ID] = updated
+	task.UpdatedAt = updated.UpdatedAt
+	task.Version = updated.Version
+	return nil
+}
+
+// DeleteTask soft deletes a task. If expectedVersion is not 0 the task is only
+// deleted if it is still at that version.
+func (r *TaskRepo) DeleteTask(ctx context.Context, id int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.tasks[id]
+	if !ok || existing.DeletedAt != nil {
+		return errTaskNotFound()
+	}
+	if expectedVersion != 0 && expectedVersion != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	existing.DeletedAt = timePtr(r.s.now())
+	r.s.tasks[id] = existing
+	return nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+func newTestTask(t *testing.T, r *TaskRepo) *models.Task {
+	t.Helper()
+	task := &models.Task{TaskName: "Call back", DueDate: time.Now().Add(24 * time.Hour), Status: "Pending", AssignedTo: 1}
+	if err := r.CreateTask(context.Background(), task); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	return task
+}
+
+func TestTaskRepoUpdateVersion(t *testing.T) {
+	ctx := context.Background()
+	r := NewTaskRepository(NewStore())
+	task := newTestTask(t, r)
+	if task.Version != 1 {
+		t.Fatalf("created task has version %d, want 1", task.Version)
+	}
+
+	stale := *task
+	task.TaskName = "Call back again"
+	if err := r.UpdateTask(ctx, task); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if task.Version != 2 {
+		t.Errorf("updated task has version %d, want 2", task.Version)
+	}
+
+	stale.TaskName = "Lost update"
+	if err := r.UpdateTask(ctx, &stale); !errors.Is(err, postgres.ErrVersionConflict) {
+		t.Fatalf("UpdateTask with a stale version: got %v, want ErrVersionConflict", err)
+	}
+	got, err := r.GetTaskByID(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("GetTaskByID: %v", err)
+	}
+	if got.TaskName != "Call back again" || got.Version != 2 {
+		t.Errorf("after a conflict the task is %q at version %d, want %q at version 2", got.TaskName, got.Version, "Call back again")
+	}
+
+	// Version 0 skips the check.
+	unchecked := *got
+	unchecked.Version = 0
+	if err := r.UpdateTask(ctx, &unchecked); err != nil {
+		t.Fatalf("UpdateTask without a version: %v", err)
+	}
+	if unchecked.Version != 3 {
+		t.Errorf("unchecked update left version %d, want 3", unchecked.Version)
+	}
+}
+
+func TestTaskRepoDeleteVersion(t *testing.T) {
+	ctx := context.Background()
+	r := NewTaskRepository(NewStore())
+	task := newTestTask(t, r)
+
+	if err := r.DeleteTask(ctx, task.ID, task.Version+1); !errors.Is(err, postgres.ErrVersionConflict) {
+		t.Fatalf("DeleteTask with a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if err := r.DeleteTask(ctx, task.ID, task.Version); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+
+	// A deleted task is gone for every method.
+	if _, err := r.GetTaskByID(ctx, task.ID); !errors.Is(err, postgres.ErrNotFound) {
+		t.Errorf("GetTaskByID of a deleted task: got %v, want ErrNotFound", err)
+	}
+	if err := r.UpdateTask(ctx, task); !errors.Is(err, postgres.ErrNotFound) {
+		t.Errorf("UpdateTask of a deleted task: got %v, want ErrNotFound", err)
+	}
+	if err := r.DeleteTask(ctx, task.ID, 0); !errors.Is(err, postgres.ErrNotFound) {
+		t.Errorf("DeleteTask of a deleted task: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+)
+
+// TaskSeriesRepo is an in-memory postgres.TaskSeriesRepository.
+type TaskSeriesRepo struct {
+	s *Store
+}
+
+// NewTaskSeriesRepo creates a new TaskSeriesRepo backed by s.
+func NewTaskSeriesRepo(s *Store) *TaskSeriesRepo {
+	return &TaskSeriesRepo{s: s}
+}
+
+// Create inserts a series and fills in its ID, timestamps and version.
+func (r *TaskSeriesRepo) Create(ctx context.Context, ts *models.TaskSeries) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := r.s.now()
+	ts.ID = r.s.nextID("task_series")
+	ts.EndedAt = nil
+	ts.CreatedAt = now
+	ts.UpdatedAt = now
+	ts.Version = 1
+	r.s.taskSeries[ts.ID] = *ts
+	return nil
+}
+
+// GetByID returns a series, or nil if there is none.
+func (r *TaskSeriesRepo) GetByID(ctx context.Context, id int) (*models.TaskSeries, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	ts, ok := r.s.taskSeries[id]
+	if !ok {
+		return nil, nil
+	}
+	return &ts, nil
+}
+
+// Update saves the rule and template of a series. If ts.Version is not 0 the
+// series is only updated if it is still at that version.
+func (r *TaskSeriesRepo) Update(ctx context.Context, ts *models.TaskSeries) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.taskSeries[ts.ID]
+	if !ok {
+		return fmt.Errorf("task series %w", postgres.ErrNotFound)
+	}
+	if ts.Version != 0 && ts.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+	updated := existing
+	updated.RRule = ts.RRule
+	updated.DTStart = ts.DTStart
+	updated.StartOccurrence = ts.StartOccurrence
+	updated.UntilDealClosed = ts.UntilDealClosed
+	updated.TaskName = ts.TaskName
+	updated.TaskDescription = ts.TaskDescription
+	updated.AssignedTo = ts.AssignedTo
+	updated.LeadID = ts.LeadID
+	updated.DealID = ts.DealID
+	updated.TaskType = ts.TaskType
+	updated.UpdatedAt = r.s.now()
+	updated.Version = existing.Version + 1
+	r.s.taskSeries[ts.ID] = updated
+	ts.UpdatedAt = updated.UpdatedAt
+	ts.Version = updated.Version
+	return nil
+}
+
+// End stops a series from creating further occurrences. Ending a series that
+// has already ended is not an error.
+func (r *TaskSeriesRepo) End(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	ts, ok := r.s.taskSeries[id]
+	if !ok || ts.EndedAt != nil {
+		return nil
+	}
+	now := r.s.now()
+	ts.EndedAt = timePtr(now)
+	ts.UpdatedAt = now
+	ts.Version++
+	r.s.taskSeries[id] = ts
+	return nil
+}
+
+// CreateOccurrence inserts task as occurrence task.Occurrence of series
+// task.SeriesID. It returns false, leaving task untouched, if that occurrence
+// already exists.
+func (r *TaskSeriesRepo) CreateOccurrence(ctx context.Context, task *models.Task) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.tasks {
+		if t.SeriesID != nil && task.SeriesID != nil && *t.SeriesID == *task.SeriesID &&
+			t.Occurrence != nil && task.Occurrence != nil && *t.Occurrence == *task.Occurrence {
+			return false, nil
+		}
+	}
+	now := r.s.now()
+	task.ID = r.s.nextID("tasks")
+	task.CreatedAt = now
+	task.UpdatedAt = timePtr(now)
+	task.DeletedAt = nil
+	task.Version = 1
+	r.s.tasks[task.ID] = *task
+	return true, nil
+}
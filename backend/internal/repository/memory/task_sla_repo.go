@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"crm-project/internal/models"
+)
+
+// TaskSLARepo is an in-memory postgres.TaskSLARepository.
+type TaskSLARepo struct {
+	tasks *TaskRepo
+}
+
+// NewTaskSLARepo creates a new TaskSLARepo backed by s.
+func NewTaskSLARepo(s *Store) *TaskSLARepo {
+	return &TaskSLARepo{tasks: NewTaskRepository(s)}
+}
+
+// GetUnbreachedOpenTasks returns the tasks of a type that are not completed
+// or deleted, were created before the given time and have no breach recorded
+// yet, oldest first.
+func (r *TaskSLARepo) GetUnbreachedOpenTasks(ctx context.Context, taskType string, createdBefore time.Time) ([]models.Task, error) {
+	s := r.tasks.s
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tasks := sortedValues(s.tasks, func(t models.Task) bool {
+		return t.DeletedAt == nil && t.Status != "Completed" && t.TaskType != nil && *t.TaskType == taskType &&
+			t.CreatedAt.Before(createdBefore) && !r.breached(t.ID)
+	}, func(a, b models.Task) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return tasks, nil
+}
+
+// breached reports whether a breach is recorded for the task. The caller
+// must hold the lock.
+func (r *TaskSLARepo) breached(taskID int) bool {
+	for _, b := range r.tasks.s.slaBreaches {
+		if b.TaskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordBreach inserts b and fills in its ID and breach time. It returns
+// false, leaving b untouched, if a breach is already recorded for the task.
+func (r *TaskSLARepo) RecordBreach(ctx context.Context, b *models.TaskSLABreach) (bool, error) {
+	s := r.tasks.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.breached(b.TaskID) {
+		return false, nil
+	}
+	if _, ok := s.tasks[b.TaskID]; !ok {
+		return false, foreignKeyViolation("tasks", "task_sla_breaches_task_id_fkey", "task_sla_breaches")
+	}
+	if _, ok := s.users[b.AssignedTo]; !ok {
+		return false, foreignKeyViolation("users", "task_sla_breaches_assigned_to_fkey", "task_sla_breaches")
+	}
+	b.ID = s.nextID("task_sla_breaches")
+	b.BreachedAt = s.now()
+	s.slaBreaches[b.ID] = *b
+	return true, nil
+}
+
+// Reassign assigns an open task from one user to another and returns it as
+// updated, or nil if the task is no longer open or assigned to from.
+func (r *TaskSLARepo) Reassign(ctx context.Context, taskID, from, to int) (*models.Task, error) {
+	s := r.tasks.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[taskID]
+	if !ok || t.DeletedAt != nil || t.Status == "Completed" || t.AssignedTo != from {
+		return nil, nil
+	}
+	t.AssignedTo = to
+	t.UpdatedAt = timePtr(s.now())
+	t.Version++
+	s.tasks[taskID] = t
+	return &t, nil
+}
+
+// GetBreachReport counts the recorded breaches per agent the tasks were
+// assigned to, most breaches first.
+func (r *TaskSLARepo) GetBreachReport(ctx context.Context) ([]models.SLABreachReportRow, error) {
+	s := r.tasks.s
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byAgent := make(map[int]*models.SLABreachReportRow)
+	for _, b := range s.slaBreaches {
+		u, ok := s.users[b.AssignedTo]
+		if !ok {
+			continue
+		}
+		row := byAgent[u.ID]
+		if row == nil {
+			row = &models.SLABreachReportRow{EmployeeID: u.ID, EmployeeName: u.Username}
+			byAgent[u.ID] = row
+		}
+		row.Breaches++
+		if b.Escalation == "reassign" {
+			row.Reassigned++
+		}
+		if t := s.tasks[b.TaskID]; t.Status != "Completed" && t.DeletedAt == nil {
+			row.StillOpen++
+		}
+	}
+	rows := []models.SLABreachReportRow{}
+	for _, row := range byAgent {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Breaches != rows[j].Breaches {
+			return rows[i].Breaches > rows[j].Breaches
+		}
+		return rows[i].EmployeeName < rows[j].EmployeeName
+	})
+	return rows, nil
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+type txKey struct{}
+
+// TxManager is an in-memory postgres.Transactor. If the unit of work fails,
+// every table is restored to the state it had when the unit of work started.
+// Work done by other goroutines in the meantime is rolled back too, so tests
+// that rely on rollback should not run writers concurrently.
+type TxManager struct {
+	s *Store
+}
+
+// NewTxManager creates a new TxManager backed by s.
+func NewTxManager(s *Store) *TxManager {
+	return &TxManager{s: s}
+}
+
+// WithinTx runs fn, undoing all of its changes if it returns an error. A
+// nested call joins the outer unit of work.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+	snap := m.s.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		m.s.restore(snap)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies every table in the store.
+func (s *Store) snapshot() *Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &Store{
+		ids:              maps.Clone(s.ids),
+		roles:            maps.Clone(s.roles),
+		sites:            maps.Clone(s.sites),
+		propertyTypes:    maps.Clone(s.propertyTypes),
+		leadSources:      maps.Clone(s.leadSources),
+		leadStatuses:     maps.Clone(s.leadStatuses),
+		dealStages:       maps.Clone(s.dealStages),
+		users:            maps.Clone(s.users),
+		roleChanges:      slices.Clone(s.roleChanges),
+		contacts:         maps.Clone(s.contacts),
+		properties:       maps.Clone(s.properties),
+		leads:            maps.Clone(s.leads),
+		deals:            maps.Clone(s.deals),
+		tasks:            maps.Clone(s.tasks),
+		taskSeries:       maps.Clone(s.taskSeries),
+		notifications:    maps.Clone(s.notifications),
+		taskComments:     maps.Clone(s.taskComments),
+		taskCommentEdits: maps.Clone(s.taskCommentEdits),
+		checklistItems:   maps.Clone(s.checklistItems),
+		playbooks:        maps.Clone(s.playbooks),
+		playbookRuns:     maps.Clone(s.playbookRuns),
+		slaBreaches:      maps.Clone(s.slaBreaches),
+		notes:            maps.Clone(s.notes),
+		events:           maps.Clone(s.events),
+		commLogs:         maps.Clone(s.commLogs),
+		invitations:      maps.Clone(s.invitations),
+		apiKeys:          maps.Clone(s.apiKeys),
+		auditLog:         slices.Clone(s.auditLog),
+		idempotency:      maps.Clone(s.idempotency),
+	}
+}
+
+// restore replaces every table with the copy taken by snapshot.
+func (s *Store) restore(snap *Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = snap.ids
+	s.roles = snap.roles
+	s.sites = snap.sites
+	s.propertyTypes = snap.propertyTypes
+	s.leadSources = snap.leadSources
+	s.leadStatuses = snap.leadStatuses
+	s.dealStages = snap.dealStages
+	s.users = snap.users
+	s.roleChanges = snap.roleChanges
+	s.contacts = snap.contacts
+	s.properties = snap.properties
+	s.leads = snap.leads
+	s.deals = snap.deals
+	s.tasks = snap.tasks
+	s.taskSeries = snap.taskSeries
+	s.notifications = snap.notifications
+	s.taskComments = snap.taskComments
+	s.taskCommentEdits = snap.taskCommentEdits
+	s.checklistItems = snap.checklistItems
+	s.playbooks = snap.playbooks
+	s.playbookRuns = snap.playbookRuns
+	s.slaBreaches = snap.slaBreaches
+	s.notes = snap.notes
+	s.events = snap.events
+	s.commLogs = snap.commLogs
+	s.invitations = snap.invitations
+	s.apiKeys = snap.apiKeys
+	s.auditLog = snap.auditLog
+	s.idempotency = snap.idempotency
+}
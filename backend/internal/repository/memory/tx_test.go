@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTxManagerRollback(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	tx := NewTxManager(s)
+	r := NewTaskRepository(s)
+	kept := newTestTask(t, r)
+
+	errFail := errors.New("fail")
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		newTestTask(t, r)
+		kept.TaskName = "Renamed"
+		if err := r.UpdateTask(ctx, kept); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("WithinTx: got %v, want the error of fn", err)
+	}
+
+	all, err := r.GetAllTasks(ctx)
+	if err != nil {
+		t.Fatalf("GetAllTasks: %v", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("after rollback there are %d tasks, want 1", len(all))
+	}
+	if all[0].TaskName != "Call back" || all[0].Version != 1 {
+		t.Errorf("after rollback the task is %q at version %d, want the original", all[0].TaskName, all[0].Version)
+	}
+
+	// IDs handed out in the rolled back unit of work are reused, as they
+	// would not be in Postgres; tests should not depend on either.
+	next := newTestTask(t, r)
+	if next.ID != kept.ID+1 {
+		t.Errorf("next task has ID %d, want %d", next.ID, kept.ID+1)
+	}
+}
+
+func TestTxManagerCommit(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	tx := NewTxManager(s)
+	r := NewTaskRepository(s)
+
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		newTestTask(t, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %v", err)
+	}
+	if all, _ := r.GetAllTasks(ctx); len(all) != 1 {
+		t.Errorf("after commit there are %d tasks, want 1", len(all))
+	}
+}
+
+func TestTxManagerNested(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	tx := NewTxManager(s)
+	r := NewTaskRepository(s)
+
+	errFail := errors.New("fail")
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			newTestTask(t, r)
+			return nil
+		}); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("WithinTx: got %v, want the error of fn", err)
+	}
+	// The inner call joined the outer one, so its work is rolled back too.
+	if all, _ := r.GetAllTasks(ctx); len(all) != 0 {
+		t.Errorf("after rollback there are %d tasks, want 0", len(all))
+	}
+}
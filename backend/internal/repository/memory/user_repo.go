@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"crm-project/internal/models"
+)
+
+// UserRepo is an in-memory postgres.UserRepository.
+type UserRepo struct {
+	s *Store
+}
+
+// NewUserRepo creates a new UserRepo backed by s.
+func NewUserRepo(s *Store) *UserRepo {
+	return &UserRepo{s: s}
+}
+
+// withoutHash returns u without its password hash, matching the queries that
+// do not select that column.
+func withoutHash(u models.User) models.User {
+	u.PasswordHash = ""
+	return u
+}
+
+// duplicateUser reports the unique constraint u would violate, ignoring the row
+// with u's own ID. The caller must hold the lock.
+func (s *Store) duplicateUser(u models.User) error {
+	for _, other := range s.users {
+		if other.ID == u.ID {
+			continue
+		}
+		if other.Username == u.Username {
+			return uniqueViolation("users", "users_username_key")
+		}
+		if other.Email == u.Email {
+			return uniqueViolation("users", "users_email_key")
+		}
+	}
+	return nil
+}
+
+// insertUser stores a new user and returns its ID. The caller must hold the
+// write lock.
+func (s *Store) insertUser(u models.User) (int, error) {
+	u.ID = 0
+	if err := s.duplicateUser(u); err != nil {
+		return 0, err
+	}
+	u.ID = s.nextID("users")
+	u.Password = ""
+	u.RoleName = ""
+	u.CreatedAt = s.now()
+	u.UpdatedAt = u.CreatedAt
+	s.users[u.ID] = u
+	return u.ID, nil
+}
+
+// Create inserts a new user and returns its ID.
+func (r *UserRepo) Create(ctx context.Context, user models.User) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.insertUser(user)
+}
+
+// GetAll retrieves all users, newest first.
+func (r *UserRepo) GetAll(ctx context.Context) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	users := sortedValues(r.s.users, nil, func(a, b models.User) bool {
+		return newestFirst(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	for i := range users {
+		users[i] = withoutHash(users[i])
+	}
+	return users, nil
+}
+
+// GetByID retrieves a single user. It returns nil, nil if it does not exist.
+func (r *UserRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, nil
+	}
+	u = withoutHash(u)
+	return &u, nil
+}
+
+// Update modifies an existing user. The password hash is only replaced if a
+// new one is given.
+func (r *UserRepo) Update(ctx context.Context, user models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	existing, ok := r.s.users[user.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := r.s.duplicateUser(user); err != nil {
+		return err
+	}
+	existing.Username = user.Username
+	existing.Email = user.Email
+	existing.RoleID = user.RoleID
+	if user.PasswordHash != "" {
+		existing.PasswordHash = user.PasswordHash
+	}
+	existing.UpdatedAt = r.s.now()
+	r.s.users[user.ID] = existing
+	return nil
+}
+
+// Delete removes a user.
+func (r *UserRepo) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.s.users, id)
+	return nil
+}
+
+// GetAllSalesAgents retrieves all users with the Sales_Agent role, ordered by
+// username. Only the ID, username and email are filled in.
+func (r *UserRepo) GetAllSalesAgents(ctx context.Context) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	salesAgentID := lookupID(r.s.roles, "Sales_Agent")
+	var agents []models.User
+	for _, u := range r.s.users {
+		if u.RoleID == salesAgentID {
+			agents = append(agents, models.User{ID: u.ID, Username: u.Username, Email: u.Email})
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Username < agents[j].Username })
+	return agents, nil
+}
+
+// findUser returns the first user accepted by match. The caller must hold the
+// lock.
+func (s *Store) findUser(match func(models.User) bool) (models.User, bool) {
+	for _, u := range s.users {
+		if match(u) {
+			return u, true
+		}
+	}
+	return models.User{}, false
+}
+
+// GetByUsername retrieves a user, including the password hash, by username. It
+// returns nil, nil if there is no such user.
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.findUser(func(u models.User) bool { return u.Username == username })
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+// GetByUsernameWithRole is GetByUsername with RoleName filled in.
+func (r *UserRepo) GetByUsernameWithRole(ctx context.Context, username string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.findUser(func(u models.User) bool { return u.Username == username })
+	if !ok {
+		return nil, nil
+	}
+	role, ok := r.s.roles[u.RoleID]
+	if !ok {
+		return nil, nil
+	}
+	u.RoleName = role
+	return &u, nil
+}
+
+// GetByEmail retrieves a user, including the password hash, by email. It
+// returns nil, nil if there is no such user.
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.findUser(func(u models.User) bool { return u.Email == email })
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+// ChangeRole updates a user's role and records the change. It returns the role
+// the user had before the change, or sql.ErrNoRows if the user does not exist.
+func (r *UserRepo) ChangeRole(ctx context.Context, userID, newRoleID, changedBy int, reason *string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	oldRoleID := u.RoleID
+	now := r.s.now()
+	u.RoleID = newRoleID
+	u.UpdatedAt = now
+	r.s.users[userID] = u
+	r.s.roleChanges = append(r.s.roleChanges, models.RoleChange{
+		ID:        r.s.nextID("role_changes"),
+		UserID:    userID,
+		OldRoleID: oldRoleID,
+		NewRoleID: newRoleID,
+		ChangedBy: changedBy,
+		Reason:    reason,
+		ChangedAt: now,
+	})
+	return oldRoleID, nil
+}
+
+// GetRoleChanges retrieves the role change history for a user, newest first.
+func (r *UserRepo) GetRoleChanges(ctx context.Context, userID int) ([]models.RoleChange, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var changes []models.RoleChange
+	for i := len(r.s.roleChanges) - 1; i >= 0; i-- {
+		if c := r.s.roleChanges[i]; c.UserID == userID {
+			changes = append(changes, c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].ChangedAt.After(changes[j].ChangedAt) })
+	return changes, nil
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LookupSeed is a lookup table and the rows every installation needs in it.
+type LookupSeed struct {
+	Table  string
+	Column string
+	Names  []string
+}
+
+// LookupSeeds are the reference rows the migrations insert. SeedLookups adds
+// any that are missing, e.g. after an operator deleted one by hand.
+var LookupSeeds = []LookupSeed{
+	{Table: "roles", Column: "role_name", Names: []string{"Sales_Agent", "Reception"}},
+	{Table: "property_types", Column: "name", Names: []string{"Apartment", "Villa", "Office", "Townhouse"}},
+	{Table: "lead_sources", Column: "name", Names: []string{"Website Inquiry", "Phone Call", "Social Media", "Referral"}},
+	{Table: "lead_statuses", Column: "name", Names: []string{"New", "Contacted", "Qualified", "Converted", "Lost"}},
+	{Table: "deal_stages", Column: "name", Names: []string{"Prospecting", "Qualification", "Negotiation", "Closing"}},
+}
+
+// ReassignCounts reports how many rows ReassignRecords moved to the new user.
+type ReassignCounts struct {
+	Contacts int64
+	Leads    int64
+	Deals    int64
+	Tasks    int64
+	Events   int64
+}
+
+// AdminRepo holds the maintenance queries used by crmctl. They work across
+// tables and bypass the ownership rules the services enforce.
+type AdminRepo struct {
+	db *sqlx.DB
+}
+
+func NewAdminRepo(db *sqlx.DB) *AdminRepo {
+	return &AdminRepo{db: db}
+}
+
+// SeedLookups inserts any LookupSeeds rows that are missing and returns how
+// many rows were added to each table. Existing rows are left alone, so it is
+// safe to run repeatedly.
+func (r *AdminRepo) SeedLookups(ctx context.Context) (map[string]int64, error) {
+	added := make(map[string]int64, len(LookupSeeds))
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		for _, seed := range LookupSeeds {
+			query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT (%s) DO NOTHING`, seed.Table, seed.Column, seed.Column)
+			for _, name := range seed.Names {
+				result, err := tx.ExecContext(ctx, query, name)
+				if err != nil {
+					return fmt.Errorf("seeding %s: %w", seed.Table, err)
+				}
+				n, err := result.RowsAffected()
+				if err != nil {
+					return err
+				}
+				added[seed.Table] += n
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// GetRoleID returns the ID of the role with the given name, or 0 if there is
+// none.
+func (r *AdminRepo) GetRoleID(ctx context.Context, roleName string) (int, error) {
+	var id int
+	err := conn(ctx, r.db).GetContext(ctx, &id, `SELECT role_id FROM roles WHERE role_name = $1`, roleName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// ReassignRecords moves the contacts, leads and deals owned by one user, the
+// tasks still open for them and the events they organize that have not started
+// yet to another user, in a single transaction. Notes, communication logs and
+// completed work keep their original author.
+func (r *AdminRepo) ReassignRecords(ctx context.Context, fromUserID, toUserID int) (*ReassignCounts, error) {
+	var counts ReassignCounts
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		steps := []struct {
+			query string
+			dst   *int64
+		}{
+			{`UPDATE contacts SET created_by = $2, updated_at = NOW(), version = version + 1 WHERE created_by = $1`, &counts.Contacts},
+			{`UPDATE leads SET assigned_to = $2, updated_at = NOW(), version = version + 1 WHERE assigned_to = $1`, &counts.Leads},
+			{`UPDATE deals SET created_by = $2, updated_at = NOW(), version = version + 1 WHERE created_by = $1`, &counts.Deals},
+			{`UPDATE tasks SET assigned_to = $2, updated_at = NOW(), version = version + 1 WHERE assigned_to = $1 AND status <> 'Completed'`, &counts.Tasks},
+			{`UPDATE events SET organizer_id = $2, updated_at = NOW(), version = version + 1 WHERE organizer_id = $1 AND start_time > NOW()`, &counts.Events},
+		}
+		for _, step := range steps {
+			result, err := tx.ExecContext(ctx, step.query, fromUserID, toUserID)
+			if err != nil {
+				return err
+			}
+			if *step.dst, err = result.RowsAffected(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// BackfillContactCreatedBy sets created_by on every contact that has none and
+// returns how many contacts were updated.
+func (r *AdminRepo) BackfillContactCreatedBy(ctx context.Context, userID int) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE contacts SET created_by = $1, updated_at = NOW(), version = version + 1 WHERE created_by IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
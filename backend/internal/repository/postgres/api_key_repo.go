@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// APIKeyRepo is a repository for the api_keys table.
+type APIKeyRepo struct {
+	db *sqlx.DB
+}
+
+// NewAPIKeyRepo creates a new APIKeyRepo.
+func NewAPIKeyRepo(db *sqlx.DB) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+// APIKeyOwner is an active API key joined with its owner's current role, so
+// role changes take effect on existing keys immediately.
+type APIKeyOwner struct {
+	models.APIKey
+	Username string `db:"username"`
+	RoleID   int    `db:"role_id"`
+}
+
+// Create inserts a new API key and returns its ID.
+func (r *APIKeyRepo) Create(ctx context.Context, k models.APIKey) (int, error) {
+	var newID int
+	query := `INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING api_key_id`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, k.UserID, k.Name, k.KeyPrefix, k.KeyHash, k.Scopes, k.ExpiresAt).Scan(&newID)
+	return newID, err
+}
+
+// GetByID retrieves a single API key. It returns nil, nil if it does not exist.
+func (r *APIKeyRepo) GetByID(ctx context.Context, id int) (*models.APIKey, error) {
+	var key models.APIKey
+	query := `SELECT api_key_id, user_id, name, key_prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+			  FROM api_keys
+			  WHERE api_key_id = $1`
+	err := conn(ctx, r.db).GetContext(ctx, &key, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAllForUser retrieves all API keys owned by a user, newest first.
+func (r *APIKeyRepo) GetAllForUser(ctx context.Context, userID int) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	query := `SELECT api_key_id, user_id, name, key_prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+			  FROM api_keys
+			  WHERE user_id = $1
+			  ORDER BY created_at DESC`
+	err := conn(ctx, r.db).SelectContext(ctx, &keys, query, userID)
+	return keys, err
+}
+
+// GetActiveByHash looks up an unrevoked, unexpired key by the hash of its
+// secret. It returns nil, nil if no such key exists.
+func (r *APIKeyRepo) GetActiveByHash(ctx context.Context, keyHash string) (*APIKeyOwner, error) {
+	var key APIKeyOwner
+	query := `SELECT
+				k.api_key_id, k.user_id, k.name, k.key_prefix, k.key_hash, k.scopes, k.expires_at,
+				k.last_used_at, k.revoked_at, k.created_at,
+				u.username, u.role_id
+			  FROM api_keys k
+			  JOIN users u ON k.user_id = u.user_id
+			  WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND k.expires_at > NOW()`
+	err := conn(ctx, r.db).GetContext(ctx, &key, query, keyHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// TouchLastUsed records that a key was used. To avoid a write on every request
+// the timestamp is only moved forward once per minute.
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id int) error {
+	query := `UPDATE api_keys SET last_used_at = NOW()
+			  WHERE api_key_id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	return err
+}
+
+// Revoke marks a key as revoked.
+func (r *APIKeyRepo) Revoke(ctx context.Context, id int) error {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE api_key_id = $1 AND revoked_at IS NULL`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditRepo is a repository for the append-only audit_log table.
+type AuditRepo struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepo creates a new AuditRepo.
+func NewAuditRepo(db *sqlx.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+// Insert appends an entry to the audit log.
+func (r *AuditRepo) Insert(ctx context.Context, e models.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor_user_id, api_key_id, entity_type, entity_id, action, before, after, request_id)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, e.ActorUserID, e.APIKeyID, e.EntityType, e.EntityID, e.Action, e.Before, e.After, e.RequestID)
+	return err
+}
+
+// Find returns audit entries matching the filter, newest first.
+func (r *AuditRepo) Find(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.EntityType != "" {
+		add("entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID > 0 {
+		add("entity_id = $%d", f.EntityID)
+	}
+	if f.ActorUserID > 0 {
+		add("actor_user_id = $%d", f.ActorUserID)
+	}
+	if f.From != nil {
+		add("occurred_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("occurred_at < $%d", *f.To)
+	}
+
+	query := `SELECT audit_id, occurred_at, actor_user_id, api_key_id, entity_type, entity_id, action, before, after, request_id
+			  FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	args = append(args, limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, audit_id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	var entries []models.AuditEntry
+	err := conn(ctx, r.db).SelectContext(ctx, &entries, query, args...)
+	return entries, err
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+    "context"
     "database/sql"
     "fmt"
     "time"
@@ -9,6 +10,9 @@ import (
     "github.com/jmoiron/sqlx"
 )
 
+// commLogExistsQuery reports whether a row exists and has not been soft deleted.
+const commLogExistsQuery = `SELECT EXISTS(SELECT 1 FROM communication_logs WHERE log_id = $1 AND deleted_at IS NULL)`
+
 // CommLogRepo implements the CommLogRepository interface
 type CommLogRepo struct {
     db *sqlx.DB
@@ -19,17 +23,18 @@ func NewCommLogRepository(db *sqlx.DB) *CommLogRepo {
 }
 
 // CreateCommLog creates a new communication log
-func (r *CommLogRepo) CreateCommLog(log *models.CommLog) error {
+func (r *CommLogRepo) CreateCommLog(ctx context.Context, log *models.CommLog) error {
     query := `
         INSERT INTO communication_logs (contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, updated_at)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-        RETURNING log_id, created_at, updated_at
+        RETURNING log_id, created_at, updated_at, version
     `
 
     currentTime := time.Now()
     var createdAt time.Time
     var updatedAt time.Time
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         log.ContactID,
         log.UserID,
@@ -40,7 +45,7 @@ func (r *CommLogRepo) CreateCommLog(log *models.CommLog) error {
         log.Notes,
         currentTime,
         currentTime,
-    ).Scan(&log.ID, &createdAt, &updatedAt)
+    ).Scan(&log.ID, &createdAt, &updatedAt, &log.Version)
 
     if err != nil {
         return fmt.Errorf("failed to create communication log: %w", err)
@@ -52,18 +57,18 @@ func (r *CommLogRepo) CreateCommLog(log *models.CommLog) error {
 }
 
 // GetCommLogByID retrieves a communication log by ID
-func (r *CommLogRepo) GetCommLogByID(id int) (*models.CommLog, error) {
+func (r *CommLogRepo) GetCommLogByID(ctx context.Context, id int) (*models.CommLog, error) {
     query := `
-        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, updated_at, deleted_at
+        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, updated_at, deleted_at, version
         FROM communication_logs
         WHERE log_id = $1 AND deleted_at IS NULL
     `
 
     var log models.CommLog
-    err := r.db.Get(&log, query, id)
+    err := conn(ctx, r.db).GetContext(ctx, &log, query, id)
     if err != nil {
         if err == sql.ErrNoRows {
-            return nil, fmt.Errorf("communication log not found")
+            return nil, fmt.Errorf("communication log %w", ErrNotFound)
         }
         return nil, fmt.Errorf("failed to get communication log: %w", err)
     }
@@ -72,16 +77,16 @@ func (r *CommLogRepo) GetCommLogByID(id int) (*models.CommLog, error) {
 }
 
 // GetCommLogsByDealID retrieves all communication logs for a specific deal
-func (r *CommLogRepo) GetCommLogsByDealID(dealID int) ([]models.CommLog, error) {
+func (r *CommLogRepo) GetCommLogsByDealID(ctx context.Context, dealID int) ([]models.CommLog, error) {
     query := `
-        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at
+        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at, version
         FROM communication_logs
         WHERE deal_id = $1 AND deleted_at IS NULL
         ORDER BY interaction_date DESC
     `
 
     var logs []models.CommLog
-    err := r.db.Select(&logs, query, dealID)
+    err := conn(ctx, r.db).SelectContext(ctx, &logs, query, dealID)
     if err != nil {
         return nil, fmt.Errorf("failed to get communication logs by deal ID: %w", err)
     }
@@ -89,17 +94,35 @@ func (r *CommLogRepo) GetCommLogsByDealID(dealID int) ([]models.CommLog, error)
     return logs, nil
 }
 
+// GetCommLogsByLeadID retrieves all communication logs for a specific lead
+func (r *CommLogRepo) GetCommLogsByLeadID(ctx context.Context, leadID int) ([]models.CommLog, error) {
+    query := `
+        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at, version
+        FROM communication_logs
+        WHERE lead_id = $1 AND deleted_at IS NULL
+        ORDER BY interaction_date DESC
+    `
+
+    var logs []models.CommLog
+    err := conn(ctx, r.db).SelectContext(ctx, &logs, query, leadID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get communication logs by lead ID: %w", err)
+    }
+
+    return logs, nil
+}
+
 // GetCommLogsByContactID retrieves all communication logs for a specific contact
-func (r *CommLogRepo) GetCommLogsByContactID(contactID int) ([]models.CommLog, error) {
+func (r *CommLogRepo) GetCommLogsByContactID(ctx context.Context, contactID int) ([]models.CommLog, error) {
     query := `
-        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at
+        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at, version
         FROM communication_logs
         WHERE contact_id = $1 AND deleted_at IS NULL
         ORDER BY interaction_date DESC
     `
 
     var logs []models.CommLog
-    err := r.db.Select(&logs, query, contactID)
+    err := conn(ctx, r.db).SelectContext(ctx, &logs, query, contactID)
     if err != nil {
         return nil, fmt.Errorf("failed to get communication logs by contact ID: %w", err)
     }
@@ -108,16 +131,16 @@ func (r *CommLogRepo) GetCommLogsByContactID(contactID int) ([]models.CommLog, e
 }
 
 // GetAllCommLogs retrieves all communication logs
-func (r *CommLogRepo) GetAllCommLogs() ([]models.CommLog, error) {
+func (r *CommLogRepo) GetAllCommLogs(ctx context.Context) ([]models.CommLog, error) {
     query := `
-        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at
+        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at, version
         FROM communication_logs
         WHERE deleted_at IS NULL
         ORDER BY interaction_date DESC
     `
 
     var logs []models.CommLog
-    err := r.db.Select(&logs, query)
+    err := conn(ctx, r.db).SelectContext(ctx, &logs, query)
     if err != nil {
         return nil, fmt.Errorf("failed to get all communication logs: %w", err)
     }
@@ -126,17 +149,18 @@ func (r *CommLogRepo) GetAllCommLogs() ([]models.CommLog, error) {
 }
 
 // UpdateCommLog updates an existing communication log
-func (r *CommLogRepo) UpdateCommLog(log *models.CommLog) error {
+func (r *CommLogRepo) UpdateCommLog(ctx context.Context, log *models.CommLog) error {
     query := `
         UPDATE communication_logs 
-        SET contact_id = $1, user_id = $2, lead_id = $3, deal_id = $4, interaction_date = $5, interaction_type = $6, notes = $7, created_at = $8
-        WHERE log_id = $9 AND deleted_at IS NULL
-        RETURNING created_at
+        SET contact_id = $1, user_id = $2, lead_id = $3, deal_id = $4, interaction_date = $5, interaction_type = $6, notes = $7, created_at = $8, version = version + 1
+        WHERE log_id = $9 AND deleted_at IS NULL AND ($10 = 0 OR version = $10)
+        RETURNING created_at, version
     `
 
     currentTime := time.Now()
     var createdAt time.Time
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         log.ContactID,
         log.UserID,
@@ -147,11 +171,12 @@ func (r *CommLogRepo) UpdateCommLog(log *models.CommLog) error {
         log.Notes,
         currentTime,
         log.ID,
-    ).Scan(&createdAt)
+        log.Version,
+    ).Scan(&createdAt, &log.Version)
 
     if err != nil {
         if err == sql.ErrNoRows {
-            return fmt.Errorf("communication log not found")
+            return missingOrConflict(ctx, r.db, commLogExistsQuery, log.ID, fmt.Errorf("communication log %w", ErrNotFound))
         }
         return fmt.Errorf("failed to update communication log: %w", err)
     }
@@ -160,15 +185,16 @@ func (r *CommLogRepo) UpdateCommLog(log *models.CommLog) error {
     return nil
 }
 
-// DeleteCommLog soft deletes a communication log
-func (r *CommLogRepo) DeleteCommLog(id int) error {
+// DeleteCommLog soft deletes a communication log. If expectedVersion is not 0
+// the log is only deleted if it is still at that version.
+func (r *CommLogRepo) DeleteCommLog(ctx context.Context, id int, expectedVersion int) error {
     query := `
         UPDATE communication_logs 
         SET deleted_at = $1
-        WHERE log_id = $2 AND deleted_at IS NULL
+        WHERE log_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
     `
 
-    result, err := r.db.Exec(query, time.Now(), id)
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), id, expectedVersion)
     if err != nil {
         return fmt.Errorf("failed to delete communication log: %w", err)
     }
@@ -179,23 +205,23 @@ func (r *CommLogRepo) DeleteCommLog(id int) error {
     }
 
     if rowsAffected == 0 {
-        return fmt.Errorf("communication log not found")
+        return missingOrConflict(ctx, r.db, commLogExistsQuery, id, fmt.Errorf("communication log %w", ErrNotFound))
     }
 
     return nil
 }
 
 // GetCommLogsForUser retrieves communication logs for a specific user
-func (r *CommLogRepo) GetCommLogsForUser(userID int) ([]models.CommLog, error) {
+func (r *CommLogRepo) GetCommLogsForUser(ctx context.Context, userID int) ([]models.CommLog, error) {
     query := `
-        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at
+        SELECT log_id, contact_id, user_id, lead_id, deal_id, interaction_date, interaction_type, notes, created_at, deleted_at, version
         FROM communication_logs
         WHERE user_id = $1 AND deleted_at IS NULL
         ORDER BY interaction_date DESC
     `
 
     var logs []models.CommLog
-    err := r.db.Select(&logs, query, userID)
+    err := conn(ctx, r.db).SelectContext(ctx, &logs, query, userID)
     if err != nil {
         return nil, fmt.Errorf("failed to get communication logs for user: %w", err)
     }
@@ -6,7 +6,6 @@ import (
     "database/sql"
 	"github.com/jmoiron/sqlx"
 	"context"
-	"log"
 )
 
 // ContactRepo is a repository for the contacts table.
@@ -26,11 +25,11 @@ func (r *ContactRepo) GetAll(ctx context.Context) ([]models.Contact, error) {
 	// Bug Fix: Select all columns to match the Contact struct and other queries.
 	query := `SELECT 
 				contact_id, first_name, last_name, email, primary_phone, 
-				secondary_phone, address, city, sub_city, contact_source, created_at, updated_at, created_by
+				secondary_phone, address, city, sub_city, contact_source, created_at, updated_at, created_by, version
 			  FROM contacts 
 			  ORDER BY created_at DESC`
 	
-	err := r.db.SelectContext(ctx, &contacts, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &contacts, query)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +62,7 @@ func (r *ContactRepo) Create(ctx context.Context, c models.Contact) (int, error)
 				address, city, sub_city, contact_source, created_by
 			  ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 			  RETURNING contact_id`
-	err := r.db.QueryRowxContext(ctx, query,
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query,
 		c.FirstName, c.LastName, c.Email, c.PrimaryPhone, c.SecondaryPhone,
 		c.Address, c.City, c.SubCity, c.ContactSource, c.CreatedBy,
 	).Scan(&newID)
@@ -92,18 +91,16 @@ func (r *ContactRepo) Create(ctx context.Context, c models.Contact) (int, error)
 func (r *ContactRepo) GetByID(ctx context.Context, id int) (*models.Contact, error) {
 	var contact models.Contact
 
-	log.Printf("GetByID called with id: %d", id) // Added log
-
 	// Use all columns from your model to ensure everything is populated
 	query := `SELECT 
 				contact_id, first_name, last_name, email, primary_phone, 
-				secondary_phone, address, city, sub_city, contact_source, created_at, updated_at, created_by
+				secondary_phone, address, city, sub_city, contact_source, created_at, updated_at, created_by, version
 			  FROM contacts 
 			  WHERE contact_id = $1`
 
 	// db.Get is a convenient sqlx method for fetching a single row
 	// and scanning it into a struct.
-	err := r.db.GetContext(ctx, &contact, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &contact, query, id)
 	if err != nil {
 		// It's idiomatic in Go for repository Get methods to return a specific
 		// error when a row isn't found, so the service layer can handle it.
@@ -131,10 +128,11 @@ func (r *ContactRepo) Update(ctx context.Context, contact models.Contact) error
 				city = $7,
 				sub_city = $8,
 				contact_source = $9,
+				version = version + 1,
 				updated_at = NOW()
-			  WHERE contact_id = $10`
+			  WHERE contact_id = $10 AND ($11 = 0 OR version = $11)`
 
-	result, err := r.db.ExecContext(
+	result, err := conn(ctx, r.db).ExecContext(
 		ctx,
 		query,
 		contact.FirstName,
@@ -147,6 +145,7 @@ func (r *ContactRepo) Update(ctx context.Context, contact models.Contact) error
 		contact.SubCity,
 		contact.ContactSource,
 		contact.ID, // The ID for the WHERE clause
+		contact.Version, // The version the caller last read; 0 skips the check
 	)
 
 	if err != nil {
@@ -161,19 +160,21 @@ func (r *ContactRepo) Update(ctx context.Context, contact models.Contact) error
 	}
 
 	if rowsAffected == 0 {
-		// We can return sql.ErrNoRows to signal "not found" to the service layer.
-		return sql.ErrNoRows
+		// We can return sql.ErrNoRows to signal "not found" to the service layer,
+		// or ErrVersionConflict if the contact exists but has changed.
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM contacts WHERE contact_id = $1)`, contact.ID, sql.ErrNoRows)
 	}
 
 	return nil
 }
 
 
-// Delete removes a contact from the database by its ID.
-func (r *ContactRepo) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM contacts WHERE contact_id = $1`
+// Delete removes a contact from the database by its ID. If expectedVersion is
+// not 0 the contact is only deleted if it is still at that version.
+func (r *ContactRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `DELETE FROM contacts WHERE contact_id = $1 AND ($2 = 0 OR version = $2)`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
 	if err != nil {
 		return err
 	}
@@ -186,7 +187,7 @@ func (r *ContactRepo) Delete(ctx context.Context, id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return sql.ErrNoRows // Signal "not found"
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM contacts WHERE contact_id = $1)`, id, sql.ErrNoRows)
 	}
 
 	return nil
@@ -197,17 +198,17 @@ func (r *ContactRepo) GetAllForUser(ctx context.Context, userID int) ([]models.C
     var contacts []models.Contact
     query := `SELECT 
 				contact_id, first_name, last_name, email, primary_phone, 
-				secondary_phone, address, city, sub_city, contact_source, created_at, updated_at, created_by
+				secondary_phone, address, city, sub_city, contact_source, created_at, updated_at, created_by, version
 			  FROM contacts 
 			  WHERE created_by = $1 ORDER BY created_at DESC`
-    err := r.db.SelectContext(ctx, &contacts, query, userID)
+    err := conn(ctx, r.db).SelectContext(ctx, &contacts, query, userID)
     return contacts, err
 }
 
 // UpdateCreatedBy updates the created_by field of a contact.
 func (r *ContactRepo) UpdateCreatedBy(ctx context.Context, contactID int, createdBy int) error {
 	query := `UPDATE contacts SET created_by = $1 WHERE contact_id = $2`
-	result, err := r.db.ExecContext(ctx, query, createdBy, contactID)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, createdBy, contactID)
 	if err != nil {
 		return err
 	}
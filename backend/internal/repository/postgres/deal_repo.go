@@ -20,28 +20,28 @@ func (r *DealRepo) Create(ctx context.Context,d models.Deal) (int, error) {
 	var newID int
 	query := `INSERT INTO deals (lead_id, property_id, stage_id, deal_status, deal_amount, closing_date, notes, created_by)
 			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING deal_id`
-	err := r.db.QueryRowxContext(ctx,query, d.LeadID, d.PropertyID, d.StageID, d.DealStatus, d.DealAmount, d.ClosingDate, d.Notes, d.CreatedBy).Scan(&newID)
+	err := conn(ctx, r.db).QueryRowxContext(ctx,query, d.LeadID, d.PropertyID, d.StageID, d.DealStatus, d.DealAmount, d.ClosingDate, d.Notes, d.CreatedBy).Scan(&newID)
 	return newID, err
 }
 
 func (r *DealRepo) GetAll(ctx context.Context,) ([]models.Deal, error) {
 	var deals []models.Deal
 	query := `SELECT * FROM deals`
-	err := r.db.SelectContext(ctx,&deals, query)
+	err := conn(ctx, r.db).SelectContext(ctx,&deals, query)
 	return deals, err
 }
 
 func (r *DealRepo) GetAllForUser(ctx context.Context, userID int) ([]models.Deal, error) {
 	var deals []models.Deal
 	query := `SELECT * FROM deals WHERE created_by = $1`
-	err := r.db.SelectContext(ctx, &deals, query, userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &deals, query, userID)
 	return deals, err
 }
 
 func (r *DealRepo) GetByID( ctx context.Context,id int) (*models.Deal, error) {
 	var deal models.Deal
 	query := `SELECT * FROM deals WHERE deal_id = $1`
-	err := r.db.GetContext(ctx, &deal, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &deal, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -60,28 +60,31 @@ func (r *DealRepo) Update(ctx context.Context, d models.Deal) error {
 				deal_amount = $5,
 				closing_date = $6,
 				notes = $7,
+				version = version + 1,
 				updated_at = NOW()
-			  WHERE deal_id = $8`
-	result, err := r.db.ExecContext(ctx, query, d.LeadID, d.PropertyID, d.StageID, d.DealStatus, d.DealAmount, d.ClosingDate, d.Notes, d.ID)
+			  WHERE deal_id = $8 AND ($9 = 0 OR version = $9)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, d.LeadID, d.PropertyID, d.StageID, d.DealStatus, d.DealAmount, d.ClosingDate, d.Notes, d.ID, d.Version)
 	if err != nil {
 		return err
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM deals WHERE deal_id = $1)`, d.ID, sql.ErrNoRows)
 	}
 	return nil
 }
 
-func (r *DealRepo) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM deals WHERE deal_id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+// Delete removes a deal. If expectedVersion is not 0 the deal is only deleted
+// if it is still at that version.
+func (r *DealRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `DELETE FROM deals WHERE deal_id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
 	if err != nil {
 		return err
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM deals WHERE deal_id = $1)`, id, sql.ErrNoRows)
 	}
 	return nil
 }
@@ -114,7 +117,7 @@ func (r *DealRepo) GetEmployeeSalesReport(ctx context.Context) ([]EmployeeSalesR
 		ORDER BY
 			total_sales_amount DESC
 	`
-	err := r.db.SelectContext(ctx, &reportRows, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &reportRows, query)
 	return reportRows, err
 }
 
@@ -138,7 +141,7 @@ func (r *DealRepo) GetEmployeeSalesReportForUser(ctx context.Context, userID int
 		ORDER BY
 			total_sales_amount DESC
 	`
-	err := r.db.SelectContext(ctx, &reportRows, query, userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &reportRows, query, userID)
 	return reportRows, err
 }
 
@@ -173,7 +176,7 @@ func (r *DealRepo) GetSourceSalesReport(ctx context.Context) ([]SourceSalesRepor
 		ORDER BY
 			total_sales_amount DESC
 	`
-	err := r.db.SelectContext(ctx, &reportRows, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &reportRows, query)
 	return reportRows, err
 }
 
@@ -182,7 +185,7 @@ func (r *DealRepo) GetSourceSalesReport(ctx context.Context) ([]SourceSalesRepor
 func (r *LeadRepo) GetAllForUser(ctx context.Context, userID int) ([]models.Lead, error) {
 	var leads []models.Lead
 	query := `SELECT * FROM leads WHERE assigned_to = $1 ORDER BY created_at DESC`
-	err := r.db.SelectContext(ctx, &leads, query, userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &leads, query, userID)
 	return leads, err
 }
 
@@ -210,6 +213,6 @@ func (r *DealRepo) GetDealsPipelineReport(ctx context.Context) ([]DealsPipelineR
 		ORDER BY
 			ds.stage_id
 	`
-	err := r.db.SelectContext(ctx, &reportRows, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &reportRows, query)
 	return reportRows, err
 }
\ No newline at end of file
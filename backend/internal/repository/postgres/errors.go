@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNotFound is wrapped by the activity repositories (tasks, notes, events,
+// communication logs) when a record does not exist or has been deleted.
+var ErrNotFound = errors.New("not found")
+
+// IsForeignKeyViolation reports whether err is a Postgres foreign key
+// violation, e.g. deleting a row that other rows still reference.
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+    "context"
     "database/sql" // Add this import
     "fmt"
     "time"
@@ -9,6 +10,9 @@ import (
     "github.com/jmoiron/sqlx"
 )
 
+// eventExistsQuery reports whether a row exists and has not been soft deleted.
+const eventExistsQuery = `SELECT EXISTS(SELECT 1 FROM events WHERE event_id = $1 AND deleted_at IS NULL)`
+
 // EventRepo implements the EventRepository interface
 type EventRepo struct {
     db *sqlx.DB
@@ -19,16 +23,17 @@ func NewEventRepository(db *sqlx.DB) *EventRepo {
 }
 
 // CreateEvent creates a new event
-func (r *EventRepo) CreateEvent(event *models.Event) error {
+func (r *EventRepo) CreateEvent(ctx context.Context, event *models.Event) error {
     query := `
         INSERT INTO events (event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-        RETURNING event_id, updated_at
+        RETURNING event_id, updated_at, version
     `
 
     currentTime := time.Now()
     var updatedAt time.Time
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         event.EventName,
         event.EventDescription,
@@ -40,7 +45,7 @@ func (r *EventRepo) CreateEvent(event *models.Event) error {
         event.DealID,
         event.CreatedAt,
         currentTime,
-    ).Scan(&event.ID, &updatedAt)
+    ).Scan(&event.ID, &updatedAt, &event.Version)
 
     if err != nil {
         return fmt.Errorf("failed to create event: %w", err)
@@ -51,9 +56,9 @@ func (r *EventRepo) CreateEvent(event *models.Event) error {
 }
 
 // GetEventByID retrieves an event by ID
-func (r *EventRepo) GetEventByID(id int) (*models.Event, error) {
+func (r *EventRepo) GetEventByID(ctx context.Context, id int) (*models.Event, error) {
     query := `
-        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at, version
         FROM events
         WHERE event_id = $1 AND deleted_at IS NULL
     `
@@ -61,10 +66,10 @@ func (r *EventRepo) GetEventByID(id int) (*models.Event, error) {
     var event models.Event
     var updatedAt sql.NullTime
     var deletedAt sql.NullTime
-    err := r.db.Get(&event, query, id)
+    err := conn(ctx, r.db).GetContext(ctx, &event, query, id)
     if err != nil {
         if err == sql.ErrNoRows {
-            return nil, fmt.Errorf("event not found")
+            return nil, fmt.Errorf("event %w", ErrNotFound)
         }
         return nil, fmt.Errorf("failed to get event: %w", err)
     }
@@ -80,16 +85,16 @@ func (r *EventRepo) GetEventByID(id int) (*models.Event, error) {
 }
 
 // GetEventsByDealID retrieves all events for a specific deal
-func (r *EventRepo) GetEventsByDealID(dealID int) ([]models.Event, error) {
+func (r *EventRepo) GetEventsByDealID(ctx context.Context, dealID int) ([]models.Event, error) {
     query := `
-        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at, version
         FROM events
         WHERE deal_id = $1 AND deleted_at IS NULL
         ORDER BY start_time ASC
     `
 
     var events []models.Event
-    err := r.db.Select(&events, query, dealID)
+    err := conn(ctx, r.db).SelectContext(ctx, &events, query, dealID)
     if err != nil {
         return nil, fmt.Errorf("failed to get events by deal ID: %w", err)
     }
@@ -97,17 +102,35 @@ func (r *EventRepo) GetEventsByDealID(dealID int) ([]models.Event, error) {
     return events, nil
 }
 
+// GetEventsByLeadID retrieves all events for a specific lead
+func (r *EventRepo) GetEventsByLeadID(ctx context.Context, leadID int) ([]models.Event, error) {
+    query := `
+        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at, version
+        FROM events
+        WHERE lead_id = $1 AND deleted_at IS NULL
+        ORDER BY start_time ASC
+    `
+
+    var events []models.Event
+    err := conn(ctx, r.db).SelectContext(ctx, &events, query, leadID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get events by lead ID: %w", err)
+    }
+
+    return events, nil
+}
+
 // GetAllEvents retrieves all events (global)
-func (r *EventRepo) GetAllEvents() ([]models.Event, error) {
+func (r *EventRepo) GetAllEvents(ctx context.Context) ([]models.Event, error) {
     query := `
-        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at, version
         FROM events
         WHERE deleted_at IS NULL
         ORDER BY start_time ASC
     `
 
     var events []models.Event
-    err := r.db.Select(&events, query)
+    err := conn(ctx, r.db).SelectContext(ctx, &events, query)
     if err != nil {
         return nil, fmt.Errorf("failed to get all events: %w", err)
     }
@@ -116,17 +139,18 @@ func (r *EventRepo) GetAllEvents() ([]models.Event, error) {
 }
 
 // UpdateEvent updates an existing event
-func (r *EventRepo) UpdateEvent(event *models.Event) error {
+func (r *EventRepo) UpdateEvent(ctx context.Context, event *models.Event) error {
     query := `
         UPDATE events 
-        SET event_name = $1, event_description = $2, start_time = $3, end_time = $4, location = $5, organizer_id = $6, lead_id = $7, deal_id = $8, updated_at = $9
-        WHERE event_id = $10 AND deleted_at IS NULL
-        RETURNING updated_at
+        SET event_name = $1, event_description = $2, start_time = $3, end_time = $4, location = $5, organizer_id = $6, lead_id = $7, deal_id = $8, updated_at = $9, version = version + 1
+        WHERE event_id = $10 AND deleted_at IS NULL AND ($11 = 0 OR version = $11)
+        RETURNING updated_at, version
     `
 
     currentTime := time.Now()
     var updatedAt time.Time
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         event.EventName,
         event.EventDescription,
@@ -138,11 +162,12 @@ func (r *EventRepo) UpdateEvent(event *models.Event) error {
         event.DealID,
         currentTime,
         event.ID,
-    ).Scan(&updatedAt)
+        event.Version,
+    ).Scan(&updatedAt, &event.Version)
 
     if err != nil {
         if err == sql.ErrNoRows {
-            return fmt.Errorf("event not found")
+            return missingOrConflict(ctx, r.db, eventExistsQuery, event.ID, fmt.Errorf("event %w", ErrNotFound))
         }
         return fmt.Errorf("failed to update event: %w", err)
     }
@@ -151,15 +176,16 @@ func (r *EventRepo) UpdateEvent(event *models.Event) error {
     return nil
 }
 
-// DeleteEvent soft deletes an event
-func (r *EventRepo) DeleteEvent(id int) error {
+// DeleteEvent soft deletes an event. If expectedVersion is not 0 the event is
+// only deleted if it is still at that version.
+func (r *EventRepo) DeleteEvent(ctx context.Context, id int, expectedVersion int) error {
     query := `
         UPDATE events 
         SET deleted_at = $1
-        WHERE event_id = $2 AND deleted_at IS NULL
+        WHERE event_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
     `
 
-    result, err := r.db.Exec(query, time.Now(), id)
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), id, expectedVersion)
     if err != nil {
         return fmt.Errorf("failed to delete event: %w", err)
     }
@@ -170,23 +196,23 @@ func (r *EventRepo) DeleteEvent(id int) error {
     }
 
     if rowsAffected == 0 {
-        return fmt.Errorf("event not found")
+        return missingOrConflict(ctx, r.db, eventExistsQuery, id, fmt.Errorf("event %w", ErrNotFound))
     }
 
     return nil
 }
 
 // GetEventsForUser retrieves events for a specific user (organizer)
-func (r *EventRepo) GetEventsForUser(userID int) ([]models.Event, error) {
+func (r *EventRepo) GetEventsForUser(ctx context.Context, userID int) ([]models.Event, error) {
     query := `
-        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT event_id, event_name, event_description, start_time, end_time, location, organizer_id, lead_id, deal_id, created_at, updated_at, deleted_at, version
         FROM events
         WHERE organizer_id = $1 AND deleted_at IS NULL
         ORDER BY start_time ASC
     `
 
     var events []models.Event
-    err := r.db.Select(&events, query, userID)
+    err := conn(ctx, r.db).SelectContext(ctx, &events, query, userID)
     if err != nil {
         return nil, fmt.Errorf("failed to get events for user: %w", err)
     }
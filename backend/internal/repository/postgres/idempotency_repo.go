@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyRepo is a repository for stored Idempotency-Key responses.
+type IdempotencyRepo struct {
+	db *sqlx.DB
+}
+
+// NewIdempotencyRepo creates a new IdempotencyRepo.
+func NewIdempotencyRepo(db *sqlx.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+// Reserve claims a key for an in-progress request. An expired record with the
+// same key is replaced. It returns false if a live record already exists.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, rec models.IdempotencyRecord) (bool, error) {
+	query := `INSERT INTO idempotency_keys (user_id, idempotency_key, request_method, request_path, request_hash, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+				request_method = EXCLUDED.request_method,
+				request_path = EXCLUDED.request_path,
+				request_hash = EXCLUDED.request_hash,
+				status_code = NULL,
+				response_headers = NULL,
+				response_body = NULL,
+				created_at = NOW(),
+				expires_at = EXCLUDED.expires_at
+			  WHERE idempotency_keys.expires_at <= NOW()
+			  RETURNING true`
+	var reserved bool
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, rec.UserID, rec.Key, rec.Method, rec.Path, rec.RequestHash, rec.ExpiresAt).Scan(&reserved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return reserved, err
+}
+
+// Get returns the record for a key, or nil if there is none.
+func (r *IdempotencyRepo) Get(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	var rec models.IdempotencyRecord
+	query := `SELECT user_id, idempotency_key, request_method, request_path, request_hash, status_code,
+				response_headers, response_body, created_at, expires_at
+			  FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`
+	err := conn(ctx, r.db).GetContext(ctx, &rec, query, userID, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Complete stores the response of the request that reserved a key.
+func (r *IdempotencyRepo) Complete(ctx context.Context, userID int, key string, statusCode int, headers models.StoredHeaders, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $1, response_headers = $2, response_body = $3
+			  WHERE user_id = $4 AND idempotency_key = $5`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, statusCode, headers, body, userID, key)
+	return err
+}
+
+// Release removes an in-progress reservation so the request can be retried.
+func (r *IdempotencyRepo) Release(ctx context.Context, userID int, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID, key)
+	return err
+}
+
+// DeleteExpired removes all expired records and returns how many were removed.
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInvitationUnavailable is returned when an invitation has already been
+// used, revoked or has expired by the time it is redeemed.
+var ErrInvitationUnavailable = errors.New("invitation is no longer valid")
+
+// InvitationRepo is a repository for the invitations table.
+type InvitationRepo struct {
+	db *sqlx.DB
+}
+
+// NewInvitationRepo creates a new InvitationRepo.
+func NewInvitationRepo(db *sqlx.DB) *InvitationRepo {
+	return &InvitationRepo{db: db}
+}
+
+// Create inserts a new invitation and returns its ID.
+func (r *InvitationRepo) Create(ctx context.Context, inv models.Invitation) (int, error) {
+	var newID int
+	query := `INSERT INTO invitations (email, role_id, created_by, expires_at)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING invitation_id`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, inv.Email, inv.RoleID, inv.CreatedBy, inv.ExpiresAt).Scan(&newID)
+	return newID, err
+}
+
+// GetByID retrieves a single invitation. It returns nil, nil if it does not exist.
+func (r *InvitationRepo) GetByID(ctx context.Context, id int) (*models.Invitation, error) {
+	var inv models.Invitation
+	query := `SELECT invitation_id, email, role_id, created_by, expires_at, used_at, used_by, revoked_at, created_at
+			  FROM invitations
+			  WHERE invitation_id = $1`
+	err := conn(ctx, r.db).GetContext(ctx, &inv, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// GetAll retrieves all invitations, newest first.
+func (r *InvitationRepo) GetAll(ctx context.Context) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	query := `SELECT invitation_id, email, role_id, created_by, expires_at, used_at, used_by, revoked_at, created_at
+			  FROM invitations
+			  ORDER BY created_at DESC`
+	err := conn(ctx, r.db).SelectContext(ctx, &invitations, query)
+	return invitations, err
+}
+
+// Revoke marks an unused invitation as revoked.
+func (r *InvitationRepo) Revoke(ctx context.Context, id int) error {
+	query := `UPDATE invitations SET revoked_at = NOW()
+			  WHERE invitation_id = $1 AND used_at IS NULL AND revoked_at IS NULL`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Redeem creates the user and marks the invitation as used in one transaction,
+// or as part of the caller's unit of work if ctx carries one.
+// The invitation row is locked so the same invite cannot be redeemed twice.
+func (r *InvitationRepo) Redeem(ctx context.Context, invitationID int, user models.User) (int, error) {
+	var newUserID int
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+
+		var available bool
+		query := `SELECT used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+				  FROM invitations
+				  WHERE invitation_id = $1
+				  FOR UPDATE`
+		if err := tx.GetContext(ctx, &available, query, invitationID); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvitationUnavailable
+			}
+			return err
+		}
+		if !available {
+			return ErrInvitationUnavailable
+		}
+
+		query = `INSERT INTO users (username, password_hash, email, role_id)
+				 VALUES ($1, $2, $3, $4)
+				 RETURNING user_id`
+		if err := tx.QueryRowxContext(ctx, query, user.Username, user.PasswordHash, user.Email, user.RoleID).Scan(&newUserID); err != nil {
+			return err
+		}
+
+		query = `UPDATE invitations SET used_at = NOW(), used_by = $1 WHERE invitation_id = $2`
+		_, err := tx.ExecContext(ctx, query, newUserID, invitationID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newUserID, nil
+}
@@ -21,7 +21,7 @@ func (r *LeadRepo) Create(ctx context.Context, l models.Lead) (int, error) {
 	var newID int
 	query := `INSERT INTO leads (contact_id, property_id, source_id, status_id, assigned_to, notes)
 			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING lead_id`
-	err := r.db.QueryRowxContext(ctx, query, l.ContactID, l.PropertyID, l.SourceID, l.StatusID, l.AssignedTo, l.Notes).Scan(&newID)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, l.ContactID, l.PropertyID, l.SourceID, l.StatusID, l.AssignedTo, l.Notes).Scan(&newID)
 	if err != nil {
 		return 0, err
 	}
@@ -31,14 +31,14 @@ func (r *LeadRepo) Create(ctx context.Context, l models.Lead) (int, error) {
 func (r *LeadRepo) GetAll(ctx context.Context,) ([]models.Lead, error) {
 	var leads []models.Lead
 	query := `SELECT * FROM leads ORDER BY created_at DESC`
-	err := r.db.SelectContext(ctx, &leads, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &leads, query)
 	return leads, err
 }
 
 func (r *LeadRepo) GetByID(ctx context.Context, id int) (*models.Lead, error) {
 	var lead models.Lead
 	query := `SELECT * FROM leads WHERE lead_id = $1`
-	err := r.db.GetContext(ctx, &lead, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &lead, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -56,28 +56,31 @@ func (r *LeadRepo) Update(ctx context.Context, l models.Lead) error {
 				status_id = $4,
 				assigned_to = $5,
 				notes = $6,
+				version = version + 1,
 				updated_at = NOW()
-			  WHERE lead_id = $7`
-	result, err := r.db.ExecContext(ctx, query, l.ContactID, l.PropertyID, l.SourceID, l.StatusID, l.AssignedTo, l.Notes, l.ID)
+			  WHERE lead_id = $7 AND ($8 = 0 OR version = $8)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, l.ContactID, l.PropertyID, l.SourceID, l.StatusID, l.AssignedTo, l.Notes, l.ID, l.Version)
 	if err != nil {
 		return err
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM leads WHERE lead_id = $1)`, l.ID, sql.ErrNoRows)
 	}
 	return nil
 }
 
-func (r *LeadRepo) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM leads WHERE lead_id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+// Delete removes a lead. If expectedVersion is not 0 the lead is only deleted
+// if it is still at that version.
+func (r *LeadRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `DELETE FROM leads WHERE lead_id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
 	if err != nil {
 		return err
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM leads WHERE lead_id = $1)`, id, sql.ErrNoRows)
 	}
 	return nil
 }
@@ -112,7 +115,7 @@ func (r *LeadRepo) GetLeadCountsByUserID(ctx context.Context, userID int) (*Lead
 		WHERE l.assigned_to = $1
 	`
 	// Use the context-aware GetContext method
-	err := r.db.GetContext(ctx, &counts, query, userID)
+	err := conn(ctx, r.db).GetContext(ctx, &counts, query, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return &LeadStatusCounts{}, nil
@@ -126,6 +129,28 @@ func (r *LeadRepo) GetLeadCountsByUserID(ctx context.Context, userID int) (*Lead
 
 
 
+// LeadStatusCountRow is the number of leads in one status.
+type LeadStatusCountRow struct {
+	Status string `db:"status"`
+	Count  int    `db:"lead_count"`
+}
+
+// GetOpenLeadCountsByStatus counts the leads in every status except Converted
+// and Lost. Statuses without leads are included with a count of 0.
+func (r *LeadRepo) GetOpenLeadCountsByStatus(ctx context.Context) ([]LeadStatusCountRow, error) {
+	var rows []LeadStatusCountRow
+	query := `
+		SELECT ls.name AS status, COUNT(l.lead_id) AS lead_count
+		FROM lead_statuses ls
+		LEFT JOIN leads l ON l.status_id = ls.status_id
+		WHERE ls.name NOT IN ('Converted', 'Lost')
+		GROUP BY ls.status_id, ls.name
+		ORDER BY ls.status_id
+	`
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query)
+	return rows, err
+}
+
 // Add this new struct to lead_repo.go to hold the rich report data
 type SourceLeadReportRow struct {
 	LeadDate         time.Time `db:"lead_date" json:"lead_date"`
@@ -164,7 +189,7 @@ func (r *LeadRepo) GetSourceLeadReport(ctx context.Context) ([]SourceLeadReportR
 			l.created_at DESC
 	`
 	// In a real app, you would add WHERE clauses here for date filtering.
-	err := r.db.SelectContext(ctx, &reportRows, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &reportRows, query)
 	return reportRows, err
 }
 
@@ -180,13 +205,13 @@ func (r *LeadRepo) CheckForOpenLeadByContactID(ctx context.Context, contactID in
             WHERE l.contact_id = $1 AND ls.name NOT IN ('Converted', 'Lost')
         )
     `
-    err := r.db.GetContext(ctx, &exists, query, contactID)
+    err := conn(ctx, r.db).GetContext(ctx, &exists, query, contactID)
     return exists, err
 }
 
 func (r *LeadRepo) GetAllLeadsForUser(ctx context.Context, userID int) ([]models.Lead, error) {
 	var leads []models.Lead
 	query := `SELECT * FROM leads WHERE assigned_to = $1 ORDER BY created_at DESC`
-	err := r.db.SelectContext(ctx, &leads, query, userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &leads, query, userID)
 	return leads, err
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+    "context"
     "database/sql"
     "fmt"
     "time"
@@ -9,6 +10,9 @@ import (
     "github.com/jmoiron/sqlx"
 )
 
+// noteExistsQuery reports whether a row exists and has not been soft deleted.
+const noteExistsQuery = `SELECT EXISTS(SELECT 1 FROM notes WHERE note_id = $1 AND deleted_at IS NULL)`
+
 // NoteRepo implements NoteRepository interface
 type NoteRepo struct {
     db *sqlx.DB
@@ -19,15 +23,16 @@ func NewNoteRepository(db *sqlx.DB) *NoteRepo {
 }
 
 // CreateNote creates a new note
-func (r *NoteRepo) CreateNote(note *models.Note) error {
+func (r *NoteRepo) CreateNote(ctx context.Context, note *models.Note) error {
     query := `
         INSERT INTO notes (user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at)
         VALUES ($1, $2, $3, $4, $5, $6, $7)
-        RETURNING note_id
+        RETURNING note_id, version
     `
 
     currentTime := time.Now()
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         note.UserID,
         note.ContactID,
@@ -36,7 +41,7 @@ func (r *NoteRepo) CreateNote(note *models.Note) error {
         note.Content,
         currentTime,
         currentTime,
-    ).Scan(&note.ID)
+    ).Scan(&note.ID, &note.Version)
 
     if err != nil {
         return fmt.Errorf("failed to create note: %w", err)
@@ -49,19 +54,19 @@ func (r *NoteRepo) CreateNote(note *models.Note) error {
 }
 
 // GetNoteByID retrieves a note by its ID
-func (r *NoteRepo) GetNoteByID(id int) (*models.Note, error) {
+func (r *NoteRepo) GetNoteByID(ctx context.Context, id int) (*models.Note, error) {
     query := `
-        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at
+        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at, version
         FROM notes
         WHERE note_id = $1 AND deleted_at IS NULL
     `
 
     var note models.Note
-    err := r.db.Get(&note, query, id)
+    err := conn(ctx, r.db).GetContext(ctx, &note, query, id)
 
     if err != nil {
         if err == sql.ErrNoRows {
-            return nil, fmt.Errorf("note not found")
+            return nil, fmt.Errorf("note %w", ErrNotFound)
         }
         return nil, fmt.Errorf("failed to get note: %w", err)
     }
@@ -70,16 +75,16 @@ func (r *NoteRepo) GetNoteByID(id int) (*models.Note, error) {
 }
 
 // GetNotesByContactID retrieves all notes for a specific contact
-func (r *NoteRepo) GetNotesByContactID(contactID int) ([]models.Note, error) {
+func (r *NoteRepo) GetNotesByContactID(ctx context.Context, contactID int) ([]models.Note, error) {
     query := `
-        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at
+        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at, version
         FROM notes
         WHERE contact_id = $1 AND deleted_at IS NULL
         ORDER BY created_at DESC
     `
 
     var notes []models.Note
-    err := r.db.Select(&notes, query, contactID)
+    err := conn(ctx, r.db).SelectContext(ctx, &notes, query, contactID)
 
     if err != nil {
         return nil, fmt.Errorf("failed to get notes by contact ID: %w", err)
@@ -89,27 +94,29 @@ func (r *NoteRepo) GetNotesByContactID(contactID int) ([]models.Note, error) {
 }
 
 // UpdateNote updates an existing note
-func (r *NoteRepo) UpdateNote(note *models.Note) error {
+func (r *NoteRepo) UpdateNote(ctx context.Context, note *models.Note) error {
     query := `
         UPDATE notes 
-        SET note_text = $1, updated_at = $2
-        WHERE note_id = $3 AND deleted_at IS NULL
-        RETURNING updated_at
+        SET note_text = $1, updated_at = $2, version = version + 1
+        WHERE note_id = $3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)
+        RETURNING updated_at, version
     `
 
     currentTime := time.Now()
     var updatedAt time.Time
 
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         note.Content,
         currentTime,
         note.ID,
-    ).Scan(&updatedAt)
+        note.Version,
+    ).Scan(&updatedAt, &note.Version)
 
     if err != nil {
         if err == sql.ErrNoRows {
-            return fmt.Errorf("note not found")
+            return missingOrConflict(ctx, r.db, noteExistsQuery, note.ID, fmt.Errorf("note %w", ErrNotFound))
         }
         return fmt.Errorf("failed to update note: %w", err)
     }
@@ -118,15 +125,16 @@ func (r *NoteRepo) UpdateNote(note *models.Note) error {
     return nil
 }
 
-// DeleteNote soft deletes a note
-func (r *NoteRepo) DeleteNote(id int) error {
+// DeleteNote soft deletes a note. If expectedVersion is not 0 the note is only
+// deleted if it is still at that version.
+func (r *NoteRepo) DeleteNote(ctx context.Context, id int, expectedVersion int) error {
     query := `
         UPDATE notes 
         SET deleted_at = $1
-        WHERE note_id = $2 AND deleted_at IS NULL
+        WHERE note_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
     `
 
-    result, err := r.db.Exec(query, time.Now(), id)
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), id, expectedVersion)
     if err != nil {
         return fmt.Errorf("failed to delete note: %w", err)
     }
@@ -137,23 +145,23 @@ func (r *NoteRepo) DeleteNote(id int) error {
     }
 
     if rowsAffected == 0 {
-        return fmt.Errorf("note not found")
+        return missingOrConflict(ctx, r.db, noteExistsQuery, id, fmt.Errorf("note %w", ErrNotFound))
     }
 
     return nil
 }
 
 // GetNotesByUserID retrieves all notes created by a specific user
-func (r *NoteRepo) GetNotesByUserID(userID int) ([]models.Note, error) {
+func (r *NoteRepo) GetNotesByUserID(ctx context.Context, userID int) ([]models.Note, error) {
     query := `
-        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at
+        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at, version
         FROM notes
         WHERE user_id = $1 AND deleted_at IS NULL
         ORDER BY created_at DESC
     `
 
     var notes []models.Note
-    err := r.db.Select(&notes, query, userID)
+    err := conn(ctx, r.db).SelectContext(ctx, &notes, query, userID)
 
     if err != nil {
         return nil, fmt.Errorf("failed to get notes by user ID: %w", err)
@@ -163,16 +171,16 @@ func (r *NoteRepo) GetNotesByUserID(userID int) ([]models.Note, error) {
 }
 
 // GetNotesByDealID retrieves all notes for a specific deal
-func (r *NoteRepo) GetNotesByDealID(dealID int) ([]models.Note, error) {
+func (r *NoteRepo) GetNotesByDealID(ctx context.Context, dealID int) ([]models.Note, error) {
     query := `
-        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at
+        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at, version
         FROM notes
         WHERE deal_id = $1 AND deleted_at IS NULL
         ORDER BY created_at DESC
     `
 
     var notes []models.Note
-    err := r.db.Select(&notes, query, dealID)
+    err := conn(ctx, r.db).SelectContext(ctx, &notes, query, dealID)
 
     if err != nil {
         return nil, fmt.Errorf("failed to get notes by deal ID: %w", err)
@@ -181,20 +189,21 @@ func (r *NoteRepo) GetNotesByDealID(dealID int) ([]models.Note, error) {
     return notes, nil
 }
 
-// CreateDealNote creates a new note for a deal
-func (r *NoteRepo) CreateDealNote(note *models.Note) error {
-    if note.DealID == nil {
-        return fmt.Errorf("deal ID is required")
+// GetNotesByLeadID retrieves all notes for a specific lead
+func (r *NoteRepo) GetNotesByLeadID(ctx context.Context, leadID int) ([]models.Note, error) {
+    query := `
+        SELECT note_id, user_id, contact_id, lead_id, deal_id, note_text, created_at, updated_at, deleted_at, version
+        FROM notes
+        WHERE lead_id = $1 AND deleted_at IS NULL
+        ORDER BY created_at DESC
+    `
+
+    var notes []models.Note
+    err := conn(ctx, r.db).SelectContext(ctx, &notes, query, leadID)
+
+    if err != nil {
+        return nil, fmt.Errorf("failed to get notes by lead ID: %w", err)
     }
-    return r.CreateNote(note)
-}
 
-// UpdateDealNote updates a deal note
-func (r *NoteRepo) UpdateDealNote(note *models.Note) error {
-    return r.UpdateNote(note)
+    return notes, nil
 }
-
-// DeleteDealNote deletes a deal note
-func (r *NoteRepo) DeleteDealNote(id int) error {
-    return r.DeleteNote(id)
-}
\ No newline at end of file
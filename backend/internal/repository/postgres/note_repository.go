@@ -1,19 +1,23 @@
 package postgres
 
-import "crm-project/internal/models"
+import (
+	"context"
+
+	"crm-project/internal/models"
+)
 
 // NoteRepository defines the interface for note data operations
 type NoteRepository interface {
-	CreateNote(note *models.Note) error
-	GetNoteByID(id int) (*models.Note, error)
-	GetNotesByContactID(contactID int) ([]models.Note, error)
-	GetNotesByUserID(userID int) ([]models.Note, error)
-	UpdateNote(note *models.Note) error
-	DeleteNote(id int) error
+	CreateNote(ctx context.Context, note *models.Note) error
+	GetNoteByID(ctx context.Context, id int) (*models.Note, error)
+	GetNotesByContactID(ctx context.Context, contactID int) ([]models.Note, error)
+	GetNotesByUserID(ctx context.Context, userID int) ([]models.Note, error)
+	UpdateNote(ctx context.Context, note *models.Note) error
+	DeleteNote(ctx context.Context, id int, expectedVersion int) error
 
 	// --- Deal-specific methods ---
-	GetNotesByDealID(dealID int) ([]models.Note, error)
-	CreateDealNote(note *models.Note) error
-	UpdateDealNote(note *models.Note) error
-	DeleteDealNote(id int) error
+	GetNotesByDealID(ctx context.Context, dealID int) ([]models.Note, error)
+
+	// --- Lead-specific methods ---
+	GetNotesByLeadID(ctx context.Context, leadID int) ([]models.Note, error)
 }
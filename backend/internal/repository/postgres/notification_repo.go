@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NotificationRepo is a repository for the notifications table.
+type NotificationRepo struct {
+	db *sqlx.DB
+}
+
+// NewNotificationRepo creates a new NotificationRepo.
+func NewNotificationRepo(db *sqlx.DB) *NotificationRepo {
+	return &NotificationRepo{db: db}
+}
+
+// Create inserts a notification and fills in its ID and creation time. If
+// n.DedupKey is set and a notification with that key exists it returns false
+// and leaves n untouched.
+func (r *NotificationRepo) Create(ctx context.Context, n *models.Notification) (bool, error) {
+	query := `INSERT INTO notifications (user_id, kind, title, body, task_id, dedup_key)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (dedup_key) DO NOTHING
+			  RETURNING notification_id, created_at`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, n.UserID, n.Kind, n.Title, n.Body, n.TaskID, n.DedupKey).
+		Scan(&n.ID, &n.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetAllForUser returns a user's most recent notifications, newest first.
+func (r *NotificationRepo) GetAllForUser(ctx context.Context, userID int, unreadOnly bool, limit int) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	query := `SELECT notification_id, user_id, kind, title, body, task_id, dedup_key, read_at, created_at
+			  FROM notifications
+			  WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+			  ORDER BY created_at DESC, notification_id DESC
+			  LIMIT $3`
+	err := conn(ctx, r.db).SelectContext(ctx, &notifications, query, userID, unreadOnly, limit)
+	return notifications, err
+}
+
+// MarkRead marks one of a user's notifications as read. Marking it again is
+// not an error. It returns sql.ErrNoRows if the user has no such notification.
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID, id int) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+			  WHERE notification_id = $1 AND user_id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user as read and returns
+// how many there were.
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const playbookExistsQuery = `SELECT EXISTS(SELECT 1 FROM playbooks WHERE playbook_id = $1)`
+
+const playbookColumns = `playbook_id, name, deal_stage_id, lead_status_id, active, COALESCE(created_by, 0) AS created_by,
+				created_at, updated_at, version`
+
+// PlaybookRepo is a repository for task playbooks, their task templates and
+// the record of which deals and leads they have run for.
+type PlaybookRepo struct {
+	db *sqlx.DB
+}
+
+// NewPlaybookRepo creates a new PlaybookRepo.
+func NewPlaybookRepo(db *sqlx.DB) *PlaybookRepo {
+	return &PlaybookRepo{db: db}
+}
+
+// Create inserts a playbook with its task templates and fills in their IDs,
+// timestamps and version. Call it in a transaction so a failed template
+// does not leave the playbook half created.
+func (r *PlaybookRepo) Create(ctx context.Context, p *models.Playbook) error {
+	query := `INSERT INTO playbooks (name, deal_stage_id, lead_status_id, active, created_by)
+			  VALUES ($1, $2, $3, $4, NULLIF($5, 0))
+			  RETURNING playbook_id, created_at, updated_at, version`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, p.Name, p.DealStageID, p.LeadStatusID, p.Active, p.CreatedBy).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt, &p.Version)
+	if err != nil {
+		return err
+	}
+	return r.insertTasks(ctx, p)
+}
+
+// GetByID returns a playbook with its task templates, or nil if there is
+// none.
+func (r *PlaybookRepo) GetByID(ctx context.Context, id int) (*models.Playbook, error) {
+	playbooks, err := r.selectPlaybooks(ctx, `WHERE playbook_id = $1`, id)
+	if err != nil || len(playbooks) == 0 {
+		return nil, err
+	}
+	return &playbooks[0], nil
+}
+
+// GetAll returns every playbook with its task templates, ordered by name.
+func (r *PlaybookRepo) GetAll(ctx context.Context) ([]models.Playbook, error) {
+	return r.selectPlaybooks(ctx, ``)
+}
+
+// GetActiveForDealStage returns the active playbooks triggered by a deal
+// entering a stage.
+func (r *PlaybookRepo) GetActiveForDealStage(ctx context.Context, stageID int) ([]models.Playbook, error) {
+	return r.selectPlaybooks(ctx, `WHERE active AND deal_stage_id = $1`, stageID)
+}
+
+// GetActiveForLeadStatus returns the active playbooks triggered by a lead
+// reaching a status.
+func (r *PlaybookRepo) GetActiveForLeadStatus(ctx context.Context, statusID int) ([]models.Playbook, error) {
+	return r.selectPlaybooks(ctx, `WHERE active AND lead_status_id = $1`, statusID)
+}
+
+// Update saves a playbook and replaces its task templates. If p.Version is
+// not 0 the playbook is only updated if it is still at that version. Call it
+// in a transaction.
+func (r *PlaybookRepo) Update(ctx context.Context, p *models.Playbook) error {
+	query := `UPDATE playbooks SET name = $1, deal_stage_id = $2, lead_status_id = $3, active = $4,
+				updated_at = NOW(), version = version + 1
+			  WHERE playbook_id = $5 AND ($6 = 0 OR version = $6)
+			  RETURNING created_at, updated_at, version`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, p.Name, p.DealStageID, p.LeadStatusID, p.Active, p.ID, p.Version).
+		Scan(&p.CreatedAt, &p.UpdatedAt, &p.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return missingOrConflict(ctx, r.db, playbookExistsQuery, p.ID, fmt.Errorf("playbook %w", ErrNotFound))
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM playbook_tasks WHERE playbook_id = $1`, p.ID); err != nil {
+		return err
+	}
+	return r.insertTasks(ctx, p)
+}
+
+// Delete removes a playbook. Tasks it created are kept. If expectedVersion
+// is not 0 the playbook is only deleted if it is still at that version.
+func (r *PlaybookRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `DELETE FROM playbooks WHERE playbook_id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return missingOrConflict(ctx, r.db, playbookExistsQuery, id, fmt.Errorf("playbook %w", ErrNotFound))
+	}
+	return nil
+}
+
+// StartRun records that a playbook runs for a deal or a lead (exactly one of
+// dealID and leadID is set). It returns false if it has run for it before.
+func (r *PlaybookRepo) StartRun(ctx context.Context, playbookID int, dealID, leadID *int) (bool, error) {
+	var query string
+	if dealID != nil {
+		query = `INSERT INTO playbook_runs (playbook_id, deal_id, lead_id) VALUES ($1, $2, $3)
+				 ON CONFLICT (playbook_id, deal_id) WHERE deal_id IS NOT NULL DO NOTHING`
+	} else {
+		query = `INSERT INTO playbook_runs (playbook_id, deal_id, lead_id) VALUES ($1, $2, $3)
+				 ON CONFLICT (playbook_id, lead_id) WHERE lead_id IS NOT NULL DO NOTHING`
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, playbookID, dealID, leadID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// selectPlaybooks returns the playbooks matching where, ordered by name, with
+// their task templates.
+func (r *PlaybookRepo) selectPlaybooks(ctx context.Context, where string, args ...any) ([]models.Playbook, error) {
+	playbooks := []models.Playbook{}
+	query := `SELECT ` + playbookColumns + ` FROM playbooks ` + where + ` ORDER BY name, playbook_id`
+	if err := conn(ctx, r.db).SelectContext(ctx, &playbooks, query, args...); err != nil {
+		return nil, err
+	}
+	if len(playbooks) == 0 {
+		return playbooks, nil
+	}
+
+	var tasks []models.PlaybookTask
+	query = `SELECT playbook_task_id, playbook_id, task_name, task_description, due_in_days, assign_to, position
+			 FROM playbook_tasks
+			 WHERE playbook_id IN (SELECT playbook_id FROM playbooks ` + where + `)
+			 ORDER BY position, playbook_task_id`
+	if err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, args...); err != nil {
+		return nil, err
+	}
+	byPlaybook := make(map[int][]models.PlaybookTask, len(playbooks))
+	for _, t := range tasks {
+		byPlaybook[t.PlaybookID] = append(byPlaybook[t.PlaybookID], t)
+	}
+	for i := range playbooks {
+		playbooks[i].Tasks = byPlaybook[playbooks[i].ID]
+		if playbooks[i].Tasks == nil {
+			playbooks[i].Tasks = []models.PlaybookTask{}
+		}
+	}
+	return playbooks, nil
+}
+
+// insertTasks inserts the task templates of p.
+func (r *PlaybookRepo) insertTasks(ctx context.Context, p *models.Playbook) error {
+	query := `INSERT INTO playbook_tasks (playbook_id, task_name, task_description, due_in_days, assign_to, position)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING playbook_task_id`
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		t.PlaybookID = p.ID
+		err := conn(ctx, r.db).QueryRowxContext(ctx, query, p.ID, t.TaskName, t.TaskDescription, t.DueInDays, t.AssignTo, t.Position).
+			Scan(&t.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ func (r *PropertyRepo) Create(ctx context.Context, p models.Property) (int, erro
 	var newID int
 	query := `INSERT INTO properties (name, site_id, property_type_id, unit_no, size_sqft, price, status)
 			  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING property_id`
-	err := r.db.QueryRowxContext(ctx, query, p.Name, p.SiteID, p.PropertyTypeID, p.UnitNo, p.SizeSqft, p.Price, p.Status).Scan(&newID)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, p.Name, p.SiteID, p.PropertyTypeID, p.UnitNo, p.SizeSqft, p.Price, p.Status).Scan(&newID)
 	return newID, err
 }
 
@@ -29,7 +29,7 @@ func (r *PropertyRepo) Create(ctx context.Context, p models.Property) (int, erro
 func (r *PropertyRepo) GetAll(ctx context.Context,) ([]models.Property, error) {
 	var properties []models.Property
 	query := `SELECT * FROM properties ORDER BY created_at DESC`
-	err := r.db.SelectContext(ctx, &properties, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &properties, query)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +40,7 @@ func (r *PropertyRepo) GetAll(ctx context.Context,) ([]models.Property, error) {
 func (r *PropertyRepo) GetByID(ctx context.Context, id int) (*models.Property, error) {
 	var property models.Property
 	query := `SELECT * FROM properties WHERE property_id = $1`
-	err := r.db.GetContext(ctx, &property, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &property, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -60,9 +60,10 @@ func (r *PropertyRepo) Update(ctx context.Context, p models.Property) error {
 				size_sqft = $5,
 				price = $6,
 				status = $7,
+				version = version + 1,
 				updated_at = NOW()
-			  WHERE property_id = $8`
-	result, err := r.db.ExecContext(ctx, query, p.Name, p.SiteID, p.PropertyTypeID, p.UnitNo, p.SizeSqft, p.Price, p.Status, p.ID)
+			  WHERE property_id = $8 AND ($9 = 0 OR version = $9)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, p.Name, p.SiteID, p.PropertyTypeID, p.UnitNo, p.SizeSqft, p.Price, p.Status, p.ID, p.Version)
 	if err != nil {
 		return err
 	}
@@ -71,15 +72,16 @@ func (r *PropertyRepo) Update(ctx context.Context, p models.Property) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM properties WHERE property_id = $1)`, p.ID, sql.ErrNoRows)
 	}
 	return nil
 }
 
-// Delete removes a property from the database by its ID.
-func (r *PropertyRepo) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM properties WHERE property_id = $1`
-	result, err := r.db.ExecContext(ctx,query, id)
+// Delete removes a property from the database by its ID. If expectedVersion
+// is not 0 the property is only deleted if it is still at that version.
+func (r *PropertyRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `DELETE FROM properties WHERE property_id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := conn(ctx, r.db).ExecContext(ctx,query, id, expectedVersion)
 	if err != nil {
 		return err
 	}
@@ -88,7 +90,7 @@ func (r *PropertyRepo) Delete(ctx context.Context, id int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return missingOrConflict(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM properties WHERE property_id = $1)`, id, sql.ErrNoRows)
 	}
 	return nil
 }
@@ -97,7 +99,7 @@ func (r *PropertyRepo) Delete(ctx context.Context, id int) error {
 func (r *PropertyRepo) SiteExists(ctx context.Context, siteID int) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM sites WHERE site_id = $1)`
-	err := r.db.GetContext(ctx, &exists, query, siteID)
+	err := conn(ctx, r.db).GetContext(ctx, &exists, query, siteID)
 	if err != nil {
 		return false, err
 	}
@@ -108,7 +110,7 @@ func (r *PropertyRepo) SiteExists(ctx context.Context, siteID int) (bool, error)
 func (r *PropertyRepo) PropertyTypeExists(ctx context.Context, propertyTypeID int) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM property_types WHERE property_type_id = $1)`
-	err := r.db.GetContext(ctx, &exists, query, propertyTypeID)
+	err := conn(ctx, r.db).GetContext(ctx, &exists, query, propertyTypeID)
 	if err != nil {
 		return false, err
 	}
@@ -130,6 +132,6 @@ func (r *PropertyRepo) IsPropertyInOpenLeadOrDeal(ctx context.Context, propertyI
 			WHERE d.property_id = $1 AND d.deal_status NOT IN ('Closed-Won', 'Closed-Lost')
 		)
 	`
-	err := r.db.GetContext(ctx, &exists, query, propertyID)
+	err := conn(ctx, r.db).GetContext(ctx, &exists, query, propertyID)
 	return exists, err
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"crm-project/internal/ratelimit"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RateLimitRepo is a ratelimit.Store backed by the rate_limit_buckets table,
+// so every server instance draws from the same buckets.
+type RateLimitRepo struct {
+	db *sqlx.DB
+}
+
+// NewRateLimitRepo creates a new RateLimitRepo.
+func NewRateLimitRepo(db *sqlx.DB) *RateLimitRepo {
+	return &RateLimitRepo{db: db}
+}
+
+var _ ratelimit.Store = (*RateLimitRepo)(nil)
+
+// Take refills and takes a token from the bucket for key in one statement; the
+// row lock taken by the upsert serializes concurrent requests for a key.
+func (r *RateLimitRepo) Take(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+	// $2 is the capacity and $3 the refill rate per second. SET expressions see
+	// the row as it was before the update, so refill is the bucket refilled up
+	// to now.
+	const refill = `LEAST($2::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at) * $3::float8)`
+	query := `INSERT INTO rate_limit_buckets (bucket_key, tokens, allowed, updated_at)
+			  VALUES ($1, $2::float8 - 1, true, NOW())
+			  ON CONFLICT (bucket_key) DO UPDATE SET
+				tokens = CASE WHEN ` + refill + ` >= 1 THEN ` + refill + ` - 1 ELSE ` + refill + ` END,
+				allowed = ` + refill + ` >= 1,
+				updated_at = NOW()
+			  RETURNING tokens, allowed`
+	var row struct {
+		Tokens  float64 `db:"tokens"`
+		Allowed bool    `db:"allowed"`
+	}
+	if err := conn(ctx, r.db).GetContext(ctx, &row, query, key, l.Burst, l.Rate); err != nil {
+		return ratelimit.Result{}, err
+	}
+	return ratelimit.NewResult(l, row.Tokens, row.Allowed), nil
+}
+
+// DeleteIdle removes buckets untouched for longer than idle, which must be at
+// least the longest time a bucket takes to refill. It returns how many were
+// removed.
+func (r *RateLimitRepo) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - make_interval(secs => $1)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, idle.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -1,42 +1,251 @@
 package postgres
 
 import (
+	"context"
+	"time"
+
 	"crm-project/internal/models"
 )
 
 // EventRepository defines the interface for event data access
 type EventRepository interface {
-	CreateEvent(event *models.Event) error
-	GetEventByID(id int) (*models.Event, error)
-	GetEventsByDealID(dealID int) ([]models.Event, error)
-	GetAllEvents() ([]models.Event, error)
-	UpdateEvent(event *models.Event) error
-	DeleteEvent(id int) error
-	GetEventsForUser(userID int) ([]models.Event, error)
+	CreateEvent(ctx context.Context, event *models.Event) error
+	GetEventByID(ctx context.Context, id int) (*models.Event, error)
+	GetEventsByDealID(ctx context.Context, dealID int) ([]models.Event, error)
+	GetEventsByLeadID(ctx context.Context, leadID int) ([]models.Event, error)
+	GetAllEvents(ctx context.Context) ([]models.Event, error)
+	UpdateEvent(ctx context.Context, event *models.Event) error
+	DeleteEvent(ctx context.Context, id int, expectedVersion int) error
+	GetEventsForUser(ctx context.Context, userID int) ([]models.Event, error)
 }
 
 type TaskRepository interface {
-    CreateTask(task *models.Task) error
-    GetTaskByID(id int) (*models.Task, error)
-    GetTasksByDealID(dealID int) ([]models.Task, error)
-    GetAllTasks() ([]models.Task, error)
-    UpdateTask(task *models.Task) error
-    DeleteTask(id int) error
-    GetTasksForUser(userID int) ([]models.Task, error)
-    GetTasksByDealIDForUser(dealID int, userID int) ([]models.Task, error)
+    CreateTask(ctx context.Context, task *models.Task) error
+    GetTaskByID(ctx context.Context, id int) (*models.Task, error)
+    GetTasksByDealID(ctx context.Context, dealID int) ([]models.Task, error)
+    GetAllTasks(ctx context.Context) ([]models.Task, error)
+    UpdateTask(ctx context.Context, task *models.Task) error
+    DeleteTask(ctx context.Context, id int, expectedVersion int) error
+    GetTasksForUser(ctx context.Context, userID int) ([]models.Task, error)
+    GetTasksByDealIDForUser(ctx context.Context, dealID int, userID int) ([]models.Task, error)
+    GetTasksByLeadID(ctx context.Context, leadID int) ([]models.Task, error)
+    GetTasksByLeadIDForUser(ctx context.Context, leadID int, userID int) ([]models.Task, error)
 }
 
-// CommLogRepository defines the interface for communication log data access
+// TaskSeriesRepository defines the interface for recurring task series
+type TaskSeriesRepository interface {
+	Create(ctx context.Context, ts *models.TaskSeries) error
+	GetByID(ctx context.Context, id int) (*models.TaskSeries, error)
+	Update(ctx context.Context, ts *models.TaskSeries) error
+	End(ctx context.Context, id int) error
+	CreateOccurrence(ctx context.Context, task *models.Task) (bool, error)
+}
+
+// TaskReminderRepository defines the task queries of the reminder scheduler
+type TaskReminderRepository interface {
+	GetOpenTasksDueBefore(ctx context.Context, before time.Time) ([]models.Task, error)
+	MarkOverdue(ctx context.Context, now time.Time) ([]models.Task, error)
+}
 
+// TaskBulkRepository defines the task selection queries of bulk operations
+type TaskBulkRepository interface {
+	GetByIDs(ctx context.Context, ids []int) ([]models.Task, error)
+	FindIDs(ctx context.Context, f models.TaskFilter, limit int) ([]int, error)
+}
+
+// TaskSLARepository defines the task queries of the SLA scheduler and the SLA breach report
+type TaskSLARepository interface {
+	GetUnbreachedOpenTasks(ctx context.Context, taskType string, createdBefore time.Time) ([]models.Task, error)
+	RecordBreach(ctx context.Context, b *models.TaskSLABreach) (bool, error)
+	Reassign(ctx context.Context, taskID, from, to int) (*models.Task, error)
+	GetBreachReport(ctx context.Context) ([]models.SLABreachReportRow, error)
+}
+
+// NotificationRepository defines the interface for in-app notification data access
+type NotificationRepository interface {
+	Create(ctx context.Context, n *models.Notification) (bool, error)
+	GetAllForUser(ctx context.Context, userID int, unreadOnly bool, limit int) ([]models.Notification, error)
+	MarkRead(ctx context.Context, userID, id int) error
+	MarkAllRead(ctx context.Context, userID int) (int64, error)
+}
+
+// TaskCommentRepository defines the interface for task comment data access
+type TaskCommentRepository interface {
+	Create(ctx context.Context, c *models.TaskComment) error
+	GetByID(ctx context.Context, id int) (*models.TaskComment, error)
+	GetByTaskID(ctx context.Context, taskID int) ([]models.TaskComment, error)
+	Update(ctx context.Context, c *models.TaskComment) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	AddEdit(ctx context.Context, e *models.TaskCommentEdit) error
+	GetEdits(ctx context.Context, commentID int) ([]models.TaskCommentEdit, error)
+}
+
+// TaskChecklistRepository defines the interface for task checklist data access
+type TaskChecklistRepository interface {
+	Create(ctx context.Context, item *models.ChecklistItem) error
+	GetByID(ctx context.Context, id int) (*models.ChecklistItem, error)
+	GetByTaskID(ctx context.Context, taskID int) ([]models.ChecklistItem, error)
+	Update(ctx context.Context, item *models.ChecklistItem) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	CountOpenRequired(ctx context.Context, taskID int) (int, error)
+}
+
+// PlaybookRepository defines the interface for task playbook data access
+type PlaybookRepository interface {
+	Create(ctx context.Context, p *models.Playbook) error
+	GetByID(ctx context.Context, id int) (*models.Playbook, error)
+	GetAll(ctx context.Context) ([]models.Playbook, error)
+	GetActiveForDealStage(ctx context.Context, stageID int) ([]models.Playbook, error)
+	GetActiveForLeadStatus(ctx context.Context, statusID int) ([]models.Playbook, error)
+	Update(ctx context.Context, p *models.Playbook) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	StartRun(ctx context.Context, playbookID int, dealID, leadID *int) (bool, error)
+}
 
 // CommLogRepository defines the interface for communication log data access
 type CommLogRepository interface {
-    CreateCommLog(log *models.CommLog) error
-    GetCommLogByID(id int) (*models.CommLog, error)
-    GetCommLogsByDealID(dealID int) ([]models.CommLog, error)
-    GetCommLogsByContactID(contactID int) ([]models.CommLog, error) // Added
-    GetAllCommLogs() ([]models.CommLog, error)
-    UpdateCommLog(log *models.CommLog) error
-    DeleteCommLog(id int) error
-    GetCommLogsForUser(userID int) ([]models.CommLog, error)
-}
\ No newline at end of file
+    CreateCommLog(ctx context.Context, log *models.CommLog) error
+    GetCommLogByID(ctx context.Context, id int) (*models.CommLog, error)
+    GetCommLogsByDealID(ctx context.Context, dealID int) ([]models.CommLog, error)
+    GetCommLogsByLeadID(ctx context.Context, leadID int) ([]models.CommLog, error)
+    GetCommLogsByContactID(ctx context.Context, contactID int) ([]models.CommLog, error) // Added
+    GetAllCommLogs(ctx context.Context) ([]models.CommLog, error)
+    UpdateCommLog(ctx context.Context, log *models.CommLog) error
+    DeleteCommLog(ctx context.Context, id int, expectedVersion int) error
+    GetCommLogsForUser(ctx context.Context, userID int) ([]models.CommLog, error)
+}
+// ContactRepository defines the interface for contact data access
+type ContactRepository interface {
+	Create(ctx context.Context, c models.Contact) (int, error)
+	GetAll(ctx context.Context) ([]models.Contact, error)
+	GetAllForUser(ctx context.Context, userID int) ([]models.Contact, error)
+	GetByID(ctx context.Context, id int) (*models.Contact, error)
+	Update(ctx context.Context, contact models.Contact) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	UpdateCreatedBy(ctx context.Context, contactID int, createdBy int) error
+}
+
+// LeadRepository defines the interface for lead data access
+type LeadRepository interface {
+	Create(ctx context.Context, l models.Lead) (int, error)
+	GetAll(ctx context.Context) ([]models.Lead, error)
+	GetAllLeadsForUser(ctx context.Context, userID int) ([]models.Lead, error)
+	GetByID(ctx context.Context, id int) (*models.Lead, error)
+	Update(ctx context.Context, l models.Lead) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	CheckForOpenLeadByContactID(ctx context.Context, contactID int) (bool, error)
+	GetLeadCountsByUserID(ctx context.Context, userID int) (*LeadStatusCounts, error)
+	GetSourceLeadReport(ctx context.Context) ([]SourceLeadReportRow, error)
+	GetOpenLeadCountsByStatus(ctx context.Context) ([]LeadStatusCountRow, error)
+}
+
+// DealRepository defines the interface for deal data access
+type DealRepository interface {
+	Create(ctx context.Context, d models.Deal) (int, error)
+	GetAll(ctx context.Context) ([]models.Deal, error)
+	GetAllForUser(ctx context.Context, userID int) ([]models.Deal, error)
+	GetByID(ctx context.Context, id int) (*models.Deal, error)
+	Update(ctx context.Context, d models.Deal) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	GetEmployeeSalesReport(ctx context.Context) ([]EmployeeSalesReportRow, error)
+	GetEmployeeSalesReportForUser(ctx context.Context, userID int) ([]EmployeeSalesReportRow, error)
+	GetSourceSalesReport(ctx context.Context) ([]SourceSalesReportRow, error)
+	GetDealsPipelineReport(ctx context.Context) ([]DealsPipelineReportRow, error)
+}
+
+// PropertyRepository defines the interface for property data access
+type PropertyRepository interface {
+	Create(ctx context.Context, p models.Property) (int, error)
+	GetAll(ctx context.Context) ([]models.Property, error)
+	GetByID(ctx context.Context, id int) (*models.Property, error)
+	Update(ctx context.Context, p models.Property) error
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	SiteExists(ctx context.Context, siteID int) (bool, error)
+	PropertyTypeExists(ctx context.Context, propertyTypeID int) (bool, error)
+	IsPropertyInOpenLeadOrDeal(ctx context.Context, propertyID int) (bool, error)
+}
+
+// UserRepository defines the interface for user data access
+type UserRepository interface {
+	Create(ctx context.Context, user models.User) (int, error)
+	GetAll(ctx context.Context) ([]models.User, error)
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	Update(ctx context.Context, user models.User) error
+	Delete(ctx context.Context, id int) error
+	GetAllSalesAgents(ctx context.Context) ([]models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByUsernameWithRole(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	ChangeRole(ctx context.Context, userID, newRoleID, changedBy int, reason *string) (int, error)
+	GetRoleChanges(ctx context.Context, userID int) ([]models.RoleChange, error)
+}
+
+// InvitationRepository defines the interface for invitation data access
+type InvitationRepository interface {
+	Create(ctx context.Context, inv models.Invitation) (int, error)
+	GetByID(ctx context.Context, id int) (*models.Invitation, error)
+	GetAll(ctx context.Context) ([]models.Invitation, error)
+	Revoke(ctx context.Context, id int) error
+	Redeem(ctx context.Context, invitationID int, user models.User) (int, error)
+}
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	Create(ctx context.Context, k models.APIKey) (int, error)
+	GetByID(ctx context.Context, id int) (*models.APIKey, error)
+	GetAllForUser(ctx context.Context, userID int) ([]models.APIKey, error)
+	GetActiveByHash(ctx context.Context, keyHash string) (*APIKeyOwner, error)
+	TouchLastUsed(ctx context.Context, id int) error
+	Revoke(ctx context.Context, id int) error
+}
+
+// AuditRepository defines the interface for audit log data access
+type AuditRepository interface {
+	Insert(ctx context.Context, e models.AuditEntry) error
+	Find(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// IdempotencyRepository defines the interface for stored Idempotency-Key responses
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, rec models.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, userID int, key string, statusCode int, headers models.StoredHeaders, body []byte) error
+	Release(ctx context.Context, userID int, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// AdminRepository defines the interface for the maintenance queries crmctl runs
+type AdminRepository interface {
+	SeedLookups(ctx context.Context) (map[string]int64, error)
+	GetRoleID(ctx context.Context, roleName string) (int, error)
+	ReassignRecords(ctx context.Context, fromUserID, toUserID int) (*ReassignCounts, error)
+	BackfillContactCreatedBy(ctx context.Context, userID int) (int64, error)
+}
+
+// Compile-time checks that the Postgres repositories and transaction manager
+// satisfy the interfaces.
+var (
+	_ ContactRepository       = (*ContactRepo)(nil)
+	_ LeadRepository          = (*LeadRepo)(nil)
+	_ DealRepository          = (*DealRepo)(nil)
+	_ PropertyRepository      = (*PropertyRepo)(nil)
+	_ UserRepository          = (*UserRepo)(nil)
+	_ InvitationRepository    = (*InvitationRepo)(nil)
+	_ APIKeyRepository        = (*APIKeyRepo)(nil)
+	_ AuditRepository         = (*AuditRepo)(nil)
+	_ IdempotencyRepository   = (*IdempotencyRepo)(nil)
+	_ TaskRepository          = (*TaskRepo)(nil)
+	_ TaskSeriesRepository    = (*TaskSeriesRepo)(nil)
+	_ TaskReminderRepository  = (*TaskReminderRepo)(nil)
+	_ TaskSLARepository       = (*TaskSLARepo)(nil)
+	_ TaskBulkRepository      = (*TaskBulkRepo)(nil)
+	_ NotificationRepository  = (*NotificationRepo)(nil)
+	_ TaskCommentRepository   = (*TaskCommentRepo)(nil)
+	_ TaskChecklistRepository = (*TaskChecklistRepo)(nil)
+	_ PlaybookRepository      = (*PlaybookRepo)(nil)
+	_ NoteRepository          = (*NoteRepo)(nil)
+	_ EventRepository         = (*EventRepo)(nil)
+	_ CommLogRepository       = (*CommLogRepo)(nil)
+	_ AdminRepository         = (*AdminRepo)(nil)
+	_ Transactor              = (*TxManager)(nil)
+)
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// taskBulkColumns are the task columns read by TaskBulkRepo.
+const taskBulkColumns = `task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id,
+				created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type`
+
+// TaskBulkRepo holds the queries that select the tasks of a bulk operation.
+// The changes themselves go through TaskRepo.
+type TaskBulkRepo struct {
+	db *sqlx.DB
+}
+
+// NewTaskBulkRepo creates a new TaskBulkRepo.
+func NewTaskBulkRepo(db *sqlx.DB) *TaskBulkRepo {
+	return &TaskBulkRepo{db: db}
+}
+
+// GetByIDs returns the tasks with the given IDs that exist and are not
+// deleted, in no particular order.
+func (r *TaskBulkRepo) GetByIDs(ctx context.Context, ids []int) ([]models.Task, error) {
+	tasks := []models.Task{}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	query, args, err := sqlx.In(`SELECT `+taskBulkColumns+` FROM tasks WHERE task_id IN (?) AND deleted_at IS NULL`, ids)
+	if err != nil {
+		return nil, err
+	}
+	err = conn(ctx, r.db).SelectContext(ctx, &tasks, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	return tasks, err
+}
+
+// FindIDs returns the IDs of up to limit tasks that are not deleted and
+// match f, ordered by due date.
+func (r *TaskBulkRepo) FindIDs(ctx context.Context, f models.TaskFilter, limit int) ([]int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.AssignedTo > 0 {
+		add("assigned_to = $%d", f.AssignedTo)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.TaskType != "" {
+		add("task_type = $%d", f.TaskType)
+	}
+	if f.LeadID > 0 {
+		add("lead_id = $%d", f.LeadID)
+	}
+	if f.DealID > 0 {
+		add("deal_id = $%d", f.DealID)
+	}
+	if f.DueAfter != nil {
+		add("due_date >= $%d", *f.DueAfter)
+	}
+	if f.DueBefore != nil {
+		add("due_date < $%d", *f.DueBefore)
+	}
+
+	args = append(args, limit)
+	query := `SELECT task_id FROM tasks WHERE ` + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY due_date, task_id LIMIT $%d", len(args))
+
+	ids := []int{}
+	err := conn(ctx, r.db).SelectContext(ctx, &ids, query, args...)
+	return ids, err
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const checklistItemExistsQuery = `SELECT EXISTS(SELECT 1 FROM task_checklist_items WHERE item_id = $1)`
+
+const checklistItemColumns = `item_id, task_id, title, required, position, completed_at, completed_by,
+				COALESCE(created_by, 0) AS created_by, created_at, updated_at, version`
+
+// TaskChecklistRepo is a repository for the checklist items of tasks.
+type TaskChecklistRepo struct {
+	db *sqlx.DB
+}
+
+// NewTaskChecklistRepo creates a new TaskChecklistRepo.
+func NewTaskChecklistRepo(db *sqlx.DB) *TaskChecklistRepo {
+	return &TaskChecklistRepo{db: db}
+}
+
+// Create inserts a checklist item and fills in its ID, timestamps and
+// version. An item without a position goes after the task's last item.
+func (r *TaskChecklistRepo) Create(ctx context.Context, item *models.ChecklistItem) error {
+	query := `INSERT INTO task_checklist_items (task_id, title, required, position, completed_at, completed_by, created_by)
+			  VALUES ($1, $2, $3,
+				COALESCE(NULLIF($4, 0), (SELECT COALESCE(MAX(position), 0) + 1 FROM task_checklist_items WHERE task_id = $1)),
+				$5, $6, $7)
+			  RETURNING item_id, position, created_at, updated_at, version`
+	return conn(ctx, r.db).QueryRowxContext(ctx, query, item.TaskID, item.Title, item.Required, item.Position,
+		item.CompletedAt, item.CompletedBy, item.CreatedBy,
+	).Scan(&item.ID, &item.Position, &item.CreatedAt, &item.UpdatedAt, &item.Version)
+}
+
+// GetByID returns a checklist item, or nil if there is none.
+func (r *TaskChecklistRepo) GetByID(ctx context.Context, id int) (*models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	query := `SELECT ` + checklistItemColumns + ` FROM task_checklist_items WHERE item_id = $1`
+	err := conn(ctx, r.db).GetContext(ctx, &item, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetByTaskID returns the checklist of a task in position order.
+func (r *TaskChecklistRepo) GetByTaskID(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	items := []models.ChecklistItem{}
+	query := `SELECT ` + checklistItemColumns + ` FROM task_checklist_items
+			  WHERE task_id = $1
+			  ORDER BY position, item_id`
+	err := conn(ctx, r.db).SelectContext(ctx, &items, query, taskID)
+	return items, err
+}
+
+// Update saves a checklist item. If item.Version is not 0 the item is only
+// updated if it is still at that version.
+func (r *TaskChecklistRepo) Update(ctx context.Context, item *models.ChecklistItem) error {
+	query := `UPDATE task_checklist_items SET title = $1, required = $2, position = $3, completed_at = $4,
+				completed_by = $5, updated_at = NOW(), version = version + 1
+			  WHERE item_id = $6 AND ($7 = 0 OR version = $7)
+			  RETURNING updated_at, version`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, item.Title, item.Required, item.Position, item.CompletedAt,
+		item.CompletedBy, item.ID, item.Version,
+	).Scan(&item.UpdatedAt, &item.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return missingOrConflict(ctx, r.db, checklistItemExistsQuery, item.ID, fmt.Errorf("checklist item %w", ErrNotFound))
+	}
+	return err
+}
+
+// Delete removes a checklist item. If expectedVersion is not 0 the item is
+// only deleted if it is still at that version.
+func (r *TaskChecklistRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `DELETE FROM task_checklist_items WHERE item_id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return missingOrConflict(ctx, r.db, checklistItemExistsQuery, id, fmt.Errorf("checklist item %w", ErrNotFound))
+	}
+	return nil
+}
+
+// CountOpenRequired returns how many required items of a task's checklist
+// are not done.
+func (r *TaskChecklistRepo) CountOpenRequired(ctx context.Context, taskID int) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM task_checklist_items WHERE task_id = $1 AND required AND completed_at IS NULL`
+	err := conn(ctx, r.db).GetContext(ctx, &count, query, taskID)
+	return count, err
+}
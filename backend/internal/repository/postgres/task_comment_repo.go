@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const taskCommentExistsQuery = `SELECT EXISTS(SELECT 1 FROM task_comments WHERE comment_id = $1 AND deleted_at IS NULL)`
+
+const taskCommentColumns = `c.comment_id, c.task_id, c.parent_id, COALESCE(c.author_id, 0) AS author_id, c.body,
+				EXISTS(SELECT 1 FROM task_comment_edits e WHERE e.comment_id = c.comment_id) AS edited,
+				c.created_at, c.updated_at, c.deleted_at, c.version`
+
+// TaskCommentRepo is a repository for task comments and their edit history.
+type TaskCommentRepo struct {
+	db *sqlx.DB
+}
+
+// NewTaskCommentRepo creates a new TaskCommentRepo.
+func NewTaskCommentRepo(db *sqlx.DB) *TaskCommentRepo {
+	return &TaskCommentRepo{db: db}
+}
+
+// Create inserts a comment and fills in its ID, timestamps and version.
+func (r *TaskCommentRepo) Create(ctx context.Context, c *models.TaskComment) error {
+	query := `INSERT INTO task_comments (task_id, parent_id, author_id, body)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING comment_id, created_at, updated_at, version`
+	return conn(ctx, r.db).QueryRowxContext(ctx, query, c.TaskID, c.ParentID, c.AuthorID, c.Body).
+		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.Version)
+}
+
+// GetByID returns a comment, deleted or not, or nil if there is none.
+func (r *TaskCommentRepo) GetByID(ctx context.Context, id int) (*models.TaskComment, error) {
+	var c models.TaskComment
+	query := `SELECT ` + taskCommentColumns + ` FROM task_comments c WHERE c.comment_id = $1`
+	err := conn(ctx, r.db).GetContext(ctx, &c, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetByTaskID returns the comments on a task, deleted ones included, oldest
+// first.
+func (r *TaskCommentRepo) GetByTaskID(ctx context.Context, taskID int) ([]models.TaskComment, error) {
+	comments := []models.TaskComment{}
+	query := `SELECT ` + taskCommentColumns + ` FROM task_comments c
+			  WHERE c.task_id = $1
+			  ORDER BY c.created_at, c.comment_id`
+	err := conn(ctx, r.db).SelectContext(ctx, &comments, query, taskID)
+	return comments, err
+}
+
+// Update saves the body of a comment that has not been deleted. If c.Version
+// is not 0 the comment is only updated if it is still at that version.
+func (r *TaskCommentRepo) Update(ctx context.Context, c *models.TaskComment) error {
+	query := `UPDATE task_comments SET body = $1, updated_at = NOW(), version = version + 1
+			  WHERE comment_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+			  RETURNING updated_at, version`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, c.Body, c.ID, c.Version).Scan(&c.UpdatedAt, &c.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return missingOrConflict(ctx, r.db, taskCommentExistsQuery, c.ID, fmt.Errorf("task comment %w", ErrNotFound))
+	}
+	return err
+}
+
+// Delete marks a comment deleted and clears its body and edit history. If
+// expectedVersion is not 0 the comment is only deleted if it is still at that
+// version.
+func (r *TaskCommentRepo) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `UPDATE task_comments SET body = '', deleted_at = NOW(), updated_at = NOW(), version = version + 1
+			  WHERE comment_id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return missingOrConflict(ctx, r.db, taskCommentExistsQuery, id, fmt.Errorf("task comment %w", ErrNotFound))
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM task_comment_edits WHERE comment_id = $1`, id)
+	return err
+}
+
+// AddEdit records the body a comment had before an edit and fills in the
+// edit's ID and time.
+func (r *TaskCommentRepo) AddEdit(ctx context.Context, e *models.TaskCommentEdit) error {
+	query := `INSERT INTO task_comment_edits (comment_id, body, edited_by)
+			  VALUES ($1, $2, $3)
+			  RETURNING edit_id, edited_at`
+	return conn(ctx, r.db).QueryRowxContext(ctx, query, e.CommentID, e.Body, e.EditedBy).Scan(&e.ID, &e.EditedAt)
+}
+
+// GetEdits returns the edit history of a comment, oldest first.
+func (r *TaskCommentRepo) GetEdits(ctx context.Context, commentID int) ([]models.TaskCommentEdit, error) {
+	edits := []models.TaskCommentEdit{}
+	query := `SELECT edit_id, comment_id, body, COALESCE(edited_by, 0) AS edited_by, edited_at
+			  FROM task_comment_edits
+			  WHERE comment_id = $1
+			  ORDER BY edited_at, edit_id`
+	err := conn(ctx, r.db).SelectContext(ctx, &edits, query, commentID)
+	return edits, err
+}
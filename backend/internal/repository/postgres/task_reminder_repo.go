@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TaskReminderRepo holds the task queries of the reminder scheduler.
+type TaskReminderRepo struct {
+	db *sqlx.DB
+}
+
+// NewTaskReminderRepo creates a new TaskReminderRepo.
+func NewTaskReminderRepo(db *sqlx.DB) *TaskReminderRepo {
+	return &TaskReminderRepo{db: db}
+}
+
+// GetOpenTasksDueBefore returns the tasks that are not completed or deleted
+// and are due before the given time, including overdue ones, ordered by due
+// date.
+func (r *TaskReminderRepo) GetOpenTasksDueBefore(ctx context.Context, before time.Time) ([]models.Task, error) {
+	tasks := []models.Task{}
+	query := `SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id,
+				created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
+			  FROM tasks
+			  WHERE status <> 'Completed' AND deleted_at IS NULL AND due_date < $1
+			  ORDER BY due_date, task_id`
+	err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, before)
+	return tasks, err
+}
+
+// MarkOverdue sets the status of the pending tasks due before now to Overdue
+// and returns them as updated. Each task is returned by one call only, so
+// concurrent schedulers do not both act on it.
+func (r *TaskReminderRepo) MarkOverdue(ctx context.Context, now time.Time) ([]models.Task, error) {
+	tasks := []models.Task{}
+	query := `UPDATE tasks SET status = 'Overdue', updated_at = NOW(), version = version + 1
+			  WHERE status = 'Pending' AND deleted_at IS NULL AND due_date < $1
+			  RETURNING task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id,
+				created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type`
+	err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, now)
+	return tasks, err
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+    "context"
     "database/sql"
     "fmt"
     "time"
@@ -9,6 +10,9 @@ import (
     "github.com/jmoiron/sqlx"
 )
 
+// taskExistsQuery reports whether a row exists and has not been soft deleted.
+const taskExistsQuery = `SELECT EXISTS(SELECT 1 FROM tasks WHERE task_id = $1 AND deleted_at IS NULL)`
+
 // TaskRepo implements the TaskRepository interface
 type TaskRepo struct {
     db *sqlx.DB
@@ -18,17 +22,22 @@ func NewTaskRepository(db *sqlx.DB) *TaskRepo {
     return &TaskRepo{db: db}
 }
 
-// CreateTask creates a new task
-func (r *TaskRepo) CreateTask(task *models.Task) error {
+// CreateTask creates a new task. A zero task.CreatedAt is set to now.
+func (r *TaskRepo) CreateTask(ctx context.Context, task *models.Task) error {
     query := `
-        INSERT INTO tasks (task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-        RETURNING task_id, updated_at
+        INSERT INTO tasks (task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, series_id, occurrence, created_by, task_type)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, 0), $13)
+        RETURNING task_id, created_at, updated_at, version
     `
 
     currentTime := time.Now()
+    createdAt := task.CreatedAt
+    if createdAt.IsZero() {
+        createdAt = currentTime
+    }
     var updatedAt time.Time
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         task.TaskName,
         task.TaskDescription,
@@ -37,9 +46,13 @@ func (r *TaskRepo) CreateTask(task *models.Task) error {
         task.AssignedTo,
         task.LeadID,
         task.DealID,
-        task.CreatedAt,
+        createdAt,
         currentTime,
-    ).Scan(&task.ID, &updatedAt)
+        task.SeriesID,
+        task.Occurrence,
+        task.CreatedBy,
+        task.TaskType,
+    ).Scan(&task.ID, &task.CreatedAt, &updatedAt, &task.Version)
 
     if err != nil {
         return fmt.Errorf("failed to create task: %w", err)
@@ -50,9 +63,9 @@ func (r *TaskRepo) CreateTask(task *models.Task) error {
 }
 
 // GetTaskByID retrieves a task by ID
-func (r *TaskRepo) GetTaskByID(id int) (*models.Task, error) {
+func (r *TaskRepo) GetTaskByID(ctx context.Context, id int) (*models.Task, error) {
     query := `
-        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
         FROM tasks
         WHERE task_id = $1 AND deleted_at IS NULL
     `
@@ -60,10 +73,10 @@ func (r *TaskRepo) GetTaskByID(id int) (*models.Task, error) {
     var task models.Task
     var updatedAt sql.NullTime
     var deletedAt sql.NullTime
-    err := r.db.Get(&task, query, id)
+    err := conn(ctx, r.db).GetContext(ctx, &task, query, id)
     if err != nil {
         if err == sql.ErrNoRows {
-            return nil, fmt.Errorf("task not found")
+            return nil, fmt.Errorf("task %w", ErrNotFound)
         }
         return nil, fmt.Errorf("failed to get task: %w", err)
     }
@@ -79,16 +92,16 @@ func (r *TaskRepo) GetTaskByID(id int) (*models.Task, error) {
 }
 
 // GetTasksByDealID retrieves all tasks for a specific deal
-func (r *TaskRepo) GetTasksByDealID(dealID int) ([]models.Task, error) {
+func (r *TaskRepo) GetTasksByDealID(ctx context.Context, dealID int) ([]models.Task, error) {
     query := `
-        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
         FROM tasks
         WHERE deal_id = $1 AND deleted_at IS NULL
         ORDER BY due_date ASC
     `
 
     var tasks []models.Task
-    err := r.db.Select(&tasks, query, dealID)
+    err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, dealID)
     if err != nil {
         return nil, fmt.Errorf("failed to get tasks by deal ID: %w", err)
     }
@@ -96,16 +109,16 @@ func (r *TaskRepo) GetTasksByDealID(dealID int) ([]models.Task, error) {
     return tasks, nil
 }
 
-func (r *TaskRepo) GetTasksByDealIDForUser(dealID int, userID int) ([]models.Task, error) {
+func (r *TaskRepo) GetTasksByDealIDForUser(ctx context.Context, dealID int, userID int) ([]models.Task, error) {
     query := `
-        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
         FROM tasks
         WHERE deal_id = $1 AND assigned_to = $2 AND deleted_at IS NULL
         ORDER BY due_date ASC
     `
 
     var tasks []models.Task
-    err := r.db.Select(&tasks, query, dealID, userID)
+    err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, dealID, userID)
     if err != nil {
         return nil, fmt.Errorf("failed to get tasks by deal ID for user: %w", err)
     }
@@ -113,17 +126,52 @@ func (r *TaskRepo) GetTasksByDealIDForUser(dealID int, userID int) ([]models.Tas
     return tasks, nil
 }
 
+// GetTasksByLeadID retrieves all tasks for a specific lead
+func (r *TaskRepo) GetTasksByLeadID(ctx context.Context, leadID int) ([]models.Task, error) {
+    query := `
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
+        FROM tasks
+        WHERE lead_id = $1 AND deleted_at IS NULL
+        ORDER BY due_date ASC
+    `
+
+    var tasks []models.Task
+    err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, leadID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get tasks by lead ID: %w", err)
+    }
+
+    return tasks, nil
+}
+
+func (r *TaskRepo) GetTasksByLeadIDForUser(ctx context.Context, leadID int, userID int) ([]models.Task, error) {
+    query := `
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
+        FROM tasks
+        WHERE lead_id = $1 AND assigned_to = $2 AND deleted_at IS NULL
+        ORDER BY due_date ASC
+    `
+
+    var tasks []models.Task
+    err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, leadID, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get tasks by lead ID for user: %w", err)
+    }
+
+    return tasks, nil
+}
+
 // GetAllTasks retrieves all tasks
-func (r *TaskRepo) GetAllTasks() ([]models.Task, error) {
+func (r *TaskRepo) GetAllTasks(ctx context.Context) ([]models.Task, error) {
     query := `
-        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
         FROM tasks
         WHERE deleted_at IS NULL
         ORDER BY due_date ASC
     `
 
     var tasks []models.Task
-    err := r.db.Select(&tasks, query)
+    err := conn(ctx, r.db).SelectContext(ctx, &tasks, query)
     if err != nil {
         return nil, fmt.Errorf("failed to get all tasks: %w", err)
     }
@@ -132,17 +180,18 @@ func (r *TaskRepo) GetAllTasks() ([]models.Task, error) {
 }
 
 // UpdateTask updates an existing task
-func (r *TaskRepo) UpdateTask(task *models.Task) error {
+func (r *TaskRepo) UpdateTask(ctx context.Context, task *models.Task) error {
     query := `
         UPDATE tasks 
-        SET task_name = $1, task_description = $2, due_date = $3, status = $4, assigned_to = $5, lead_id = $6, deal_id = $7, updated_at = $8
-        WHERE task_id = $9 AND deleted_at IS NULL
-        RETURNING updated_at
+        SET task_name = $1, task_description = $2, due_date = $3, status = $4, assigned_to = $5, lead_id = $6, deal_id = $7, updated_at = $8, task_type = $11, version = version + 1
+        WHERE task_id = $9 AND deleted_at IS NULL AND ($10 = 0 OR version = $10)
+        RETURNING updated_at, version
     `
 
     currentTime := time.Now()
     var updatedAt time.Time
-    err := r.db.QueryRowx(
+    err := conn(ctx, r.db).QueryRowxContext(
+        ctx,
         query,
         task.TaskName,
         task.TaskDescription,
@@ -153,11 +202,13 @@ func (r *TaskRepo) UpdateTask(task *models.Task) error {
         task.DealID,
         currentTime,
         task.ID,
-    ).Scan(&updatedAt)
+        task.Version,
+        task.TaskType,
+    ).Scan(&updatedAt, &task.Version)
 
     if err != nil {
         if err == sql.ErrNoRows {
-            return fmt.Errorf("task not found")
+            return missingOrConflict(ctx, r.db, taskExistsQuery, task.ID, fmt.Errorf("task %w", ErrNotFound))
         }
         return fmt.Errorf("failed to update task: %w", err)
     }
@@ -166,15 +217,16 @@ func (r *TaskRepo) UpdateTask(task *models.Task) error {
     return nil
 }
 
-// DeleteTask soft deletes a task
-func (r *TaskRepo) DeleteTask(id int) error {
+// DeleteTask soft deletes a task. If expectedVersion is not 0 the task is only
+// deleted if it is still at that version.
+func (r *TaskRepo) DeleteTask(ctx context.Context, id int, expectedVersion int) error {
     query := `
         UPDATE tasks 
         SET deleted_at = $1
-        WHERE task_id = $2 AND deleted_at IS NULL
+        WHERE task_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
     `
 
-    result, err := r.db.Exec(query, time.Now(), id)
+    result, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), id, expectedVersion)
     if err != nil {
         return fmt.Errorf("failed to delete task: %w", err)
     }
@@ -185,23 +237,23 @@ func (r *TaskRepo) DeleteTask(id int) error {
     }
 
     if rowsAffected == 0 {
-        return fmt.Errorf("task not found")
+        return missingOrConflict(ctx, r.db, taskExistsQuery, id, fmt.Errorf("task %w", ErrNotFound))
     }
 
     return nil
 }
 
 // GetTasksForUser retrieves tasks for a specific user
-func (r *TaskRepo) GetTasksForUser(userID int) ([]models.Task, error) {
+func (r *TaskRepo) GetTasksForUser(ctx context.Context, userID int) ([]models.Task, error) {
     query := `
-        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at
+        SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
         FROM tasks
         WHERE assigned_to = $1 AND deleted_at IS NULL
         ORDER BY due_date ASC
     `
 
     var tasks []models.Task
-    err := r.db.Select(&tasks, query, userID)
+    err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, userID)
     if err != nil {
         return nil, fmt.Errorf("failed to get tasks for user: %w", err)
     }
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const taskSeriesExistsQuery = `SELECT EXISTS(SELECT 1 FROM task_series WHERE series_id = $1)`
+
+// TaskSeriesRepo is a repository for recurring task series and the creation
+// of their occurrences.
+type TaskSeriesRepo struct {
+	db *sqlx.DB
+}
+
+// NewTaskSeriesRepo creates a new TaskSeriesRepo.
+func NewTaskSeriesRepo(db *sqlx.DB) *TaskSeriesRepo {
+	return &TaskSeriesRepo{db: db}
+}
+
+// Create inserts a series and fills in its ID, timestamps and version.
+func (r *TaskSeriesRepo) Create(ctx context.Context, ts *models.TaskSeries) error {
+	query := `INSERT INTO task_series (rrule, dtstart, start_occurrence, until_deal_closed, task_name, task_description,
+				assigned_to, lead_id, deal_id, created_by, task_type)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			  RETURNING series_id, created_at, updated_at, version`
+	return conn(ctx, r.db).QueryRowxContext(ctx, query, ts.RRule, ts.DTStart, ts.StartOccurrence, ts.UntilDealClosed,
+		ts.TaskName, ts.TaskDescription, ts.AssignedTo, ts.LeadID, ts.DealID, ts.CreatedBy, ts.TaskType,
+	).Scan(&ts.ID, &ts.CreatedAt, &ts.UpdatedAt, &ts.Version)
+}
+
+// GetByID returns a series, or nil if there is none.
+func (r *TaskSeriesRepo) GetByID(ctx context.Context, id int) (*models.TaskSeries, error) {
+	var ts models.TaskSeries
+	query := `SELECT series_id, rrule, dtstart, start_occurrence, until_deal_closed, task_name, task_description,
+				assigned_to, lead_id, deal_id, task_type, ended_at, created_by, created_at, updated_at, version
+			  FROM task_series WHERE series_id = $1`
+	err := conn(ctx, r.db).GetContext(ctx, &ts, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ts, nil
+}
+
+// Update saves the rule and template of a series. If ts.Version is not 0 the
+// series is only updated if it is still at that version.
+func (r *TaskSeriesRepo) Update(ctx context.Context, ts *models.TaskSeries) error {
+	query := `UPDATE task_series SET rrule = $1, dtstart = $2, start_occurrence = $3, until_deal_closed = $4,
+				task_name = $5, task_description = $6, assigned_to = $7, lead_id = $8, deal_id = $9,
+				task_type = $12, updated_at = NOW(), version = version + 1
+			  WHERE series_id = $10 AND ($11 = 0 OR version = $11)
+			  RETURNING updated_at, version`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, ts.RRule, ts.DTStart, ts.StartOccurrence, ts.UntilDealClosed,
+		ts.TaskName, ts.TaskDescription, ts.AssignedTo, ts.LeadID, ts.DealID, ts.ID, ts.Version, ts.TaskType,
+	).Scan(&ts.UpdatedAt, &ts.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return missingOrConflict(ctx, r.db, taskSeriesExistsQuery, ts.ID, fmt.Errorf("task series %w", ErrNotFound))
+	}
+	return err
+}
+
+// End stops a series from creating further occurrences. Ending a series that
+// has already ended is not an error.
+func (r *TaskSeriesRepo) End(ctx context.Context, id int) error {
+	query := `UPDATE task_series SET ended_at = NOW(), updated_at = NOW(), version = version + 1
+			  WHERE series_id = $1 AND ended_at IS NULL`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	return err
+}
+
+// CreateOccurrence inserts task as occurrence task.Occurrence of series
+// task.SeriesID. It returns false, leaving task untouched, if that occurrence
+// already exists, e.g. because a concurrent request created it.
+func (r *TaskSeriesRepo) CreateOccurrence(ctx context.Context, task *models.Task) (bool, error) {
+	query := `INSERT INTO tasks (task_name, task_description, due_date, status, assigned_to, lead_id, deal_id, series_id, occurrence, created_by, task_type)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0), $11)
+			  ON CONFLICT (series_id, occurrence) WHERE series_id IS NOT NULL DO NOTHING
+			  RETURNING task_id, created_at, updated_at, version`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, task.TaskName, task.TaskDescription, task.DueDate, task.Status,
+		task.AssignedTo, task.LeadID, task.DealID, task.SeriesID, task.Occurrence, task.CreatedBy, task.TaskType,
+	).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt, &task.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"crm-project/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TaskSLARepo holds the queries of the SLA scheduler and of the SLA breach
+// report.
+type TaskSLARepo struct {
+	db *sqlx.DB
+}
+
+// NewTaskSLARepo creates a new TaskSLARepo.
+func NewTaskSLARepo(db *sqlx.DB) *TaskSLARepo {
+	return &TaskSLARepo{db: db}
+}
+
+// GetUnbreachedOpenTasks returns the tasks of a type that are not completed
+// or deleted, were created before the given time and have no breach recorded
+// yet, oldest first.
+func (r *TaskSLARepo) GetUnbreachedOpenTasks(ctx context.Context, taskType string, createdBefore time.Time) ([]models.Task, error) {
+	tasks := []models.Task{}
+	query := `SELECT task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id,
+				created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type
+			  FROM tasks t
+			  WHERE status <> 'Completed' AND deleted_at IS NULL AND task_type = $1 AND created_at < $2
+				AND NOT EXISTS (SELECT 1 FROM task_sla_breaches b WHERE b.task_id = t.task_id)
+			  ORDER BY created_at, task_id`
+	err := conn(ctx, r.db).SelectContext(ctx, &tasks, query, taskType, createdBefore)
+	return tasks, err
+}
+
+// RecordBreach inserts b and fills in its ID and breach time. It returns
+// false, leaving b untouched, if a breach is already recorded for the task.
+func (r *TaskSLARepo) RecordBreach(ctx context.Context, b *models.TaskSLABreach) (bool, error) {
+	query := `INSERT INTO task_sla_breaches (task_id, task_type, assigned_to, escalation, reassigned_to, due_by)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (task_id) DO NOTHING
+			  RETURNING breach_id, breached_at`
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		b.TaskID, b.TaskType, b.AssignedTo, b.Escalation, b.ReassignedTo, b.DueBy,
+	).Scan(&b.ID, &b.BreachedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reassign assigns an open task from one user to another and returns it as
+// updated, or nil if the task is no longer open or assigned to from.
+func (r *TaskSLARepo) Reassign(ctx context.Context, taskID, from, to int) (*models.Task, error) {
+	var task models.Task
+	query := `UPDATE tasks SET assigned_to = $3, updated_at = NOW(), version = version + 1
+			  WHERE task_id = $1 AND assigned_to = $2 AND status <> 'Completed' AND deleted_at IS NULL
+			  RETURNING task_id, task_name, task_description, due_date, status, assigned_to, lead_id, deal_id,
+				created_at, updated_at, deleted_at, version, series_id, occurrence, COALESCE(created_by, 0) AS created_by, task_type`
+	err := conn(ctx, r.db).GetContext(ctx, &task, query, taskID, from, to)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetBreachReport counts the recorded breaches per agent the tasks were
+// assigned to, most breaches first.
+func (r *TaskSLARepo) GetBreachReport(ctx context.Context) ([]models.SLABreachReportRow, error) {
+	rows := []models.SLABreachReportRow{}
+	query := `SELECT u.user_id AS employee_id, u.username AS employee_name,
+				COUNT(*) AS breaches,
+				COUNT(*) FILTER (WHERE b.escalation = 'reassign') AS reassigned,
+				COUNT(*) FILTER (WHERE t.status <> 'Completed' AND t.deleted_at IS NULL) AS still_open
+			  FROM task_sla_breaches b
+			  JOIN users u ON u.user_id = b.assigned_to
+			  JOIN tasks t ON t.task_id = b.task_id
+			  GROUP BY u.user_id, u.username
+			  ORDER BY breaches DESC, u.username`
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query)
+	return rows, err
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"crm-project/internal/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedConn wraps a dbtx so every statement gets a span carrying its
+// sanitized text. conn returns one, so all repositories that take a context
+// are traced.
+type tracedConn struct {
+	dbtx
+}
+
+func (c tracedConn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := startQuerySpan(ctx, query)
+	err := c.dbtx.GetContext(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	return err
+}
+
+func (c tracedConn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := startQuerySpan(ctx, query)
+	err := c.dbtx.SelectContext(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	return err
+}
+
+func (c tracedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	result, err := c.dbtx.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+// QueryRowxContext ends its span when the row has been fetched rather than
+// scanned, since sqlx.Row does not report when scanning finishes.
+func (c tracedConn) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	row := c.dbtx.QueryRowxContext(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "db."+tracing.Operation(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(tracing.Operation(query)),
+			semconv.DBQueryText(tracing.SanitizeSQL(query)),
+		))
+}
+
+// endQuerySpan marks the span failed unless err is nil or just means no row
+// matched, which repositories report as "not found" rather than an error.
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"crm-project/internal/tracing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultTxAttempts is how many times TxManager runs a transaction that keeps
+// failing with a serialization failure or deadlock before giving up.
+const defaultTxAttempts = 3
+
+// Transactor runs a function as a single unit of work. Repository calls made
+// with the context passed to fn take part in the same transaction; if fn
+// returns an error everything it did is rolled back.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// dbtx is the part of *sqlx.DB and *sqlx.Tx the repositories use, so the same
+// query code runs inside and outside a transaction.
+type dbtx interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+}
+
+type txKey struct{}
+
+// conn returns the transaction stored in ctx by TxManager, or db if there is
+// none, traced so each statement gets a span.
+func conn(ctx context.Context, db *sqlx.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tracedConn{tx}
+	}
+	return tracedConn{db}
+}
+
+// TxManager runs units of work in Postgres transactions.
+type TxManager struct {
+	db          *sqlx.DB
+	opts        *sql.TxOptions
+	maxAttempts int
+}
+
+// NewTxManager creates a TxManager. Transactions run at the serializable
+// isolation level and are retried on serialization failures.
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{
+		db:          db,
+		opts:        &sql.TxOptions{Isolation: sql.LevelSerializable},
+		maxAttempts: defaultTxAttempts,
+	}
+}
+
+// WithinTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. When the transaction fails with a serialization failure or
+// deadlock the whole of fn is run again, so fn must not have side effects
+// outside the database. If ctx already carries a transaction fn simply joins
+// it, and the outermost WithinTx decides whether to commit or retry.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	var err error
+	for attempt := 1; attempt <= m.maxAttempts; attempt++ {
+		err = runTx(ctx, m.db, m.opts, fn)
+		if !IsSerializationFailure(err) {
+			return err
+		}
+		if attempt == m.maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("transaction failed after %d attempts: %w", m.maxAttempts, err)
+}
+
+// withTx runs fn in the transaction carried by ctx, or in a new one if there
+// is none. Repository methods that must be atomic on their own use it so they
+// also join a surrounding unit of work.
+func withTx(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+	return runTx(ctx, db, nil, fn)
+}
+
+func runTx(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracing.Start(ctx, "db.transaction", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endQuerySpan(span, err) }()
+
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsSerializationFailure reports whether err means Postgres aborted a
+// transaction because of a concurrent one, so running it again may succeed.
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
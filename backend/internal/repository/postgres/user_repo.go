@@ -26,7 +26,7 @@ func (r *UserRepo) Create(ctx context.Context, user models.User) (int, error) {
 			  VALUES ($1, $2, $3, $4)
 			  RETURNING user_id`
 
-	err := r.db.QueryRowxContext(ctx, query, user.Username, user.PasswordHash, user.Email, user.RoleID).Scan(&newUserID)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, user.Username, user.PasswordHash, user.Email, user.RoleID).Scan(&newUserID)
 	if err != nil {
 		return 0, err
 	}
@@ -41,7 +41,7 @@ func (r *UserRepo) GetAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
 	query := `SELECT user_id, username, email, role_id, created_at, updated_at FROM users ORDER BY created_at DESC`
 	
-	err := r.db.SelectContext(ctx, &users, query)
+	err := conn(ctx, r.db).SelectContext(ctx, &users, query)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +56,7 @@ func (r *UserRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
 	var user models.User
 	query := `SELECT user_id, username, email, role_id, created_at, updated_at FROM users WHERE user_id = $1`
 	
-	err := r.db.GetContext(ctx, &user, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &user, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -88,7 +88,7 @@ func (r *UserRepo) Update(ctx context.Context, user models.User) error {
 	query += " WHERE user_id = $" + strconv.Itoa(len(args)+1)
 	args = append(args, user.ID)
 	
-	result, err := r.db.ExecContext(ctx, query, args...)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -109,7 +109,7 @@ func (r *UserRepo) Update(ctx context.Context, user models.User) error {
 // Delete removes a user from the database by their ID.
 func (r *UserRepo) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE user_id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -132,7 +132,7 @@ func (r *UserRepo) GetAllSalesAgents(ctx context.Context) ([]models.User, error)
 	// We are assuming role_id 1 is 'Sales_Agent' based on our first migration.
 	query := `SELECT user_id, username, email FROM users WHERE role_id = 1 ORDER BY username`
 	
-	err := r.db.SelectContext(ctx,&agents, query)
+	err := conn(ctx, r.db).SelectContext(ctx,&agents, query)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +143,7 @@ func (r *UserRepo) GetAllSalesAgents(ctx context.Context) ([]models.User, error)
 
 func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.GetContext(ctx, &user, "SELECT user_id, username, password_hash, email, role_id, created_at, updated_at FROM users WHERE username=$1", username)
+	err := conn(ctx, r.db).GetContext(ctx, &user, "SELECT user_id, username, password_hash, email, role_id, created_at, updated_at FROM users WHERE username=$1", username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // User not found
@@ -163,7 +163,7 @@ func (r *UserRepo) GetByUsernameWithRole(ctx context.Context, username string) (
 		JOIN roles r ON u.role_id = r.role_id
 		WHERE u.username = $1
 	`
-	err := r.db.GetContext(ctx, &user, query, username)
+	err := conn(ctx, r.db).GetContext(ctx, &user, query, username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // User not found
@@ -177,7 +177,7 @@ func (r *UserRepo) GetByUsernameWithRole(ctx context.Context, username string) (
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	query := `SELECT user_id, username, email, role_id, password_hash FROM users WHERE email = $1`
-	err := r.db.GetContext(ctx, &user, query, email)
+	err := conn(ctx, r.db).GetContext(ctx, &user, query, email)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Return nil, nil for not found
@@ -185,4 +185,42 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User,
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
+// ChangeRole updates a user's role and records the change in role_changes in a
+// single transaction, or as part of the caller's unit of work if ctx carries
+// one. It returns the role the user had before the change.
+func (r *UserRepo) ChangeRole(ctx context.Context, userID, newRoleID, changedBy int, reason *string) (int, error) {
+	var oldRoleID int
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		err := tx.GetContext(ctx, &oldRoleID, `SELECT role_id FROM users WHERE user_id = $1 FOR UPDATE`, userID)
+		if err != nil {
+			return err // sql.ErrNoRows signals "not found"
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE users SET role_id = $1, updated_at = NOW() WHERE user_id = $2`, newRoleID, userID)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO role_changes (user_id, old_role_id, new_role_id, changed_by, reason)
+				  VALUES ($1, $2, $3, $4, $5)`
+		_, err = tx.ExecContext(ctx, query, userID, oldRoleID, newRoleID, changedBy, reason)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return oldRoleID, nil
+}
+
+// GetRoleChanges retrieves the role change history for a user, newest first.
+func (r *UserRepo) GetRoleChanges(ctx context.Context, userID int) ([]models.RoleChange, error) {
+	var changes []models.RoleChange
+	query := `SELECT role_change_id, user_id, old_role_id, new_role_id, changed_by, reason, changed_at
+			  FROM role_changes
+			  WHERE user_id = $1
+			  ORDER BY changed_at DESC`
+	err := conn(ctx, r.db).SelectContext(ctx, &changes, query, userID)
+	return changes, err
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrVersionConflict is returned by versioned updates and deletes when the row
+// still exists but has been modified since the caller read it.
+var ErrVersionConflict = errors.New("version conflict: the record was modified by someone else")
+
+// missingOrConflict explains why a versioned update or delete matched no rows.
+// existsQuery must select a single boolean for the row identified by $1. It
+// returns ErrVersionConflict if the row exists and notFound otherwise.
+func missingOrConflict(ctx context.Context, db *sqlx.DB, existsQuery string, id int, notFound error) error {
+	var exists bool
+	if err := conn(ctx, db).GetContext(ctx, &exists, existsQuery, id); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return notFound
+}
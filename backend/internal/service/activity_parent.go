@@ -0,0 +1,61 @@
+package service
+
+import "fmt"
+
+// Parent kinds for notes, tasks, events and communication logs filed under a
+// deal or a lead.
+const (
+	ParentDeal = "deal"
+	ParentLead = "lead"
+)
+
+// ActivityParent identifies the deal or lead an activity record is filed
+// under, e.g. the deal in /deals/{dealId}/notes.
+type ActivityParent struct {
+	Kind string
+	ID   int
+}
+
+// DealParent returns the parent for activities filed under a deal.
+func DealParent(dealID int) ActivityParent {
+	return ActivityParent{Kind: ParentDeal, ID: dealID}
+}
+
+// LeadParent returns the parent for activities filed under a lead.
+func LeadParent(leadID int) ActivityParent {
+	return ActivityParent{Kind: ParentLead, ID: leadID}
+}
+
+func (p ActivityParent) String() string {
+	return fmt.Sprintf("%s %d", p.Kind, p.ID)
+}
+
+// validate checks that p names an existing kind and a positive ID.
+func (p ActivityParent) validate() error {
+	if p.Kind != ParentDeal && p.Kind != ParentLead {
+		return Invalid("unknown parent kind %q", p.Kind)
+	}
+	if p.ID <= 0 {
+		return Invalid("invalid %s ID", p.Kind)
+	}
+	return nil
+}
+
+// Owns reports whether a record with the given deal and lead links is filed
+// under p.
+func (p ActivityParent) Owns(dealID, leadID *int) bool {
+	link := dealID
+	if p.Kind == ParentLead {
+		link = leadID
+	}
+	return link != nil && *link == p.ID
+}
+
+// Links returns the deal_id and lead_id values for a new record filed under p.
+func (p ActivityParent) Links() (dealID, leadID *int) {
+	id := p.ID
+	if p.Kind == ParentLead {
+		return nil, &id
+	}
+	return &id, nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyPrefix         = "crm_"
+	defaultAPIKeyTTLDays = 90
+)
+
+// ErrInvalidAPIKey is returned for any API key that cannot be used to authenticate.
+var ErrInvalidAPIKey = errors.New("invalid or expired API key")
+
+type APIKeyService struct {
+	repo   postgres.APIKeyRepository
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewAPIKeyService(repo postgres.APIKeyRepository, cfg *config.Config, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{repo: repo, cfg: cfg, logger: logger}
+}
+
+// CreatedAPIKey is returned once, when the key is created. Only a hash of Key
+// is stored, so it cannot be shown again.
+type CreatedAPIKey struct {
+	APIKey *models.APIKey `json:"api_key"`
+	Key    string         `json:"key"`
+}
+
+// CreateAPIKey issues a new personal API key for the current user.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req dto.CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	// Keys can only be issued from a login session, so a leaked key cannot be
+	// used to mint further keys.
+	if claims.APIKeyID != 0 {
+		s.logger.WarnContext(ctx, "Permission denied for CreateAPIKey: called with an API key", "user_id", claims.UserID, "api_key_id", claims.APIKeyID)
+		return nil, Forbidden("API keys cannot be created using an API key")
+	}
+
+	if err := validateStruct(req); err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !util.IsValidScope(scope) {
+			return nil, InvalidField("scopes", fmt.Sprintf("invalid scope: %q", scope))
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyTTLDays
+	}
+
+	rawKey, prefix, err := generateAPIKey()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to generate API key", "error", err)
+		return nil, errors.New("failed to create API key")
+	}
+
+	key := models.APIKey{
+		UserID:    claims.UserID,
+		Name:      req.Name,
+		KeyPrefix: prefix,
+		KeyHash:   hashAPIKey(rawKey),
+		Scopes:    models.Scopes(req.Scopes),
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	newID, err := s.repo.Create(ctx, key)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to create API key in database", "error", err)
+		return nil, errors.New("failed to create API key")
+	}
+	created, err := s.repo.GetByID(ctx, newID)
+	if err != nil || created == nil {
+		s.logger.ErrorContext(ctx, "failed to fetch created API key", "api_key_id", newID, "error", err)
+		return nil, errors.New("failed to retrieve created API key")
+	}
+
+	s.logger.InfoContext(ctx, "API key created", "api_key_id", newID, "user_id", claims.UserID, "scopes", req.Scopes)
+	return &CreatedAPIKey{APIKey: created, Key: rawKey}, nil
+}
+
+// GetMyAPIKeys lists the current user's API keys, including revoked and expired ones.
+func (s *APIKeyService) GetMyAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.GetMyAPIKeys")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	return s.repo.GetAllForUser(ctx, claims.UserID)
+}
+
+// RevokeAPIKey revokes one of the current user's API keys.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return NotFound("API key with ID %d not found", id)
+	}
+
+	// --- PERMISSION CHECK ---
+	// Users manage their own keys; managers can revoke anyone's.
+	if key.UserID != claims.UserID && claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for RevokeAPIKey", "user_id", claims.UserID, "api_key_id", id)
+		return Forbidden("you can only revoke your own API keys")
+	}
+
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		if err == sql.ErrNoRows {
+			return NotFound("API key with ID %d not found or already revoked", id)
+		}
+		return err
+	}
+	s.logger.InfoContext(ctx, "API key revoked", "api_key_id", id, "revoked_by", claims.UserID)
+	return nil
+}
+
+// Authenticate resolves a raw API key to request claims. The role is read from
+// the owner's current user record rather than captured when the key was made.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*dto.Claims, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.repo.GetActiveByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, key.ID); err != nil {
+		// Not worth failing the request over.
+		s.logger.WarnContext(ctx, "failed to record API key usage", "api_key_id", key.ID, "error", err)
+	}
+
+	return &dto.Claims{
+		UserID:   key.UserID,
+		RoleID:   key.RoleID,
+		Username: key.Username,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+// generateAPIKey returns a new key of the form crm_<prefix>_<secret> and its
+// public prefix, which is stored so users can tell their keys apart.
+func generateAPIKey() (key, prefix string, err error) {
+	buf := make([]byte, 36)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	prefix = apiKeyPrefix + hex.EncodeToString(buf[:4])
+	return prefix + "_" + hex.EncodeToString(buf[4:]), prefix, nil
+}
+
+// hashAPIKey hashes a key for storage. Keys carry 256 bits of randomness, so a
+// fast hash is enough and lets keys be looked up by hash directly.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
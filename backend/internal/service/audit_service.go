@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/logging"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+)
+
+// Entity types recorded in the audit log.
+const (
+	AuditEntityContact  = "contact"
+	AuditEntityLead     = "lead"
+	AuditEntityDeal     = "deal"
+	AuditEntityProperty = "property"
+	AuditEntityTask     = "task"
+	AuditEntityEvent    = "event"
+	AuditEntityNote     = "note"
+	AuditEntityCommLog  = "comm_log"
+	AuditEntityPlaybook = "playbook"
+)
+
+// Audit actions.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// auditIgnoredFields are bookkeeping columns left out of audit diffs; the
+// entry's own timestamp already records when the change happened.
+var auditIgnoredFields = []string{"created_at", "updated_at"}
+
+type AuditService struct {
+	repo   postgres.AuditRepository
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewAuditService(repo postgres.AuditRepository, cfg *config.Config, logger *slog.Logger) *AuditService {
+	return &AuditService{repo: repo, cfg: cfg, logger: logger}
+}
+
+// Created records the creation of an entity.
+func (s *AuditService) Created(ctx context.Context, entityType string, entityID int, after interface{}) {
+	ctx, span := tracing.Start(ctx, "AuditService.Created")
+	defer span.End()
+
+	s.record(ctx, entityType, entityID, AuditActionCreate, nil, after)
+}
+
+// Updated records an update. Only the fields that differ between before and
+// after are stored.
+func (s *AuditService) Updated(ctx context.Context, entityType string, entityID int, before, after interface{}) {
+	ctx, span := tracing.Start(ctx, "AuditService.Updated")
+	defer span.End()
+
+	s.record(ctx, entityType, entityID, AuditActionUpdate, before, after)
+}
+
+// UpdatedStored records an update with the entity as reload reads it back
+// after the update. The values passed to an Update method are not what was
+// stored: their version is the caller's expected one, and columns the update
+// does not set are left zero. If the entity cannot be read back the failure
+// is logged and nothing is recorded.
+func (s *AuditService) UpdatedStored(ctx context.Context, entityType string, entityID int, before interface{}, reload func() (interface{}, error)) {
+	ctx, span := tracing.Start(ctx, "AuditService.UpdatedStored")
+	defer span.End()
+
+	if s == nil {
+		return
+	}
+	after, err := reload()
+	if err == nil {
+		if rv := reflect.ValueOf(after); after == nil || rv.Kind() == reflect.Ptr && rv.IsNil() {
+			err = errors.New("not found")
+		}
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read back updated entity for audit log", "entity_type", entityType, "entity_id", entityID, "error", err)
+		return
+	}
+	s.record(ctx, entityType, entityID, AuditActionUpdate, before, after)
+}
+
+// Deleted records the deletion of an entity.
+func (s *AuditService) Deleted(ctx context.Context, entityType string, entityID int, before interface{}) {
+	ctx, span := tracing.Start(ctx, "AuditService.Deleted")
+	defer span.End()
+
+	s.record(ctx, entityType, entityID, AuditActionDelete, before, nil)
+}
+
+// record writes an audit entry. The change it describes has already been
+// committed, so a failure here is logged rather than returned to the caller.
+// A nil AuditService records nothing.
+func (s *AuditService) record(ctx context.Context, entityType string, entityID int, action string, before, after interface{}) {
+	if s == nil {
+		return
+	}
+
+	entry := models.AuditEntry{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+	}
+	if claims, ok := util.GetClaimsFromContext(ctx); ok {
+		entry.ActorUserID = &claims.UserID
+		if claims.APIKeyID != 0 {
+			entry.APIKeyID = &claims.APIKeyID
+		}
+	}
+	if reqID := logging.RequestID(ctx); reqID != "" {
+		entry.RequestID = &reqID
+	}
+
+	var err error
+	entry.Before, entry.After, err = diffFields(before, after)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to compute audit diff", "entity_type", entityType, "entity_id", entityID, "error", err)
+		return
+	}
+
+	if err := s.repo.Insert(ctx, entry); err != nil {
+		s.logger.ErrorContext(ctx, "failed to write audit log entry", "entity_type", entityType, "entity_id", entityID, "action", action, "error", err)
+	}
+}
+
+// GetAuditLog queries the audit log. Only managers can read it.
+func (s *AuditService) GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.GetAuditLog")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for GetAuditLog", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can view the audit log")
+	}
+
+	return s.repo.Find(ctx, filter)
+}
+
+// diffFields converts before and after to their JSON field maps and keeps only
+// the fields whose values differ. A nil side yields a nil map.
+func diffFields(before, after interface{}) (models.AuditChanges, models.AuditChanges, error) {
+	b, err := toFieldMap(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := toFieldMap(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b == nil || a == nil {
+		return b, a, nil
+	}
+
+	changedBefore := models.AuditChanges{}
+	changedAfter := models.AuditChanges{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(bv, av) {
+			changedBefore[k] = bv
+			changedAfter[k] = a[k]
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changedBefore[k] = nil
+			changedAfter[k] = av
+		}
+	}
+	return changedBefore, changedAfter, nil
+}
+
+func toFieldMap(v interface{}) (models.AuditChanges, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m models.AuditChanges
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	for _, field := range auditIgnoredFields {
+		delete(m, field)
+	}
+	return m, nil
+}
@@ -6,95 +6,148 @@ import (
 	"crm-project/internal/dto"
 	"crm-project/internal/models" // Import models for User struct
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Login outcomes reported to a LoginRecorder.
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginError              = "error"
+)
+
+// LoginRecorder is told the outcome of every login attempt, e.g. to count
+// them in metrics.
+type LoginRecorder interface {
+	RecordLogin(outcome string)
+}
+
 type AuthService struct {
-	userRepo *postgres.UserRepo
-	cfg      *config.Config // Store the entire config
-	logger   *slog.Logger
+	userRepo    postgres.UserRepository
+	invitations *InvitationService
+	logins      LoginRecorder  // May be nil
+	cfg         *config.Config // Store the entire config
+	logger      *slog.Logger
 }
 
-func NewAuthService(userRepo *postgres.UserRepo, cfg *config.Config, logger *slog.Logger) *AuthService {
+func NewAuthService(userRepo postgres.UserRepository, invitations *InvitationService, logins LoginRecorder, cfg *config.Config, logger *slog.Logger) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		cfg:      cfg,
-		logger:   logger,
+		userRepo:    userRepo,
+		invitations: invitations,
+		logins:      logins,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+func (s *AuthService) recordLogin(outcome string) {
+	if s.logins != nil {
+		s.logins.RecordLogin(outcome)
 	}
 }
 
 func (s *AuthService) LoginUser(ctx context.Context, username, password string) (string, int, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LoginUser")
+	defer span.End()
+
 	user, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
-		s.logger.Error("database error finding user by username", "error", err, "username", username)
+		s.logger.ErrorContext(ctx, "database error finding user by username", "error", err, "username", username)
+		s.recordLogin(LoginError)
 		return "", 0, err
 	}
 	if user == nil {
+		s.recordLogin(LoginInvalidCredentials)
 		return "", 0, errors.New("invalid credentials")
 	}
 
-	s.logger.Info("user found, checking password", "user_id", user.ID, "username", user.Username, "password_hash", user.PasswordHash)
+	s.logger.InfoContext(ctx, "user found, checking password", "user_id", user.ID, "username", user.Username)
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
+		s.recordLogin(LoginInvalidCredentials)
 		return "", 0, errors.New("invalid credentials")
 	}
 
-	s.logger.Info("user authenticated successfully", "user_id", user.ID, "username", user.Username)
+	s.logger.InfoContext(ctx, "user authenticated successfully", "user_id", user.ID, "username", user.Username)
 	token, err := s.generateJWT(user.ID, user.RoleID, user.Username)
+	if err != nil {
+		s.recordLogin(LoginError)
+	} else {
+		s.recordLogin(LoginSuccess)
+	}
 	return token, user.RoleID, err
 }
 
+// RegisterUser creates an account from a valid invitation. The new user's role
+// is always the one preset on the invitation.
 func (s *AuthService) RegisterUser(ctx context.Context, req *dto.RegisterRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+
+	if err := validateStruct(req); err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.invitations.ResolveInvite(ctx, req.InviteToken)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Email != nil && !strings.EqualFold(*invitation.Email, req.Email) {
+		s.logger.WarnContext(ctx, "registration email does not match invitation", "invitation_id", invitation.ID)
+		return nil, ErrInvalidInvite
+	}
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
-		s.logger.Error("database error checking for existing user", "error", err, "username", req.Username)
+		s.logger.ErrorContext(ctx, "database error checking for existing user", "error", err, "username", req.Username)
 		return nil, err
 	}
 	if existingUser != nil {
-		return nil, errors.New("username already taken")
+		return nil, Conflict("username already taken")
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		s.logger.Error("failed to hash password", "error", err)
+		s.logger.ErrorContext(ctx, "failed to hash password", "error", err)
 		return nil, errors.New("failed to process password")
 	}
 
-	// Create new user with role from request
-	newUser := &models.User{
+	newUser := models.User{
 		Username:     req.Username,
 		PasswordHash: string(hashedPassword),
 		Email:        req.Email,
-		RoleID:       req.RoleID, // Assign role from request
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		RoleID:       invitation.RoleID, // Role comes from the invitation, never the request
 	}
 
-		newUserID, err := s.userRepo.Create(ctx, *newUser)
+	newUserID, err := s.invitations.Redeem(ctx, invitation, newUser)
 	if err != nil {
-		s.logger.Error("failed to create user in database", "error", err)
+		if errors.Is(err, ErrInvalidInvite) {
+			return nil, err
+		}
+		s.logger.ErrorContext(ctx, "failed to create user in database", "error", err)
 		return nil, errors.New("failed to register user")
 	}
 
 	createdUser, err := s.userRepo.GetByID(ctx, newUserID)
 	if err != nil {
-		s.logger.Error("failed to fetch created user from database", "error", err)
+		s.logger.ErrorContext(ctx, "failed to fetch created user from database", "error", err)
 		return nil, errors.New("failed to retrieve registered user")
 	}
 
-	s.logger.Info("new user registered successfully", "user_id", createdUser.ID, "username", createdUser.Username, "role_id", createdUser.RoleID)
+	s.logger.InfoContext(ctx, "new user registered successfully", "user_id", createdUser.ID, "username", createdUser.Username, "role_id", createdUser.RoleID, "invitation_id", invitation.ID)
 	return createdUser, nil
 }
 
-
 func (s *AuthService) generateJWT(userID, roleID int, username string) (string, error) {
 	claims := &dto.Claims{
 		UserID:   userID,
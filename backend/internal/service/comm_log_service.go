@@ -1,217 +1,381 @@
 package service
 
 import (
+    "context"
     "errors"
     "fmt"
+    "log/slog"
 
+    "crm-project/internal/config"
+    "crm-project/internal/dto"
     "crm-project/internal/models"
     "crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+    "crm-project/internal/util"
 )
 
 type CommLogService struct {
     commLogRepo postgres.CommLogRepository
+    audit       *AuditService
+    cfg         *config.Config
+    logger      *slog.Logger
 }
 
-func NewCommLogService(commLogRepo postgres.CommLogRepository) *CommLogService {
-    return &CommLogService{commLogRepo: commLogRepo}
+func NewCommLogService(commLogRepo postgres.CommLogRepository, audit *AuditService, cfg *config.Config, logger *slog.Logger) *CommLogService {
+    return &CommLogService{commLogRepo: commLogRepo, audit: audit, cfg: cfg, logger: logger}
 }
 
-// CreateCommLog creates a new communication log
-func (s *CommLogService) CreateCommLog(log *models.CommLog) error {
-    if log.ContactID != nil && *log.ContactID <= 0 {
-        return errors.New("invalid contact ID")
+// canAccess reports whether the caller may view or change log. Reception can
+// access every log; everyone else only the logs they recorded.
+func (s *CommLogService) canAccess(claims *dto.Claims, log *models.CommLog) bool {
+    return claims.RoleID == s.cfg.Roles.ReceptionID || log.UserID == claims.UserID
+}
+
+// visibleCommLogs drops the logs the caller is not allowed to see.
+func (s *CommLogService) visibleCommLogs(claims *dto.Claims, logs []models.CommLog) []models.CommLog {
+    if claims.RoleID == s.cfg.Roles.ReceptionID {
+        return logs
+    }
+    visible := make([]models.CommLog, 0, len(logs))
+    for _, log := range logs {
+        if log.UserID == claims.UserID {
+            visible = append(visible, log)
+        }
     }
-    if log.UserID == 0 {
-        return errors.New("user ID is required")
+    return visible
+}
+
+// CreateCommLog creates a new communication log recorded by the logged-in user
+func (s *CommLogService) CreateCommLog(ctx context.Context, log *models.CommLog) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.CreateCommLog")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return errors.New("could not retrieve user claims from context")
+    }
+
+    if log.ContactID != nil && *log.ContactID <= 0 {
+        return Invalid("invalid contact ID")
     }
     if log.InteractionDate.IsZero() {
-        return errors.New("interaction date is required")
+        return Invalid("interaction date is required")
     }
     if log.InteractionType == "" {
-        return errors.New("interaction type is required")
+        return Invalid("interaction type is required")
     }
 
-    return s.commLogRepo.CreateCommLog(log)
+    // The log is always recorded by the logged-in user.
+    log.UserID = claims.UserID
+
+    if err := s.commLogRepo.CreateCommLog(ctx, log); err != nil {
+        return err
+    }
+    s.audit.Created(ctx, AuditEntityCommLog, log.ID, log)
+    return nil
 }
 
-// GetCommLogByID retrieves a communication log by ID
-func (s *CommLogService) GetCommLogByID(id int) (*models.CommLog, error) {
+// GetCommLogByID retrieves a communication log by ID with permission check
+func (s *CommLogService) GetCommLogByID(ctx context.Context, id int) (*models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetCommLogByID")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return nil, errors.New("could not retrieve user claims from context")
+    }
+
     if id <= 0 {
-        return nil, errors.New("invalid communication log ID")
+        return nil, Invalid("invalid communication log ID")
+    }
+
+    log, err := s.commLogRepo.GetCommLogByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    // --- PERMISSION CHECK ---
+    // A user can view if they are a Receptionist OR if they recorded the log.
+    if !s.canAccess(claims, log) {
+        s.logger.WarnContext(ctx, "Permission denied for GetCommLogByID", "user_id", claims.UserID, "role_id", claims.RoleID, "log_id", id, "log_user_id", log.UserID)
+        return nil, Forbidden("you do not have permission to view this communication log")
     }
 
-    return s.commLogRepo.GetCommLogByID(id)
+    return log, nil
 }
 
-// GetCommLogsByDealID retrieves all communication logs for a specific deal
-func (s *CommLogService) GetCommLogsByDealID(dealID int) ([]models.CommLog, error) {
-    if dealID <= 0 {
-        return nil, errors.New("invalid deal ID")
+// GetCommLogsForParent retrieves the communication logs filed under a deal or
+// lead that the logged-in user can see
+func (s *CommLogService) GetCommLogsForParent(ctx context.Context, parent ActivityParent) ([]models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetCommLogsForParent")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return nil, errors.New("could not retrieve user claims from context")
+    }
+
+    if err := parent.validate(); err != nil {
+        return nil, err
     }
 
-    return s.commLogRepo.GetCommLogsByDealID(dealID)
+    var logs []models.CommLog
+    var err error
+    if parent.Kind == ParentLead {
+        logs, err = s.commLogRepo.GetCommLogsByLeadID(ctx, parent.ID)
+    } else {
+        logs, err = s.commLogRepo.GetCommLogsByDealID(ctx, parent.ID)
+    }
+    if err != nil {
+        return nil, err
+    }
+    return s.visibleCommLogs(claims, logs), nil
 }
 
-// GetCommLogsByContactID retrieves all communication logs for a specific contact
-func (s *CommLogService) GetCommLogsByContactID(contactID int) ([]models.CommLog, error) {
+// GetCommLogsByContactID retrieves the communication logs for a specific
+// contact that the logged-in user can see
+func (s *CommLogService) GetCommLogsByContactID(ctx context.Context, contactID int) ([]models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetCommLogsByContactID")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return nil, errors.New("could not retrieve user claims from context")
+    }
+
     if contactID <= 0 {
-        return nil, errors.New("invalid contact ID")
+        return nil, Invalid("invalid contact ID")
     }
 
-    return s.commLogRepo.GetCommLogsByContactID(contactID)
+    logs, err := s.commLogRepo.GetCommLogsByContactID(ctx, contactID)
+    if err != nil {
+        return nil, err
+    }
+    return s.visibleCommLogs(claims, logs), nil
 }
 
-// GetAllCommLogs retrieves all communication logs
-func (s *CommLogService) GetAllCommLogs() ([]models.CommLog, error) {
-    return s.commLogRepo.GetAllCommLogs()
+// GetAllCommLogs retrieves all communication logs for Reception and the
+// caller's own logs for everyone else
+func (s *CommLogService) GetAllCommLogs(ctx context.Context) ([]models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetAllCommLogs")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return nil, errors.New("could not retrieve user claims from context")
+    }
+
+    if claims.RoleID != s.cfg.Roles.ReceptionID {
+        s.logger.DebugContext(ctx, "fetching communication logs for single user", "user_id", claims.UserID)
+        return s.commLogRepo.GetCommLogsForUser(ctx, claims.UserID)
+    }
+
+    s.logger.DebugContext(ctx, "fetching all communication logs for manager role", "user_id", claims.UserID)
+    return s.commLogRepo.GetAllCommLogs(ctx)
 }
 
-// UpdateCommLog updates an existing communication log
-func (s *CommLogService) UpdateCommLog(log *models.CommLog) error {
+// UpdateCommLog updates an existing communication log with permission check
+func (s *CommLogService) UpdateCommLog(ctx context.Context, log *models.CommLog) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.UpdateCommLog")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return errors.New("could not retrieve user claims from context")
+    }
+
     if log.ID <= 0 {
-        return errors.New("invalid communication log ID")
+        return Invalid("invalid communication log ID")
     }
     if log.ContactID != nil && *log.ContactID <= 0 {
-        return errors.New("invalid contact ID")
-    }
-    if log.UserID == 0 {
-        return errors.New("user ID is required")
+        return Invalid("invalid contact ID")
     }
     if log.InteractionType == "" {
-        return errors.New("interaction type is required")
+        return Invalid("interaction type is required")
     }
 
-    existingLog, err := s.commLogRepo.GetCommLogByID(log.ID)
+    existingLog, err := s.commLogRepo.GetCommLogByID(ctx, log.ID)
     if err != nil {
         return fmt.Errorf("failed to verify communication log existence: %w", err)
     }
 
-    if existingLog.UserID != log.UserID {
-        return errors.New("unauthorized to update this communication log")
+    // --- PERMISSION CHECK ---
+    // A user can update if they are a Receptionist OR if they recorded the log.
+    if !s.canAccess(claims, existingLog) {
+        s.logger.WarnContext(ctx, "Permission denied for UpdateCommLog", "user_id", claims.UserID, "role_id", claims.RoleID, "log_id", log.ID, "log_user_id", existingLog.UserID)
+        return Forbidden("you do not have permission to update this communication log")
     }
 
-    return s.commLogRepo.UpdateCommLog(log)
+    // The user who recorded the log never changes.
+    log.UserID = existingLog.UserID
+
+    if err := s.commLogRepo.UpdateCommLog(ctx, log); err != nil {
+        return err
+    }
+    s.audit.UpdatedStored(ctx, AuditEntityCommLog, log.ID, existingLog, func() (interface{}, error) {
+        return s.commLogRepo.GetCommLogByID(ctx, log.ID)
+    })
+    return nil
 }
 
-// DeleteCommLog soft deletes a communication log
-func (s *CommLogService) DeleteCommLog(id int) error {
+// DeleteCommLog soft deletes a communication log with permission check
+func (s *CommLogService) DeleteCommLog(ctx context.Context, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.DeleteCommLog")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return errors.New("could not retrieve user claims from context")
+    }
+
     if id <= 0 {
-        return errors.New("invalid communication log ID")
+        return Invalid("invalid communication log ID")
     }
 
-    return s.commLogRepo.DeleteCommLog(id)
-}
+    existingLog, err := s.commLogRepo.GetCommLogByID(ctx, id)
+    if err != nil {
+        return err
+    }
 
-// GetCommLogsForUser retrieves communication logs for a specific user
-func (s *CommLogService) GetCommLogsForUser(userID int) ([]models.CommLog, error) {
-    if userID <= 0 {
-        return nil, errors.New("invalid user ID")
+    // --- PERMISSION CHECK ---
+    // A user can delete if they are a Receptionist OR if they recorded the log.
+    if !s.canAccess(claims, existingLog) {
+        s.logger.WarnContext(ctx, "Permission denied for DeleteCommLog", "user_id", claims.UserID, "role_id", claims.RoleID, "log_id", id, "log_user_id", existingLog.UserID)
+        return Forbidden("you can only delete your own communication logs")
     }
 
-    return s.commLogRepo.GetCommLogsForUser(userID)
+    if err := s.commLogRepo.DeleteCommLog(ctx, id, expectedVersion); err != nil {
+        return err
+    }
+    s.audit.Deleted(ctx, AuditEntityCommLog, id, existingLog)
+    return nil
 }
 
-// CreateDealCommLog creates a new communication log for a deal
-func (s *CommLogService) CreateDealCommLog(log *models.CommLog) error {
-    if log.ContactID != nil && *log.ContactID <= 0 {
-        return errors.New("invalid contact ID")
-    }
-    if log.UserID == 0 {
-        return errors.New("user ID is required")
-    }
-    if log.DealID == nil || *log.DealID <= 0 {
-        return errors.New("deal ID is required")
+// GetCommLogsForUser retrieves communication logs recorded by a specific user.
+// Only Reception can list another user's logs.
+func (s *CommLogService) GetCommLogsForUser(ctx context.Context, userID int) ([]models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetCommLogsForUser")
+	defer span.End()
+
+    claims, ok := util.GetClaimsFromContext(ctx)
+    if !ok {
+        return nil, errors.New("could not retrieve user claims from context")
     }
-    if log.InteractionDate.IsZero() {
-        return errors.New("interaction date is required")
+
+    if userID <= 0 {
+        return nil, Invalid("invalid user ID")
     }
-    if log.InteractionType == "" {
-        return errors.New("interaction type is required")
+
+    // --- PERMISSION CHECK ---
+    if claims.RoleID != s.cfg.Roles.ReceptionID && userID != claims.UserID {
+        s.logger.WarnContext(ctx, "Permission denied for GetCommLogsForUser", "user_id", claims.UserID, "role_id", claims.RoleID, "requested_user_id", userID)
+        return nil, Forbidden("you can only view your own communication logs")
     }
 
-    return s.commLogRepo.CreateCommLog(log)
+    return s.commLogRepo.GetCommLogsForUser(ctx, userID)
 }
 
-// UpdateDealCommLog updates a communication log for a deal
-func (s *CommLogService) UpdateDealCommLog(log *models.CommLog) error {
-    if log.ID <= 0 {
-        return errors.New("invalid communication log ID")
+// GetCommLogForParent retrieves a communication log, reporting it as not found
+// unless it is filed under parent
+func (s *CommLogService) GetCommLogForParent(ctx context.Context, parent ActivityParent, id int) (*models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetCommLogForParent")
+	defer span.End()
+
+    log, err := s.GetCommLogByID(ctx, id)
+    if err != nil {
+        return nil, err
     }
-    if log.ContactID != nil && *log.ContactID <= 0 {
-        return errors.New("invalid contact ID")
+    if !parent.Owns(log.DealID, log.LeadID) {
+        return nil, NotFound("communication log not found for this %s", parent.Kind)
     }
-    if log.InteractionType == "" {
-        return errors.New("interaction type is required")
+    return log, nil
+}
+
+// CreateCommLogForParent creates a communication log filed under a deal or lead
+func (s *CommLogService) CreateCommLogForParent(ctx context.Context, parent ActivityParent, log *models.CommLog) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.CreateCommLogForParent")
+	defer span.End()
+
+    if err := parent.validate(); err != nil {
+        return err
     }
+    log.DealID, log.LeadID = parent.Links()
+    return s.CreateCommLog(ctx, log)
+}
+
+// UpdateCommLogForParent updates a communication log filed under a deal or
+// lead. The log stays linked to the deal and lead it was linked to before.
+func (s *CommLogService) UpdateCommLogForParent(ctx context.Context, parent ActivityParent, log *models.CommLog) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.UpdateCommLogForParent")
+	defer span.End()
 
-    existingLog, err := s.commLogRepo.GetCommLogByID(log.ID)
+    existingLog, err := s.GetCommLogForParent(ctx, parent, log.ID)
     if err != nil {
-        return fmt.Errorf("failed to verify communication log existence: %w", err)
+        return err
     }
+    log.DealID, log.LeadID = existingLog.DealID, existingLog.LeadID
+    return s.UpdateCommLog(ctx, log)
+}
 
-    if existingLog.DealID == nil || *existingLog.DealID != *log.DealID {
-        return errors.New("communication log not found for this deal")
-    }
+// DeleteCommLogForParent deletes a communication log filed under a deal or lead
+func (s *CommLogService) DeleteCommLogForParent(ctx context.Context, parent ActivityParent, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.DeleteCommLogForParent")
+	defer span.End()
 
-    return s.commLogRepo.UpdateCommLog(log)
+    if _, err := s.GetCommLogForParent(ctx, parent, id); err != nil {
+        return err
+    }
+    return s.DeleteCommLog(ctx, id, expectedVersion)
 }
 
-// DeleteDealCommLog deletes a communication log for a deal
-func (s *CommLogService) DeleteDealCommLog(id int) error {
-    if id <= 0 {
-        return errors.New("invalid communication log ID")
-    }
+// GetContactCommLog retrieves a communication log, reporting it as not found
+// unless it belongs to the contact
+func (s *CommLogService) GetContactCommLog(ctx context.Context, contactID int, id int) (*models.CommLog, error) {
+	ctx, span := tracing.Start(ctx, "CommLogService.GetContactCommLog")
+	defer span.End()
 
-    return s.commLogRepo.DeleteCommLog(id)
+    log, err := s.GetCommLogByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if log.ContactID == nil || *log.ContactID != contactID {
+        return nil, NotFound("communication log not found for this contact")
+    }
+    return log, nil
 }
 
 // CreateContactCommLog creates a new communication log for a contact
-func (s *CommLogService) CreateContactCommLog(log *models.CommLog) error {
+func (s *CommLogService) CreateContactCommLog(ctx context.Context, log *models.CommLog) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.CreateContactCommLog")
+	defer span.End()
+
     if log.ContactID == nil || *log.ContactID <= 0 {
-        return errors.New("contact ID is required")
-    }
-    if log.UserID == 0 {
-        return errors.New("user ID is required")
-    }
-    if log.InteractionDate.IsZero() {
-        return errors.New("interaction date is required")
+        return Invalid("contact ID is required")
     }
-    if log.InteractionType == "" {
-        return errors.New("interaction type is required")
-    }
-
-    return s.commLogRepo.CreateCommLog(log)
+    return s.CreateCommLog(ctx, log)
 }
 
 // UpdateContactCommLog updates a communication log for a contact
-func (s *CommLogService) UpdateContactCommLog(log *models.CommLog) error {
-    if log.ID <= 0 {
-        return errors.New("invalid communication log ID")
-    }
-    if log.ContactID == nil || *log.ContactID <= 0 {
-        return errors.New("contact ID is required")
-    }
-    if log.InteractionType == "" {
-        return errors.New("interaction type is required")
-    }
+func (s *CommLogService) UpdateContactCommLog(ctx context.Context, log *models.CommLog) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.UpdateContactCommLog")
+	defer span.End()
 
-    existingLog, err := s.commLogRepo.GetCommLogByID(log.ID)
-    if err != nil {
-        return fmt.Errorf("failed to verify communication log existence: %w", err)
+    if log.ContactID == nil || *log.ContactID <= 0 {
+        return Invalid("contact ID is required")
     }
-
-    if existingLog.ContactID == nil || log.ContactID == nil || *existingLog.ContactID != *log.ContactID {
-        return errors.New("communication log not found for this contact")
+    if _, err := s.GetContactCommLog(ctx, *log.ContactID, log.ID); err != nil {
+        return err
     }
-
-    return s.commLogRepo.UpdateCommLog(log)
+    return s.UpdateCommLog(ctx, log)
 }
 
 // DeleteContactCommLog deletes a communication log for a contact
-func (s *CommLogService) DeleteContactCommLog(id int) error {
-    if id <= 0 {
-        return errors.New("invalid communication log ID")
-    }
+func (s *CommLogService) DeleteContactCommLog(ctx context.Context, contactID int, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "CommLogService.DeleteContactCommLog")
+	defer span.End()
 
-    return s.commLogRepo.DeleteCommLog(id)
-}
\ No newline at end of file
+    if _, err := s.GetContactCommLog(ctx, contactID, id); err != nil {
+        return err
+    }
+    return s.DeleteCommLog(ctx, id, expectedVersion)
+}
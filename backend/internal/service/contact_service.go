@@ -5,26 +5,29 @@ import (
 	"crm-project/internal/config" // Import config
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"crm-project/internal/util" // <-- Import for context helpers
 	"database/sql"
 	"errors"
-	"fmt"
 	"log/slog"
-	"strings"
 )
 
 type ContactService struct {
-	repo   *postgres.ContactRepo
+	repo   postgres.ContactRepository
+	audit  *AuditService
 	cfg    *config.Config // Add config here
 	logger *slog.Logger
 }
 
-func NewContactService(repo *postgres.ContactRepo, cfg *config.Config, logger *slog.Logger) *ContactService {
-	return &ContactService{repo: repo, cfg: cfg, logger: logger}
+func NewContactService(repo postgres.ContactRepository, audit *AuditService, cfg *config.Config, logger *slog.Logger) *ContactService {
+	return &ContactService{repo: repo, audit: audit, cfg: cfg, logger: logger}
 }
 
 // CreateContact now automatically assigns the logged-in user as the creator.
 func (s *ContactService) CreateContact(ctx context.Context, contact models.Contact) (int, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.CreateContact")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return 0, errors.New("could not retrieve user claims from context")
@@ -33,8 +36,8 @@ func (s *ContactService) CreateContact(ctx context.Context, contact models.Conta
 	// --- PERMISSION CHECK ---
 	// Only Reception can create contacts.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for CreateContact", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return 0, fmt.Errorf("forbidden: only receptionists can create contacts")
+		s.logger.WarnContext(ctx, "Permission denied for CreateContact", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return 0, Forbidden("only receptionists can create contacts")
 	}
 
 	// Set the creator of the contact to the currently logged-in user's ID.
@@ -42,14 +45,23 @@ func (s *ContactService) CreateContact(ctx context.Context, contact models.Conta
 
 	// You can add more validation here if needed (e.g., check for duplicate phone numbers)
 	if contact.FirstName == "" || contact.PrimaryPhone == "" {
-		return 0, errors.New("first name and primary phone are required")
+		return 0, Invalid("first name and primary phone are required")
 	}
 
-	return s.repo.Create(ctx, contact)
+	newID, err := s.repo.Create(ctx, contact)
+	if err != nil {
+		return 0, err
+	}
+	contact.ID = newID
+	s.audit.Created(ctx, AuditEntityContact, newID, contact)
+	return newID, nil
 }
 
 // GetAllContacts now intelligently filters the list based on the user's role.
 func (s *ContactService) GetAllContacts(ctx context.Context) ([]models.Contact, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.GetAllContacts")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -57,20 +69,23 @@ func (s *ContactService) GetAllContacts(ctx context.Context) ([]models.Contact,
 
 	// If the user is a Sales Agent, only show contacts they created.
 	if claims.RoleID == s.cfg.Roles.SalesAgentID { // Use cfg.Roles.SalesAgentID
-		s.logger.Debug("fetching contacts for single sales agent", "user_id", claims.UserID)
+		s.logger.DebugContext(ctx, "fetching contacts for single sales agent", "user_id", claims.UserID)
 		return s.repo.GetAllForUser(ctx, claims.UserID)
 	}
 
 	// Otherwise (for Reception/Manager), show all contacts.
-	s.logger.Debug("fetching all contacts for manager role", "user_id", claims.UserID)
+	s.logger.DebugContext(ctx, "fetching all contacts for manager role", "user_id", claims.UserID)
 	return s.repo.GetAll(ctx)
 }
 
 // GetContactByID now includes a permission check.
 func (s *ContactService) GetContactByID(ctx context.Context, id int) (*models.Contact, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.GetContactByID")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		s.logger.Error("Could not retrieve user claims for GetContactByID", "contact_id", id)
+		s.logger.ErrorContext(ctx, "Could not retrieve user claims for GetContactByID", "contact_id", id)
 		return nil, errors.New("could not retrieve user claims")
 	}
 
@@ -79,7 +94,7 @@ func (s *ContactService) GetContactByID(ctx context.Context, id int) (*models.Co
 		return nil, err
 	}
 	if contact == nil {
-		return nil, fmt.Errorf("contact with ID %d not found", id)
+		return nil, NotFound("contact with ID %d not found", id)
 	}
 
 	// --- PERMISSION CHECK ---
@@ -87,8 +102,8 @@ func (s *ContactService) GetContactByID(ctx context.Context, id int) (*models.Co
 	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (contact.CreatedBy != nil && *contact.CreatedBy == claims.UserID)
 
 	if !isAllowed {
-		s.logger.Warn("Permission denied for GetContactByID", "user_id", claims.UserID, "role_id", claims.RoleID, "contact_id", id, "contact_created_by", contact.CreatedBy)
-		return nil, fmt.Errorf("forbidden: you do not have permission to view this contact")
+		s.logger.WarnContext(ctx, "Permission denied for GetContactByID", "user_id", claims.UserID, "role_id", claims.RoleID, "contact_id", id, "contact_created_by", contact.CreatedBy)
+		return nil, Forbidden("you do not have permission to view this contact")
 	}
 
 	return contact, nil
@@ -96,106 +111,139 @@ func (s *ContactService) GetContactByID(ctx context.Context, id int) (*models.Co
 
 // UpdateContact now includes a permission check with logging.
 func (s *ContactService) UpdateContact(ctx context.Context, id int, contact models.Contact) error {
+	ctx, span := tracing.Start(ctx, "ContactService.UpdateContact")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		s.logger.Error("Could not retrieve user claims for contact update", "contact_id", id)
+		s.logger.ErrorContext(ctx, "Could not retrieve user claims for contact update", "contact_id", id)
 		return errors.New("could not retrieve user claims")
 	}
 
-	s.logger.Debug("Updating contact", "contact_id", id, "user_id", claims.UserID, "role_id", claims.RoleID)
+	s.logger.DebugContext(ctx, "Updating contact", "contact_id", id, "user_id", claims.UserID, "role_id", claims.RoleID)
 
 	// First, get the contact we want to update to check its owner.
 	existingContact, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing contact for update", "contact_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to fetch existing contact for update", "contact_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("contact with ID %d not found", id)
+			return NotFound("contact with ID %d not found", id)
 		}
 		return err
 	}
 	if existingContact == nil {
-		s.logger.Warn("Existing contact not found", "contact_id", id)
-		return fmt.Errorf("contact with ID %d not found", id)
+		s.logger.WarnContext(ctx, "Existing contact not found", "contact_id", id)
+		return NotFound("contact with ID %d not found", id)
 	}
 
-	s.logger.Debug("Existing contact fetched", "contact_id", id, "created_by", existingContact.CreatedBy)
+	s.logger.DebugContext(ctx, "Existing contact fetched", "contact_id", id, "created_by", existingContact.CreatedBy)
 
 	// --- PERMISSION CHECK ---
-	s.logger.Info("Permission check values", "claims.RoleID", claims.RoleID, "s.cfg.Roles.ReceptionID", s.cfg.Roles.ReceptionID, "existingContact.CreatedBy", existingContact.CreatedBy, "claims.UserID", claims.UserID)
+	s.logger.InfoContext(ctx, "Permission check values", "claims.RoleID", claims.RoleID, "s.cfg.Roles.ReceptionID", s.cfg.Roles.ReceptionID, "existingContact.CreatedBy", existingContact.CreatedBy, "claims.UserID", claims.UserID)
 	// A user can update if they are a Receptionist OR if they are the original creator.
 	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (existingContact.CreatedBy != nil && *existingContact.CreatedBy == claims.UserID)
 
 	if !isAllowed {
-		s.logger.Warn("Permission denied for contact update", "user_id", claims.UserID, "role_id", claims.RoleID, "contact_id", id, "contact_created_by", existingContact.CreatedBy)
-		return fmt.Errorf("forbidden: you do not have permission to update this contact")
+		s.logger.WarnContext(ctx, "Permission denied for contact update", "user_id", claims.UserID, "role_id", claims.RoleID, "contact_id", id, "contact_created_by", existingContact.CreatedBy)
+		return Forbidden("you do not have permission to update this contact")
 	}
 
 	contact.ID = id
 	// We should preserve the original creator
 	contact.CreatedBy = existingContact.CreatedBy
 
-	s.logger.Debug("Permission granted, updating contact", "contact_id", id)
+	s.logger.DebugContext(ctx, "Permission granted, updating contact", "contact_id", id)
 
 	err = s.repo.Update(ctx, contact)
 	if err != nil {
-		s.logger.Error("Failed to update contact in repo", "contact_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to update contact in repo", "contact_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("contact with ID %d not found during update", id)
+			return NotFound("contact with ID %d not found during update", id)
 		}
 		return err
 	}
-	s.logger.Info("Successfully updated contact", "contact_id", id, "user_id", claims.UserID)
+	s.audit.UpdatedStored(ctx, AuditEntityContact, id, existingContact, func() (interface{}, error) {
+		return s.repo.GetByID(ctx, id)
+	})
+	s.logger.InfoContext(ctx, "Successfully updated contact", "contact_id", id, "user_id", claims.UserID)
 	return nil
 }
 
+// PatchContact applies a JSON merge patch (RFC 7396) to a contact and returns
+// the updated contact. The same permission rules as UpdateContact apply.
+func (s *ContactService) PatchContact(ctx context.Context, id int, patch []byte, expectedVersion int) (*models.Contact, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.PatchContact")
+	defer span.End()
+
+	existingContact, err := s.GetContactByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var contact models.Contact
+	if err := applyMergePatch(existingContact, patch, contactPatchFields, contactPatchFields, &contact); err != nil {
+		return nil, err
+	}
+	contact.Version = patchVersion(existingContact.Version, expectedVersion)
+
+	if err := s.UpdateContact(ctx, id, contact); err != nil {
+		return nil, err
+	}
+	return s.GetContactByID(ctx, id)
+}
+
 // DeleteContact now includes the same permission check with logging.
-func (s *ContactService) DeleteContact(ctx context.Context, id int) error {
+func (s *ContactService) DeleteContact(ctx context.Context, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "ContactService.DeleteContact")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		s.logger.Error("Could not retrieve user claims for contact deletion", "contact_id", id)
+		s.logger.ErrorContext(ctx, "Could not retrieve user claims for contact deletion", "contact_id", id)
 		return errors.New("could not retrieve user claims")
 	}
 
-	s.logger.Debug("Deleting contact", "contact_id", id, "user_id", claims.UserID, "role_id", claims.RoleID)
+	s.logger.DebugContext(ctx, "Deleting contact", "contact_id", id, "user_id", claims.UserID, "role_id", claims.RoleID)
 
 	existingContact, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing contact for deletion", "contact_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to fetch existing contact for deletion", "contact_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("contact with ID %d not found", id)
+			return NotFound("contact with ID %d not found", id)
 		}
 		return err
 	}
 	if existingContact == nil {
-		s.logger.Warn("Existing contact not found", "contact_id", id)
-		return fmt.Errorf("contact with ID %d not found", id)
+		s.logger.WarnContext(ctx, "Existing contact not found", "contact_id", id)
+		return NotFound("contact with ID %d not found", id)
 	}
 
-	s.logger.Debug("Existing contact fetched", "contact_id", id, "created_by", existingContact.CreatedBy)
+	s.logger.DebugContext(ctx, "Existing contact fetched", "contact_id", id, "created_by", existingContact.CreatedBy)
 
 	// --- PERMISSION CHECK ---
-	s.logger.Info("Permission check values", "claims.RoleID", claims.RoleID, "s.cfg.Roles.ReceptionID", s.cfg.Roles.ReceptionID, "existingContact.CreatedBy", existingContact.CreatedBy, "claims.UserID", claims.UserID)
+	s.logger.InfoContext(ctx, "Permission check values", "claims.RoleID", claims.RoleID, "s.cfg.Roles.ReceptionID", s.cfg.Roles.ReceptionID, "existingContact.CreatedBy", existingContact.CreatedBy, "claims.UserID", claims.UserID)
 	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (existingContact.CreatedBy != nil && *existingContact.CreatedBy == claims.UserID)
 
 	if !isAllowed {
-		s.logger.Warn("Permission denied for contact deletion", "user_id", claims.UserID, "role_id", claims.RoleID, "contact_id", id, "contact_created_by", existingContact.CreatedBy)
-		return fmt.Errorf("forbidden: you do not have permission to delete this contact")
+		s.logger.WarnContext(ctx, "Permission denied for contact deletion", "user_id", claims.UserID, "role_id", claims.RoleID, "contact_id", id, "contact_created_by", existingContact.CreatedBy)
+		return Forbidden("you do not have permission to delete this contact")
 	}
 
-	s.logger.Debug("Permission granted, deleting contact", "contact_id", id)
+	s.logger.DebugContext(ctx, "Permission granted, deleting contact", "contact_id", id)
 
-	err = s.repo.Delete(ctx, id)
+	err = s.repo.Delete(ctx, id, expectedVersion)
 	if err != nil {
-		s.logger.Error("Failed to delete contact in repo", "contact_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to delete contact in repo", "contact_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("contact with ID %d not found", id)
+			return NotFound("contact with ID %d not found", id)
 		}
 		// Also check for foreign key constraint errors
-		if strings.Contains(err.Error(), "violates foreign key constraint") {
-			return fmt.Errorf("cannot delete contact: it is linked to existing leads or deals")
+		if postgres.IsForeignKeyViolation(err) {
+			return Conflict("cannot delete contact: it is linked to existing leads or deals")
 		}
 		return err
 	}
-	s.logger.Info("Successfully deleted contact", "contact_id", id, "user_id", claims.UserID)
+	s.audit.Deleted(ctx, AuditEntityContact, id, existingContact)
+	s.logger.InfoContext(ctx, "Successfully deleted contact", "contact_id", id, "user_id", claims.UserID)
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"crm-project/internal/config" // Import config
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"crm-project/internal/util" // Import util for claims
 	"database/sql"
 	"errors"
@@ -13,27 +14,33 @@ import (
 )
 
 type DealService struct {
-	dealRepo     *postgres.DealRepo
-	leadRepo     *postgres.LeadRepo
-	propertyRepo *postgres.PropertyRepo
+	dealRepo     postgres.DealRepository
+	leadRepo     postgres.LeadRepository
+	propertyRepo postgres.PropertyRepository
+	tx           postgres.Transactor
+	audit        *AuditService
+	playbooks    *PlaybookService
 	cfg          *config.Config // Add config here
 	logger       *slog.Logger
 }
 
-func NewDealService(dr *postgres.DealRepo, lr *postgres.LeadRepo, pr *postgres.PropertyRepo, cfg *config.Config, logger *slog.Logger) *DealService {
-	return &DealService{dealRepo: dr, leadRepo: lr, propertyRepo: pr, cfg: cfg, logger: logger}
+func NewDealService(dr postgres.DealRepository, lr postgres.LeadRepository, pr postgres.PropertyRepository, tx postgres.Transactor, audit *AuditService, playbooks *PlaybookService, cfg *config.Config, logger *slog.Logger) *DealService {
+	return &DealService{dealRepo: dr, leadRepo: lr, propertyRepo: pr, tx: tx, audit: audit, playbooks: playbooks, cfg: cfg, logger: logger}
 }
 
 // THIS METHOD NOW HAS ADVANCED VALIDATION AND ROLE-AWARENESS
 func (s *DealService) CreateDeal(ctx context.Context, d models.Deal) (int, error) {
-	s.logger.Debug("Attempting to create deal", "incoming_deal", d)
+	ctx, span := tracing.Start(ctx, "DealService.CreateDeal")
+	defer span.End()
+
+	s.logger.DebugContext(ctx, "Attempting to create deal", "incoming_deal", d)
 
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
-		s.logger.Error("Could not retrieve user claims from context for CreateDeal")
+		s.logger.ErrorContext(ctx, "Could not retrieve user claims from context for CreateDeal")
 		return 0, errors.New("could not retrieve user claims from context")
 	}
-	s.logger.Debug("Claims retrieved from context", "user_id", claims.UserID, "role_id", claims.RoleID)
+	s.logger.DebugContext(ctx, "Claims retrieved from context", "user_id", claims.UserID, "role_id", claims.RoleID)
 
 	// --- PERMISSION CHECK ---
 	// Sales agents can only create deals for themselves.
@@ -45,96 +52,139 @@ func (s *DealService) CreateDeal(ctx context.Context, d models.Deal) (int, error
 			d.CreatedBy = sql.NullInt64{Int64: int64(claims.UserID), Valid: true}
 		}
 	} else {
-		s.logger.Warn("Permission denied for CreateDeal", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return 0, fmt.Errorf("forbidden: only receptionists and sales agents can create deals")
+		s.logger.WarnContext(ctx, "Permission denied for CreateDeal", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return 0, Forbidden("only receptionists and sales agents can create deals")
 	}
 
 	// --- Deal Integrity Validation ---
 	lead, err := s.leadRepo.GetByID(ctx, d.LeadID)
 	if err != nil {
-		s.logger.Warn("Failed to retrieve lead for deal creation", "lead_id", d.LeadID, "error", err)
-		return 0, fmt.Errorf("invalid lead_id: %d, error: %w", d.LeadID, err)
+		s.logger.WarnContext(ctx, "Failed to retrieve lead for deal creation", "lead_id", d.LeadID, "error", err)
+		return 0, fmt.Errorf("failed to retrieve lead %d: %w", d.LeadID, err)
 	}
 	if lead == nil {
-		s.logger.Warn("Lead not found for deal creation", "lead_id", d.LeadID)
-		return 0, fmt.Errorf("lead with ID %d not found", d.LeadID)
+		s.logger.WarnContext(ctx, "Lead not found for deal creation", "lead_id", d.LeadID)
+		return 0, InvalidField("lead_id", fmt.Sprintf("lead %d does not exist", d.LeadID))
 	}
-	s.logger.Debug("Lead retrieved", "lead", lead)
+	s.logger.DebugContext(ctx, "Lead retrieved", "lead", lead)
 
 	if lead.PropertyID == nil {
-		s.logger.Warn("Lead not linked to a property", "lead_id", d.LeadID)
-		return 0, errors.New("cannot create a deal from a lead that is not linked to a property")
+		s.logger.WarnContext(ctx, "Lead not linked to a property", "lead_id", d.LeadID)
+		return 0, Invalid("cannot create a deal from a lead that is not linked to a property")
 	}
 	if d.PropertyID != *lead.PropertyID {
-		s.logger.Warn("Deal property ID mismatch with lead's property ID", "deal_property_id", d.PropertyID, "lead_property_id", *lead.PropertyID)
-		return 0, fmt.Errorf("deal property ID (%d) does not match the lead's property ID (%d)", d.PropertyID, *lead.PropertyID)
+		s.logger.WarnContext(ctx, "Deal property ID mismatch with lead's property ID", "deal_property_id", d.PropertyID, "lead_property_id", *lead.PropertyID)
+		return 0, InvalidField("property_id", fmt.Sprintf("deal property ID (%d) does not match the lead's property ID (%d)", d.PropertyID, *lead.PropertyID))
 	}
 	if d.DealAmount <= 0 {
-		s.logger.Warn("Deal amount is not positive", "deal_amount", d.DealAmount)
-		return 0, errors.New("deal amount must be positive")
+		s.logger.WarnContext(ctx, "Deal amount is not positive", "deal_amount", d.DealAmount)
+		return 0, InvalidField("deal_amount", "must be positive")
 	}
 
-	s.logger.Debug("CreatedBy set for deal", "created_by", d.CreatedBy.Int64)
+	s.logger.DebugContext(ctx, "CreatedBy set for deal", "created_by", d.CreatedBy.Int64)
 
-	newID, err := s.dealRepo.Create(ctx, d)
-	if err != nil {
-		s.logger.Error("Failed to create deal in repository", "deal", d, "error", err)
-		return 0, fmt.Errorf("failed to create deal: %w", err)
-	}
-	s.logger.Info("Deal successfully created in repository", "deal_id", newID)
+	// The deal and the property status change are one unit of work, so a
+	// Closed-Won deal is never left on a property that is still Available.
+	var newID int
+	var soldProperty *models.Property
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		soldProperty = nil
+		newID, err = s.dealRepo.Create(ctx, d)
+		if err != nil {
+			return fmt.Errorf("failed to create deal: %w", err)
+		}
+		d.ID = newID
 
-	// --- Automatic Property Status Update ---
-	if d.DealStatus == "Closed-Won" {
-		if err := s.updatePropertyStatusOnDealClose(ctx, d.PropertyID); err != nil {
-			s.logger.Warn("Deal created, but failed to update property status", "deal_id", newID, "error", err)
+		// --- Automatic Property Status Update ---
+		if d.DealStatus == "Closed-Won" {
+			if soldProperty, err = s.updatePropertyStatusOnDealClose(ctx, d.PropertyID); err != nil {
+				return fmt.Errorf("failed to mark property %d as sold: %w", d.PropertyID, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to create deal", "deal", d, "error", err)
+		return 0, err
 	}
+	s.audit.Created(ctx, AuditEntityDeal, newID, d)
+	s.auditPropertySold(ctx, soldProperty)
+	s.logger.InfoContext(ctx, "Deal successfully created in repository", "deal_id", newID)
+
+	// A new deal enters its first stage, which may have a playbook.
+	s.playbooks.ApplyForDeal(ctx, &d)
 	return newID, nil
 }
 
-// New helper function
-func (s *DealService) updatePropertyStatusOnDealClose(ctx context.Context, propertyID int) error {
-	s.logger.Info("deal closed, attempting to update property status to Sold", "property_id", propertyID)
+// updatePropertyStatusOnDealClose marks a property as sold and returns it as
+// it was before, for auditPropertySold to record once the change commits.
+func (s *DealService) updatePropertyStatusOnDealClose(ctx context.Context, propertyID int) (*models.Property, error) {
+	s.logger.InfoContext(ctx, "deal closed, attempting to update property status to Sold", "property_id", propertyID)
 	property, err := s.propertyRepo.GetByID(ctx, propertyID)
 	if err != nil {
-		return fmt.Errorf("could not find property to update: %w", err)
+		return nil, fmt.Errorf("could not find property to update: %w", err)
+	}
+
+	if property == nil {
+		return nil, NotFound("property with ID %d not found", propertyID)
 	}
 
+	before := *property
 	property.Status = "Sold"
-	return s.propertyRepo.Update(ctx, *property)
+	if err := s.propertyRepo.Update(ctx, *property); err != nil {
+		return nil, err
+	}
+	return &before, nil
+}
+
+// auditPropertySold records the update of a property marked as sold by
+// updatePropertyStatusOnDealClose. A nil before records nothing.
+func (s *DealService) auditPropertySold(ctx context.Context, before *models.Property) {
+	if before == nil {
+		return
+	}
+	s.audit.UpdatedStored(ctx, AuditEntityProperty, before.ID, before, func() (interface{}, error) {
+		return s.propertyRepo.GetByID(ctx, before.ID)
+	})
 }
 
 // GetAllDeals now intelligently filters the list based on the user's role.
 func (s *DealService) GetAllDeals(ctx context.Context, userID int, roleID int) ([]models.Deal, error) {
+	ctx, span := tracing.Start(ctx, "DealService.GetAllDeals")
+	defer span.End()
+
 	// If the user is a Sales Agent, only show deals they created.
 	if roleID == s.cfg.Roles.SalesAgentID {
-		s.logger.Debug("fetching deals for single sales agent", "user_id", userID)
+		s.logger.DebugContext(ctx, "fetching deals for single sales agent", "user_id", userID)
 		return s.dealRepo.GetAllForUser(ctx, userID)
 	}
 
 	// For other roles (like Manager/Reception), show all deals.
-	s.logger.Debug("fetching all deals for manager role", "user_id", userID)
+	s.logger.DebugContext(ctx, "fetching all deals for manager role", "user_id", userID)
 	return s.dealRepo.GetAll(ctx)
 }
 
 func (s *DealService) GetDealByID(ctx context.Context, dealID int, userID int, roleID int) (*models.Deal, error) {
+	ctx, span := tracing.Start(ctx, "DealService.GetDealByID")
+	defer span.End()
+
 	deal, err := s.dealRepo.GetByID(ctx, dealID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("deal with ID %d not found", dealID)
+			return nil, NotFound("deal with ID %d not found", dealID)
 		}
 		return nil, fmt.Errorf("failed to get deal by ID: %w", err)
 	}
 	if deal == nil {
-		return nil, fmt.Errorf("deal with ID %d not found", dealID)
+		return nil, NotFound("deal with ID %d not found", dealID)
 	}
 
 	// Permission check: Receptionist can view all, SalesAgent can view their own
 	isAllowed := roleID == s.cfg.Roles.ReceptionID || (deal.CreatedBy.Valid && deal.CreatedBy.Int64 == int64(userID))
 
 	if !isAllowed {
-		s.logger.Warn("Permission denied for deal viewing", "user_id", userID, "role_id", roleID, "deal_id", dealID, "deal_created_by", deal.CreatedBy)
-		return nil, fmt.Errorf("unauthorized to view this deal")
+		s.logger.WarnContext(ctx, "Permission denied for deal viewing", "user_id", userID, "role_id", roleID, "deal_id", dealID, "deal_created_by", deal.CreatedBy)
+		return nil, Forbidden("you do not have permission to view this deal")
 	}
 
 	return deal, nil
@@ -143,17 +193,20 @@ func (s *DealService) GetDealByID(ctx context.Context, dealID int, userID int, r
 
 // UpdateDeal now includes a permission check.
 func (s *DealService) UpdateDeal(ctx context.Context, id int, d models.Deal, userID int, roleID int) error {
+	ctx, span := tracing.Start(ctx, "DealService.UpdateDeal")
+	defer span.End()
+
 	existingDeal, err := s.dealRepo.GetByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing deal for update", "deal_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to fetch existing deal for update", "deal_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("deal with ID %d not found", id)
+			return NotFound("deal with ID %d not found", id)
 		}
 		return err
 	}
 	if existingDeal == nil {
-		s.logger.Warn("Existing deal not found", "deal_id", id)
-		return fmt.Errorf("deal with ID %d not found", id)
+		s.logger.WarnContext(ctx, "Existing deal not found", "deal_id", id)
+		return NotFound("deal with ID %d not found", id)
 	}
 
 	// --- PERMISSION CHECK ---
@@ -163,48 +216,93 @@ func (s *DealService) UpdateDeal(ctx context.Context, id int, d models.Deal, use
 	} else if roleID == s.cfg.Roles.SalesAgentID {
 		// Sales agents can only update deals they created.
 		if !existingDeal.CreatedBy.Valid || existingDeal.CreatedBy.Int64 != int64(userID) {
-			s.logger.Warn("Permission denied for deal update: Sales agent attempting to update another user's deal", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
-			return fmt.Errorf("unauthorized: sales agents can only update their own deals")
+			s.logger.WarnContext(ctx, "Permission denied for deal update: Sales agent attempting to update another user's deal", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
+			return Forbidden("sales agents can only update their own deals")
 		}
 	} else {
-		s.logger.Warn("Permission denied for deal update", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
-		return fmt.Errorf("unauthorized")
+		s.logger.WarnContext(ctx, "Permission denied for deal update", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
+		return Forbidden("you do not have permission to perform this action")
 	}
 
 	d.ID = id
 	// Preserve original creator
 	d.CreatedBy = existingDeal.CreatedBy
 
-	err = s.dealRepo.Update(ctx, d)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("deal with ID %d not found during update", id)
+	// The deal and the property status change are one unit of work.
+	var soldProperty *models.Property
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		soldProperty = nil
+		err := s.dealRepo.Update(ctx, d)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return NotFound("deal with ID %d not found during update", id)
+			}
+			return err
+		}
+
+		// --- Automatic Property Status Update (only if status changes to Closed-Won) ---
+		if d.DealStatus == "Closed-Won" && existingDeal.DealStatus != "Closed-Won" {
+			if soldProperty, err = s.updatePropertyStatusOnDealClose(ctx, d.PropertyID); err != nil {
+				s.logger.ErrorContext(ctx, "Failed to update property status, rolling back deal update", "deal_id", id, "error", err)
+				return fmt.Errorf("failed to mark property %d as sold: %w", d.PropertyID, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	s.audit.UpdatedStored(ctx, AuditEntityDeal, id, existingDeal, func() (interface{}, error) {
+		return s.dealRepo.GetByID(ctx, id)
+	})
+	s.auditPropertySold(ctx, soldProperty)
 
-	// --- Automatic Property Status Update (only if status changes to Closed-Won) ---
-	if d.DealStatus == "Closed-Won" && existingDeal.DealStatus != "Closed-Won" {
-		if err := s.updatePropertyStatusOnDealClose(ctx, d.PropertyID); err != nil {
-			s.logger.Warn("Deal updated, but failed to update property status", "deal_id", id, "error", err)
-		}
+	// Entering a new stage runs its playbooks.
+	if d.StageID != existingDeal.StageID {
+		s.playbooks.ApplyForDeal(ctx, &d)
 	}
 	return nil
 }
 
+// PatchDeal applies a JSON merge patch (RFC 7396) to a deal and returns the
+// updated deal. The same permission rules as UpdateDeal apply.
+func (s *DealService) PatchDeal(ctx context.Context, id int, patch []byte, expectedVersion int, userID int, roleID int) (*models.Deal, error) {
+	ctx, span := tracing.Start(ctx, "DealService.PatchDeal")
+	defer span.End()
+
+	existingDeal, err := s.GetDealByID(ctx, id, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var d models.Deal
+	if err := applyMergePatch(existingDeal, patch, dealPatchFields, dealPatchFields, &d); err != nil {
+		return nil, err
+	}
+	d.Version = patchVersion(existingDeal.Version, expectedVersion)
+
+	if err := s.UpdateDeal(ctx, id, d, userID, roleID); err != nil {
+		return nil, err
+	}
+	return s.GetDealByID(ctx, id, userID, roleID)
+}
+
 // DeleteDeal now includes a permission check.
-func (s *DealService) DeleteDeal(ctx context.Context, id int, userID int, roleID int) error {
+func (s *DealService) DeleteDeal(ctx context.Context, id int, expectedVersion int, userID int, roleID int) error {
+	ctx, span := tracing.Start(ctx, "DealService.DeleteDeal")
+	defer span.End()
+
 	existingDeal, err := s.dealRepo.GetByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing deal for deletion", "deal_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to fetch existing deal for deletion", "deal_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("deal with ID %d not found", id)
+			return NotFound("deal with ID %d not found", id)
 		}
 		return err
 	}
 	if existingDeal == nil {
-		s.logger.Warn("Existing deal not found", "deal_id", id)
-		return fmt.Errorf("deal with ID %d not found", id)
+		s.logger.WarnContext(ctx, "Existing deal not found", "deal_id", id)
+		return NotFound("deal with ID %d not found", id)
 	}
 
 	// --- PERMISSION CHECK ---
@@ -214,20 +312,21 @@ func (s *DealService) DeleteDeal(ctx context.Context, id int, userID int, roleID
 	} else if roleID == s.cfg.Roles.SalesAgentID {
 		// Sales agents can only delete deals they created.
 		if !existingDeal.CreatedBy.Valid || existingDeal.CreatedBy.Int64 != int64(userID) {
-			s.logger.Warn("Permission denied for deal deletion: Sales agent attempting to delete another user's deal", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
-			return fmt.Errorf("unauthorized: sales agents can only delete their own deals")
+			s.logger.WarnContext(ctx, "Permission denied for deal deletion: Sales agent attempting to delete another user's deal", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
+			return Forbidden("sales agents can only delete their own deals")
 		}
 	} else {
-		s.logger.Warn("Permission denied for deal deletion", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
-		return fmt.Errorf("unauthorized")
+		s.logger.WarnContext(ctx, "Permission denied for deal deletion", "user_id", userID, "role_id", roleID, "deal_id", id, "deal_created_by", existingDeal.CreatedBy)
+		return Forbidden("you do not have permission to perform this action")
 	}
 
-	err = s.dealRepo.Delete(ctx, id)
+	err = s.dealRepo.Delete(ctx, id, expectedVersion)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("deal with ID %d not found", id)
+			return NotFound("deal with ID %d not found", id)
 		}
 		return err
 	}
+	s.audit.Deleted(ctx, AuditEntityDeal, id, existingDeal)
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/util"
+	"errors"
+	"fmt"
+)
+
+// Domain errors. Handlers map them to HTTP status codes: NotFoundError to
+// 404, ForbiddenError to 403, ValidationError to 400 and ConflictError to 409.
+// Any other error is treated as an internal error.
+
+// NotFoundError reports that a record does not exist or is not visible.
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string { return e.Message }
+
+// ForbiddenError reports that the caller is not allowed to perform an action.
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string { return e.Message }
+
+// ValidationError reports invalid input. Fields lists the offending fields,
+// if known.
+type ValidationError struct {
+	Message string
+	Fields  []util.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	return e.Message + ": " + util.FieldErrors(e.Fields).Error()
+}
+
+// ConflictError reports that an action conflicts with the current state,
+// e.g. a duplicate username.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string { return e.Message }
+
+// NotFound returns a NotFoundError with a formatted message.
+func NotFound(format string, args ...interface{}) error {
+	return &NotFoundError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden returns a ForbiddenError with a formatted message.
+func Forbidden(format string, args ...interface{}) error {
+	return &ForbiddenError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Invalid returns a ValidationError with a formatted message.
+func Invalid(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidField returns a ValidationError for a single field.
+func InvalidField(field, message string) error {
+	return &ValidationError{
+		Message: "validation failed",
+		Fields:  []util.FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Conflict returns a ConflictError with a formatted message.
+func Conflict(format string, args ...interface{}) error {
+	return &ConflictError{Message: fmt.Sprintf(format, args...)}
+}
+
+// validateStruct runs the struct tag validator and reports failures as a
+// ValidationError with one entry per field.
+func validateStruct(s interface{}) error {
+	err := util.ValidateStruct(s)
+	var fieldErrs util.FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return &ValidationError{Message: "validation failed", Fields: fieldErrs}
+	}
+	return err
+}
+
+// ErrVersionConflict is returned by update and delete methods when the caller
+// passed an expected version and the record has since been modified.
+var ErrVersionConflict = postgres.ErrVersionConflict
+
+// ErrNotFound is wrapped by errors from the activity repositories when a
+// record does not exist.
+var ErrNotFound = postgres.ErrNotFound
+
+// ErrInvalidPatch is returned by Patch methods when the merge patch is
+// malformed or names a field that cannot be patched.
+var ErrInvalidPatch = errors.New("invalid patch")
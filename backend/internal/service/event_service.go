@@ -1,145 +1,304 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
 )
 
 type EventService struct {
 	eventRepo postgres.EventRepository
+	audit     *AuditService
+	cfg       *config.Config
+	logger    *slog.Logger
 }
 
-func NewEventService(eventRepo postgres.EventRepository) *EventService {
-	return &EventService{eventRepo: eventRepo}
+func NewEventService(eventRepo postgres.EventRepository, audit *AuditService, cfg *config.Config, logger *slog.Logger) *EventService {
+	return &EventService{eventRepo: eventRepo, audit: audit, cfg: cfg, logger: logger}
 }
 
-// CreateEvent creates a new event
-func (s *EventService) CreateEvent(event *models.Event) error {
-	if event.EventName == "" {
-		return errors.New("event name cannot be empty")
+// canAccess reports whether the caller may view or change event. Reception can
+// access every event; everyone else only the events they organize.
+func (s *EventService) canAccess(claims *dto.Claims, event *models.Event) bool {
+	return claims.RoleID == s.cfg.Roles.ReceptionID || event.OrganizerID == claims.UserID
+}
+
+// visibleEvents drops the events the caller is not allowed to see.
+func (s *EventService) visibleEvents(claims *dto.Claims, events []models.Event) []models.Event {
+	if claims.RoleID == s.cfg.Roles.ReceptionID {
+		return events
 	}
-	if event.OrganizerID == 0 {
-		return errors.New("organizer ID is required")
+	visible := make([]models.Event, 0, len(events))
+	for _, event := range events {
+		if event.OrganizerID == claims.UserID {
+			visible = append(visible, event)
+		}
+	}
+	return visible
+}
+
+// CreateEvent creates a new event organized by the logged-in user
+func (s *EventService) CreateEvent(ctx context.Context, event *models.Event) error {
+	ctx, span := tracing.Start(ctx, "EventService.CreateEvent")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
+	if event.EventName == "" {
+		return Invalid("event name cannot be empty")
 	}
 	if event.StartTime.After(event.EndTime) {
-		return errors.New("start time must be before end time")
+		return Invalid("start time must be before end time")
 	}
 
-	return s.eventRepo.CreateEvent(event)
+	// The organizer is always the logged-in user.
+	event.OrganizerID = claims.UserID
+
+	if err := s.eventRepo.CreateEvent(ctx, event); err != nil {
+		return err
+	}
+	s.audit.Created(ctx, AuditEntityEvent, event.ID, event)
+	return nil
 }
 
-// GetEventByID retrieves an event by ID
-func (s *EventService) GetEventByID(id int) (*models.Event, error) {
+// GetEventByID retrieves an event by ID with permission check
+func (s *EventService) GetEventByID(ctx context.Context, id int) (*models.Event, error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEventByID")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
 	if id <= 0 {
-		return nil, errors.New("invalid event ID")
+		return nil, Invalid("invalid event ID")
+	}
+
+	event, err := s.eventRepo.GetEventByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- PERMISSION CHECK ---
+	// A user can view if they are a Receptionist OR if they organize the event.
+	if !s.canAccess(claims, event) {
+		s.logger.WarnContext(ctx, "Permission denied for GetEventByID", "user_id", claims.UserID, "role_id", claims.RoleID, "event_id", id, "event_organizer_id", event.OrganizerID)
+		return nil, Forbidden("you do not have permission to view this event")
 	}
 
-	return s.eventRepo.GetEventByID(id)
+	return event, nil
 }
 
-// GetEventsByDealID retrieves all events for a specific deal
-func (s *EventService) GetEventsByDealID(dealID int) ([]models.Event, error) {
-	if dealID <= 0 {
-		return nil, errors.New("invalid deal ID")
+// GetEventsForParent retrieves the events filed under a deal or lead that the
+// logged-in user can see
+func (s *EventService) GetEventsForParent(ctx context.Context, parent ActivityParent) ([]models.Event, error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEventsForParent")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
 	}
 
-	return s.eventRepo.GetEventsByDealID(dealID)
+	if err := parent.validate(); err != nil {
+		return nil, err
+	}
+
+	var events []models.Event
+	var err error
+	if parent.Kind == ParentLead {
+		events, err = s.eventRepo.GetEventsByLeadID(ctx, parent.ID)
+	} else {
+		events, err = s.eventRepo.GetEventsByDealID(ctx, parent.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.visibleEvents(claims, events), nil
 }
 
-// GetAllEvents retrieves all events
-func (s *EventService) GetAllEvents() ([]models.Event, error) {
-	return s.eventRepo.GetAllEvents()
+// GetAllEvents retrieves all events for Reception and the caller's own events
+// for everyone else
+func (s *EventService) GetAllEvents(ctx context.Context) ([]models.Event, error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetAllEvents")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.DebugContext(ctx, "fetching events for single user", "user_id", claims.UserID)
+		return s.eventRepo.GetEventsForUser(ctx, claims.UserID)
+	}
+
+	s.logger.DebugContext(ctx, "fetching all events for manager role", "user_id", claims.UserID)
+	return s.eventRepo.GetAllEvents(ctx)
 }
 
-// UpdateEvent updates an existing event
-func (s *EventService) UpdateEvent(event *models.Event) error {
+// UpdateEvent updates an existing event with permission check
+func (s *EventService) UpdateEvent(ctx context.Context, event *models.Event) error {
+	ctx, span := tracing.Start(ctx, "EventService.UpdateEvent")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
 	if event.ID <= 0 {
-		return errors.New("invalid event ID")
+		return Invalid("invalid event ID")
 	}
 	if event.EventName == "" {
-		return errors.New("event name cannot be empty")
+		return Invalid("event name cannot be empty")
 	}
-	if event.OrganizerID == 0 {
-		return errors.New("organizer ID is required")
+	if event.StartTime.After(event.EndTime) {
+		return Invalid("start time must be before end time")
 	}
 
-	existingEvent, err := s.eventRepo.GetEventByID(event.ID)
+	existingEvent, err := s.eventRepo.GetEventByID(ctx, event.ID)
 	if err != nil {
 		return fmt.Errorf("failed to verify event existence: %w", err)
 	}
 
-	if existingEvent.OrganizerID != event.OrganizerID {
-		return errors.New("unauthorized to update this event")
+	// --- PERMISSION CHECK ---
+	// A user can update if they are a Receptionist OR if they organize the event.
+	if !s.canAccess(claims, existingEvent) {
+		s.logger.WarnContext(ctx, "Permission denied for UpdateEvent", "user_id", claims.UserID, "role_id", claims.RoleID, "event_id", event.ID, "event_organizer_id", existingEvent.OrganizerID)
+		return Forbidden("you do not have permission to update this event")
 	}
 
-	return s.eventRepo.UpdateEvent(event)
+	// The organizer never changes.
+	event.OrganizerID = existingEvent.OrganizerID
+
+	if err := s.eventRepo.UpdateEvent(ctx, event); err != nil {
+		return err
+	}
+	s.audit.UpdatedStored(ctx, AuditEntityEvent, event.ID, existingEvent, func() (interface{}, error) {
+		return s.eventRepo.GetEventByID(ctx, event.ID)
+	})
+	return nil
 }
 
-// DeleteEvent soft deletes an event
-func (s *EventService) DeleteEvent(id int) error {
+// DeleteEvent soft deletes an event with permission check
+func (s *EventService) DeleteEvent(ctx context.Context, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "EventService.DeleteEvent")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
 	if id <= 0 {
-		return errors.New("invalid event ID")
+		return Invalid("invalid event ID")
 	}
 
-	return s.eventRepo.DeleteEvent(id)
-}
+	existingEvent, err := s.eventRepo.GetEventByID(ctx, id)
+	if err != nil {
+		return err
+	}
 
-// GetEventsForUser retrieves events for a specific user
-func (s *EventService) GetEventsForUser(userID int) ([]models.Event, error) {
-	if userID <= 0 {
-		return nil, errors.New("invalid user ID")
+	// --- PERMISSION CHECK ---
+	// A user can delete if they are a Receptionist OR if they organize the event.
+	if !s.canAccess(claims, existingEvent) {
+		s.logger.WarnContext(ctx, "Permission denied for DeleteEvent", "user_id", claims.UserID, "role_id", claims.RoleID, "event_id", id, "event_organizer_id", existingEvent.OrganizerID)
+		return Forbidden("you can only delete your own events")
 	}
 
-	return s.eventRepo.GetEventsForUser(userID)
+	if err := s.eventRepo.DeleteEvent(ctx, id, expectedVersion); err != nil {
+		return err
+	}
+	s.audit.Deleted(ctx, AuditEntityEvent, id, existingEvent)
+	return nil
 }
 
-// CreateDealEvent creates a new event for a deal (nested route)
-func (s *EventService) CreateDealEvent(event *models.Event) error {
-	if event.EventName == "" {
-		return errors.New("event name cannot be empty")
-	}
-	if event.OrganizerID == 0 {
-		return errors.New("organizer ID is required")
+// GetEventsForUser retrieves events organized by a specific user. Only
+// Reception can list another user's events.
+func (s *EventService) GetEventsForUser(ctx context.Context, userID int) ([]models.Event, error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEventsForUser")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
 	}
-	if event.DealID == nil || *event.DealID <= 0 {
-		return errors.New("deal ID is required")
+
+	if userID <= 0 {
+		return nil, Invalid("invalid user ID")
 	}
-	if event.StartTime.After(event.EndTime) {
-		return errors.New("start time must be before end time")
+
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID && userID != claims.UserID {
+		s.logger.WarnContext(ctx, "Permission denied for GetEventsForUser", "user_id", claims.UserID, "role_id", claims.RoleID, "requested_user_id", userID)
+		return nil, Forbidden("you can only view your own events")
 	}
 
-	return s.eventRepo.CreateEvent(event)
+	return s.eventRepo.GetEventsForUser(ctx, userID)
 }
 
-// UpdateDealEvent updates an event for a deal
-func (s *EventService) UpdateDealEvent(event *models.Event) error {
-	if event.ID <= 0 {
-		return errors.New("invalid event ID")
-	}
-	if event.EventName == "" {
-		return errors.New("event name cannot be empty")
-	}
+// GetEventForParent retrieves an event, reporting it as not found unless it is
+// filed under parent
+func (s *EventService) GetEventForParent(ctx context.Context, parent ActivityParent, id int) (*models.Event, error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEventForParent")
+	defer span.End()
 
-	existingEvent, err := s.eventRepo.GetEventByID(event.ID)
+	event, err := s.GetEventByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to verify event existence: %w", err)
+		return nil, err
 	}
-
-	if existingEvent.DealID == nil || *existingEvent.DealID != *event.DealID {
-		return errors.New("event not found for this deal")
+	if !parent.Owns(event.DealID, event.LeadID) {
+		return nil, NotFound("event not found for this %s", parent.Kind)
 	}
+	return event, nil
+}
+
+// CreateEventForParent creates an event filed under a deal or lead
+func (s *EventService) CreateEventForParent(ctx context.Context, parent ActivityParent, event *models.Event) error {
+	ctx, span := tracing.Start(ctx, "EventService.CreateEventForParent")
+	defer span.End()
 
-	return s.eventRepo.UpdateEvent(event)
+	if err := parent.validate(); err != nil {
+		return err
+	}
+	event.DealID, event.LeadID = parent.Links()
+	return s.CreateEvent(ctx, event)
 }
 
-// DeleteDealEvent deletes an event for a deal
-func (s *EventService) DeleteDealEvent(id int) error {
-	if id <= 0 {
-		return errors.New("invalid event ID")
+// UpdateEventForParent updates an event filed under a deal or lead. The event
+// stays linked to the records it was linked to before.
+func (s *EventService) UpdateEventForParent(ctx context.Context, parent ActivityParent, event *models.Event) error {
+	ctx, span := tracing.Start(ctx, "EventService.UpdateEventForParent")
+	defer span.End()
+
+	existingEvent, err := s.GetEventForParent(ctx, parent, event.ID)
+	if err != nil {
+		return err
 	}
+	event.DealID, event.LeadID = existingEvent.DealID, existingEvent.LeadID
+	return s.UpdateEvent(ctx, event)
+}
+
+// DeleteEventForParent deletes an event filed under a deal or lead
+func (s *EventService) DeleteEventForParent(ctx context.Context, parent ActivityParent, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "EventService.DeleteEventForParent")
+	defer span.End()
 
-	return s.eventRepo.DeleteEvent(id)
-}
\ No newline at end of file
+	if _, err := s.GetEventForParent(ctx, parent, id); err != nil {
+		return err
+	}
+	return s.DeleteEvent(ctx, id, expectedVersion)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Errors returned by IdempotencyService.Begin.
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+type IdempotencyService struct {
+	repo   postgres.IdempotencyRepository
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewIdempotencyService(repo postgres.IdempotencyRepository, cfg *config.Config, logger *slog.Logger) *IdempotencyService {
+	return &IdempotencyService{repo: repo, cfg: cfg, logger: logger}
+}
+
+// Begin starts processing a request sent with an idempotency key. If the key
+// is new it is reserved and Begin returns nil, and the caller must later call
+// Complete or Release. If the same request was already completed, the stored
+// record is returned so its response can be replayed.
+func (s *IdempotencyService) Begin(ctx context.Context, userID int, key, method, path string, body []byte) (*models.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	hash := requestHash(method, path, body)
+	reserved, err := s.repo.Reserve(ctx, models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		RequestHash: hash,
+		ExpiresAt:   time.Now().Add(s.cfg.Idempotency.TTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	rec, err := s.repo.Get(ctx, userID, key)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		// The record expired and was purged in the meantime; start over.
+		return s.Begin(ctx, userID, key, method, path, body)
+	}
+	if rec.RequestHash != hash {
+		s.logger.WarnContext(ctx, "idempotency key reused for a different request", "user_id", userID, "method", method, "path", path)
+		return nil, ErrIdempotencyKeyReused
+	}
+	if rec.StatusCode == nil {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	s.logger.InfoContext(ctx, "replaying response for idempotency key", "user_id", userID, "method", method, "path", path, "status", *rec.StatusCode)
+	return rec, nil
+}
+
+// Complete stores the response for a key reserved by Begin.
+func (s *IdempotencyService) Complete(ctx context.Context, userID int, key string, statusCode int, headers map[string]string, body []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	return s.repo.Complete(ctx, userID, key, statusCode, models.StoredHeaders(headers), body)
+}
+
+// Release drops a key reserved by Begin without storing a response, so a
+// retry is processed again. Used when the request failed with a server error.
+func (s *IdempotencyService) Release(ctx context.Context, userID int, key string) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Release")
+	defer span.End()
+
+	return s.repo.Release(ctx, userID, key)
+}
+
+// PurgeExpired removes records older than the idempotency window.
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.PurgeExpired")
+	defer span.End()
+
+	n, err := s.repo.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.DebugContext(ctx, "purged expired idempotency keys", "count", n)
+	}
+	return nil
+}
+
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const inviteTokenSubject = "invitation"
+
+// ErrInvalidInvite is returned for any invite token that cannot be used to register.
+var ErrInvalidInvite error = &ValidationError{Message: "invalid or expired invitation"}
+
+type InvitationService struct {
+	repo   postgres.InvitationRepository
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewInvitationService(repo postgres.InvitationRepository, cfg *config.Config, logger *slog.Logger) *InvitationService {
+	return &InvitationService{repo: repo, cfg: cfg, logger: logger}
+}
+
+// CreatedInvitation is returned once, when the invite is issued. The link is
+// not stored and cannot be retrieved again.
+type CreatedInvitation struct {
+	Invitation *models.Invitation `json:"invitation"`
+	Token      string             `json:"token"`
+	Link       string             `json:"link"`
+}
+
+// CreateInvitation issues a signed invite link with a preset role.
+func (s *InvitationService) CreateInvitation(ctx context.Context, req dto.CreateInvitationRequest) (*CreatedInvitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.CreateInvitation")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	// Only Reception (Manager) can invite new users.
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for CreateInvitation", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can invite users")
+	}
+
+	if err := validateStruct(req); err != nil {
+		return nil, err
+	}
+	if !s.isKnownRole(req.RoleID) {
+		return nil, Invalid("unknown role_id: %d", req.RoleID)
+	}
+
+	inv := models.Invitation{
+		RoleID:    req.RoleID,
+		CreatedBy: claims.UserID,
+		ExpiresAt: time.Now().Add(s.cfg.Auth.InviteTTL),
+	}
+	if req.Email != "" {
+		inv.Email = &req.Email
+	}
+
+	newID, err := s.repo.Create(ctx, inv)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to create invitation in database", "error", err)
+		return nil, errors.New("failed to create invitation")
+	}
+	created, err := s.repo.GetByID(ctx, newID)
+	if err != nil || created == nil {
+		s.logger.ErrorContext(ctx, "failed to fetch created invitation", "invitation_id", newID, "error", err)
+		return nil, errors.New("failed to retrieve created invitation")
+	}
+
+	token, err := s.signInvite(created)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to sign invitation", "invitation_id", newID, "error", err)
+		return nil, errors.New("failed to create invitation")
+	}
+
+	s.logger.InfoContext(ctx, "invitation created", "invitation_id", newID, "role_id", req.RoleID, "created_by", claims.UserID)
+	return &CreatedInvitation{Invitation: created, Token: token, Link: s.inviteLink(token)}, nil
+}
+
+// GetAllInvitations lists issued invitations for managers.
+func (s *InvitationService) GetAllInvitations(ctx context.Context) ([]models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.GetAllInvitations")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for GetAllInvitations", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can view invitations")
+	}
+
+	return s.repo.GetAll(ctx)
+}
+
+// RevokeInvitation invalidates an unused invitation.
+func (s *InvitationService) RevokeInvitation(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "InvitationService.RevokeInvitation")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for RevokeInvitation", "user_id", claims.UserID, "role_id", claims.RoleID, "invitation_id", id)
+		return Forbidden("only managers can revoke invitations")
+	}
+
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		if err == sql.ErrNoRows {
+			return NotFound("invitation with ID %d not found or already used", id)
+		}
+		return err
+	}
+	s.logger.InfoContext(ctx, "invitation revoked", "invitation_id", id, "revoked_by", claims.UserID)
+	return nil
+}
+
+// ResolveInvite verifies the token signature and returns the invitation if it
+// can still be redeemed. It does not consume the invitation.
+func (s *InvitationService) ResolveInvite(ctx context.Context, token string) (*models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.ResolveInvite")
+	defer span.End()
+
+	claims := &dto.InviteClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return s.signingKey(), nil
+	}, jwt.WithSubject(inviteTokenSubject))
+	if err != nil || !parsed.Valid {
+		s.logger.WarnContext(ctx, "invite token validation failed", "error", err)
+		return nil, ErrInvalidInvite
+	}
+	if claims.ID != strconv.Itoa(claims.InvitationID) {
+		return nil, ErrInvalidInvite
+	}
+
+	inv, err := s.repo.GetByID(ctx, claims.InvitationID)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.UsedAt != nil || inv.RevokedAt != nil || time.Now().After(inv.ExpiresAt) {
+		return nil, ErrInvalidInvite
+	}
+	return inv, nil
+}
+
+// Redeem creates the user and consumes the invitation atomically.
+func (s *InvitationService) Redeem(ctx context.Context, inv *models.Invitation, user models.User) (int, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.Redeem")
+	defer span.End()
+
+	newUserID, err := s.repo.Redeem(ctx, inv.ID, user)
+	if err != nil {
+		if errors.Is(err, postgres.ErrInvitationUnavailable) {
+			return 0, ErrInvalidInvite
+		}
+		return 0, err
+	}
+	s.logger.InfoContext(ctx, "invitation redeemed", "invitation_id", inv.ID, "user_id", newUserID)
+	return newUserID, nil
+}
+
+func (s *InvitationService) signInvite(inv *models.Invitation) (string, error) {
+	claims := &dto.InviteClaims{
+		InvitationID: inv.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strconv.Itoa(inv.ID),
+			Subject:   inviteTokenSubject,
+			ExpiresAt: jwt.NewNumericDate(inv.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(inv.CreatedAt),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.signingKey())
+}
+
+// signingKey is derived from the JWT secret so invite tokens and login tokens
+// are never interchangeable.
+func (s *InvitationService) signingKey() []byte {
+	return []byte(s.cfg.Auth.JWTSecret + ":" + inviteTokenSubject)
+}
+
+func (s *InvitationService) inviteLink(token string) string {
+	if s.cfg.Auth.InviteURL == "" {
+		return ""
+	}
+	return s.cfg.Auth.InviteURL + "?invite=" + url.QueryEscape(token)
+}
+
+func (s *InvitationService) isKnownRole(roleID int) bool {
+	return roleID == s.cfg.Roles.SalesAgentID || roleID == s.cfg.Roles.ReceptionID
+}
@@ -1,133 +1,298 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
 )
 
 type NoteService struct {
 	noteRepo postgres.NoteRepository
+	audit    *AuditService
+	cfg      *config.Config
+	logger   *slog.Logger
 }
 
-func NewNoteService(noteRepo postgres.NoteRepository) *NoteService {
-	return &NoteService{noteRepo: noteRepo}
+func NewNoteService(noteRepo postgres.NoteRepository, audit *AuditService, cfg *config.Config, logger *slog.Logger) *NoteService {
+	return &NoteService{noteRepo: noteRepo, audit: audit, cfg: cfg, logger: logger}
 }
 
-// CreateNote creates a new note
-func (s *NoteService) CreateNote(note *models.Note) error {
+// canAccess reports whether the caller may view or change note. Reception can
+// access every note; everyone else only the notes they wrote.
+func (s *NoteService) canAccess(claims *dto.Claims, note *models.Note) bool {
+	return claims.RoleID == s.cfg.Roles.ReceptionID || note.UserID == claims.UserID
+}
+
+// visibleNotes drops the notes the caller is not allowed to see.
+func (s *NoteService) visibleNotes(claims *dto.Claims, notes []models.Note) []models.Note {
+	if claims.RoleID == s.cfg.Roles.ReceptionID {
+		return notes
+	}
+	visible := make([]models.Note, 0, len(notes))
+	for _, note := range notes {
+		if note.UserID == claims.UserID {
+			visible = append(visible, note)
+		}
+	}
+	return visible
+}
+
+// CreateNote creates a new note written by the logged-in user
+func (s *NoteService) CreateNote(ctx context.Context, note *models.Note) error {
+	ctx, span := tracing.Start(ctx, "NoteService.CreateNote")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
 	if note.Content == "" {
-		return errors.New("note content cannot be empty")
+		return Invalid("note content cannot be empty")
 	}
-	
-	if note.UserID == 0 {
-		return errors.New("user ID is required")
+
+	// The author is always the logged-in user.
+	note.UserID = claims.UserID
+
+	if err := s.noteRepo.CreateNote(ctx, note); err != nil {
+		return err
 	}
-	
-	return s.noteRepo.CreateNote(note)
+	s.audit.Created(ctx, AuditEntityNote, note.ID, note)
+	return nil
 }
 
-// GetNoteByID retrieves a note by its ID
-func (s *NoteService) GetNoteByID(id int) (*models.Note, error) {
+// GetNoteByID retrieves a note by its ID with permission check
+func (s *NoteService) GetNoteByID(ctx context.Context, id int) (*models.Note, error) {
+	ctx, span := tracing.Start(ctx, "NoteService.GetNoteByID")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
 	if id <= 0 {
-		return nil, errors.New("invalid note ID")
+		return nil, Invalid("invalid note ID")
+	}
+
+	note, err := s.noteRepo.GetNoteByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	
-	return s.noteRepo.GetNoteByID(id)
+
+	// --- PERMISSION CHECK ---
+	// A user can view if they are a Receptionist OR if they wrote the note.
+	if !s.canAccess(claims, note) {
+		s.logger.WarnContext(ctx, "Permission denied for GetNoteByID", "user_id", claims.UserID, "role_id", claims.RoleID, "note_id", id, "note_user_id", note.UserID)
+		return nil, Forbidden("you do not have permission to view this note")
+	}
+
+	return note, nil
 }
 
-// GetNotesByContactID retrieves all notes for a specific contact
-func (s *NoteService) GetNotesByContactID(contactID int) ([]models.Note, error) {
+// GetNotesByContactID retrieves the notes for a specific contact that the
+// logged-in user can see
+func (s *NoteService) GetNotesByContactID(ctx context.Context, contactID int) ([]models.Note, error) {
+	ctx, span := tracing.Start(ctx, "NoteService.GetNotesByContactID")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
 	if contactID <= 0 {
-		return nil, errors.New("invalid contact ID")
+		return nil, Invalid("invalid contact ID")
+	}
+
+	notes, err := s.noteRepo.GetNotesByContactID(ctx, contactID)
+	if err != nil {
+		return nil, err
 	}
-	
-	return s.noteRepo.GetNotesByContactID(contactID)
+	return s.visibleNotes(claims, notes), nil
 }
 
-// UpdateNote updates an existing note
-func (s *NoteService) UpdateNote(note *models.Note) error {
+// UpdateNote updates an existing note with permission check
+func (s *NoteService) UpdateNote(ctx context.Context, note *models.Note) error {
+	ctx, span := tracing.Start(ctx, "NoteService.UpdateNote")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
 	if note.ID <= 0 {
-		return errors.New("invalid note ID")
+		return Invalid("invalid note ID")
 	}
-	
+
 	if note.Content == "" {
-		return errors.New("note content cannot be empty")
+		return Invalid("note content cannot be empty")
 	}
-	
-	// Verify the note exists first
-	existingNote, err := s.noteRepo.GetNoteByID(note.ID)
+
+	existingNote, err := s.noteRepo.GetNoteByID(ctx, note.ID)
 	if err != nil {
 		return fmt.Errorf("failed to verify note existence: %w", err)
 	}
-	
-	// Ensure user can only update their own notes (optional security check)
-	if note.UserID != 0 && existingNote.UserID != note.UserID {
-		return errors.New("unauthorized to update this note")
+
+	// --- PERMISSION CHECK ---
+	// A user can update if they are a Receptionist OR if they wrote the note.
+	if !s.canAccess(claims, existingNote) {
+		s.logger.WarnContext(ctx, "Permission denied for UpdateNote", "user_id", claims.UserID, "role_id", claims.RoleID, "note_id", note.ID, "note_user_id", existingNote.UserID)
+		return Forbidden("you do not have permission to update this note")
+	}
+
+	// The author never changes.
+	note.UserID = existingNote.UserID
+
+	if err := s.noteRepo.UpdateNote(ctx, note); err != nil {
+		return err
 	}
-	
-	return s.noteRepo.UpdateNote(note)
+	s.audit.UpdatedStored(ctx, AuditEntityNote, note.ID, existingNote, func() (interface{}, error) {
+		return s.noteRepo.GetNoteByID(ctx, note.ID)
+	})
+	return nil
 }
 
-// DeleteNote deletes a note
-func (s *NoteService) DeleteNote(id int) error {
+// DeleteNote deletes a note with permission check
+func (s *NoteService) DeleteNote(ctx context.Context, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "NoteService.DeleteNote")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
 	if id <= 0 {
-		return errors.New("invalid note ID")
+		return Invalid("invalid note ID")
 	}
-	
-	return s.noteRepo.DeleteNote(id)
-}
 
-// GetNotesByUserID retrieves all notes created by a specific user
-func (s *NoteService) GetNotesByUserID(userID int) ([]models.Note, error) {
-	if userID <= 0 {
-		return nil, errors.New("invalid user ID")
+	existingNote, err := s.noteRepo.GetNoteByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// --- PERMISSION CHECK ---
+	// A user can delete if they are a Receptionist OR if they wrote the note.
+	if !s.canAccess(claims, existingNote) {
+		s.logger.WarnContext(ctx, "Permission denied for DeleteNote", "user_id", claims.UserID, "role_id", claims.RoleID, "note_id", id, "note_user_id", existingNote.UserID)
+		return Forbidden("you can only delete your own notes")
 	}
-	
-	return s.noteRepo.GetNotesByUserID(userID)
+
+	if err := s.noteRepo.DeleteNote(ctx, id, expectedVersion); err != nil {
+		return err
+	}
+	s.audit.Deleted(ctx, AuditEntityNote, id, existingNote)
+	return nil
 }
 
+// GetNotesByUserID retrieves all notes created by a specific user. Only
+// Reception can list another user's notes.
+func (s *NoteService) GetNotesByUserID(ctx context.Context, userID int) ([]models.Note, error) {
+	ctx, span := tracing.Start(ctx, "NoteService.GetNotesByUserID")
+	defer span.End()
 
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
 
+	if userID <= 0 {
+		return nil, Invalid("invalid user ID")
+	}
 
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID && userID != claims.UserID {
+		s.logger.WarnContext(ctx, "Permission denied for GetNotesByUserID", "user_id", claims.UserID, "role_id", claims.RoleID, "requested_user_id", userID)
+		return nil, Forbidden("you can only view your own notes")
+	}
 
+	return s.noteRepo.GetNotesByUserID(ctx, userID)
+}
 
+// GetNotesForParent retrieves the notes filed under a deal or lead that the
+// logged-in user can see
+func (s *NoteService) GetNotesForParent(ctx context.Context, parent ActivityParent) ([]models.Note, error) {
+	ctx, span := tracing.Start(ctx, "NoteService.GetNotesForParent")
+	defer span.End()
 
-func (s *NoteService) GetNotesByDealID(dealID int) ([]models.Note, error) {
-	if dealID <= 0 {
-		return nil, errors.New("invalid deal ID")
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
 	}
-	return s.noteRepo.GetNotesByDealID(dealID)
-}
 
-func (s *NoteService) CreateDealNote(note *models.Note) error {
-	if note.Content == "" {
-		return errors.New("note content cannot be empty")
+	if err := parent.validate(); err != nil {
+		return nil, err
 	}
-	if note.UserID == 0 {
-		return errors.New("user ID is required")
+
+	var notes []models.Note
+	var err error
+	if parent.Kind == ParentLead {
+		notes, err = s.noteRepo.GetNotesByLeadID(ctx, parent.ID)
+	} else {
+		notes, err = s.noteRepo.GetNotesByDealID(ctx, parent.ID)
 	}
-	if note.DealID == nil || *note.DealID <= 0 {
-		return errors.New("deal ID is required")
+	if err != nil {
+		return nil, err
 	}
-	return s.noteRepo.CreateDealNote(note)
+	return s.visibleNotes(claims, notes), nil
 }
 
-func (s *NoteService) UpdateDealNote(note *models.Note) error {
-	if note.ID <= 0 {
-		return errors.New("invalid note ID")
+// GetNoteForParent retrieves a note, reporting it as not found unless it is
+// filed under parent
+func (s *NoteService) GetNoteForParent(ctx context.Context, parent ActivityParent, id int) (*models.Note, error) {
+	ctx, span := tracing.Start(ctx, "NoteService.GetNoteForParent")
+	defer span.End()
+
+	note, err := s.GetNoteByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if note.Content == "" {
-		return errors.New("note content cannot be empty")
+	if !parent.Owns(note.DealID, note.LeadID) {
+		return nil, NotFound("note not found for this %s", parent.Kind)
 	}
-	return s.noteRepo.UpdateDealNote(note)
+	return note, nil
 }
 
-func (s *NoteService) DeleteDealNote(id int) error {
-	if id <= 0 {
-		return errors.New("invalid note ID")
+// CreateNoteForParent creates a note filed under a deal or lead
+func (s *NoteService) CreateNoteForParent(ctx context.Context, parent ActivityParent, note *models.Note) error {
+	ctx, span := tracing.Start(ctx, "NoteService.CreateNoteForParent")
+	defer span.End()
+
+	if err := parent.validate(); err != nil {
+		return err
+	}
+	note.DealID, note.LeadID = parent.Links()
+	return s.CreateNote(ctx, note)
+}
+
+// UpdateNoteForParent updates a note filed under a deal or lead
+func (s *NoteService) UpdateNoteForParent(ctx context.Context, parent ActivityParent, note *models.Note) error {
+	ctx, span := tracing.Start(ctx, "NoteService.UpdateNoteForParent")
+	defer span.End()
+
+	if _, err := s.GetNoteForParent(ctx, parent, note.ID); err != nil {
+		return err
+	}
+	return s.UpdateNote(ctx, note)
+}
+
+// DeleteNoteForParent deletes a note filed under a deal or lead
+func (s *NoteService) DeleteNoteForParent(ctx context.Context, parent ActivityParent, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "NoteService.DeleteNoteForParent")
+	defer span.End()
+
+	if _, err := s.GetNoteForParent(ctx, parent, id); err != nil {
+		return err
 	}
-	return s.noteRepo.DeleteDealNote(id)
+	return s.DeleteNote(ctx, id, expectedVersion)
 }
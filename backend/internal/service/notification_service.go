@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/models"
+	"crm-project/internal/notify"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// notificationListLimit caps how many notifications are listed at once.
+const notificationListLimit = 100
+
+// NotificationService gives users access to their own in-app notifications.
+type NotificationService struct {
+	repo   postgres.NotificationRepository
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewNotificationService(repo postgres.NotificationRepository, cfg *config.Config, logger *slog.Logger) *NotificationService {
+	return &NotificationService{repo: repo, cfg: cfg, logger: logger}
+}
+
+// GetMyNotifications returns the current user's most recent notifications,
+// newest first, optionally only the unread ones.
+func (s *NotificationService) GetMyNotifications(ctx context.Context, unreadOnly bool) ([]models.Notification, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.GetMyNotifications")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	return s.repo.GetAllForUser(ctx, claims.UserID, unreadOnly, notificationListLimit)
+}
+
+// MarkRead marks one of the current user's notifications as read.
+func (s *NotificationService) MarkRead(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.MarkRead")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	if id <= 0 {
+		return Invalid("invalid notification ID")
+	}
+	// Other users' notifications are reported as missing.
+	if err := s.repo.MarkRead(ctx, claims.UserID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NotFound("notification with ID %d not found", id)
+		}
+		return err
+	}
+	return nil
+}
+
+// MarkAllRead marks all of the current user's notifications as read and
+// returns how many were unread.
+func (s *NotificationService) MarkAllRead(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.MarkAllRead")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return 0, errors.New("could not retrieve user claims from context")
+	}
+	return s.repo.MarkAllRead(ctx, claims.UserID)
+}
+
+// notifier stores in-app notifications and emails them to their users, for
+// the schedulers that notify users.
+type notifier struct {
+	notifications postgres.NotificationRepository
+	users         postgres.UserRepository
+	email         notify.EmailSender
+	logger        *slog.Logger
+}
+
+// notify stores n and, unless a notification with the same key was stored
+// before, emails it to the user. Email failures are logged rather than
+// returned, since the in-app notification has been delivered.
+func (s *notifier) notify(ctx context.Context, n *models.Notification) error {
+	created, err := s.notifications.Create(ctx, n)
+	if err != nil {
+		return fmt.Errorf("storing %s notification: %w", n.Kind, err)
+	}
+	if !created {
+		return nil
+	}
+
+	user, err := s.users.GetByID(ctx, n.UserID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to look up user to email", "user_id", n.UserID, "notification_id", n.ID, "error", err)
+		return nil
+	}
+	if user == nil || user.Email == "" {
+		s.logger.WarnContext(ctx, "user has no email address, notification not emailed", "user_id", n.UserID, "notification_id", n.ID)
+		return nil
+	}
+	if err := s.email.Send(ctx, notify.Email{To: user.Email, Subject: n.Title, Body: n.Body}); err != nil {
+		s.logger.ErrorContext(ctx, "failed to email notification", "user_id", n.UserID, "notification_id", n.ID, "error", err)
+		return nil
+	}
+	s.logger.DebugContext(ctx, "notification sent", "user_id", n.UserID, "notification_id", n.ID, "kind", n.Kind)
+	return nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"crm-project/internal/util"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON fields that can be changed with PATCH, per entity. Everything else
+// (ids, timestamps, created_by, version) is read-only.
+var (
+	contactPatchFields = []string{
+		"first_name", "last_name", "email", "primary_phone", "secondary_phone",
+		"address", "city", "sub_city", "contact_source",
+	}
+	propertyPatchFields = []string{
+		"name", "site_id", "property_type_id", "unit_no", "size_sqft", "price", "status",
+	}
+	leadPatchFields = []string{
+		"contact_id", "property_id", "source_id", "status_id", "assigned_to", "notes",
+	}
+	dealPatchFields = []string{
+		"lead_id", "property_id", "stage_id", "deal_status", "deal_amount", "closing_date", "notes",
+	}
+	taskPatchFields = []string{
+		"task_name", "task_description", "due_date", "status", "assigned_to", "lead_id", "deal_id", "task_type",
+	}
+	// Sales agents work on the tasks assigned to them but cannot move them
+	// to someone else or to another lead or deal, nor change the type their
+	// SLA depends on.
+	taskPatchFieldsSalesAgent = []string{
+		"task_name", "task_description", "due_date", "status",
+	}
+)
+
+// applyMergePatch applies a JSON merge patch to current and decodes the result
+// into dst. Fields outside patchable are rejected with ErrInvalidPatch; fields
+// that are patchable but not in allowed are rejected as forbidden.
+func applyMergePatch(current interface{}, patch []byte, patchable, allowed []string, dst interface{}) error {
+	fields, err := util.MergePatchFields(patch)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	for _, field := range fields {
+		if !containsField(patchable, field) {
+			return fmt.Errorf("%w: field %q cannot be patched", ErrInvalidPatch, field)
+		}
+		if !containsField(allowed, field) {
+			return Forbidden("you do not have permission to change %s", field)
+		}
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	patched, err := util.ApplyMergePatch(doc, patch)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if err := json.Unmarshal(patched, dst); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return nil
+}
+
+// patchVersion is the version a patch is written against. Without an explicit
+// expected version the patch applies to the version that was just read, so a
+// concurrent update is reported as a conflict rather than silently reverted.
+func patchVersion(current, expected int) int {
+	if expected != 0 {
+		return expected
+	}
+	return current
+}
+
+func containsField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Limits on playbooks, so a misconfigured one cannot flood the task list.
+const (
+	maxPlaybookTasks     = 50
+	maxPlaybookDueInDays = 365
+)
+
+// PlaybookService manages task playbooks and applies them. When a deal
+// enters a stage or a lead reaches a status, each active playbook for it
+// creates its tasks, assigned to the deal's or lead's owner unless a template
+// names someone else. A playbook runs at most once per deal or lead.
+type PlaybookService struct {
+	repo   postgres.PlaybookRepository
+	tasks  postgres.TaskRepository
+	users  postgres.UserRepository
+	tx     postgres.Transactor
+	audit  *AuditService
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewPlaybookService(repo postgres.PlaybookRepository, tasks postgres.TaskRepository, users postgres.UserRepository, tx postgres.Transactor, audit *AuditService, cfg *config.Config, logger *slog.Logger) *PlaybookService {
+	return &PlaybookService{repo: repo, tasks: tasks, users: users, tx: tx, audit: audit, cfg: cfg, logger: logger}
+}
+
+// GetPlaybooks returns every playbook, ordered by name.
+func (s *PlaybookService) GetPlaybooks(ctx context.Context) ([]models.Playbook, error) {
+	ctx, span := tracing.Start(ctx, "PlaybookService.GetPlaybooks")
+	defer span.End()
+
+	if err := s.requireManager(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAll(ctx)
+}
+
+// GetPlaybook returns one playbook with its task templates.
+func (s *PlaybookService) GetPlaybook(ctx context.Context, id int) (*models.Playbook, error) {
+	ctx, span := tracing.Start(ctx, "PlaybookService.GetPlaybook")
+	defer span.End()
+
+	if err := s.requireManager(ctx); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, Invalid("invalid playbook ID")
+	}
+	p, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, NotFound("playbook with ID %d not found", id)
+	}
+	return p, nil
+}
+
+// CreatePlaybook creates a playbook. Its task templates keep the order they
+// are given in.
+func (s *PlaybookService) CreatePlaybook(ctx context.Context, p *models.Playbook) error {
+	ctx, span := tracing.Start(ctx, "PlaybookService.CreatePlaybook")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	if err := s.requireManager(ctx); err != nil {
+		return err
+	}
+	if err := s.validate(ctx, p); err != nil {
+		return err
+	}
+
+	p.CreatedBy = claims.UserID
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return s.repo.Create(ctx, p)
+	})
+	if err != nil {
+		return triggerError(p, err)
+	}
+	s.audit.Created(ctx, AuditEntityPlaybook, p.ID, p)
+	return nil
+}
+
+// UpdatePlaybook replaces a playbook and its task templates. Deals and leads
+// it has already run for are not affected.
+func (s *PlaybookService) UpdatePlaybook(ctx context.Context, p *models.Playbook) error {
+	ctx, span := tracing.Start(ctx, "PlaybookService.UpdatePlaybook")
+	defer span.End()
+
+	existing, err := s.GetPlaybook(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(ctx, p); err != nil {
+		return err
+	}
+	p.CreatedBy = existing.CreatedBy
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return s.repo.Update(ctx, p)
+	})
+	if err != nil {
+		return triggerError(p, err)
+	}
+	s.audit.UpdatedStored(ctx, AuditEntityPlaybook, p.ID, existing, func() (interface{}, error) {
+		return s.repo.GetByID(ctx, p.ID)
+	})
+	return nil
+}
+
+// DeletePlaybook deletes a playbook. Tasks it created are kept.
+func (s *PlaybookService) DeletePlaybook(ctx context.Context, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "PlaybookService.DeletePlaybook")
+	defer span.End()
+
+	existing, err := s.GetPlaybook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, expectedVersion); err != nil {
+		return err
+	}
+	s.audit.Deleted(ctx, AuditEntityPlaybook, id, existing)
+	return nil
+}
+
+// ApplyForDeal runs the active playbooks for the stage deal is in. Call it
+// after the deal has entered the stage. Failures are logged; the stage
+// change itself has already been saved.
+func (s *PlaybookService) ApplyForDeal(ctx context.Context, deal *models.Deal) {
+	ctx, span := tracing.Start(ctx, "PlaybookService.ApplyForDeal")
+	defer span.End()
+
+	playbooks, err := s.repo.GetActiveForDealStage(ctx, deal.StageID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load playbooks for deal stage", "deal_id", deal.ID, "stage_id", deal.StageID, "error", err)
+		return
+	}
+	owner := 0
+	if deal.CreatedBy.Valid {
+		owner = int(deal.CreatedBy.Int64)
+	}
+	for i := range playbooks {
+		s.run(ctx, &playbooks[i], owner, &deal.ID, nil)
+	}
+}
+
+// ApplyForLead runs the active playbooks for the status lead has. Call it
+// after the lead has reached the status. Failures are logged; the status
+// change itself has already been saved.
+func (s *PlaybookService) ApplyForLead(ctx context.Context, lead *models.Lead) {
+	ctx, span := tracing.Start(ctx, "PlaybookService.ApplyForLead")
+	defer span.End()
+
+	playbooks, err := s.repo.GetActiveForLeadStatus(ctx, lead.StatusID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load playbooks for lead status", "lead_id", lead.ID, "status_id", lead.StatusID, "error", err)
+		return
+	}
+	for i := range playbooks {
+		s.run(ctx, &playbooks[i], lead.AssignedTo, nil, &lead.ID)
+	}
+}
+
+// run creates the tasks of a playbook for a deal or a lead, unless it has
+// run for it before. Tasks without an assignee of their own go to owner, or
+// to the current user if the record has no owner.
+func (s *PlaybookService) run(ctx context.Context, p *models.Playbook, owner int, dealID, leadID *int) {
+	actor := 0
+	if claims, ok := util.GetClaimsFromContext(ctx); ok {
+		actor = claims.UserID
+	}
+	if owner == 0 {
+		owner = actor
+	}
+
+	now := time.Now()
+	var created []models.Task
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		created = nil
+		started, err := s.repo.StartRun(ctx, p.ID, dealID, leadID)
+		if err != nil || !started {
+			return err
+		}
+		for _, tmpl := range p.Tasks {
+			task := models.Task{
+				TaskName:        tmpl.TaskName,
+				TaskDescription: tmpl.TaskDescription,
+				DueDate:         now.AddDate(0, 0, tmpl.DueInDays),
+				Status:          taskStatusPending,
+				AssignedTo:      owner,
+				LeadID:          leadID,
+				DealID:          dealID,
+				CreatedBy:       actor,
+			}
+			if tmpl.AssignTo != nil {
+				task.AssignedTo = *tmpl.AssignTo
+			}
+			if task.AssignedTo == 0 {
+				return fmt.Errorf("task %q has no one to assign it to", tmpl.TaskName)
+			}
+			if err := s.tasks.CreateTask(ctx, &task); err != nil {
+				return fmt.Errorf("creating task %q: %w", tmpl.TaskName, err)
+			}
+			created = append(created, task)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to apply playbook", "playbook_id", p.ID, "deal_id", dealID, "lead_id", leadID, "error", err)
+		return
+	}
+	if created == nil {
+		return
+	}
+	for i := range created {
+		s.audit.Created(ctx, AuditEntityTask, created[i].ID, created[i])
+	}
+	s.logger.InfoContext(ctx, "playbook applied", "playbook_id", p.ID, "deal_id", dealID, "lead_id", leadID, "tasks", len(created))
+}
+
+func (s *PlaybookService) requireManager(ctx context.Context) error {
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for playbooks", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return Forbidden("only managers can manage playbooks")
+	}
+	return nil
+}
+
+// validate checks a playbook and numbers its task templates in order.
+func (s *PlaybookService) validate(ctx context.Context, p *models.Playbook) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return InvalidField("name", "is required")
+	}
+	if len([]rune(p.Name)) > 255 {
+		return InvalidField("name", "must be at most 255 characters")
+	}
+	if (p.DealStageID == nil) == (p.LeadStatusID == nil) {
+		return Invalid("a playbook needs exactly one of deal_stage_id and lead_status_id")
+	}
+	if len(p.Tasks) == 0 {
+		return InvalidField("tasks", "must not be empty")
+	}
+	if len(p.Tasks) > maxPlaybookTasks {
+		return InvalidField("tasks", fmt.Sprintf("must have at most %d entries", maxPlaybookTasks))
+	}
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		field := fmt.Sprintf("tasks[%d]", i)
+		t.TaskName = strings.TrimSpace(t.TaskName)
+		if t.TaskName == "" {
+			return InvalidField(field+".task_name", "is required")
+		}
+		if len([]rune(t.TaskName)) > 255 {
+			return InvalidField(field+".task_name", "must be at most 255 characters")
+		}
+		if t.DueInDays < 0 || t.DueInDays > maxPlaybookDueInDays {
+			return InvalidField(field+".due_in_days", fmt.Sprintf("must be between 0 and %d", maxPlaybookDueInDays))
+		}
+		if t.AssignTo != nil {
+			user, err := s.users.GetByID(ctx, *t.AssignTo)
+			if err != nil {
+				return err
+			}
+			if user == nil {
+				return InvalidField(field+".assign_to", fmt.Sprintf("user %d does not exist", *t.AssignTo))
+			}
+		}
+		t.Position = i + 1
+	}
+	return nil
+}
+
+// triggerError reports a deal stage or lead status that does not exist as a
+// validation error.
+func triggerError(p *models.Playbook, err error) error {
+	if !postgres.IsForeignKeyViolation(err) {
+		return err
+	}
+	if p.DealStageID != nil {
+		return InvalidField("deal_stage_id", fmt.Sprintf("deal stage %d does not exist", *p.DealStageID))
+	}
+	return InvalidField("lead_status_id", fmt.Sprintf("lead status %d does not exist", *p.LeadStatusID))
+}
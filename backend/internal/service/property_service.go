@@ -5,6 +5,7 @@ import (
 	"crm-project/internal/config" // Import config
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"crm-project/internal/util" // Import util for claims
 	"database/sql"
 	"errors"
@@ -13,16 +14,20 @@ import (
 )
 
 type PropertyService struct {
-	repo   *postgres.PropertyRepo
+	repo   postgres.PropertyRepository
+	audit  *AuditService
 	cfg    *config.Config // Add config here
 	logger *slog.Logger
 }
 
-func NewPropertyService(repo *postgres.PropertyRepo, cfg *config.Config, logger *slog.Logger) *PropertyService {
-	return &PropertyService{repo: repo, cfg: cfg, logger: logger}
+func NewPropertyService(repo postgres.PropertyRepository, audit *AuditService, cfg *config.Config, logger *slog.Logger) *PropertyService {
+	return &PropertyService{repo: repo, audit: audit, cfg: cfg, logger: logger}
 }
 
 func (s *PropertyService) CreateProperty(ctx context.Context, p models.Property) (int, error) {
+	ctx, span := tracing.Start(ctx, "PropertyService.CreateProperty")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return 0, errors.New("could not retrieve user claims from context")
@@ -31,12 +36,12 @@ func (s *PropertyService) CreateProperty(ctx context.Context, p models.Property)
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can create properties.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for CreateProperty", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return 0, fmt.Errorf("forbidden: only managers can create properties")
+		s.logger.WarnContext(ctx, "Permission denied for CreateProperty", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return 0, Forbidden("only managers can create properties")
 	}
 
 	if p.Name == "" || p.Price <= 0 || p.SiteID <= 0 || p.PropertyTypeID <= 0 {
-		return 0, errors.New("name, price, site_id, and property_type_id are required fields")
+		return 0, Invalid("name, price, site_id, and property_type_id are required fields")
 	}
 
 	// Validate that site_id exists.
@@ -45,7 +50,7 @@ func (s *PropertyService) CreateProperty(ctx context.Context, p models.Property)
 		return 0, fmt.Errorf("error validating site: %w", err)
 	}
 	if !siteExists {
-		return 0, fmt.Errorf("site with ID %d does not exist", p.SiteID)
+		return 0, InvalidField("site_id", fmt.Sprintf("site %d does not exist", p.SiteID))
 	}
 
 	// Validate that property_type_id exists.
@@ -54,30 +59,45 @@ func (s *PropertyService) CreateProperty(ctx context.Context, p models.Property)
 		return 0, fmt.Errorf("error validating property type: %w", err)
 	}
 	if !propertyTypeExists {
-		return 0, fmt.Errorf("property type with ID %d does not exist", p.PropertyTypeID)
+		return 0, InvalidField("property_type_id", fmt.Sprintf("property type %d does not exist", p.PropertyTypeID))
 	}
 
-	return s.repo.Create(ctx, p)
+	newID, err := s.repo.Create(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	p.ID = newID
+	s.audit.Created(ctx, AuditEntityProperty, newID, p)
+	return newID, nil
 }
 
 func (s *PropertyService) GetAllProperties(ctx context.Context) ([]models.Property, error) {
+	ctx, span := tracing.Start(ctx, "PropertyService.GetAllProperties")
+	defer span.End()
+
 	// Both roles can view all properties, no specific filtering needed here.
 	return s.repo.GetAll(ctx)
 }
 
 func (s *PropertyService) GetPropertyByID(ctx context.Context, id int) (*models.Property, error) {
+	ctx, span := tracing.Start(ctx, "PropertyService.GetPropertyByID")
+	defer span.End()
+
 	// Both roles can view properties by ID, no specific filtering needed here.
 	property, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if property == nil {
-		return nil, fmt.Errorf("property with ID %d not found", id)
+		return nil, NotFound("property with ID %d not found", id)
 	}
 	return property, nil
 }
 
 func (s *PropertyService) UpdateProperty(ctx context.Context, id int, p models.Property) error {
+	ctx, span := tracing.Start(ctx, "PropertyService.UpdateProperty")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return errors.New("could not retrieve user claims from context")
@@ -86,17 +106,17 @@ func (s *PropertyService) UpdateProperty(ctx context.Context, id int, p models.P
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can update properties.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for UpdateProperty", "user_id", claims.UserID, "role_id", claims.RoleID, "property_id", id)
-		return fmt.Errorf("forbidden: only managers can update properties")
+		s.logger.WarnContext(ctx, "Permission denied for UpdateProperty", "user_id", claims.UserID, "role_id", claims.RoleID, "property_id", id)
+		return Forbidden("only managers can update properties")
 	}
 
-	_, err := s.GetPropertyByID(ctx, id)
+	existingProperty, err := s.GetPropertyByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	p.ID = id
 	if p.Name == "" || p.Price <= 0 || p.SiteID <= 0 || p.PropertyTypeID <= 0 {
-		return errors.New("name, price, site_id, and property_type_id are required fields")
+		return Invalid("name, price, site_id, and property_type_id are required fields")
 	}
 
 	// Validate that site_id exists.
@@ -105,7 +125,7 @@ func (s *PropertyService) UpdateProperty(ctx context.Context, id int, p models.P
 		return fmt.Errorf("error validating site: %w", err)
 	}
 	if !siteExists {
-		return fmt.Errorf("site with ID %d does not exist", p.SiteID)
+		return InvalidField("site_id", fmt.Sprintf("site %d does not exist", p.SiteID))
 	}
 
 	// Validate that property_type_id exists.
@@ -114,20 +134,49 @@ func (s *PropertyService) UpdateProperty(ctx context.Context, id int, p models.P
 		return fmt.Errorf("error validating property type: %w", err)
 	}
 	if !propertyTypeExists {
-		return fmt.Errorf("property type with ID %d does not exist", p.PropertyTypeID)
+		return InvalidField("property_type_id", fmt.Sprintf("property type %d does not exist", p.PropertyTypeID))
 	}
 
 	err = s.repo.Update(ctx, p)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("property with ID %d not found during update", id)
+			return NotFound("property with ID %d not found during update", id)
 		}
 		return err
 	}
+	s.audit.UpdatedStored(ctx, AuditEntityProperty, id, existingProperty, func() (interface{}, error) {
+		return s.repo.GetByID(ctx, id)
+	})
 	return nil
 }
 
-func (s *PropertyService) DeleteProperty(ctx context.Context, id int) error {
+// PatchProperty applies a JSON merge patch (RFC 7396) to a property and
+// returns the updated property. Only managers can patch properties.
+func (s *PropertyService) PatchProperty(ctx context.Context, id int, patch []byte, expectedVersion int) (*models.Property, error) {
+	ctx, span := tracing.Start(ctx, "PropertyService.PatchProperty")
+	defer span.End()
+
+	existingProperty, err := s.GetPropertyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var p models.Property
+	if err := applyMergePatch(existingProperty, patch, propertyPatchFields, propertyPatchFields, &p); err != nil {
+		return nil, err
+	}
+	p.Version = patchVersion(existingProperty.Version, expectedVersion)
+
+	if err := s.UpdateProperty(ctx, id, p); err != nil {
+		return nil, err
+	}
+	return s.GetPropertyByID(ctx, id)
+}
+
+func (s *PropertyService) DeleteProperty(ctx context.Context, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "PropertyService.DeleteProperty")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return errors.New("could not retrieve user claims from context")
@@ -136,16 +185,25 @@ func (s *PropertyService) DeleteProperty(ctx context.Context, id int) error {
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can delete properties.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for DeleteProperty", "user_id", claims.UserID, "role_id", claims.RoleID, "property_id", id)
-		return fmt.Errorf("forbidden: only managers can delete properties")
+		s.logger.WarnContext(ctx, "Permission denied for DeleteProperty", "user_id", claims.UserID, "role_id", claims.RoleID, "property_id", id)
+		return Forbidden("only managers can delete properties")
+	}
+
+	existingProperty, err := s.GetPropertyByID(ctx, id)
+	if err != nil {
+		return err
 	}
 
-	err := s.repo.Delete(ctx, id)
+	err = s.repo.Delete(ctx, id, expectedVersion)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("property with ID %d not found", id)
+			return NotFound("property with ID %d not found", id)
+		}
+		if postgres.IsForeignKeyViolation(err) {
+			return Conflict("cannot delete property: it is linked to existing leads or deals")
 		}
 		return err
 	}
+	s.audit.Deleted(ctx, AuditEntityProperty, id, existingProperty)
 	return nil
 }
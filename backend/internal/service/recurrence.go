@@ -0,0 +1,121 @@
+package service
+
+import (
+	"crm-project/internal/models"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// EditScope says which occurrences of a recurring task an edit or delete
+// applies to.
+type EditScope string
+
+const (
+	// EditThis changes only the given occurrence. The series carries on as
+	// before, so the next occurrence is still due when the rule says.
+	EditThis EditScope = "this"
+	// EditFuture also changes the series, so every occurrence created from
+	// now on follows the edited task (and the new rule, if one is given).
+	EditFuture EditScope = "future"
+)
+
+// ParseEditScope parses the scope query parameter. An empty value means
+// EditThis.
+func ParseEditScope(s string) (EditScope, error) {
+	switch EditScope(s) {
+	case "", EditThis:
+		return EditThis, nil
+	case EditFuture:
+		return EditFuture, nil
+	}
+	return "", Invalid("scope must be %q or %q", EditThis, EditFuture)
+}
+
+// parseRRule checks a recurrence rule from a client and returns it in
+// canonical form. A leading "RRULE:" is accepted. COUNT is the total number
+// of occurrences, the first task included.
+func parseRRule(rule string) (string, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return "", Invalid("recurrence rrule is required")
+	}
+	if strings.ContainsAny(rule, "\r\n") {
+		return "", Invalid("recurrence rrule must be a single RRULE without DTSTART")
+	}
+	opt, err := rrule.StrToROption(rule)
+	if err != nil {
+		return "", Invalid("invalid recurrence rrule: %v", err)
+	}
+	// rrule's frequencies run from YEARLY (0) to SECONDLY (6).
+	if opt.Freq > rrule.DAILY {
+		return "", Invalid("tasks can recur at most daily")
+	}
+	if opt.Count < 0 {
+		return "", Invalid("recurrence COUNT must be positive")
+	}
+	if _, err := rrule.NewRRule(*opt); err != nil {
+		return "", Invalid("invalid recurrence rrule: %v", err)
+	}
+	return opt.RRuleString(), nil
+}
+
+// occurrenceDue returns when occurrence number n of a series is due. The
+// occurrence at ts.StartOccurrence is due at ts.DTStart and later ones fall
+// on the dates the rule produces after it. It returns false once COUNT or
+// UNTIL has been reached.
+func occurrenceDue(ts *models.TaskSeries, n int) (time.Time, bool, error) {
+	opt, err := rrule.StrToROption(ts.RRule)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	k := n - ts.StartOccurrence
+	if k <= 0 {
+		return ts.DTStart, k == 0, nil
+	}
+	if opt.Count > 0 && k >= opt.Count {
+		return time.Time{}, false, nil
+	}
+	// COUNT is applied above so that it counts the task at DTStart even when
+	// DTStart itself does not match the rule.
+	opt.Count = 0
+	opt.Dtstart = ts.DTStart
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	next := r.Iterator()
+	for {
+		t, ok := next()
+		if !ok {
+			return time.Time{}, false, nil
+		}
+		if !t.After(ts.DTStart) {
+			continue
+		}
+		if k--; k == 0 {
+			return t, true, nil
+		}
+	}
+}
+
+// rebaseRRule adjusts a rule for a series restarted skipped occurrences
+// later, so a COUNT keeps limiting the series as a whole. The restarting
+// occurrence itself always remains.
+func rebaseRRule(rule string, skipped int) (string, error) {
+	opt, err := rrule.StrToROption(rule)
+	if err != nil {
+		return "", err
+	}
+	if opt.Count == 0 || skipped <= 0 {
+		return rule, nil
+	}
+	opt.Count = max(1, opt.Count-skipped)
+	return opt.RRuleString(), nil
+}
+
+// isDealClosed reports whether a deal has reached a final status.
+func isDealClosed(d *models.Deal) bool {
+	return d.DealStatus == "Closed-Won" || d.DealStatus == "Closed-Lost"
+}
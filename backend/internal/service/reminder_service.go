@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/models"
+	"crm-project/internal/notify"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layouts of the times shown in reminders and digests.
+const (
+	reminderTimeLayout = "15:04"
+	reminderDateLayout = "Mon 2 Jan 2006"
+)
+
+// ReminderService watches task due dates. Each run reminds assignees of tasks
+// coming due, marks lapsed tasks Overdue and, once a day, sends every user
+// with open tasks a digest of them. Notifications are stored in-app and
+// emailed to the user.
+type ReminderService struct {
+	tasks    postgres.TaskReminderRepository
+	notifier notifier
+	cfg      *config.Config
+	logger   *slog.Logger
+	loc      *time.Location // Time zone of the digest hour and of times in messages
+
+	mu         sync.Mutex
+	digestDate string // Local date of the last completed digest run
+}
+
+func NewReminderService(tasks postgres.TaskReminderRepository, notifications postgres.NotificationRepository, users postgres.UserRepository, email notify.EmailSender, cfg *config.Config, logger *slog.Logger) *ReminderService {
+	return &ReminderService{
+		tasks:    tasks,
+		notifier: notifier{notifications: notifications, users: users, email: email, logger: logger},
+		cfg:      cfg,
+		logger:   logger,
+		loc:      cfg.ReminderLocation(),
+	}
+}
+
+// Run checks due dates every reminders.interval until ctx is cancelled.
+func (s *ReminderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Reminders.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.ErrorContext(ctx, "task reminder run failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends what is due at now. Every notification is stored under a key
+// naming what it is about, and one already stored is not sent again, so runs
+// may be repeated and several instances may run at once.
+func (s *ReminderService) RunOnce(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "ReminderService.RunOnce")
+	defer span.End()
+
+	return errors.Join(
+		s.markOverdue(ctx, now),
+		s.sendReminders(ctx, now),
+		s.sendDigests(ctx, now),
+	)
+}
+
+// markOverdue marks the pending tasks whose due date has passed Overdue and
+// tells their assignees.
+func (s *ReminderService) markOverdue(ctx context.Context, now time.Time) error {
+	tasks, err := s.tasks.MarkOverdue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("marking tasks overdue: %w", err)
+	}
+	// The tasks are marked already, so a failed notification does not stop
+	// the others from being sent.
+	var errs []error
+	for _, t := range tasks {
+		s.logger.InfoContext(ctx, "task marked overdue", "task_id", t.ID, "assigned_to", t.AssignedTo, "due_date", t.DueDate)
+		err := s.notifier.notify(ctx, &models.Notification{
+			UserID:   t.AssignedTo,
+			Kind:     models.NotificationTaskOverdue,
+			Title:    "Task overdue: " + t.TaskName,
+			Body:     fmt.Sprintf("%q was due %s and is not completed.", t.TaskName, s.formatTime(t.DueDate)),
+			TaskID:   &t.ID,
+			DedupKey: dedupKey("task:%d:overdue:%d", t.ID, t.DueDate.Unix()),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendReminders reminds assignees of open tasks due within the largest
+// offset. A task gets one reminder per offset, sent once the time left is
+// below it; if several offsets have passed since the last run (or since the
+// task was created) only the shortest is sent.
+func (s *ReminderService) sendReminders(ctx context.Context, now time.Time) error {
+	offsets := slices.Sorted(slices.Values(s.cfg.Reminders.Offsets))
+	if len(offsets) == 0 {
+		return nil
+	}
+	tasks, err := s.tasks.GetOpenTasksDueBefore(ctx, now.Add(offsets[len(offsets)-1]))
+	if err != nil {
+		return fmt.Errorf("loading tasks due soon: %w", err)
+	}
+	for _, t := range tasks {
+		left := t.DueDate.Sub(now)
+		if left <= 0 {
+			continue
+		}
+		i, _ := slices.BinarySearch(offsets, left)
+		offset := offsets[i]
+		err := s.notifier.notify(ctx, &models.Notification{
+			UserID:   t.AssignedTo,
+			Kind:     models.NotificationTaskReminder,
+			Title:    "Task due soon: " + t.TaskName,
+			Body:     fmt.Sprintf("%q is due %s.", t.TaskName, s.formatTime(t.DueDate)),
+			TaskID:   &t.ID,
+			DedupKey: dedupKey("task:%d:reminder:%s:%d", t.ID, offset, t.DueDate.Unix()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendDigests sends each user a summary of their open tasks due by the end
+// of the day, overdue ones included, once reminders.digest_hour has passed.
+func (s *ReminderService) sendDigests(ctx context.Context, now time.Time) error {
+	local := now.In(s.loc)
+	if local.Hour() < s.cfg.Reminders.DigestHour {
+		return nil
+	}
+	date := local.Format(time.DateOnly)
+	s.mu.Lock()
+	done := s.digestDate == date
+	s.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	y, m, d := local.Date()
+	endOfDay := time.Date(y, m, d+1, 0, 0, 0, 0, s.loc)
+	tasks, err := s.tasks.GetOpenTasksDueBefore(ctx, endOfDay)
+	if err != nil {
+		return fmt.Errorf("loading tasks for digest: %w", err)
+	}
+	byUser := make(map[int][]models.Task)
+	for _, t := range tasks {
+		byUser[t.AssignedTo] = append(byUser[t.AssignedTo], t)
+	}
+	startOfDay := time.Date(y, m, d, 0, 0, 0, 0, s.loc)
+	for _, userID := range slices.Sorted(maps.Keys(byUser)) {
+		err := s.notifier.notify(ctx, &models.Notification{
+			UserID:   userID,
+			Kind:     models.NotificationTaskDigest,
+			Title:    fmt.Sprintf("Your tasks for %s: %s", local.Format(reminderDateLayout), pluralize(len(byUser[userID]), "open task")),
+			Body:     s.digestBody(byUser[userID], startOfDay, now),
+			DedupKey: dedupKey("digest:%d:%s", userID, date),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.digestDate = date
+	s.mu.Unlock()
+	return nil
+}
+
+// digestBody lists tasks, which are ordered by due date, one per line.
+func (s *ReminderService) digestBody(tasks []models.Task, startOfDay, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("Open tasks due today or overdue:\n\n")
+	for _, t := range tasks {
+		switch {
+		case t.DueDate.Before(startOfDay):
+			fmt.Fprintf(&b, "- %s (overdue, was due %s)\n", t.TaskName, s.formatTime(t.DueDate))
+		case t.DueDate.Before(now):
+			fmt.Fprintf(&b, "- %s (overdue, was due at %s)\n", t.TaskName, t.DueDate.In(s.loc).Format(reminderTimeLayout))
+		default:
+			fmt.Fprintf(&b, "- %s (due at %s)\n", t.TaskName, t.DueDate.In(s.loc).Format(reminderTimeLayout))
+		}
+	}
+	return b.String()
+}
+
+// formatTime shows t in the reminder time zone.
+func (s *ReminderService) formatTime(t time.Time) string {
+	return t.In(s.loc).Format(reminderDateLayout + " " + reminderTimeLayout + " MST")
+}
+
+func dedupKey(format string, args ...any) *string {
+	key := fmt.Sprintf(format, args...)
+	return &key
+}
+
+// pluralize formats a count of things, e.g. "1 open task" or "3 open tasks".
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -5,32 +5,40 @@ import (
 	"crm-project/internal/config"
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"crm-project/internal/util"
 	"errors"
-	"fmt"
 	"log/slog"
 	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ReportService struct {
-	userRepo *postgres.UserRepo
-	leadRepo *postgres.LeadRepo
-	dealRepo *postgres.DealRepo
+	userRepo postgres.UserRepository
+	leadRepo postgres.LeadRepository
+	dealRepo postgres.DealRepository
+	slaRepo  postgres.TaskSLARepository
 	cfg      *config.Config // Add config here
 	logger   *slog.Logger
 }
 
-func NewReportService(ur *postgres.UserRepo, lr *postgres.LeadRepo, dr *postgres.DealRepo, cfg *config.Config, logger *slog.Logger) *ReportService {
+func NewReportService(ur postgres.UserRepository, lr postgres.LeadRepository, dr postgres.DealRepository, sr postgres.TaskSLARepository, cfg *config.Config, logger *slog.Logger) *ReportService {
 	return &ReportService{
 		userRepo: ur,
 		leadRepo: lr,
 		dealRepo: dr,
+		slaRepo:  sr,
 		cfg:      cfg,
 		logger:   logger,
 	}
 }
 
 func (s *ReportService) GenerateEmployeeLeadReport(ctx context.Context) (*models.EmployeeLeadReport, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GenerateEmployeeLeadReport")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -38,14 +46,14 @@ func (s *ReportService) GenerateEmployeeLeadReport(ctx context.Context) (*models
 
 	// --- PERMISSION CHECK ---
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GenerateEmployeeLeadReport", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return nil, fmt.Errorf("forbidden: only managers can generate this report")
+		s.logger.WarnContext(ctx, "Permission denied for GenerateEmployeeLeadReport", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can generate this report")
 	}
 
-	s.logger.Info("starting generation of employee lead report")
+	s.logger.InfoContext(ctx, "starting generation of employee lead report")
 	agents, err := s.userRepo.GetAllSalesAgents(ctx)
 	if err != nil {
-		s.logger.Error("failed to get sales agents for report", "error", err)
+		s.logger.ErrorContext(ctx, "failed to get sales agents for report", "error", err)
 		return nil, err
 	}
 
@@ -57,10 +65,14 @@ func (s *ReportService) GenerateEmployeeLeadReport(ctx context.Context) (*models
 		wg.Add(1)
 		go func(currentAgent models.User) {
 			defer wg.Done()
+			// One span per agent makes the fan-out visible in a trace.
+			ctx, span := tracing.Start(ctx, "ReportService.agentLeadCounts",
+				trace.WithAttributes(attribute.Int("agent_id", currentAgent.ID)))
+			defer span.End()
 
 			counts, err := s.leadRepo.GetLeadCountsByUserID(ctx, currentAgent.ID)
 			if err != nil {
-				s.logger.Error("failed to get lead counts for agent", "agent_id", currentAgent.ID, "error", err)
+				s.logger.ErrorContext(ctx, "failed to get lead counts for agent", "agent_id", currentAgent.ID, "error", err)
 				return
 			}
 			resultsChan <- models.EmployeeLeadRow{
@@ -90,15 +102,18 @@ func (s *ReportService) GenerateEmployeeLeadReport(ctx context.Context) (*models
 	}
 
 	if ctx.Err() != nil {
-		s.logger.Warn("employee lead report generation cancelled by context", "error", ctx.Err())
+		s.logger.WarnContext(ctx, "employee lead report generation cancelled by context", "error", ctx.Err())
 		return nil, ctx.Err()
 	}
 
-	s.logger.Info("successfully generated employee lead report", "row_count", len(report.Rows))
+	s.logger.InfoContext(ctx, "successfully generated employee lead report", "row_count", len(report.Rows))
 	return report, nil
 }
 
 func (s *ReportService) GetSourceLeadReport(ctx context.Context) ([]postgres.SourceLeadReportRow, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetSourceLeadReport")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -106,15 +121,18 @@ func (s *ReportService) GetSourceLeadReport(ctx context.Context) ([]postgres.Sou
 
 	// --- PERMISSION CHECK ---
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GetSourceLeadReport", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return nil, fmt.Errorf("forbidden: only managers can generate this report")
+		s.logger.WarnContext(ctx, "Permission denied for GetSourceLeadReport", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can generate this report")
 	}
 
-	s.logger.Info("generating source lead report")
+	s.logger.InfoContext(ctx, "generating source lead report")
 	return s.leadRepo.GetSourceLeadReport(ctx)
 }
 
 func (s *ReportService) GetEmployeeSalesReport(ctx context.Context) ([]postgres.EmployeeSalesReportRow, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetEmployeeSalesReport")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -122,17 +140,20 @@ func (s *ReportService) GetEmployeeSalesReport(ctx context.Context) ([]postgres.
 
 	// --- PERMISSION CHECK ---
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GetEmployeeSalesReport", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return nil, fmt.Errorf("forbidden: only managers can generate this report")
+		s.logger.WarnContext(ctx, "Permission denied for GetEmployeeSalesReport", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can generate this report")
 	}
 
-	s.logger.Info("generating employee sales report")
+	s.logger.InfoContext(ctx, "generating employee sales report")
 	return s.dealRepo.GetEmployeeSalesReport(ctx)
 }
 
 
 
 func (s *ReportService) GetSourceSalesReport(ctx context.Context) ([]postgres.SourceSalesReportRow, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetSourceSalesReport")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -140,25 +161,31 @@ func (s *ReportService) GetSourceSalesReport(ctx context.Context) ([]postgres.So
 
 	// --- PERMISSION CHECK ---
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GetSourceSalesReport", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return nil, fmt.Errorf("forbidden: only managers can generate this report")
+		s.logger.WarnContext(ctx, "Permission denied for GetSourceSalesReport", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can generate this report")
 	}
 
-	s.logger.Info("generating source sales report")
+	s.logger.InfoContext(ctx, "generating source sales report")
 	return s.dealRepo.GetSourceSalesReport(ctx)
 }
 
 func (s *ReportService) GetMySalesReport(ctx context.Context) ([]postgres.EmployeeSalesReportRow, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetMySalesReport")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
 	}
 
-	s.logger.Info("generating personal sales report for user", "user_id", claims.UserID)
+	s.logger.InfoContext(ctx, "generating personal sales report for user", "user_id", claims.UserID)
 	return s.dealRepo.GetEmployeeSalesReportForUser(ctx, claims.UserID)
 }
 
 func (s *ReportService) GetDealsPipelineReport(ctx context.Context) (*models.DealsPipelineReport, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetDealsPipelineReport")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -166,15 +193,15 @@ func (s *ReportService) GetDealsPipelineReport(ctx context.Context) (*models.Dea
 
 	// --- PERMISSION CHECK ---
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GetDealsPipelineReport", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return nil, fmt.Errorf("forbidden: only managers can generate this report")
+		s.logger.WarnContext(ctx, "Permission denied for GetDealsPipelineReport", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can generate this report")
 	}
 
-	s.logger.Info("generating deals pipeline report")
+	s.logger.InfoContext(ctx, "generating deals pipeline report")
 	// Call the repository method to get the raw data
 	rawReport, err := s.dealRepo.GetDealsPipelineReport(ctx)
 	if err != nil {
-		s.logger.Error("failed to get deals pipeline report from repository", "error", err)
+		s.logger.ErrorContext(ctx, "failed to get deals pipeline report from repository", "error", err)
 		return nil, err
 	}
 
@@ -200,6 +227,39 @@ func (s *ReportService) GetDealsPipelineReport(ctx context.Context) (*models.Dea
 		TotalDealAmount: totalDealAmount,
 	}
 
-	s.logger.Info("successfully generated deals pipeline report", "row_count", len(report.Rows))
+	s.logger.InfoContext(ctx, "successfully generated deals pipeline report", "row_count", len(report.Rows))
+	return report, nil
+}
+
+// GetSLABreachReport counts, per agent, the tasks that stayed open past
+// their SLA while assigned to them.
+func (s *ReportService) GetSLABreachReport(ctx context.Context) (*models.SLABreachReport, error) {
+	ctx, span := tracing.Start(ctx, "ReportService.GetSLABreachReport")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for GetSLABreachReport", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can generate this report")
+	}
+
+	s.logger.InfoContext(ctx, "generating SLA breach report")
+	rows, err := s.slaRepo.GetBreachReport(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get SLA breach report from repository", "error", err)
+		return nil, err
+	}
+
+	report := &models.SLABreachReport{Rows: rows}
+	for _, row := range rows {
+		report.Total.Breaches += row.Breaches
+		report.Total.Reassigned += row.Reassigned
+		report.Total.StillOpen += row.StillOpen
+	}
 	return report, nil
 }
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxBulkTasks caps the number of tasks one bulk operation can change.
+const maxBulkTasks = 500
+
+// ErrBulkNotApplied is returned with the results of a bulk operation in which
+// at least one task could not be changed. Nothing is applied then: the
+// results with an error say why, the others were rolled back.
+var ErrBulkNotApplied = errors.New("bulk operation not applied")
+
+// errBulkItemFailed rolls back a bulk operation with a failed item.
+var errBulkItemFailed = errors.New("bulk item failed")
+
+// TaskSelection is the set of tasks a bulk operation applies to: either the
+// tasks with the given IDs or the tasks matching a filter, but not both.
+type TaskSelection struct {
+	IDs    []int
+	Filter *models.TaskFilter
+}
+
+// BulkTaskResult is the outcome of a bulk operation for one task.
+type BulkTaskResult struct {
+	ID   int
+	Task *models.Task // The task as changed; nil if deleted or not changed
+	Err  error        // Why the task could not be changed; a domain error
+
+	before *models.Task
+	next   *models.Task // The occurrence scheduled after a recurring task
+}
+
+// bulkChange changes one task of a bulk operation. It returns whether the
+// task needs to be saved.
+type bulkChange func(ctx context.Context, t *models.Task) (bool, error)
+
+// TaskBulkService reassigns, reschedules, changes the status of and deletes
+// many tasks at once. Each operation is all or nothing: it runs in one
+// transaction, and if any task fails the checks of its single-task
+// counterpart in TaskService none is changed.
+type TaskBulkService struct {
+	repo   postgres.TaskBulkRepository
+	tasks  *TaskService
+	users  postgres.UserRepository
+	tx     postgres.Transactor
+	audit  *AuditService
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewTaskBulkService(repo postgres.TaskBulkRepository, tasks *TaskService, users postgres.UserRepository, tx postgres.Transactor, audit *AuditService, cfg *config.Config, logger *slog.Logger) *TaskBulkService {
+	return &TaskBulkService{repo: repo, tasks: tasks, users: users, tx: tx, audit: audit, cfg: cfg, logger: logger}
+}
+
+// Reassign assigns the selected tasks to a user. Only managers can reassign
+// tasks.
+func (s *TaskBulkService) Reassign(ctx context.Context, sel TaskSelection, assignedTo int) ([]BulkTaskResult, error) {
+	ctx, span := tracing.Start(ctx, "TaskBulkService.Reassign")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for bulk reassign", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("sales agents cannot reassign tasks")
+	}
+	if assignedTo <= 0 {
+		return nil, InvalidField("assigned_to", "is required")
+	}
+	user, err := s.users.GetByID(ctx, assignedTo)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, InvalidField("assigned_to", fmt.Sprintf("user %d does not exist", assignedTo))
+	}
+
+	return s.apply(ctx, "reassign", sel, false, func(ctx context.Context, t *models.Task) (bool, error) {
+		if t.AssignedTo == assignedTo {
+			return false, nil
+		}
+		t.AssignedTo = assignedTo
+		return true, nil
+	})
+}
+
+// Reschedule moves the selected tasks to a new due date, or by shiftDays days
+// if dueDate is nil. An overdue task moved into the future is pending again.
+func (s *TaskBulkService) Reschedule(ctx context.Context, sel TaskSelection, dueDate *time.Time, shiftDays int) ([]BulkTaskResult, error) {
+	ctx, span := tracing.Start(ctx, "TaskBulkService.Reschedule")
+	defer span.End()
+
+	if (dueDate == nil) == (shiftDays == 0) {
+		return nil, Invalid("exactly one of due_date and shift_days is required")
+	}
+
+	now := time.Now()
+	return s.apply(ctx, "reschedule", sel, false, func(ctx context.Context, t *models.Task) (bool, error) {
+		if dueDate != nil {
+			t.DueDate = *dueDate
+		} else {
+			t.DueDate = t.DueDate.AddDate(0, 0, shiftDays)
+		}
+		if t.Status == taskStatusOverdue && t.DueDate.After(now) {
+			t.Status = taskStatusPending
+		}
+		return true, nil
+	})
+}
+
+// SetStatus sets the status of the selected tasks to Pending or Completed.
+// Overdue is set by the reminder scheduler only, and a task reopened after
+// its due date is pending until the scheduler marks it overdue again.
+func (s *TaskBulkService) SetStatus(ctx context.Context, sel TaskSelection, status string) ([]BulkTaskResult, error) {
+	ctx, span := tracing.Start(ctx, "TaskBulkService.SetStatus")
+	defer span.End()
+
+	if status != taskStatusPending && status != taskStatusCompleted {
+		return nil, InvalidField("status", fmt.Sprintf("must be %s or %s", taskStatusPending, taskStatusCompleted))
+	}
+
+	return s.apply(ctx, "set status", sel, false, func(ctx context.Context, t *models.Task) (bool, error) {
+		if t.Status == status {
+			return false, nil
+		}
+		// Completing a task requires its required checklist items to be done.
+		if status == taskStatusCompleted {
+			open, err := s.tasks.checklist.CountOpenRequired(ctx, t.ID)
+			if err != nil {
+				return false, err
+			}
+			if open > 0 {
+				return false, Conflict("task has %s not done", pluralize(open, "required checklist item"))
+			}
+		}
+		t.Status = status
+		return true, nil
+	})
+}
+
+// Delete soft deletes the selected tasks. Only managers can delete tasks.
+// Deleting an open occurrence of a recurring task skips it, as DeleteTask
+// does with EditThis.
+func (s *TaskBulkService) Delete(ctx context.Context, sel TaskSelection) ([]BulkTaskResult, error) {
+	ctx, span := tracing.Start(ctx, "TaskBulkService.Delete")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for bulk delete", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("only managers can delete tasks")
+	}
+
+	return s.apply(ctx, "delete", sel, true, nil)
+}
+
+// apply runs a bulk operation on the selected tasks in one transaction,
+// changing each task with change or deleting it. A task that is missing, not
+// visible to the caller or rejected by change fails; if any task fails the
+// transaction is rolled back and ErrBulkNotApplied returned with the results.
+func (s *TaskBulkService) apply(ctx context.Context, op string, sel TaskSelection, del bool, change bulkChange) ([]BulkTaskResult, error) {
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	isAgent := claims.RoleID != s.cfg.Roles.ReceptionID
+
+	filter, err := s.selectionFilter(claims, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BulkTaskResult
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		results = nil
+		ids := sel.IDs
+		if filter != nil {
+			var err error
+			ids, err = s.repo.FindIDs(ctx, *filter, maxBulkTasks+1)
+			if err != nil {
+				return err
+			}
+			if len(ids) > maxBulkTasks {
+				return Invalid("the filter matches more than %d tasks", maxBulkTasks)
+			}
+		}
+		tasks, err := s.repo.GetByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		byID := make(map[int]models.Task, len(tasks))
+		for _, t := range tasks {
+			byID[t.ID] = t
+		}
+
+		failed := false
+		for _, id := range ids {
+			res := BulkTaskResult{ID: id}
+			res.Err = s.applyOne(ctx, claims.UserID, isAgent, byID, &res, del, change)
+			if res.Err != nil {
+				if !isBulkItemError(res.Err) {
+					return fmt.Errorf("task %d: %w", id, res.Err)
+				}
+				failed = true
+			}
+			results = append(results, res)
+		}
+		if failed {
+			return errBulkItemFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkItemFailed) {
+		s.logger.InfoContext(ctx, "bulk task operation not applied", "operation", op, "user_id", claims.UserID, "tasks", len(results))
+		for i := range results {
+			results[i].Task = nil
+		}
+		return results, ErrBulkNotApplied
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changed := 0
+	for _, res := range results {
+		if res.before == nil {
+			continue
+		}
+		changed++
+		before := res.before
+		if del {
+			s.audit.Deleted(ctx, AuditEntityTask, res.ID, before)
+		} else {
+			s.audit.Updated(ctx, AuditEntityTask, res.ID, before, res.Task)
+		}
+		s.tasks.occurrenceScheduled(ctx, res.next)
+	}
+	s.logger.InfoContext(ctx, "bulk task operation applied", "operation", op, "user_id", claims.UserID, "tasks", len(results), "changed", changed)
+	return results, nil
+}
+
+// applyOne changes or deletes the task of res, filling in res.Task and
+// res.before if the task is saved.
+func (s *TaskBulkService) applyOne(ctx context.Context, userID int, isAgent bool, byID map[int]models.Task, res *BulkTaskResult, del bool, change bulkChange) error {
+	existing, ok := byID[res.ID]
+	if !ok {
+		return NotFound("task with ID %d not found", res.ID)
+	}
+	// Sales agents can only change the tasks assigned to them.
+	if isAgent && existing.AssignedTo != userID {
+		return Forbidden("you do not have permission to update task %d", res.ID)
+	}
+
+	if del {
+		if err := s.tasks.taskRepo.DeleteTask(ctx, existing.ID, existing.Version); err != nil {
+			return err
+		}
+		res.before = &existing
+		return s.scheduleNext(ctx, res)
+	}
+
+	task := existing
+	save, err := change(ctx, &task)
+	if err != nil {
+		return err
+	}
+	if save {
+		if err := s.tasks.taskRepo.UpdateTask(ctx, &task); err != nil {
+			return err
+		}
+		res.before = &existing
+	}
+	res.Task = &task
+	return s.scheduleNext(ctx, res)
+}
+
+// scheduleNext schedules the next occurrence of the recurring task of res if
+// the operation completed or deleted an open one.
+func (s *TaskBulkService) scheduleNext(ctx context.Context, res *BulkTaskResult) error {
+	before := res.before
+	if before == nil || before.SeriesID == nil || before.Status == taskStatusCompleted {
+		return nil
+	}
+	if res.Task != nil && res.Task.Status != taskStatusCompleted {
+		return nil
+	}
+	var err error
+	res.next, err = s.tasks.scheduleNextOccurrence(ctx, before)
+	return err
+}
+
+// selectionFilter checks sel and returns the filter to find its tasks by, or
+// nil if sel lists the task IDs. The filter of a sales agent is limited to
+// the tasks assigned to them.
+func (s *TaskBulkService) selectionFilter(claims *dto.Claims, sel TaskSelection) (*models.TaskFilter, error) {
+	if (len(sel.IDs) == 0) == (sel.Filter == nil) {
+		return nil, Invalid("exactly one of ids and filter is required")
+	}
+	if sel.Filter == nil {
+		if len(sel.IDs) > maxBulkTasks {
+			return nil, InvalidField("ids", fmt.Sprintf("at most %d tasks can be changed at once", maxBulkTasks))
+		}
+		seen := make(map[int]bool, len(sel.IDs))
+		for _, id := range sel.IDs {
+			if id <= 0 {
+				return nil, InvalidField("ids", "task IDs must be positive")
+			}
+			if seen[id] {
+				return nil, InvalidField("ids", fmt.Sprintf("task %d is listed more than once", id))
+			}
+			seen[id] = true
+		}
+		return nil, nil
+	}
+
+	f := *sel.Filter
+	if f.IsEmpty() {
+		return nil, InvalidField("filter", "at least one condition is required")
+	}
+	if f.Status != "" && f.Status != taskStatusPending && f.Status != taskStatusOverdue && f.Status != taskStatusCompleted {
+		return nil, InvalidField("filter.status", fmt.Sprintf("must be %s, %s or %s", taskStatusPending, taskStatusOverdue, taskStatusCompleted))
+	}
+	if f.AssignedTo < 0 || f.LeadID < 0 || f.DealID < 0 {
+		return nil, InvalidField("filter", "IDs must be positive")
+	}
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		if f.AssignedTo != 0 && f.AssignedTo != claims.UserID {
+			return nil, Forbidden("sales agents can only change the tasks assigned to them")
+		}
+		f.AssignedTo = claims.UserID
+	}
+	return &f, nil
+}
+
+// isBulkItemError reports whether err fails a single task of a bulk
+// operation rather than the whole operation.
+func isBulkItemError(err error) bool {
+	var (
+		notFound   *NotFoundError
+		forbidden  *ForbiddenError
+		validation *ValidationError
+		conflict   *ConflictError
+	)
+	return errors.As(err, &notFound) || errors.As(err, &forbidden) || errors.As(err, &validation) ||
+		errors.As(err, &conflict) || errors.Is(err, ErrVersionConflict) || errors.Is(err, ErrNotFound)
+}
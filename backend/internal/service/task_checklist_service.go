@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// maxChecklistTitleLength matches the task_checklist_items.title column.
+const maxChecklistTitleLength = 255
+
+// TaskChecklistService manages the checklist items of tasks. Everyone who
+// can see a task can add items and tick them off; only Reception and the
+// task's creator can add, change or remove required items, so the assignee
+// cannot get around the rule that a task is completed only once its required
+// items are done.
+type TaskChecklistService struct {
+	items  postgres.TaskChecklistRepository
+	tasks  *TaskService
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+func NewTaskChecklistService(items postgres.TaskChecklistRepository, tasks *TaskService, cfg *config.Config, logger *slog.Logger) *TaskChecklistService {
+	return &TaskChecklistService{items: items, tasks: tasks, cfg: cfg, logger: logger}
+}
+
+// GetChecklist returns the checklist of a task in position order.
+func (s *TaskChecklistService) GetChecklist(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	ctx, span := tracing.Start(ctx, "TaskChecklistService.GetChecklist")
+	defer span.End()
+
+	if _, err := s.tasks.GetTaskByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return s.items.GetByTaskID(ctx, taskID)
+}
+
+// AddItem adds an item to a task's checklist. Without a position it goes
+// last. An item added as completed is completed by the current user.
+func (s *TaskChecklistService) AddItem(ctx context.Context, taskID int, item *models.ChecklistItem, completed bool) error {
+	ctx, span := tracing.Start(ctx, "TaskChecklistService.AddItem")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	task, err := s.tasks.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if err := validateChecklistItem(item); err != nil {
+		return err
+	}
+	if item.Required && !s.canManageRequired(claims, task) {
+		return Forbidden("only managers and the task's creator can add required checklist items")
+	}
+
+	item.TaskID = taskID
+	item.CreatedBy = claims.UserID
+	item.CompletedAt, item.CompletedBy = nil, nil
+	if completed {
+		now := time.Now()
+		item.CompletedAt, item.CompletedBy = &now, &claims.UserID
+	}
+	if err := s.items.Create(ctx, item); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create checklist item", "task_id", taskID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateItem saves the title, required flag, position and completion state
+// of a checklist item. A position of 0 keeps the current one. On success item
+// holds the saved item.
+func (s *TaskChecklistService) UpdateItem(ctx context.Context, taskID int, item *models.ChecklistItem, completed bool) error {
+	ctx, span := tracing.Start(ctx, "TaskChecklistService.UpdateItem")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	task, existing, err := s.getItem(ctx, taskID, item.ID)
+	if err != nil {
+		return err
+	}
+	if err := validateChecklistItem(item); err != nil {
+		return err
+	}
+	// Anyone who can see the task may tick a required item off.
+	if (item.Required != existing.Required || (existing.Required && item.Title != existing.Title)) && !s.canManageRequired(claims, task) {
+		return Forbidden("only managers and the task's creator can change required checklist items")
+	}
+
+	updated := *existing
+	updated.Title = item.Title
+	updated.Required = item.Required
+	if item.Position != 0 {
+		updated.Position = item.Position
+	}
+	updated.Version = item.Version
+	switch {
+	case completed && existing.CompletedAt == nil:
+		now := time.Now()
+		updated.CompletedAt, updated.CompletedBy = &now, &claims.UserID
+	case !completed:
+		updated.CompletedAt, updated.CompletedBy = nil, nil
+	}
+	if err := s.items.Update(ctx, &updated); err != nil {
+		return err
+	}
+	*item = updated
+	return nil
+}
+
+// DeleteItem removes an item from a task's checklist.
+func (s *TaskChecklistService) DeleteItem(ctx context.Context, taskID, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "TaskChecklistService.DeleteItem")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	task, existing, err := s.getItem(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+	if existing.Required && !s.canManageRequired(claims, task) {
+		return Forbidden("only managers and the task's creator can remove required checklist items")
+	}
+	return s.items.Delete(ctx, id, expectedVersion)
+}
+
+// getItem returns a task the current user can see and one of its checklist
+// items.
+func (s *TaskChecklistService) getItem(ctx context.Context, taskID, id int) (*models.Task, *models.ChecklistItem, error) {
+	task, err := s.tasks.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if id <= 0 {
+		return nil, nil, Invalid("invalid checklist item ID")
+	}
+	item, err := s.items.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item == nil || item.TaskID != taskID {
+		return nil, nil, NotFound("checklist item with ID %d not found", id)
+	}
+	return task, item, nil
+}
+
+func (s *TaskChecklistService) canManageRequired(claims *dto.Claims, task *models.Task) bool {
+	return claims.RoleID == s.cfg.Roles.ReceptionID || (task.CreatedBy != 0 && task.CreatedBy == claims.UserID)
+}
+
+func validateChecklistItem(item *models.ChecklistItem) error {
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" {
+		return InvalidField("title", "is required")
+	}
+	if len([]rune(item.Title)) > maxChecklistTitleLength {
+		return InvalidField("title", "must be at most 255 characters")
+	}
+	if item.Position < 0 {
+		return InvalidField("position", "must not be negative")
+	}
+	return nil
+}
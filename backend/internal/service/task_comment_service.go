@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"crm-project/internal/util"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// maxCommentLength caps the length of a comment body in characters.
+const maxCommentLength = 10000
+
+// TaskCommentService manages the comment threads on tasks. Everyone who can
+// see a task (Reception, its assignee and its creator) can read and post
+// comments; only a comment's author and Reception can edit or delete it.
+type TaskCommentService struct {
+	comments postgres.TaskCommentRepository
+	tasks    *TaskService
+	tx       postgres.Transactor
+	cfg      *config.Config
+	logger   *slog.Logger
+}
+
+func NewTaskCommentService(comments postgres.TaskCommentRepository, tasks *TaskService, tx postgres.Transactor, cfg *config.Config, logger *slog.Logger) *TaskCommentService {
+	return &TaskCommentService{comments: comments, tasks: tasks, tx: tx, cfg: cfg, logger: logger}
+}
+
+// GetComments returns the comments on a task, oldest first. Deleted comments
+// are included without their body so replies to them keep their place.
+func (s *TaskCommentService) GetComments(ctx context.Context, taskID int) ([]models.TaskComment, error) {
+	ctx, span := tracing.Start(ctx, "TaskCommentService.GetComments")
+	defer span.End()
+
+	if _, err := s.tasks.GetTaskByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return s.comments.GetByTaskID(ctx, taskID)
+}
+
+// AddComment posts a comment on a task as the current user. A comment with a
+// ParentID replies to another comment on the same task.
+func (s *TaskCommentService) AddComment(ctx context.Context, taskID int, comment *models.TaskComment) error {
+	ctx, span := tracing.Start(ctx, "TaskCommentService.AddComment")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	if _, err := s.tasks.GetTaskByID(ctx, taskID); err != nil {
+		return err
+	}
+	body, err := validateCommentBody(comment.Body)
+	if err != nil {
+		return err
+	}
+
+	if comment.ParentID != nil {
+		parent, err := s.comments.GetByID(ctx, *comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if parent == nil || parent.TaskID != taskID {
+			return InvalidField("parent_id", "must be a comment on the same task")
+		}
+		if parent.DeletedAt != nil {
+			return InvalidField("parent_id", "cannot reply to a deleted comment")
+		}
+	}
+
+	comment.TaskID = taskID
+	comment.AuthorID = claims.UserID
+	comment.Body = body
+	if err := s.comments.Create(ctx, comment); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create task comment", "task_id", taskID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateComment replaces the body of a comment. The previous body is kept in
+// the comment's edit history. On success comment holds the saved comment.
+func (s *TaskCommentService) UpdateComment(ctx context.Context, taskID int, comment *models.TaskComment) error {
+	ctx, span := tracing.Start(ctx, "TaskCommentService.UpdateComment")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+	existing, err := s.getEditableComment(ctx, taskID, comment.ID)
+	if err != nil {
+		return err
+	}
+	body, err := validateCommentBody(comment.Body)
+	if err != nil {
+		return err
+	}
+	if comment.Version != 0 && comment.Version != existing.Version {
+		return postgres.ErrVersionConflict
+	}
+
+	updated := *existing
+	updated.Body = body
+	updated.Version = comment.Version
+	if body != existing.Body {
+		err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+			edit := &models.TaskCommentEdit{CommentID: existing.ID, Body: existing.Body, EditedBy: claims.UserID}
+			if err := s.comments.AddEdit(ctx, edit); err != nil {
+				return err
+			}
+			return s.comments.Update(ctx, &updated)
+		})
+		if err != nil {
+			return err
+		}
+		updated.Edited = true
+	} else {
+		updated.Version = existing.Version
+	}
+	*comment = updated
+	return nil
+}
+
+// DeleteComment deletes a comment. Its replies are kept.
+func (s *TaskCommentService) DeleteComment(ctx context.Context, taskID, id int, expectedVersion int) error {
+	ctx, span := tracing.Start(ctx, "TaskCommentService.DeleteComment")
+	defer span.End()
+
+	if _, err := s.getEditableComment(ctx, taskID, id); err != nil {
+		return err
+	}
+	return s.comments.Delete(ctx, id, expectedVersion)
+}
+
+// GetCommentHistory returns the earlier bodies of a comment, oldest first.
+func (s *TaskCommentService) GetCommentHistory(ctx context.Context, taskID, id int) ([]models.TaskCommentEdit, error) {
+	ctx, span := tracing.Start(ctx, "TaskCommentService.GetCommentHistory")
+	defer span.End()
+
+	if _, err := s.getComment(ctx, taskID, id); err != nil {
+		return nil, err
+	}
+	return s.comments.GetEdits(ctx, id)
+}
+
+// getComment returns a comment on a task the current user can see.
+func (s *TaskCommentService) getComment(ctx context.Context, taskID, id int) (*models.TaskComment, error) {
+	if _, err := s.tasks.GetTaskByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, Invalid("invalid comment ID")
+	}
+	comment, err := s.comments.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.TaskID != taskID {
+		return nil, NotFound("comment with ID %d not found", id)
+	}
+	return comment, nil
+}
+
+// getEditableComment returns a comment that has not been deleted and that
+// the current user wrote, or any such comment for Reception.
+func (s *TaskCommentService) getEditableComment(ctx context.Context, taskID, id int) (*models.TaskComment, error) {
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	comment, err := s.getComment(ctx, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.DeletedAt != nil {
+		return nil, NotFound("comment with ID %d not found", id)
+	}
+	if comment.AuthorID != claims.UserID && claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for task comment change", "user_id", claims.UserID, "role_id", claims.RoleID, "comment_id", id, "author_id", comment.AuthorID)
+		return nil, Forbidden("you can only change your own comments")
+	}
+	return comment, nil
+}
+
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", InvalidField("body", "is required")
+	}
+	if len([]rune(body)) > maxCommentLength {
+		return "", InvalidField("body", "must be at most 10000 characters")
+	}
+	return body, nil
+}
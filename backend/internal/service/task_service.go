@@ -5,25 +5,37 @@ import (
 	"crm-project/internal/config" // Import config
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"crm-project/internal/util" // Import util for claims
 	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 )
 
 type TaskService struct {
-	taskRepo postgres.TaskRepository
-	cfg      *config.Config // Add config here
-	logger   *slog.Logger
+	taskRepo  postgres.TaskRepository
+	series    postgres.TaskSeriesRepository
+	checklist postgres.TaskChecklistRepository
+	deals     postgres.DealRepository
+	tx        postgres.Transactor
+	audit     *AuditService
+	cfg       *config.Config // Add config here
+	logger    *slog.Logger
 }
 
-func NewTaskService(taskRepo postgres.TaskRepository, cfg *config.Config, logger *slog.Logger) *TaskService {
-	return &TaskService{taskRepo: taskRepo, cfg: cfg, logger: logger}
+func NewTaskService(taskRepo postgres.TaskRepository, series postgres.TaskSeriesRepository, checklist postgres.TaskChecklistRepository, deals postgres.DealRepository, tx postgres.Transactor, audit *AuditService, cfg *config.Config, logger *slog.Logger) *TaskService {
+	return &TaskService{taskRepo: taskRepo, series: series, checklist: checklist, deals: deals, tx: tx, audit: audit, cfg: cfg, logger: logger}
 }
 
-// CreateTask creates a new task with role-based assignment
-func (s *TaskService) CreateTask(ctx context.Context, task *models.Task) (int, error) {
+// CreateTask creates a new task with role-based assignment. If rec is not nil
+// the task becomes the first occurrence of a recurring series.
+func (s *TaskService) CreateTask(ctx context.Context, task *models.Task, rec *models.Recurrence) (int, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.CreateTask")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return 0, errors.New("could not retrieve user claims from context")
@@ -32,18 +44,18 @@ func (s *TaskService) CreateTask(ctx context.Context, task *models.Task) (int, e
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can create tasks.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for CreateTask", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return 0, fmt.Errorf("forbidden: only managers can create tasks")
+		s.logger.WarnContext(ctx, "Permission denied for CreateTask", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return 0, Forbidden("only managers can create tasks")
 	}
 
 	if task.TaskName == "" {
-		return 0, errors.New("task name cannot be empty")
+		return 0, Invalid("task name cannot be empty")
 	}
 	if task.DueDate.IsZero() {
-		return 0, errors.New("due date is required")
+		return 0, Invalid("due date is required")
 	}
 	if task.Status == "" {
-		task.Status = "Pending" // Default status
+		task.Status = taskStatusPending // Default status
 	}
 
 	// Set the creator of the task to the currently logged-in user's ID.
@@ -53,72 +65,66 @@ func (s *TaskService) CreateTask(ctx context.Context, task *models.Task) (int, e
 	// If manager, they can assign to anyone.
 	// If sales agent, they can only assign to themselves.
 	if claims.RoleID == s.cfg.Roles.SalesAgentID && task.AssignedTo != claims.UserID {
-		s.logger.Warn("Sales agent tried to assign task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo)
-		return 0, errors.New("sales agents can only assign tasks to themselves")
+		s.logger.WarnContext(ctx, "Sales agent tried to assign task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo)
+		return 0, Forbidden("sales agents can only assign tasks to themselves")
 	}
 	if task.AssignedTo == 0 { // If not explicitly assigned, assign to creator
 		task.AssignedTo = claims.UserID
 	}
 
-	err := s.taskRepo.CreateTask(task)
+	err := s.insertTask(ctx, task, rec)
 	if err != nil {
-		s.logger.Error("failed to create task in repository", "error", err)
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			return 0, err
+		}
+		s.logger.ErrorContext(ctx, "failed to create task in repository", "error", err)
 		return 0, fmt.Errorf("failed to create task: %w", err)
 	}
+	s.audit.Created(ctx, AuditEntityTask, task.ID, task)
 	return task.ID, nil
 }
 
 // GetTaskByID retrieves a task by ID with permission check
 func (s *TaskService) GetTaskByID(ctx context.Context, id int) (*models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTaskByID")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
 	}
 
 	if id <= 0 {
-		return nil, errors.New("invalid task ID")
+		return nil, Invalid("invalid task ID")
 	}
 
-	task, err := s.taskRepo.GetTaskByID(id)
+	task, err := s.taskRepo.GetTaskByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if task == nil {
-		return nil, fmt.Errorf("task with ID %d not found", id)
+		return nil, NotFound("task with ID %d not found", id)
 	}
 
 	// --- PERMISSION CHECK ---
-	// A user can view if they are a Receptionist OR if they are the assigned sales agent.
-	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (task.AssignedTo == claims.UserID)
+	// A user can view if they are a Receptionist, the assigned sales agent or
+	// the task's creator.
+	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || task.AssignedTo == claims.UserID || (task.CreatedBy != 0 && task.CreatedBy == claims.UserID)
 
 	if !isAllowed {
-		s.logger.Warn("Permission denied for GetTaskByID", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", id, "task_assigned_to", task.AssignedTo)
-		return nil, fmt.Errorf("forbidden: you do not have permission to view this task")
+		s.logger.WarnContext(ctx, "Permission denied for GetTaskByID", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", id, "task_assigned_to", task.AssignedTo)
+		return nil, Forbidden("you do not have permission to view this task")
 	}
 
 	return task, nil
 }
 
-// GetTasksByDealID retrieves all tasks for a specific deal with permission check
-func (s *TaskService) GetTasksByDealID(ctx context.Context, dealID int) ([]models.Task, error) {
-	claims, ok := util.GetClaimsFromContext(ctx)
-	if !ok {
-		return nil, errors.New("could not retrieve user claims from context")
-	}
-
-	if dealID <= 0 {
-		return nil, errors.New("invalid deal ID")
-	}
-
-	// If sales agent, filter by assigned_to. If manager, get all for deal.
-	if claims.RoleID == s.cfg.Roles.SalesAgentID {
-		return s.taskRepo.GetTasksByDealIDForUser(dealID, claims.UserID)
-	}
-	return s.taskRepo.GetTasksByDealID(dealID)
-}
-
 // GetAllTasks retrieves all tasks with permission check
 func (s *TaskService) GetAllTasks(ctx context.Context, assignedToUserID *int) ([]models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetAllTasks")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -126,49 +132,59 @@ func (s *TaskService) GetAllTasks(ctx context.Context, assignedToUserID *int) ([
 
 	// If the user is a Sales Agent, only show tasks assigned to them.
 	if claims.RoleID == s.cfg.Roles.SalesAgentID {
-		s.logger.Debug("fetching tasks for single sales agent", "user_id", claims.UserID)
-		return s.taskRepo.GetTasksForUser(claims.UserID)
+		s.logger.DebugContext(ctx, "fetching tasks for single sales agent", "user_id", claims.UserID)
+		return s.taskRepo.GetTasksForUser(ctx, claims.UserID)
 	}
 
 	// If assignedToUserID is provided, filter by that user.
 	if assignedToUserID != nil && *assignedToUserID > 0 {
-		s.logger.Debug("fetching tasks for specific user", "assigned_to_user_id", *assignedToUserID)
-		return s.taskRepo.GetTasksForUser(*assignedToUserID)
+		s.logger.DebugContext(ctx, "fetching tasks for specific user", "assigned_to_user_id", *assignedToUserID)
+		return s.taskRepo.GetTasksForUser(ctx, *assignedToUserID)
 	}
 
 	// Otherwise (for Reception/Manager), show all tasks.
-	s.logger.Debug("fetching all tasks for manager role", "user_id", claims.UserID)
-	return s.taskRepo.GetAllTasks()
+	s.logger.DebugContext(ctx, "fetching all tasks for manager role", "user_id", claims.UserID)
+	return s.taskRepo.GetAllTasks(ctx)
 }
 
-// UpdateTask updates an existing task with permission check
-func (s *TaskService) UpdateTask(ctx context.Context, task *models.Task) error {
+// UpdateTask updates an existing task with permission check. With EditFuture
+// the task's series is updated in the same transaction, so that the
+// occurrences after it follow the edited task and, with rec, the new rule.
+func (s *TaskService) UpdateTask(ctx context.Context, task *models.Task, scope EditScope, rec *models.Recurrence) error {
+	ctx, span := tracing.Start(ctx, "TaskService.UpdateTask")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return errors.New("could not retrieve user claims from context")
 	}
 
 	if task.ID <= 0 {
-		return errors.New("invalid task ID")
+		return Invalid("invalid task ID")
 	}
 	if task.TaskName == "" {
-		return errors.New("task name cannot be empty")
+		return Invalid("task name cannot be empty")
 	}
 	if task.AssignedTo == 0 {
-		return errors.New("assigned_to ID is required")
+		return Invalid("assigned_to ID is required")
 	}
+	taskType, err := normalizeTaskType(task.TaskType)
+	if err != nil {
+		return err
+	}
+	task.TaskType = taskType
 
-	existingTask, err := s.taskRepo.GetTaskByID(task.ID)
+	existingTask, err := s.taskRepo.GetTaskByID(ctx, task.ID)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing task for update", "task_id", task.ID, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to fetch existing task for update", "task_id", task.ID, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("task with ID %d not found", task.ID)
+			return NotFound("task with ID %d not found", task.ID)
 		}
 		return err
 	}
 	if existingTask == nil {
-		s.logger.Warn("Existing task not found", "task_id", task.ID)
-		return fmt.Errorf("task with ID %d not found", task.ID)
+		s.logger.WarnContext(ctx, "Existing task not found", "task_id", task.ID)
+		return NotFound("task with ID %d not found", task.ID)
 	}
 
 	// --- PERMISSION CHECK ---
@@ -176,77 +192,270 @@ func (s *TaskService) UpdateTask(ctx context.Context, task *models.Task) error {
 	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (existingTask.AssignedTo == claims.UserID)
 
 	if !isAllowed {
-		s.logger.Warn("Permission denied for UpdateTask", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", task.ID, "task_assigned_to", existingTask.AssignedTo)
-		return fmt.Errorf("forbidden: you do not have permission to update this task")
+		s.logger.WarnContext(ctx, "Permission denied for UpdateTask", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", task.ID, "task_assigned_to", existingTask.AssignedTo)
+		return Forbidden("you do not have permission to update this task")
 	}
 
 	// Sales agents can only update tasks assigned to them. Managers can reassign.
 	if claims.RoleID == s.cfg.Roles.SalesAgentID && task.AssignedTo != claims.UserID {
-		s.logger.Warn("Sales agent tried to reassign task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo)
-		return errors.New("sales agents cannot reassign tasks")
+		s.logger.WarnContext(ctx, "Sales agent tried to reassign task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo)
+		return Forbidden("sales agents cannot reassign tasks")
+	}
+	if claims.RoleID == s.cfg.Roles.SalesAgentID && !equalStringPtr(task.TaskType, existingTask.TaskType) {
+		s.logger.WarnContext(ctx, "Sales agent tried to change task type", "user_id", claims.UserID, "task_id", task.ID)
+		return Forbidden("sales agents cannot change the task type")
+	}
+	if claims.RoleID == s.cfg.Roles.SalesAgentID && (!equalIntPtr(task.LeadID, existingTask.LeadID) || !equalIntPtr(task.DealID, existingTask.DealID)) {
+		s.logger.WarnContext(ctx, "Sales agent tried to move task to another lead or deal", "user_id", claims.UserID, "task_id", task.ID)
+		return Forbidden("sales agents cannot move tasks to another lead or deal")
+	}
+
+	// The new rule is checked before anything is saved.
+	var rule string
+	if scope == EditFuture {
+		if existingTask.SeriesID == nil {
+			return Invalid("task %d is not a recurring task", task.ID)
+		}
+		if rec != nil {
+			if rule, err = parseRRule(rec.RRule); err != nil {
+				return err
+			}
+			if rec.UntilDealClosed && task.DealID == nil {
+				return Invalid("until_deal_closed requires the task to be linked to a deal")
+			}
+		}
+	} else if rec != nil {
+		return Invalid("recurrence can only be changed with scope=future")
+	}
+
+	task.CreatedBy = existingTask.CreatedBy
+
+	// An overdue task that is given a new due date is pending again.
+	if task.Status == taskStatusOverdue && task.DueDate.After(time.Now()) {
+		task.Status = taskStatusPending
+	}
+
+	// Completing a task requires its required checklist items to be done. The
+	// check and the update share a transaction so that an item reopened in
+	// between cannot slip through.
+	completing := existingTask.Status != taskStatusCompleted && task.Status == taskStatusCompleted
+	version := task.Version
+	var next *models.Task
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		task.Version, next = version, nil // UpdateTask advances the version; reset it if the transaction is retried
+		if completing {
+			open, err := s.checklist.CountOpenRequired(ctx, task.ID)
+			if err != nil {
+				return err
+			}
+			if open > 0 {
+				return Conflict("task has %s not done", pluralize(open, "required checklist item"))
+			}
+		}
+		if err := s.taskRepo.UpdateTask(ctx, task); err != nil {
+			return err
+		}
+		// Completing an occurrence of a recurring task schedules the next one.
+		if existingTask.SeriesID != nil && completing {
+			var err error
+			if next, err = s.scheduleNextOccurrence(ctx, existingTask); err != nil {
+				return err
+			}
+		}
+		if scope == EditFuture {
+			return s.updateFutureOccurrences(ctx, task.ID, rule, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.UpdatedStored(ctx, AuditEntityTask, task.ID, existingTask, func() (interface{}, error) {
+		return s.taskRepo.GetTaskByID(ctx, task.ID)
+	})
+	s.occurrenceScheduled(ctx, next)
+	if scope == EditFuture {
+		s.logger.InfoContext(ctx, "updated future task occurrences", "series_id", *existingTask.SeriesID, "task_id", task.ID)
+	}
+	return nil
+}
+
+// PatchTask applies a JSON merge patch (RFC 7396) to a task and returns the
+// updated task. Sales agents can patch the tasks assigned to them but cannot
+// change the assignee or what the task is linked to. scope is as for
+// UpdateTask.
+func (s *TaskService) PatchTask(ctx context.Context, id int, patch []byte, expectedVersion int, scope EditScope) (*models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.PatchTask")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	existingTask, err := s.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.taskRepo.UpdateTask(task)
+	// --- PERMISSION CHECK ---
+	allowed := taskPatchFields
+	if claims.RoleID == s.cfg.Roles.SalesAgentID {
+		allowed = taskPatchFieldsSalesAgent
+	}
+
+	var task models.Task
+	if err := applyMergePatch(existingTask, patch, taskPatchFields, allowed, &task); err != nil {
+		s.logger.WarnContext(ctx, "Rejected task patch", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", id, "error", err)
+		return nil, err
+	}
+	task.ID = id
+	task.Version = patchVersion(existingTask.Version, expectedVersion)
+
+	if err := s.UpdateTask(ctx, &task, scope, nil); err != nil {
+		return nil, err
+	}
+	return s.GetTaskByID(ctx, id)
 }
 
-// DeleteTask soft deletes a task with permission check
-func (s *TaskService) DeleteTask(ctx context.Context, id int) error {
+// DeleteTask soft deletes a task with permission check. Deleting an open
+// occurrence of a recurring task with EditThis skips it, scheduling the next
+// one; with EditFuture the series ends instead.
+func (s *TaskService) DeleteTask(ctx context.Context, id int, expectedVersion int, scope EditScope) error {
+	ctx, span := tracing.Start(ctx, "TaskService.DeleteTask")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return errors.New("could not retrieve user claims from context")
 	}
 
 	if id <= 0 {
-		return errors.New("invalid task ID")
+		return Invalid("invalid task ID")
 	}
 
-	existingTask, err := s.taskRepo.GetTaskByID(id)
+	existingTask, err := s.taskRepo.GetTaskByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing task for deletion", "task_id", id, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to fetch existing task for deletion", "task_id", id, "error", err)
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("task with ID %d not found", id)
+			return NotFound("task with ID %d not found", id)
 		}
 		return err
 	}
 	if existingTask == nil {
-		s.logger.Warn("Existing task not found", "task_id", id)
-		return fmt.Errorf("task with ID %d not found", id)
+		s.logger.WarnContext(ctx, "Existing task not found", "task_id", id)
+		return NotFound("task with ID %d not found", id)
 	}
 
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can delete tasks.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for DeleteTask", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", id)
-		return fmt.Errorf("forbidden: only managers can delete tasks")
+		s.logger.WarnContext(ctx, "Permission denied for DeleteTask", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", id)
+		return Forbidden("only managers can delete tasks")
 	}
 
-	return s.taskRepo.DeleteTask(id)
+	var next *models.Task
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		next = nil
+		if err := s.taskRepo.DeleteTask(ctx, id, expectedVersion); err != nil {
+			return err
+		}
+		if existingTask.SeriesID == nil {
+			return nil
+		}
+		if scope == EditFuture {
+			return s.endSeries(ctx, *existingTask.SeriesID)
+		}
+		if existingTask.Status != taskStatusCompleted {
+			var err error
+			next, err = s.scheduleNextOccurrence(ctx, existingTask)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.audit.Deleted(ctx, AuditEntityTask, id, existingTask)
+	s.occurrenceScheduled(ctx, next)
+	return nil
 }
 
 // GetTasksForUser retrieves tasks for a specific user (used internally or by manager)
 func (s *TaskService) GetTasksForUser(ctx context.Context, userID int) ([]models.Task, error) {
-	return s.taskRepo.GetTasksForUser(userID)
+	ctx, span := tracing.Start(ctx, "TaskService.GetTasksForUser")
+	defer span.End()
+
+	return s.taskRepo.GetTasksForUser(ctx, userID)
 }
 
-// CreateDealTask creates a new task for a deal (nested route) with role-based assignment
-func (s *TaskService) CreateDealTask(ctx context.Context, task *models.Task) (int, error) {
+// GetTasksForParent retrieves the tasks filed under a deal or lead. Sales
+// agents only see the tasks assigned to them.
+func (s *TaskService) GetTasksForParent(ctx context.Context, parent ActivityParent) ([]models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTasksForParent")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	if err := parent.validate(); err != nil {
+		return nil, err
+	}
+
+	// If sales agent, filter by assigned_to. If manager, get all for the parent.
+	if claims.RoleID == s.cfg.Roles.SalesAgentID {
+		if parent.Kind == ParentLead {
+			return s.taskRepo.GetTasksByLeadIDForUser(ctx, parent.ID, claims.UserID)
+		}
+		return s.taskRepo.GetTasksByDealIDForUser(ctx, parent.ID, claims.UserID)
+	}
+	if parent.Kind == ParentLead {
+		return s.taskRepo.GetTasksByLeadID(ctx, parent.ID)
+	}
+	return s.taskRepo.GetTasksByDealID(ctx, parent.ID)
+}
+
+// GetTaskForParent retrieves a task with permission check, reporting it as not
+// found unless it is filed under parent
+func (s *TaskService) GetTaskForParent(ctx context.Context, parent ActivityParent, id int) (*models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTaskForParent")
+	defer span.End()
+
+	task, err := s.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.Owns(task.DealID, task.LeadID) {
+		return nil, NotFound("task not found for this %s", parent.Kind)
+	}
+	return task, nil
+}
+
+// CreateTaskForParent creates a task filed under a deal or lead. Unlike
+// CreateTask, sales agents may create these, but only for themselves.
+func (s *TaskService) CreateTaskForParent(ctx context.Context, parent ActivityParent, task *models.Task, rec *models.Recurrence) (int, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.CreateTaskForParent")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return 0, errors.New("could not retrieve user claims from context")
 	}
 
-	if task.TaskName == "" {
-		return 0, errors.New("task name cannot be empty")
+	if err := parent.validate(); err != nil {
+		return 0, err
 	}
-	if task.DealID == nil || *task.DealID <= 0 {
-		return 0, errors.New("deal ID is required")
+	if task.TaskName == "" {
+		return 0, Invalid("task name cannot be empty")
 	}
 	if task.DueDate.IsZero() {
-		return 0, errors.New("due date is required")
+		return 0, Invalid("due date is required")
 	}
 	if task.Status == "" {
-		task.Status = "Pending"
+		task.Status = taskStatusPending
 	}
+	task.DealID, task.LeadID = parent.Links()
 
 	// Set the creator of the task to the currently logged-in user's ID.
 	task.CreatedBy = claims.UserID
@@ -255,102 +464,290 @@ func (s *TaskService) CreateDealTask(ctx context.Context, task *models.Task) (in
 	// If manager, they can assign to anyone.
 	// If sales agent, they can only assign to themselves.
 	if claims.RoleID == s.cfg.Roles.SalesAgentID && task.AssignedTo != claims.UserID {
-		s.logger.Warn("Sales agent tried to assign deal task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo)
-		return 0, errors.New("sales agents can only assign deal tasks to themselves")
+		s.logger.WarnContext(ctx, "Sales agent tried to assign task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo, "parent", parent)
+		return 0, Forbidden("sales agents can only assign %s tasks to themselves", parent.Kind)
 	}
 	if task.AssignedTo == 0 { // If not explicitly assigned, assign to creator
 		task.AssignedTo = claims.UserID
 	}
 
-	err := s.taskRepo.CreateTask(task)
+	err := s.insertTask(ctx, task, rec)
 	if err != nil {
-		s.logger.Error("Failed to create deal task in repository", "error", err)
-		return 0, fmt.Errorf("failed to create deal task: %w", err)
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			return 0, err
+		}
+		s.logger.ErrorContext(ctx, "Failed to create task in repository", "parent", parent, "error", err)
+		return 0, fmt.Errorf("failed to create %s task: %w", parent.Kind, err)
 	}
+	s.audit.Created(ctx, AuditEntityTask, task.ID, task)
 	return task.ID, nil
 }
 
-// UpdateDealTask updates a task for a deal with permission check
-func (s *TaskService) UpdateDealTask(ctx context.Context, task *models.Task) error {
-	claims, ok := util.GetClaimsFromContext(ctx)
-	if !ok {
-		return errors.New("could not retrieve user claims from context")
-	}
+// UpdateTaskForParent updates a task filed under a deal or lead. The task
+// stays linked to the records it was linked to before. scope and rec are as
+// for UpdateTask.
+func (s *TaskService) UpdateTaskForParent(ctx context.Context, parent ActivityParent, task *models.Task, scope EditScope, rec *models.Recurrence) error {
+	ctx, span := tracing.Start(ctx, "TaskService.UpdateTaskForParent")
+	defer span.End()
 
-	if task.ID <= 0 {
-		return errors.New("invalid task ID")
+	existingTask, err := s.GetTaskForParent(ctx, parent, task.ID)
+	if err != nil {
+		return err
 	}
-	if task.TaskName == "" {
-		return errors.New("task name cannot be empty")
+	task.DealID, task.LeadID = existingTask.DealID, existingTask.LeadID
+	return s.UpdateTask(ctx, task, scope, rec)
+}
+
+// DeleteTaskForParent deletes a task filed under a deal or lead. As with
+// DeleteTask, only managers can delete tasks.
+func (s *TaskService) DeleteTaskForParent(ctx context.Context, parent ActivityParent, id int, expectedVersion int, scope EditScope) error {
+	ctx, span := tracing.Start(ctx, "TaskService.DeleteTaskForParent")
+	defer span.End()
+
+	if _, err := s.GetTaskForParent(ctx, parent, id); err != nil {
+		return err
 	}
+	return s.DeleteTask(ctx, id, expectedVersion, scope)
+}
+
+// Task statuses the service acts on.
+const (
+	taskStatusPending   = "Pending"
+	taskStatusOverdue   = "Overdue" // Set by ReminderService once the due date has passed
+	taskStatusCompleted = "Completed"
+)
+
+// maxTaskTypeLength is the size of the task_type column.
+const maxTaskTypeLength = 50
 
-	existingTask, err := s.taskRepo.GetTaskByID(task.ID)
+// insertTask creates a task, or with rec a series whose first occurrence it is.
+func (s *TaskService) insertTask(ctx context.Context, task *models.Task, rec *models.Recurrence) error {
+	taskType, err := normalizeTaskType(task.TaskType)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing deal task for update", "task_id", task.ID, "error", err)
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("deal task with ID %d not found", task.ID)
-		}
 		return err
 	}
-	if existingTask == nil {
-		s.logger.Warn("Existing deal task not found", "task_id", task.ID)
-		return fmt.Errorf("deal task with ID %d not found", task.ID)
+	task.TaskType = taskType
+	if rec == nil {
+		return s.taskRepo.CreateTask(ctx, task)
 	}
-
-	if existingTask.DealID == nil || *existingTask.DealID != *task.DealID {
-		return errors.New("task not found for this deal")
+	rule, err := parseRRule(rec.RRule)
+	if err != nil {
+		return err
+	}
+	if rec.UntilDealClosed && task.DealID == nil {
+		return Invalid("until_deal_closed requires the task to be linked to a deal")
+	}
+	ts := &models.TaskSeries{
+		RRule:           rule,
+		DTStart:         task.DueDate,
+		StartOccurrence: 1,
+		UntilDealClosed: rec.UntilDealClosed,
+		CreatedBy:       task.CreatedBy,
 	}
+	copyTaskTemplate(ts, task)
 
-	// --- PERMISSION CHECK ---
-	// A user can update if they are a Receptionist OR if they are the assigned sales agent.
-	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (existingTask.AssignedTo == claims.UserID)
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.series.Create(ctx, ts); err != nil {
+			return err
+		}
+		first := 1
+		task.SeriesID, task.Occurrence = &ts.ID, &first
+		_, err := s.series.CreateOccurrence(ctx, task)
+		return err
+	})
+}
 
-	if !isAllowed {
-		s.logger.Warn("Permission denied for UpdateDealTask", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", task.ID, "task_assigned_to", existingTask.AssignedTo)
-		return fmt.Errorf("forbidden: you do not have permission to update this deal task")
+// normalizeTaskType trims a task type, treating a blank one as no type.
+func normalizeTaskType(taskType *string) (*string, error) {
+	if taskType == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*taskType)
+	if trimmed == "" {
+		return nil, nil
 	}
+	if len(trimmed) > maxTaskTypeLength {
+		return nil, InvalidField("task_type", fmt.Sprintf("must be at most %d characters", maxTaskTypeLength))
+	}
+	return &trimmed, nil
+}
 
-	// Sales agents can only update tasks assigned to them. Managers can reassign.
-	if claims.RoleID == s.cfg.Roles.SalesAgentID && task.AssignedTo != claims.UserID {
-		s.logger.Warn("Sales agent tried to reassign deal task to another user", "user_id", claims.UserID, "assigned_to", task.AssignedTo)
-		return errors.New("sales agents cannot reassign deal tasks")
+// equalStringPtr reports whether a and b are both nil or point to equal strings.
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return *a == *b
+}
 
-	return s.taskRepo.UpdateTask(task)
+// equalIntPtr reports whether a and b are both nil or point to equal ints.
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
-// DeleteDealTask deletes a task for a deal with permission check
-func (s *TaskService) DeleteDealTask(ctx context.Context, id int) error {
-	claims, ok := util.GetClaimsFromContext(ctx)
-	if !ok {
-		return errors.New("could not retrieve user claims from context")
+// copyTaskTemplate makes task the template for the series' next occurrences.
+func copyTaskTemplate(ts *models.TaskSeries, task *models.Task) {
+	ts.TaskName = task.TaskName
+	ts.TaskDescription = task.TaskDescription
+	ts.AssignedTo = task.AssignedTo
+	ts.LeadID = task.LeadID
+	ts.DealID = task.DealID
+	ts.TaskType = task.TaskType
+}
+
+// scheduleNextOccurrence creates the occurrence after done, or ends the series
+// if its rule or deal says there are no more, and returns the created task,
+// if any. Callers run it in the transaction that completes or deletes done,
+// so that the two changes are made together, and audit the created task once
+// that transaction commits.
+func (s *TaskService) scheduleNextOccurrence(ctx context.Context, done *models.Task) (*models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.scheduleNextOccurrence")
+	defer span.End()
+
+	var next *models.Task
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		next = nil
+		ts, err := s.series.GetByID(ctx, *done.SeriesID)
+		if err != nil || ts == nil || ts.EndedAt != nil {
+			return err
+		}
+		if ts.UntilDealClosed && ts.DealID != nil {
+			deal, err := s.deals.GetByID(ctx, *ts.DealID)
+			if err != nil {
+				return err
+			}
+			if deal == nil || isDealClosed(deal) {
+				s.logger.InfoContext(ctx, "deal closed, ending task series", "series_id", ts.ID, "deal_id", *ts.DealID)
+				return s.series.End(ctx, ts.ID)
+			}
+		}
+
+		occurrence := *done.Occurrence + 1
+		due, ok, err := occurrenceDue(ts, occurrence)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.logger.InfoContext(ctx, "task series finished", "series_id", ts.ID, "occurrences", *done.Occurrence)
+			return s.series.End(ctx, ts.ID)
+		}
+		task := &models.Task{
+			TaskName:        ts.TaskName,
+			TaskDescription: ts.TaskDescription,
+			DueDate:         due,
+			Status:          taskStatusPending,
+			AssignedTo:      ts.AssignedTo,
+			LeadID:          ts.LeadID,
+			DealID:          ts.DealID,
+			TaskType:        ts.TaskType,
+			CreatedBy:       ts.CreatedBy,
+			SeriesID:        &ts.ID,
+			Occurrence:      &occurrence,
+		}
+		created, err := s.series.CreateOccurrence(ctx, task)
+		if created {
+			next = task
+		}
+		return err
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to schedule next task occurrence", "task_id", done.ID, "series_id", *done.SeriesID, "error", err)
+		return nil, err
 	}
+	return next, nil
+}
 
-	if id <= 0 {
-		return errors.New("invalid task ID")
+// occurrenceScheduled logs and audits an occurrence created by
+// scheduleNextOccurrence. A nil next does nothing.
+func (s *TaskService) occurrenceScheduled(ctx context.Context, next *models.Task) {
+	if next == nil {
+		return
 	}
+	s.logger.InfoContext(ctx, "scheduled next task occurrence", "task_id", next.ID, "series_id", *next.SeriesID, "occurrence", *next.Occurrence, "due_date", next.DueDate)
+	s.audit.Created(ctx, AuditEntityTask, next.ID, next)
+}
 
-	existingTask, err := s.taskRepo.GetTaskByID(id)
+// updateFutureOccurrences applies an edit made to a task with EditFuture: the
+// task, as saved in the caller's transaction, becomes the template for the
+// occurrences after it, and the rule restarts from its due date. With rec the
+// series switches to rule, already checked by parseRRule, whose COUNT then
+// counts from this task; otherwise a COUNT in the current rule is reduced by
+// the occurrences already past.
+func (s *TaskService) updateFutureOccurrences(ctx context.Context, taskID int, rule string, rec *models.Recurrence) error {
+	ctx, span := tracing.Start(ctx, "TaskService.updateFutureOccurrences")
+	defer span.End()
+
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
 	if err != nil {
-		s.logger.Error("Failed to fetch existing deal task for deletion", "task_id", id, "error", err)
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("deal task with ID %d not found", id)
-		}
 		return err
 	}
-	if existingTask == nil {
-		s.logger.Warn("Existing deal task not found", "task_id", id)
-		return fmt.Errorf("deal task with ID %d not found", id)
+	ts, err := s.series.GetByID(ctx, *task.SeriesID)
+	if err != nil {
+		return err
+	}
+	if ts == nil {
+		return NotFound("task series %d not found", *task.SeriesID)
+	}
+	if rec != nil {
+		ts.RRule, ts.UntilDealClosed = rule, rec.UntilDealClosed
+	} else {
+		rule, err := rebaseRRule(ts.RRule, *task.Occurrence-ts.StartOccurrence)
+		if err != nil {
+			return err
+		}
+		ts.RRule = rule
 	}
+	ts.DTStart = task.DueDate
+	ts.StartOccurrence = *task.Occurrence
+	copyTaskTemplate(ts, task)
+	return s.series.Update(ctx, ts)
+}
 
-	// --- PERMISSION CHECK ---
-	// A user can delete if they are a Receptionist OR if they are the assigned sales agent.
-	isAllowed := claims.RoleID == s.cfg.Roles.ReceptionID || (existingTask.AssignedTo == claims.UserID)
+// GetTaskSeries returns a series. Managers can see every series, sales agents
+// the ones assigned to them.
+func (s *TaskService) GetTaskSeries(ctx context.Context, id int) (*models.TaskSeries, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTaskSeries")
+	defer span.End()
 
-	if !isAllowed {
-		s.logger.Warn("Permission denied for DeleteDealTask", "user_id", claims.UserID, "role_id", claims.RoleID, "task_id", id, "task_assigned_to", existingTask.AssignedTo)
-		return fmt.Errorf("forbidden: you do not have permission to delete this deal task")
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+	ts, err := s.series.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	if ts == nil {
+		return nil, NotFound("task series %d not found", id)
+	}
+	if claims.RoleID != s.cfg.Roles.ReceptionID && ts.AssignedTo != claims.UserID {
+		s.logger.WarnContext(ctx, "Permission denied for GetTaskSeries", "user_id", claims.UserID, "role_id", claims.RoleID, "series_id", id)
+		return nil, Forbidden("you do not have permission to view this task series")
+	}
+	return ts, nil
+}
+
+// EndTaskSeries stops a series from creating further occurrences. Existing
+// tasks, including the open one, are left as they are.
+func (s *TaskService) EndTaskSeries(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "TaskService.EndTaskSeries")
+	defer span.End()
+
+	if _, err := s.GetTaskSeries(ctx, id); err != nil {
+		return err
+	}
+	return s.endSeries(ctx, id)
+}
 
-	return s.taskRepo.DeleteTask(id)
+func (s *TaskService) endSeries(ctx context.Context, id int) error {
+	if err := s.series.End(ctx, id); err != nil {
+		s.logger.ErrorContext(ctx, "failed to end task series", "series_id", id, "error", err)
+		return err
+	}
+	s.logger.InfoContext(ctx, "task series ended", "series_id", id)
+	return nil
 }
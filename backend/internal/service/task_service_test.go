@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"crm-project/internal/config"
+	"crm-project/internal/dto"
+	"crm-project/internal/models"
+	"crm-project/internal/repository/memory"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/util"
+)
+
+const (
+	testSalesAgentRole = 1
+	testReceptionRole  = 2
+)
+
+// taskFixture is a TaskService backed by the in-memory repositories.
+type taskFixture struct {
+	svc       *TaskService
+	tasks     *memory.TaskRepo
+	checklist *memory.TaskChecklistRepo
+	manager   context.Context
+}
+
+func newTaskFixture(t *testing.T) *taskFixture {
+	t.Helper()
+	s := memory.NewStore()
+	cfg := &config.Config{}
+	cfg.Roles.SalesAgentID = testSalesAgentRole
+	cfg.Roles.ReceptionID = testReceptionRole
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	f := &taskFixture{
+		tasks:     memory.NewTaskRepository(s),
+		checklist: memory.NewTaskChecklistRepo(s),
+		manager:   util.AddClaimsToContext(context.Background(), &dto.Claims{UserID: 1, RoleID: testReceptionRole}),
+	}
+	audit := NewAuditService(memory.NewAuditRepo(s), cfg, logger)
+	f.svc = NewTaskService(f.tasks, memory.NewTaskSeriesRepo(s), f.checklist, memory.NewDealRepo(s), memory.NewTxManager(s), audit, cfg, logger)
+	return f
+}
+
+// createTask creates a pending task, recurring daily if rrule is not empty.
+func (f *taskFixture) createTask(t *testing.T, rrule string) *models.Task {
+	t.Helper()
+	task := &models.Task{TaskName: "Follow up", DueDate: time.Now().Add(time.Hour), AssignedTo: 1}
+	var rec *models.Recurrence
+	if rrule != "" {
+		rec = &models.Recurrence{RRule: rrule}
+	}
+	if _, err := f.svc.CreateTask(f.manager, task, rec); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	return task
+}
+
+func (f *taskFixture) complete(task *models.Task) error {
+	done := *task
+	done.Status = taskStatusCompleted
+	return f.svc.UpdateTask(f.manager, &done, EditThis, nil)
+}
+
+func (f *taskFixture) liveTasks(t *testing.T) []models.Task {
+	t.Helper()
+	all, err := f.tasks.GetAllTasks(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllTasks: %v", err)
+	}
+	return all
+}
+
+func TestUpdateTaskRequiresChecklist(t *testing.T) {
+	f := newTaskFixture(t)
+	task := f.createTask(t, "")
+	item := &models.ChecklistItem{TaskID: task.ID, Title: "Send contract", Required: true, CreatedBy: 1}
+	if err := f.checklist.Create(context.Background(), item); err != nil {
+		t.Fatalf("Create checklist item: %v", err)
+	}
+
+	var conflict *ConflictError
+	if err := f.complete(task); !errors.As(err, &conflict) {
+		t.Fatalf("completing with an open required item: got %v, want a ConflictError", err)
+	}
+	got, _ := f.tasks.GetTaskByID(context.Background(), task.ID)
+	if got.Status != taskStatusPending || got.Version != task.Version {
+		t.Errorf("task is %s at version %d, want it unchanged", got.Status, got.Version)
+	}
+
+	now := time.Now()
+	item.CompletedAt = &now
+	if err := f.checklist.Update(context.Background(), item); err != nil {
+		t.Fatalf("Update checklist item: %v", err)
+	}
+	if err := f.complete(task); err != nil {
+		t.Fatalf("completing with the checklist done: %v", err)
+	}
+}
+
+func TestUpdateTaskSchedulesNextOccurrence(t *testing.T) {
+	f := newTaskFixture(t)
+	task := f.createTask(t, "FREQ=DAILY;COUNT=3")
+
+	if err := f.complete(task); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	live := f.liveTasks(t)
+	if len(live) != 2 {
+		t.Fatalf("there are %d tasks, want the completed one and the next occurrence", len(live))
+	}
+	next := live[1]
+	if next.Status != taskStatusPending || next.Occurrence == nil || *next.Occurrence != 2 {
+		t.Errorf("next occurrence is %s, occurrence %v; want pending occurrence 2", next.Status, next.Occurrence)
+	}
+}
+
+// failingSeriesRepo fails to create occurrences.
+type failingSeriesRepo struct {
+	postgres.TaskSeriesRepository
+}
+
+var errCreateOccurrence = errors.New("create occurrence failed")
+
+func (r failingSeriesRepo) CreateOccurrence(ctx context.Context, task *models.Task) (bool, error) {
+	return false, errCreateOccurrence
+}
+
+func TestUpdateTaskRollsBackWhenSchedulingFails(t *testing.T) {
+	f := newTaskFixture(t)
+	task := f.createTask(t, "FREQ=DAILY;COUNT=3")
+	f.svc.series = failingSeriesRepo{f.svc.series}
+
+	if err := f.complete(task); !errors.Is(err, errCreateOccurrence) {
+		t.Fatalf("UpdateTask: got %v, want the scheduling error", err)
+	}
+	live := f.liveTasks(t)
+	if len(live) != 1 || live[0].Status != taskStatusPending || live[0].Version != task.Version {
+		t.Errorf("after a failed scheduling the tasks are %+v, want the task unchanged", live)
+	}
+}
+
+func TestUpdateTaskFutureRejectsBadRuleFirst(t *testing.T) {
+	f := newTaskFixture(t)
+	task := f.createTask(t, "FREQ=DAILY;COUNT=3")
+
+	edit := *task
+	edit.TaskName = "Renamed"
+	var validation *ValidationError
+	err := f.svc.UpdateTask(f.manager, &edit, EditFuture, &models.Recurrence{RRule: "FREQ=HOURLY"})
+	if !errors.As(err, &validation) {
+		t.Fatalf("UpdateTask with a bad rule: got %v, want a ValidationError", err)
+	}
+	got, _ := f.tasks.GetTaskByID(context.Background(), task.ID)
+	if got.TaskName != task.TaskName || got.Version != task.Version {
+		t.Errorf("task is %q at version %d, want it unchanged", got.TaskName, got.Version)
+	}
+}
+
+func TestUpdateTaskFutureUpdatesSeries(t *testing.T) {
+	f := newTaskFixture(t)
+	task := f.createTask(t, "FREQ=DAILY;COUNT=3")
+
+	edit := *task
+	edit.TaskName = "Renamed"
+	if err := f.svc.UpdateTask(f.manager, &edit, EditFuture, &models.Recurrence{RRule: "FREQ=WEEKLY;COUNT=2"}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	ts, err := f.svc.series.GetByID(context.Background(), *task.SeriesID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if ts.TaskName != "Renamed" || ts.RRule != "FREQ=WEEKLY;COUNT=2" {
+		t.Errorf("series is %q with rule %q, want the edited task and the new rule", ts.TaskName, ts.RRule)
+	}
+}
+
+func TestUpdateTaskSalesAgentCannotMoveTask(t *testing.T) {
+	f := newTaskFixture(t)
+	task := f.createTask(t, "")
+	agent := util.AddClaimsToContext(context.Background(), &dto.Claims{UserID: 1, RoleID: testSalesAgentRole})
+
+	moved := *task
+	leadID := 7
+	moved.LeadID = &leadID
+	var forbidden *ForbiddenError
+	if err := f.svc.UpdateTask(agent, &moved, EditThis, nil); !errors.As(err, &forbidden) {
+		t.Fatalf("sales agent moving a task to a lead: got %v, want a ForbiddenError", err)
+	}
+
+	renamed := *task
+	renamed.TaskName = "Renamed"
+	if err := f.svc.UpdateTask(agent, &renamed, EditThis, nil); err != nil {
+		t.Fatalf("sales agent renaming their task: %v", err)
+	}
+}
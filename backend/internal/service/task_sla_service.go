@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"crm-project/internal/config"
+	"crm-project/internal/models"
+	"crm-project/internal/notify"
+	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// errSLATaskChanged rolls back an escalation whose task was completed or
+// reassigned after it was loaded. The next run looks at the task again.
+var errSLATaskChanged = errors.New("task changed during SLA escalation")
+
+// TaskSLAService holds tasks to the SLA of their type (sla.types). A task
+// still open when its SLA runs out is recorded as a breach against its
+// assignee and escalated to its creator: the creator is told, and with the
+// reassign escalation the task is also assigned to them.
+type TaskSLAService struct {
+	repo     postgres.TaskSLARepository
+	notifier notifier
+	tx       postgres.Transactor
+	audit    *AuditService
+	cfg      *config.Config
+	logger   *slog.Logger
+	loc      *time.Location // Time zone of times in messages
+}
+
+func NewTaskSLAService(repo postgres.TaskSLARepository, notifications postgres.NotificationRepository, users postgres.UserRepository, email notify.EmailSender, tx postgres.Transactor, audit *AuditService, cfg *config.Config, logger *slog.Logger) *TaskSLAService {
+	return &TaskSLAService{
+		repo:     repo,
+		notifier: notifier{notifications: notifications, users: users, email: email, logger: logger},
+		tx:       tx,
+		audit:    audit,
+		cfg:      cfg,
+		logger:   logger,
+		loc:      cfg.ReminderLocation(),
+	}
+}
+
+// Run checks open tasks against their SLA every sla.interval until ctx is
+// cancelled.
+func (s *TaskSLAService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.SLA.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.ErrorContext(ctx, "task SLA run failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce escalates the tasks whose SLA has run out by now. A task is
+// escalated once: the breach is recorded in the same transaction as the
+// reassignment, and only the run that records it sends the notifications.
+func (s *TaskSLAService) RunOnce(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "TaskSLAService.RunOnce")
+	defer span.End()
+
+	var errs []error
+	for _, taskType := range slices.Sorted(maps.Keys(s.cfg.SLA.Types)) {
+		rule := s.cfg.SLA.Types[taskType]
+		tasks, err := s.repo.GetUnbreachedOpenTasks(ctx, taskType, now.Add(-rule.Within))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("loading %s tasks past their SLA: %w", taskType, err))
+			continue
+		}
+		for _, t := range tasks {
+			if err := s.escalate(ctx, t, rule); err != nil {
+				errs = append(errs, fmt.Errorf("escalating task %d: %w", t.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// escalate records the breach of t and escalates it as rule says.
+func (s *TaskSLAService) escalate(ctx context.Context, t models.Task, rule config.SLARule) error {
+	breach := &models.TaskSLABreach{
+		TaskID:     t.ID,
+		TaskType:   *t.TaskType,
+		AssignedTo: t.AssignedTo,
+		Escalation: config.SLAEscalateNotify,
+		DueBy:      t.CreatedAt.Add(rule.Within),
+	}
+	// A task can only be handed back to a known creator who is not already
+	// working on it.
+	if rule.Escalate == config.SLAEscalateReassign && t.CreatedBy != 0 && t.CreatedBy != t.AssignedTo {
+		breach.Escalation = config.SLAEscalateReassign
+		breach.ReassignedTo = &t.CreatedBy
+	}
+
+	var created bool
+	var reassigned *models.Task
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.repo.RecordBreach(ctx, breach)
+		if err != nil || !created {
+			return err
+		}
+		if breach.ReassignedTo == nil {
+			return nil
+		}
+		reassigned, err = s.repo.Reassign(ctx, t.ID, t.AssignedTo, t.CreatedBy)
+		if err != nil {
+			return err
+		}
+		if reassigned == nil {
+			return errSLATaskChanged
+		}
+		return nil
+	})
+	if errors.Is(err, errSLATaskChanged) {
+		s.logger.InfoContext(ctx, "task changed before SLA escalation, retrying next run", "task_id", t.ID)
+		return nil
+	}
+	if err != nil || !created {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "task SLA breached", "task_id", t.ID, "task_type", breach.TaskType, "assigned_to", t.AssignedTo, "escalation", breach.Escalation, "due_by", breach.DueBy)
+	if reassigned != nil {
+		s.audit.Updated(ctx, AuditEntityTask, t.ID, &t, reassigned)
+	}
+	if t.CreatedBy == 0 {
+		s.logger.WarnContext(ctx, "task has no creator to escalate its SLA breach to", "task_id", t.ID)
+		return nil
+	}
+
+	escalation := fmt.Sprintf("It is assigned to %s.", s.userName(ctx, t.AssignedTo))
+	if reassigned != nil {
+		escalation = fmt.Sprintf("It has been taken from %s and assigned to you.", s.userName(ctx, t.AssignedTo))
+	}
+	body := fmt.Sprintf("%q (%s, SLA %s) was created %s and is still open; its SLA ran out %s. %s",
+		t.TaskName, breach.TaskType, formatSLA(rule.Within), s.formatTime(t.CreatedAt), s.formatTime(breach.DueBy), escalation)
+	errs := []error{s.notifier.notify(ctx, &models.Notification{
+		UserID:   t.CreatedBy,
+		Kind:     models.NotificationTaskSLABreach,
+		Title:    "SLA breached: " + t.TaskName,
+		Body:     body,
+		TaskID:   &t.ID,
+		DedupKey: dedupKey("task:%d:sla_breach", t.ID),
+	})}
+	if reassigned != nil {
+		errs = append(errs, s.notifier.notify(ctx, &models.Notification{
+			UserID:   t.AssignedTo,
+			Kind:     models.NotificationTaskReassigned,
+			Title:    "Task reassigned: " + t.TaskName,
+			Body:     fmt.Sprintf("%q was not completed within its %s SLA and has been assigned to %s.", t.TaskName, formatSLA(rule.Within), s.userName(ctx, t.CreatedBy)),
+			TaskID:   &t.ID,
+			DedupKey: dedupKey("task:%d:sla_reassigned", t.ID),
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// userName returns the username of a user for a message, falling back to
+// the ID if the user cannot be looked up.
+func (s *TaskSLAService) userName(ctx context.Context, id int) string {
+	user, err := s.notifier.users.GetByID(ctx, id)
+	if err != nil || user == nil {
+		return fmt.Sprintf("user %d", id)
+	}
+	return user.Username
+}
+
+// formatTime shows t in the reminder time zone.
+func (s *TaskSLAService) formatTime(t time.Time) string {
+	return t.In(s.loc).Format(reminderDateLayout + " " + reminderTimeLayout + " MST")
+}
+
+// formatSLA shows an SLA without zero minutes and seconds, e.g. "4h" rather
+// than "4h0m0s".
+func formatSLA(d time.Duration) string {
+	str := d.String()
+	if strings.HasSuffix(str, "m0s") {
+		str = strings.TrimSuffix(str, "0s")
+	}
+	if strings.HasSuffix(str, "h0m") {
+		str = strings.TrimSuffix(str, "0m")
+	}
+	return str
+}
@@ -6,6 +6,7 @@ import (
 	"crm-project/internal/dto"
 	"crm-project/internal/models"
 	"crm-project/internal/repository/postgres"
+	"crm-project/internal/tracing"
 	"crm-project/internal/util"
 	"database/sql"
 	"errors"
@@ -16,16 +17,19 @@ import (
 )
 
 type UserService struct {
-	repo   *postgres.UserRepo
+	repo   postgres.UserRepository
 	cfg    *config.Config // Add config here
 	logger *slog.Logger
 }
 
-func NewUserService(repo *postgres.UserRepo, cfg *config.Config, logger *slog.Logger) *UserService {
+func NewUserService(repo postgres.UserRepository, cfg *config.Config, logger *slog.Logger) *UserService {
 	return &UserService{repo: repo, cfg: cfg, logger: logger}
 }
 
 func (s *UserService) CreateUser(ctx context.Context, req dto.CreateUserRequest) (int, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return 0, errors.New("could not retrieve user claims from context")
@@ -34,35 +38,35 @@ func (s *UserService) CreateUser(ctx context.Context, req dto.CreateUserRequest)
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can create users via this method.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for CreateUser", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return 0, fmt.Errorf("forbidden: only managers can create users")
+		s.logger.WarnContext(ctx, "Permission denied for CreateUser", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return 0, Forbidden("only managers can create users")
 	}
 
-	if err := util.ValidateStruct(req); err != nil {
+	if err := validateStruct(req); err != nil {
 		return 0, err
 	}
 
 	// Check if username or email already exists
 	existingUser, err := s.repo.GetByUsername(ctx, req.Username)
 	if err != nil && err != sql.ErrNoRows {
-		s.logger.Error("database error checking for existing user by username", "error", err, "username", req.Username)
+		s.logger.ErrorContext(ctx, "database error checking for existing user by username", "error", err, "username", req.Username)
 		return 0, errors.New("could not verify user existence")
 	}
 	if existingUser != nil {
-		return 0, errors.New("username already taken")
+		return 0, Conflict("username already taken")
 	}
 	existingUser, err = s.repo.GetByEmail(ctx, req.Email)
 	if err != nil && err != sql.ErrNoRows {
-		s.logger.Error("database error checking for existing user by email", "error", err, "email", req.Email)
+		s.logger.ErrorContext(ctx, "database error checking for existing user by email", "error", err, "email", req.Email)
 		return 0, errors.New("could not verify user existence")
 	}
 	if existingUser != nil {
-		return 0, errors.New("email already registered")
+		return 0, Conflict("email already registered")
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		s.logger.Error("failed to hash password during user creation", "error", err)
+		s.logger.ErrorContext(ctx, "failed to hash password during user creation", "error", err)
 		return 0, fmt.Errorf("internal server error")
 	}
 
@@ -75,14 +79,17 @@ func (s *UserService) CreateUser(ctx context.Context, req dto.CreateUserRequest)
 
 	newUserID, err := s.repo.Create(ctx, user)
 	if err != nil {
-		s.logger.Error("failed to create user in database", "error", err)
+		s.logger.ErrorContext(ctx, "failed to create user in database", "error", err)
 		return 0, errors.New("failed to create user")
 	}
-	s.logger.Info("User created successfully by manager", "manager_id", claims.UserID, "new_user_id", newUserID, "new_user_role", req.RoleID)
+	s.logger.InfoContext(ctx, "User created successfully by manager", "manager_id", claims.UserID, "new_user_id", newUserID, "new_user_role", req.RoleID)
 	return newUserID, nil
 }
 
 func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetAllUsers")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -91,14 +98,17 @@ func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can view all users.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GetAllUsers", "user_id", claims.UserID, "role_id", claims.RoleID)
-		return nil, fmt.Errorf("forbidden: you do not have permission to view all users")
+		s.logger.WarnContext(ctx, "Permission denied for GetAllUsers", "user_id", claims.UserID, "role_id", claims.RoleID)
+		return nil, Forbidden("you do not have permission to view all users")
 	}
 
 	return s.repo.GetAll(ctx)
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return nil, errors.New("could not retrieve user claims from context")
@@ -107,8 +117,8 @@ func (s *UserService) GetUserByID(ctx context.Context, id int) (*models.User, er
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can view any user by ID.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for GetUserByID", "user_id", claims.UserID, "role_id", claims.RoleID, "requested_user_id", id)
-		return nil, fmt.Errorf("forbidden: you do not have permission to view this user")
+		s.logger.WarnContext(ctx, "Permission denied for GetUserByID", "user_id", claims.UserID, "role_id", claims.RoleID, "requested_user_id", id)
+		return nil, Forbidden("you do not have permission to view this user")
 	}
 
 	user, err := s.repo.GetByID(ctx, id)
@@ -116,12 +126,15 @@ func (s *UserService) GetUserByID(ctx context.Context, id int) (*models.User, er
 		return nil, err
 	}
 	if user == nil {
-		return nil, fmt.Errorf("user with ID %d not found", id)
+		return nil, NotFound("user with ID %d not found", id)
 	}
 	return user, nil
 }
 
 func (s *UserService) UpdateUser(ctx context.Context, id int, req dto.UpdateUserRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return errors.New("could not retrieve user claims from context")
@@ -130,32 +143,32 @@ func (s *UserService) UpdateUser(ctx context.Context, id int, req dto.UpdateUser
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can update users.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for UpdateUser", "user_id", claims.UserID, "role_id", claims.RoleID, "target_user_id", id)
-		return fmt.Errorf("forbidden: only managers can update users")
+		s.logger.WarnContext(ctx, "Permission denied for UpdateUser", "user_id", claims.UserID, "role_id", claims.RoleID, "target_user_id", id)
+		return Forbidden("only managers can update users")
 	}
 
-	if err := util.ValidateStruct(req); err != nil {
+	if err := validateStruct(req); err != nil {
 		return err
 	}
 
 	// Ensure the user exists before proceeding
-	_, err := s.repo.GetByID(ctx, id)
-	if err != nil {
+	existingUser, err := s.repo.GetByID(ctx, id)
+	if err != nil || existingUser == nil {
 		// This handles both db errors and the "not found" case from the repo
-		return fmt.Errorf("user with ID %d not found", id)
+		return NotFound("user with ID %d not found", id)
 	}
 
 	user := models.User{
 		ID:       id,
 		Username: req.Username,
 		Email:    req.Email,
-		RoleID:   req.RoleID,
+		RoleID:   existingUser.RoleID, // Roles only change through ChangeUserRole
 	}
 
 	if req.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
-			s.logger.Error("failed to hash password during user update", "error", err)
+			s.logger.ErrorContext(ctx, "failed to hash password during user update", "error", err)
 			return fmt.Errorf("internal server error")
 		}
 		user.PasswordHash = string(hashedPassword)
@@ -164,15 +177,18 @@ func (s *UserService) UpdateUser(ctx context.Context, id int, req dto.UpdateUser
 	err = s.repo.Update(ctx, user)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("user with ID %d not found during update", id)
+			return NotFound("user with ID %d not found during update", id)
 		}
 		return err
 	}
-	s.logger.Info("User updated successfully by manager", "manager_id", claims.UserID, "updated_user_id", id)
+	s.logger.InfoContext(ctx, "User updated successfully by manager", "manager_id", claims.UserID, "updated_user_id", id)
 	return nil
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
 	claims, ok := util.GetClaimsFromContext(ctx)
 	if !ok {
 		return errors.New("could not retrieve user claims from context")
@@ -181,17 +197,80 @@ func (s *UserService) DeleteUser(ctx context.Context, id int) error {
 	// --- PERMISSION CHECK ---
 	// Only Reception (Manager) can delete users.
 	if claims.RoleID != s.cfg.Roles.ReceptionID {
-		s.logger.Warn("Permission denied for DeleteUser", "user_id", claims.UserID, "role_id", claims.RoleID, "target_user_id", id)
-		return fmt.Errorf("forbidden: only managers can delete users")
+		s.logger.WarnContext(ctx, "Permission denied for DeleteUser", "user_id", claims.UserID, "role_id", claims.RoleID, "target_user_id", id)
+		return Forbidden("only managers can delete users")
 	}
 
 	err := s.repo.Delete(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("user with ID %d not found", id)
+			return NotFound("user with ID %d not found", id)
 		}
 		return err
 	}
-	s.logger.Info("User deleted successfully by manager", "manager_id", claims.UserID, "deleted_user_id", id)
+	s.logger.InfoContext(ctx, "User deleted successfully by manager", "manager_id", claims.UserID, "deleted_user_id", id)
 	return nil
 }
+
+// ChangeUserRole changes a user's role and records who made the change.
+func (s *UserService) ChangeUserRole(ctx context.Context, id int, req dto.ChangeRoleRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUserRole")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	// Only Reception (Manager) can change roles.
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for ChangeUserRole", "user_id", claims.UserID, "role_id", claims.RoleID, "target_user_id", id)
+		return Forbidden("only managers can change user roles")
+	}
+
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	if req.RoleID != s.cfg.Roles.SalesAgentID && req.RoleID != s.cfg.Roles.ReceptionID {
+		return Invalid("unknown role_id: %d", req.RoleID)
+	}
+	if id == claims.UserID {
+		return Forbidden("managers cannot change their own role")
+	}
+
+	var reason *string
+	if req.Reason != "" {
+		reason = &req.Reason
+	}
+
+	oldRoleID, err := s.repo.ChangeRole(ctx, id, req.RoleID, claims.UserID, reason)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return NotFound("user with ID %d not found", id)
+		}
+		s.logger.ErrorContext(ctx, "failed to change user role", "error", err, "target_user_id", id)
+		return err
+	}
+	s.logger.InfoContext(ctx, "User role changed by manager", "manager_id", claims.UserID, "target_user_id", id, "old_role_id", oldRoleID, "new_role_id", req.RoleID)
+	return nil
+}
+
+// GetRoleChanges returns the audited role history of a user.
+func (s *UserService) GetRoleChanges(ctx context.Context, id int) ([]models.RoleChange, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetRoleChanges")
+	defer span.End()
+
+	claims, ok := util.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("could not retrieve user claims from context")
+	}
+
+	// --- PERMISSION CHECK ---
+	if claims.RoleID != s.cfg.Roles.ReceptionID {
+		s.logger.WarnContext(ctx, "Permission denied for GetRoleChanges", "user_id", claims.UserID, "role_id", claims.RoleID, "target_user_id", id)
+		return nil, Forbidden("you do not have permission to view role changes")
+	}
+
+	return s.repo.GetRoleChanges(ctx, id)
+}
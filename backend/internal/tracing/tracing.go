@@ -0,0 +1,143 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are started per HTTP
+// request (api.TracingMiddleware), per service method and per SQL statement,
+// and flow down through the request context.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone = "none" // spans are created (and propagated) but not exported
+	ExporterOTLP = "otlp" // OTLP over HTTP; the endpoint defaults to OTEL_EXPORTER_OTLP_ENDPOINT
+	ExporterFile = "file" // one JSON document per span, appended to a local file
+)
+
+const instrumentationName = "crm-project"
+
+// maxQueryLength caps the SQL text attached to spans.
+const maxQueryLength = 2048
+
+// Options configures Setup.
+type Options struct {
+	Exporter       string
+	OTLPEndpoint   string  // host:port, or empty to use the OTEL_* environment variables
+	File           string  // Path for the file exporter
+	SampleRatio    float64 // Fraction of new traces to record; parents' decisions are honoured
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. The returned function flushes buffered spans and must be
+// called before the process exits.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	switch opts.Exporter {
+	case "", ExporterNone:
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if exporter, err = otlptracehttp.New(ctx, clientOpts...); err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+	case ExporterFile:
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening trace file: %w", err)
+		}
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(f)); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("creating file exporter: %w", err)
+		}
+		closer = f
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.ServiceVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Start starts a span as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL prepares a statement for a span: literals that could carry data
+// are replaced with '?', whitespace is collapsed and long statements are
+// truncated. Bind parameters ($1) are kept, their values are never recorded.
+func SanitizeSQL(query string) string {
+	q := stringLiteral.ReplaceAllString(query, "'?'")
+	q = numericLiteral.ReplaceAllStringFunc(q, func(s string) string {
+		if strings.HasPrefix(s, "$") {
+			return s
+		}
+		return "?"
+	})
+	q = strings.TrimSpace(whitespace.ReplaceAllString(q, " "))
+	if len(q) > maxQueryLength {
+		q = q[:maxQueryLength] + "..."
+	}
+	return q
+}
+
+// Operation returns the first keyword of a statement (SELECT, UPDATE, ...),
+// used as the span name.
+func Operation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -21,5 +21,3 @@ func AddClaimsToContext(ctx context.Context, claims *dto.Claims) context.Context
     return context.WithValue(ctx, userContextKey, claims)
 }
 
-
-const HardcodedJWTSecret = "my_super_secret_debug_key_12345"
@@ -0,0 +1,62 @@
+// File: internal/util/mergepatch.go
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// ErrInvalidMergePatch is returned when a merge patch document is not a JSON object.
+var ErrInvalidMergePatch = errors.New("merge patch must be a JSON object")
+
+// ApplyMergePatch applies an RFC 7396 JSON Merge Patch to doc and returns the
+// patched document. Members set to null are removed; objects are merged
+// recursively; every other value replaces the target member.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, ErrInvalidMergePatch
+	}
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+// MergePatchFields returns the sorted top-level member names a merge patch changes.
+func MergePatchFields(patch []byte) ([]string, error) {
+	var p map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &p); err != nil || p == nil {
+		return nil, ErrInvalidMergePatch
+	}
+	fields := make([]string, 0, len(p))
+	for name := range p {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergePatch(t[name], value)
+	}
+	return t
+}
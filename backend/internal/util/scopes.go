@@ -0,0 +1,77 @@
+// File: internal/util/scopes.go
+package util
+
+import (
+	"net/http"
+	"strings"
+)
+
+// API key scopes have the form "<resource>:<action>", e.g. "contacts:read".
+// "*" grants everything the key's owner can do, "<resource>:*" grants both
+// actions on one resource. A write scope implies read on the same resource.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAll   = "*"
+)
+
+// ScopeResources lists the route groups an API key can be scoped to.
+var ScopeResources = []string{
+	"contacts", "properties", "leads", "deals", "tasks",
+	"notes", "events", "comm-logs", "reports", "users",
+}
+
+// IsValidScope reports whether s is a well-formed scope.
+func IsValidScope(s string) bool {
+	if s == ScopeAll {
+		return true
+	}
+	resource, action, ok := strings.Cut(s, ":")
+	if !ok || !isScopeResource(resource) {
+		return false
+	}
+	return action == ScopeRead || action == ScopeWrite || action == ScopeAll
+}
+
+// ScopeAllows reports whether any of the granted scopes permits action on resource.
+func ScopeAllows(granted []string, resource, action string) bool {
+	for _, g := range granted {
+		if g == ScopeAll {
+			return true
+		}
+		r, a, _ := strings.Cut(g, ":")
+		if r != resource {
+			continue
+		}
+		if a == ScopeAll || a == action || (a == ScopeWrite && action == ScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestScope maps an API request to the resource and action used for scope
+// checks. The resource is the last known route group in the path, so
+// /contacts/5/notes is checked against "notes". It returns an empty resource
+// for routes that cannot be reached with an API key.
+func RequestScope(r *http.Request) (resource, action string) {
+	action = ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		action = ScopeRead
+	}
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		if isScopeResource(segment) {
+			resource = segment
+		}
+	}
+	return resource, action
+}
+
+func isScopeResource(name string) bool {
+	for _, r := range ScopeResources {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
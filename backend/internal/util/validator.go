@@ -2,37 +2,97 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
 // a single instance of the validator
-var validate = validator.New()
+var validate = newValidator()
 
-// ValidationError wraps the validator's errors into a single error.
-type ValidationError struct {
-	Errors []string
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Report fields by their JSON name, which is what API clients send.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// FieldError describes why a single field failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
 }
 
-func (v *ValidationError) Error() string {
-	return strings.Join(v.Errors, ", ")
+// FieldErrors is the error returned by ValidateStruct.
+type FieldErrors []FieldError
+
+func (f FieldErrors) Error() string {
+	msgs := make([]string, len(f))
+	for i, e := range f {
+		msgs[i] = e.Field + " " + e.Message
+	}
+	return strings.Join(msgs, ", ")
 }
 
 // ValidateStruct performs validation on a struct's fields based on its tags.
+// Failures are returned as FieldErrors, one entry per field.
 func ValidateStruct(s interface{}) error {
 	err := validate.Struct(s)
-	if err != nil {
-		var validationErrors ValidationError
-		for _, err := range err.(validator.ValidationErrors) {
-			// Create a more user-friendly error message
-			field := err.Field()
-			tag := err.Tag()
-			message := fmt.Sprintf("Field '%s': validation failed on the '%s' tag", field, tag)
-			validationErrors.Errors = append(validationErrors.Errors, message)
+	if err == nil {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+	fields := make(FieldErrors, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+	return fields
+}
+
+// fieldMessage turns a validator failure into a user-friendly message.
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
 		}
-		return &validationErrors
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed the '%s' rule", fe.Tag())
 	}
-	return nil
-}
\ No newline at end of file
+}
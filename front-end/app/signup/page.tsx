@@ -1,17 +1,15 @@
 "use client"
 
 import { useState } from "react"
-import { useRouter } from "next/navigation"
+import { useRouter, useSearchParams } from "next/navigation"
 import Link from "next/link"
 import { Button } from "@/components/ui/button"
 import { Input } from "@/components/ui/input"
 import { Label } from "@/components/ui/label"
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
 import { Alert, AlertDescription } from "@/components/ui/alert"
-import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select"
 import { api } from "@/lib/api"
 import { Building2, Eye, EyeOff } from "lucide-react"
-import { ROLE_SALES_AGENT } from "@/lib/auth"
 
 export default function SignupPage() {
   const [formData, setFormData] = useState({
@@ -26,6 +24,7 @@ export default function SignupPage() {
   const [error, setError] = useState("")
   const [success, setSuccess] = useState("")
   const router = useRouter()
+  const inviteToken = useSearchParams().get("invite") ?? ""
 
   const handleInputChange = (field: string, value: string) => {
     setFormData((prev) => ({ ...prev, [field]: value }))
@@ -37,6 +36,12 @@ export default function SignupPage() {
     setError("")
     setSuccess("")
 
+    if (!inviteToken) {
+      setError("Registration requires an invitation link from your manager")
+      setIsLoading(false)
+      return
+    }
+
     if (formData.password !== formData.confirmPassword) {
       setError("Passwords do not match")
       setIsLoading(false)
@@ -54,14 +59,14 @@ export default function SignupPage() {
         username: formData.username,
         email: formData.email,
         password: formData.password,
-        role_id: ROLE_SALES_AGENT, // Assign default role
+        invite_token: inviteToken, // Role is preset on the invitation
       })
       setSuccess("Account created successfully! Redirecting to login...")
       setTimeout(() => {
         router.push("/login")
       }, 2000)
     } catch (err) {
-      setError("Failed to create account. Please try again.")
+      setError("Failed to create account. Your invitation may have expired or already been used.")
     } finally {
       setIsLoading(false)
     }
@@ -221,7 +221,7 @@ class ApiClient {
     })
   }
 
-  async registerUser(userData: { username: string; email: string; password: string; role_id: number }): Promise<void> {
+  async registerUser(userData: { username: string; email: string; password: string; invite_token: string }): Promise<void> {
     return this.request("/auth/register", {
       method: "POST",
       headers: { "Content-Type": "application/json" },